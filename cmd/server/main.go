@@ -1,18 +1,32 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jhvc/backend/internal/config"
 	"github.com/jhvc/backend/internal/database"
+	"github.com/jhvc/backend/internal/mail"
 	"github.com/jhvc/backend/internal/middleware"
 	"github.com/jhvc/backend/internal/modules/auth"
 	"github.com/jhvc/backend/internal/modules/calculadora"
+	"github.com/jhvc/backend/internal/modules/catalogos"
+	"github.com/jhvc/backend/internal/modules/cfdi"
+	"github.com/jhvc/backend/internal/modules/contabilidad"
+	"github.com/jhvc/backend/internal/modules/diot"
+	"github.com/jhvc/backend/internal/modules/directorio"
+	"github.com/jhvc/backend/internal/modules/empresas"
+	"github.com/jhvc/backend/internal/modules/facturacion"
+	"github.com/jhvc/backend/internal/ratelimit"
+	"github.com/jhvc/backend/internal/validacion"
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -25,18 +39,100 @@ func main() {
 	}
 	defer db.Close()
 
-	if err := createTables(db); err != nil {
+	if err := createTables(db, cfg.AdminPassword); err != nil {
 		log.Fatal("Error creando tablas:", err)
 	}
 
 	authRepo := auth.NewRepository(db)
 	authService := auth.NewService(authRepo, cfg.JWTSecret)
+	authService.RequireAdminMFA(cfg.AdminRequireMFA)
+
+	// Límite de intentos de login y de verificación de licencias; con varias
+	// instancias el estado se comparte en Postgres
+	if cfg.RateLimitStore == "postgres" {
+		store := ratelimit.NewPostgresStore(db, 24*time.Hour)
+		go store.Iniciar(context.Background(), time.Hour)
+		authService.UseRateLimits(store)
+	} else {
+		authService.UseRateLimits(ratelimit.NewMemoriaStore(24 * time.Hour))
+	}
 	authHandler := auth.NewHandler(authService)
 
 	calcService := calculadora.NewService()
 	calcHandler := calculadora.NewHandler(calcService)
 
+	if err := validacion.RegistrarValidadores(); err != nil {
+		log.Fatal("Error registrando validadores:", err)
+	}
+	validacionHandler := validacion.NewHandler()
+
+	catalogosRepo := catalogos.NewRepository(db)
+	catalogosService := catalogos.NewService(catalogosRepo)
+	catalogosService.UsarBitacora(authService)
+	if err := catalogosService.CargarBase(); err != nil {
+		log.Println("⚠️  Error precargando catálogos SAT:", err)
+	}
+	catalogosHandler := catalogos.NewHandler(catalogosService)
+
+	cfdiRepo := cfdi.NewRepository(db)
+	cfdiService := cfdi.NewService(cfdiRepo)
+	cfdiHandler := cfdi.NewHandler(cfdiService)
+
+	directorioRepo := directorio.NewRepository(db)
+	directorioService := directorio.NewService(directorioRepo, catalogosService)
+	directorioHandler := directorio.NewHandler(directorioService)
+	calcService.UsarDirectorio(directorioService)
+
+	facturacionRepo := facturacion.NewRepository(db)
+	// Sin llave propia no se aceptan CSD: nunca se reutiliza JWT_SECRET, que
+	// tiene valor por defecto y al rotarlo dejaría ilegibles las llaves guardadas
+	var cifradorCSD *facturacion.Cifrador
+	if cfg.CSDKey == "" {
+		log.Println("⚠️  CSD_ENCRYPTION_KEY no configurada: la carga de CSD y el sellado quedan deshabilitados")
+	} else if cifradorCSD, err = facturacion.NewCifrador(cfg.CSDKey); err != nil {
+		log.Fatal("Error configurando cifrado de CSD:", err)
+	}
+	var stamper facturacion.Stamper
+	var canceler facturacion.Canceler
+	switch cfg.PACProvider {
+	case "sw":
+		stamper = facturacion.NewPACSW(cfg.PACURL, cfg.PACToken)
+	case "local":
+		pacLocal, err := facturacion.NewPACLocal()
+		if err != nil {
+			log.Fatal("Error iniciando PAC local:", err)
+		}
+		log.Println("⚠️  Timbrando con el PAC local de pruebas (los UUID no son válidos ante el SAT)")
+		stamper = pacLocal
+		canceler = facturacion.NewCancelerLocal(time.Minute)
+	}
+	if stamper != nil {
+		stamper = facturacion.ConReintentos(stamper, 3, 2*time.Second)
+	}
+	facturacionService := facturacion.NewService(facturacionRepo, calcService, catalogosService, cifradorCSD,
+		stamper, canceler, cfdiService, directorioService)
+	facturacionHandler := facturacion.NewHandler(facturacionService)
+
+	empresasRepo := empresas.NewRepository(db)
+	empresasService := empresas.NewService(empresasRepo, catalogosService)
+	empresasHandler := empresas.NewHandler(empresasService)
+
+	contabilidadRepo := contabilidad.NewRepository(db)
+	contabilidadService := contabilidad.NewService(contabilidadRepo, catalogosService, cfdiService)
+	contabilidadHandler := contabilidad.NewHandler(contabilidadService)
+	empresasService.UsarPlantilla(contabilidadService)
+
+	diotRepo := diot.NewRepository(db)
+	diotService := diot.NewService(diotRepo, cfdiService)
+	diotHandler := diot.NewHandler(diotService)
+
 	r := gin.Default()
+	// Sin proxies de confianza X-Forwarded-For se ignora; si no, cualquiera
+	// podría cambiar su IP y saltarse los límites de login y licencias
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("TRUSTED_PROXIES inválido:", err)
+	}
+	r.TrustedPlatform = cfg.TrustedPlatform
 	r.Use(corsMiddleware())
 
 	// Detectar el directorio base del proyecto
@@ -45,64 +141,239 @@ func main() {
 
 	log.Printf("📁 Sirviendo frontend desde: %s", frontendDist)
 
+	// Correos: plantillas en templates/email y cola en mail_outbox
+	plantillasCorreo, err := mail.CargarPlantillas(filepath.Join(baseDir, "templates", "email"))
+	if err != nil {
+		log.Fatal("Error cargando plantillas de correo:", err)
+	}
+	var mailSender mail.Sender
+	switch {
+	case cfg.SMTPHost != "":
+		mailSender = mail.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword, cfg.MailFrom)
+	case cfg.MailDir != "":
+		mailSender = mail.NewArchivoSender(cfg.MailDir, cfg.MailFrom)
+		log.Println("⚠️  SMTP no configurado: los correos se guardan en", cfg.MailDir)
+	case cfg.MailConsole:
+		// El log lleva los enlaces de restablecimiento y verificación
+		if !esURLLocal(cfg.AppURL) {
+			log.Fatal("MAIL_CONSOLE sólo se permite con APP_URL en localhost")
+		}
+		mailSender = mail.NewConsolaSender()
+		log.Println("⚠️  SMTP no configurado: los correos se escriben en el log")
+	}
+	mailOutbox := mail.NewOutbox(db, plantillasCorreo, mailSender)
+	authService.UseMailer(mailOutbox, cfg.AppURL)
+	if mailSender != nil {
+		go mailOutbox.Iniciar(context.Background(), 30*time.Second)
+	} else {
+		log.Println("⚠️  Correo no configurado (SMTP_HOST, MAIL_DIR o MAIL_CONSOLE): los correos quedan pendientes")
+	}
+
 	// Servir archivos estáticos del frontend
 	r.Static("/assets", filepath.Join(frontendDist, "assets"))
 	r.StaticFile("/vite.svg", filepath.Join(frontendDist, "vite.svg"))
 
-	// Endpoint temporal para resetear contraseña admin
-	r.GET("/reset-admin-password", func(c *gin.Context) {
-		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("admin123"), bcrypt.DefaultCost)
-		_, err := db.Exec(`UPDATE users SET password_hash = $1 WHERE email = 'admin@jhvc.com'`, string(hashedPassword))
-		if err != nil {
-			c.JSON(500, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(200, gin.H{"message": "Contraseña reseteada a admin123"})
-	})
-
 	api := r.Group("/api")
 	{
 		api.POST("/register", authHandler.Register)
 		api.POST("/login", authHandler.Login)
+		api.POST("/login/mfa", authHandler.LoginMFA)
+		api.POST("/refresh", authHandler.Refresh)
+		api.POST("/forgot-password", authHandler.ForgotPassword)
+		api.POST("/reset-password", authHandler.ResetPassword)
+		api.POST("/verify-email", authHandler.VerifyEmail)
 		api.POST("/verify-license", authHandler.VerifyProductLicense)
 
-		protected := api.Group("")
-		protected.Use(middleware.AuthMiddleware(authService))
+		// Rutas de la propia cuenta, disponibles aunque el email no esté verificado
+		account := api.Group("")
+		account.Use(middleware.AuthMiddleware(authService), middleware.RequireSession())
 		{
-			protected.GET("/profile", authHandler.GetProfile)
+			account.GET("/profile", authHandler.GetProfile)
+			account.POST("/logout", authHandler.Logout)
+			account.GET("/sessions", authHandler.GetSessions)
+			account.DELETE("/sessions", authHandler.RevokeAllSessions)
+			account.DELETE("/sessions/:id", authHandler.RevokeSession)
+			account.POST("/resend-verification", authHandler.ResendVerification)
+
+			account.POST("/mfa/setup", authHandler.SetupMFA)
+			account.POST("/mfa/confirm", authHandler.ConfirmMFA)
+			account.POST("/mfa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+			account.POST("/mfa/disable", authHandler.DisableMFA)
+		}
 
+		protected := api.Group("")
+		protected.Use(middleware.AuthMiddleware(authService), middleware.VerifiedMiddleware())
+		{
 			calc := protected.Group("/calculadora")
 			{
 				calc.GET("/configuraciones", calcHandler.GetConfiguraciones)
 				calc.GET("/calcular", calcHandler.Calcular)
+				calc.POST("/conceptos", calcHandler.CalcularConceptos)
+			}
+
+			valid := protected.Group("/validacion")
+			{
+				valid.GET("/rfc/:rfc", validacionHandler.ValidarRFC)
+				valid.GET("/curp/:curp", validacionHandler.ValidarCURP)
+			}
+
+			cat := protected.Group("/catalogos")
+			{
+				cat.GET("", catalogosHandler.GetCatalogos)
+				cat.GET("/compatibilidad/uso-cfdi", catalogosHandler.CompatibilidadUsoCFDI)
+				cat.GET("/:catalogo", catalogosHandler.Buscar)
+				cat.GET("/:catalogo/:clave", catalogosHandler.Obtener)
+			}
+
+			// Los comprobantes y la facturación son de una empresa, indicada en
+			// el encabezado X-Company-ID
+			comprobantes := protected.Group("/cfdi")
+			comprobantes.Use(middleware.EmpresaMiddleware(empresasService))
+			{
+				comprobantes.POST("", cfdiHandler.Ingerir)
+				comprobantes.GET("", cfdiHandler.GetCFDIs)
+				comprobantes.GET("/ppd/pendientes", cfdiHandler.GetPendientesPPD)
+				comprobantes.GET("/:uuid", cfdiHandler.GetCFDI)
+				comprobantes.GET("/:uuid/xml", cfdiHandler.GetXML)
+				comprobantes.GET("/:uuid/pagos", cfdiHandler.GetHistorialPagos)
+			}
+
+			fact := protected.Group("/facturacion")
+			fact.Use(middleware.EmpresaMiddleware(empresasService))
+			{
+				fact.POST("/facturas", facturacionHandler.Generar)
+				fact.GET("/facturas", facturacionHandler.GetFacturas)
+				fact.GET("/facturas/:id", facturacionHandler.GetFactura)
+				fact.GET("/facturas/:id/xml", facturacionHandler.GetXML)
+				fact.POST("/facturas/:id/sellar", facturacionHandler.Sellar)
+				fact.POST("/facturas/:id/timbrar", facturacionHandler.Timbrar)
+				fact.POST("/facturas/:id/cancelar", facturacionHandler.Cancelar)
+				fact.POST("/facturas/:id/cancelacion/consultar", facturacionHandler.ConsultarCancelacion)
+				fact.GET("/facturas/:id/cancelaciones", facturacionHandler.GetCancelaciones)
+				fact.POST("/csd", facturacionHandler.CargarCSD)
+				fact.GET("/csd", facturacionHandler.GetCSDs)
+				fact.DELETE("/csd/:id", facturacionHandler.EliminarCSD)
+			}
+
+			protected.POST("/empresas", middleware.RequireSession(), empresasHandler.Crear)
+			protected.GET("/empresas", middleware.RequireSession(), empresasHandler.GetEmpresas)
+
+			empresa := protected.Group("/empresas/:empresaId")
+			empresa.Use(middleware.EmpresaMiddleware(empresasService))
+			{
+				empresa.GET("", empresasHandler.GetEmpresa)
+				empresa.PUT("", middleware.RequiereRol(empresas.RolPropietario), empresasHandler.Actualizar)
+
+				empresa.GET("/miembros", empresasHandler.GetMiembros)
+				empresa.POST("/miembros", middleware.RequiereRol(empresas.RolPropietario), empresasHandler.AgregarMiembro)
+				empresa.PUT("/miembros/:userId", middleware.RequiereRol(empresas.RolPropietario), empresasHandler.ActualizarMiembro)
+				empresa.DELETE("/miembros/:userId", empresasHandler.EliminarMiembro)
+
+				empresa.GET("/licencias", authHandler.GetEmpresaLicenses)
+
+				empresa.GET("/contactos", directorioHandler.GetContactos)
+				empresa.POST("/contactos", directorioHandler.CrearContacto)
+				empresa.POST("/contactos/importar", directorioHandler.ImportarCSV)
+				empresa.GET("/contactos/exportar", directorioHandler.ExportarCSV)
+				empresa.GET("/contactos/:contactoId", directorioHandler.GetContacto)
+				empresa.GET("/contactos/:contactoId/receptor", directorioHandler.GetReceptor)
+				empresa.PUT("/contactos/:contactoId", directorioHandler.ActualizarContacto)
+				empresa.DELETE("/contactos/:contactoId", directorioHandler.EliminarContacto)
+
+				empresa.POST("/calculadora/conceptos", calcHandler.CalcularConceptos)
+
+				empresa.GET("/cuentas", contabilidadHandler.GetCuentas)
+				empresa.POST("/cuentas", contabilidadHandler.CrearCuenta)
+				empresa.POST("/cuentas/plantilla", contabilidadHandler.ImportarPlantilla)
+				empresa.GET("/cuentas/:cuentaId", contabilidadHandler.GetCuenta)
+				empresa.PUT("/cuentas/:cuentaId", contabilidadHandler.ActualizarCuenta)
+				empresa.DELETE("/cuentas/:cuentaId", contabilidadHandler.EliminarCuenta)
+
+				empresa.GET("/polizas", contabilidadHandler.GetPolizas)
+				empresa.POST("/polizas", contabilidadHandler.CrearPoliza)
+				empresa.GET("/polizas/:polizaId", contabilidadHandler.GetPoliza)
+				empresa.PUT("/polizas/:polizaId", contabilidadHandler.ActualizarPoliza)
+				empresa.DELETE("/polizas/:polizaId", contabilidadHandler.EliminarPoliza)
+				empresa.POST("/polizas/:polizaId/registrar", contabilidadHandler.RegistrarPoliza)
+				empresa.POST("/polizas/registrar", contabilidadHandler.RegistrarPolizas)
+				empresa.POST("/polizas/:polizaId/revertir", contabilidadHandler.RevertirPoliza)
+
+				empresa.GET("/polizas-automaticas/configuracion", contabilidadHandler.GetConfiguracionPolizas)
+				empresa.PUT("/polizas-automaticas/configuracion", contabilidadHandler.GuardarConfiguracionPolizas)
+				empresa.POST("/polizas-automaticas/generar", contabilidadHandler.GenerarPolizasCFDI)
+				empresa.GET("/reglas-poliza", contabilidadHandler.GetReglas)
+				empresa.POST("/reglas-poliza", contabilidadHandler.CrearRegla)
+				empresa.PUT("/reglas-poliza/:reglaId", contabilidadHandler.ActualizarRegla)
+				empresa.DELETE("/reglas-poliza/:reglaId", contabilidadHandler.EliminarRegla)
+
+				empresa.GET("/periodos", contabilidadHandler.GetPeriodos)
+				empresa.POST("/periodos/:ejercicio/:mes/cerrar", contabilidadHandler.CerrarPeriodo)
+				empresa.POST("/periodos/:ejercicio/:mes/reabrir", contabilidadHandler.ReabrirPeriodo)
+
+				empresa.GET("/contabilidad-electronica/:ejercicio/:mes", contabilidadHandler.DescargarContabilidadElectronica)
+
+				empresa.GET("/reportes/balanza", contabilidadHandler.BalanzaReporte)
+				empresa.GET("/reportes/balance-general", contabilidadHandler.BalanceGeneral)
+				empresa.GET("/reportes/estado-resultados", contabilidadHandler.EstadoResultados)
+				empresa.GET("/reportes/auxiliar/:cuentaId", contabilidadHandler.AuxiliarCuenta)
+
+				empresa.GET("/diot/proveedores", diotHandler.GetProveedores)
+				empresa.PUT("/diot/proveedores", diotHandler.GuardarProveedor)
+				empresa.DELETE("/diot/proveedores/:proveedorId", diotHandler.EliminarProveedor)
+				empresa.GET("/diot/:ejercicio/:mes", diotHandler.GetDIOT)
+				empresa.GET("/diot/:ejercicio/:mes/txt", diotHandler.DescargarDIOT)
 			}
 		}
 
+		// Cada ruta admin exige su permiso; super_admin los tiene todos
 		admin := api.Group("/admin")
 		admin.Use(middleware.AuthMiddleware(authService))
 		admin.Use(middleware.AdminMiddleware(authService))
 		{
-			admin.GET("/users", authHandler.GetAllUsers)
-			admin.PUT("/users/:id/status", authHandler.UpdateUserStatus)
+			perm := middleware.RequirePermission
+
+			admin.GET("/users", perm(auth.PermUsersRead), authHandler.GetAllUsers)
+			admin.PUT("/users/:id/status", perm(auth.PermUsersManage), authHandler.UpdateUserStatus)
+			admin.DELETE("/users/:id/sessions", perm(auth.PermUsersManage), authHandler.RevokeUserSessions)
+			admin.DELETE("/users/:id/mfa", perm(auth.PermUsersManage), authHandler.ResetUserMFA)
+			admin.POST("/users/:id/unlock", perm(auth.PermUsersManage), authHandler.UnlockUser)
+			admin.PUT("/users/:id/roles", perm(auth.PermRolesManage), authHandler.SetUserRoles)
+
+			admin.GET("/roles", perm(auth.PermRolesManage), authHandler.GetRoles)
+			admin.GET("/permissions", perm(auth.PermRolesManage), authHandler.GetPermissions)
+			admin.POST("/roles", perm(auth.PermRolesManage), authHandler.CreateRole)
+			admin.PUT("/roles/:id", perm(auth.PermRolesManage), authHandler.UpdateRole)
+			admin.DELETE("/roles/:id", perm(auth.PermRolesManage), authHandler.DeleteRole)
+
+			admin.POST("/codes", perm(auth.PermCodesCreate), authHandler.CreateInvitationCode)
+			admin.GET("/codes", perm(auth.PermCodesRead), authHandler.GetAllInvitationCodes)
+			admin.PUT("/codes/:id/status", perm(auth.PermCodesCreate), authHandler.UpdateCodeStatus)
 
-			admin.POST("/codes", authHandler.CreateInvitationCode)
-			admin.GET("/codes", authHandler.GetAllInvitationCodes)
-			admin.PUT("/codes/:id/status", authHandler.UpdateCodeStatus)
+			admin.POST("/product-licenses", perm(auth.PermLicensesWrite), authHandler.CreateProductLicense)
+			admin.GET("/product-licenses", perm(auth.PermLicensesRead), authHandler.GetAllProductLicenses)
+			admin.PUT("/product-licenses/:id", perm(auth.PermLicensesWrite), authHandler.UpdateProductLicense)
+			admin.PUT("/product-licenses/:id/status", perm(auth.PermLicensesWrite), authHandler.UpdateProductLicenseStatus)
+			admin.DELETE("/product-licenses/:id", perm(auth.PermLicensesWrite), authHandler.DeleteProductLicense)
 
-			admin.POST("/product-licenses", authHandler.CreateProductLicense)
-			admin.GET("/product-licenses", authHandler.GetAllProductLicenses)
-			admin.PUT("/product-licenses/:id", authHandler.UpdateProductLicense)
-			admin.PUT("/product-licenses/:id/status", authHandler.UpdateProductLicenseStatus)
-			admin.DELETE("/product-licenses/:id", authHandler.DeleteProductLicense)
+			admin.GET("/licenses/:id/devices", perm(auth.PermLicensesRead), authHandler.GetLicenseDevices)
+			admin.DELETE("/licenses/:id/devices/:deviceId", perm(auth.PermLicensesWrite), authHandler.RemoveLicenseDevice)
 
-			admin.GET("/licenses/:id/devices", authHandler.GetLicenseDevices)
-			admin.DELETE("/licenses/:id/devices/:deviceId", authHandler.RemoveLicenseDevice)
+			admin.GET("/licenses/:id/modules", perm(auth.PermLicensesRead), authHandler.GetLicenseModules)
+			admin.POST("/licenses/:id/modules", perm(auth.PermLicensesWrite), authHandler.AddLicenseModule)
+			admin.DELETE("/licenses/:id/modules/:moduleId", perm(auth.PermLicensesWrite), authHandler.RemoveLicenseModule)
 
-			admin.GET("/licenses/:id/modules", authHandler.GetLicenseModules)
-			admin.POST("/licenses/:id/modules", authHandler.AddLicenseModule)
-			admin.DELETE("/licenses/:id/modules/:moduleId", authHandler.RemoveLicenseModule)
+			admin.GET("/products", perm(auth.PermLicensesRead), authHandler.GetProducts)
 
-			admin.GET("/products", authHandler.GetProducts)
+			admin.POST("/catalogos/:catalogo/importar", perm(auth.PermCatalogsManage), catalogosHandler.Importar)
+			admin.GET("/catalogos/:catalogo/versiones", perm(auth.PermCatalogsManage), catalogosHandler.GetVersiones)
+			admin.PUT("/catalogos/:catalogo/versiones/:id/activar", perm(auth.PermCatalogsManage), catalogosHandler.ActivarVersion)
+
+			admin.POST("/api-keys", perm(auth.PermAPIKeysManage), authHandler.CreateAPIKey)
+			admin.GET("/api-keys", perm(auth.PermAPIKeysManage), authHandler.GetAPIKeys)
+			admin.DELETE("/api-keys/:id", perm(auth.PermAPIKeysManage), authHandler.RevokeAPIKey)
+
+			admin.GET("/audit", perm(auth.PermReportsRead), authHandler.GetAuditLog)
+			admin.GET("/audit/export", perm(auth.PermReportsRead), authHandler.ExportAuditLog)
 		}
 	}
 
@@ -139,11 +410,24 @@ func getProjectRoot() string {
 	return "."
 }
 
+// esURLLocal indica si la URL pública apunta a la máquina local
+func esURLLocal(appURL string) bool {
+	u, err := url.Parse(appURL)
+	if err != nil {
+		return false
+	}
+	switch u.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}
+
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type,Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type,Authorization,X-Company-ID,X-API-Key")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -152,7 +436,7 @@ func corsMiddleware() gin.HandlerFunc {
 	}
 }
 
-func createTables(db *sql.DB) error {
+func createTables(db *sql.DB, adminPassword string) error {
 	schema := `
     CREATE TABLE IF NOT EXISTS users (
         id SERIAL PRIMARY KEY,
@@ -162,7 +446,6 @@ func createTables(db *sql.DB) error {
         company_name VARCHAR(255),
         phone VARCHAR(20),
         is_active BOOLEAN DEFAULT true,
-        is_admin BOOLEAN DEFAULT false,
         created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
     );
     
@@ -231,13 +514,510 @@ func createTables(db *sql.DB) error {
         is_active BOOLEAN DEFAULT true,
         created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
     );
+
+    CREATE TABLE IF NOT EXISTS sat_catalogo_versiones (
+        id SERIAL PRIMARY KEY,
+        catalogo VARCHAR(50) NOT NULL,
+        version VARCHAR(50) NOT NULL,
+        registros INTEGER DEFAULT 0,
+        is_current BOOLEAN DEFAULT false,
+        imported_by INTEGER REFERENCES users(id),
+        imported_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE(catalogo, version)
+    );
+
+    CREATE TABLE IF NOT EXISTS sat_catalogo_entradas (
+        id SERIAL PRIMARY KEY,
+        version_id INTEGER REFERENCES sat_catalogo_versiones(id) ON DELETE CASCADE,
+        catalogo VARCHAR(50) NOT NULL,
+        clave VARCHAR(50) NOT NULL,
+        descripcion TEXT NOT NULL DEFAULT '',
+        datos JSONB DEFAULT '{}',
+        vigencia_desde DATE,
+        vigencia_hasta DATE,
+        UNIQUE(version_id, clave)
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_sat_catalogo_entradas_texto ON sat_catalogo_entradas
+        USING GIN (to_tsvector('spanish', descripcion || ' ' || COALESCE(datos->>'palabras_similares', '')));
+
+    CREATE TABLE IF NOT EXISTS cfdis (
+        id SERIAL PRIMARY KEY,
+        user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+        uuid VARCHAR(36) NOT NULL,
+        version VARCHAR(5) NOT NULL,
+        tipo_comprobante VARCHAR(1) NOT NULL,
+        serie VARCHAR(25) NOT NULL DEFAULT '',
+        folio VARCHAR(40) NOT NULL DEFAULT '',
+        fecha TIMESTAMP NOT NULL,
+        emisor_rfc VARCHAR(13) NOT NULL,
+        emisor_nombre VARCHAR(300) NOT NULL DEFAULT '',
+        receptor_rfc VARCHAR(13) NOT NULL,
+        receptor_nombre VARCHAR(300) NOT NULL DEFAULT '',
+        uso_cfdi VARCHAR(4) NOT NULL DEFAULT '',
+        metodo_pago VARCHAR(3) NOT NULL DEFAULT '',
+        forma_pago VARCHAR(2) NOT NULL DEFAULT '',
+        moneda VARCHAR(3) NOT NULL DEFAULT 'MXN',
+        tipo_cambio NUMERIC(18,6) NOT NULL DEFAULT 0,
+        subtotal NUMERIC(18,2) NOT NULL DEFAULT 0,
+        total NUMERIC(18,2) NOT NULL DEFAULT 0,
+        xml TEXT NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE(user_id, uuid)
+    );
+
+    CREATE TABLE IF NOT EXISTS cfdi_pagos_doctos (
+        id SERIAL PRIMARY KEY,
+        user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+        pago_cfdi_id INTEGER REFERENCES cfdis(id) ON DELETE CASCADE,
+        pago_uuid VARCHAR(36) NOT NULL,
+        docto_uuid VARCHAR(36) NOT NULL,
+        docto_cfdi_id INTEGER REFERENCES cfdis(id) ON DELETE SET NULL,
+        fecha_pago TIMESTAMP NOT NULL,
+        forma_pago VARCHAR(2),
+        moneda_dr VARCHAR(3),
+        equivalencia_dr NUMERIC(18,6),
+        num_parcialidad INTEGER NOT NULL DEFAULT 1,
+        imp_saldo_ant NUMERIC(18,2) NOT NULL DEFAULT 0,
+        imp_pagado NUMERIC(18,2) NOT NULL DEFAULT 0,
+        imp_saldo_insoluto NUMERIC(18,2) NOT NULL DEFAULT 0,
+        UNIQUE(pago_cfdi_id, docto_uuid, num_parcialidad)
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_cfdi_pagos_doctos_docto ON cfdi_pagos_doctos(user_id, docto_uuid);
+
+    CREATE TABLE IF NOT EXISTS empresas (
+        id SERIAL PRIMARY KEY,
+        user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+        rfc VARCHAR(13) NOT NULL,
+        razon_social VARCHAR(300) NOT NULL,
+        regimen_fiscal VARCHAR(3) NOT NULL,
+        codigo_postal VARCHAR(5) NOT NULL,
+        is_active BOOLEAN DEFAULT true,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE(user_id, rfc)
+    );
+
+    CREATE TABLE IF NOT EXISTS cuentas_contables (
+        id SERIAL PRIMARY KEY,
+        empresa_id INTEGER REFERENCES empresas(id) ON DELETE CASCADE,
+        codigo VARCHAR(30) NOT NULL,
+        nombre VARCHAR(200) NOT NULL,
+        naturaleza CHAR(1) NOT NULL CHECK (naturaleza IN ('D', 'A')),
+        nivel INTEGER NOT NULL DEFAULT 1,
+        padre_id INTEGER REFERENCES cuentas_contables(id),
+        codigo_agrupador VARCHAR(10) NOT NULL,
+        is_active BOOLEAN DEFAULT true,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE(empresa_id, codigo)
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_cuentas_contables_padre ON cuentas_contables(padre_id);
+
+    CREATE TABLE IF NOT EXISTS polizas (
+        id SERIAL PRIMARY KEY,
+        empresa_id INTEGER REFERENCES empresas(id) ON DELETE CASCADE,
+        tipo VARCHAR(10) NOT NULL CHECK (tipo IN ('ingreso', 'egreso', 'diario')),
+        numero INTEGER,
+        fecha DATE NOT NULL,
+        concepto VARCHAR(300) NOT NULL,
+        estado VARCHAR(20) NOT NULL DEFAULT 'borrador',
+        revierte_a INTEGER REFERENCES polizas(id),
+        revertida_por INTEGER REFERENCES polizas(id),
+        created_by INTEGER REFERENCES users(id),
+        registrada_at TIMESTAMP,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_polizas_empresa_fecha ON polizas(empresa_id, fecha);
+
+    CREATE TABLE IF NOT EXISTS poliza_movimientos (
+        id SERIAL PRIMARY KEY,
+        poliza_id INTEGER REFERENCES polizas(id) ON DELETE CASCADE,
+        orden INTEGER NOT NULL,
+        cuenta_id INTEGER NOT NULL REFERENCES cuentas_contables(id),
+        concepto VARCHAR(300) NOT NULL DEFAULT '',
+        cargo NUMERIC(18,2) NOT NULL DEFAULT 0,
+        abono NUMERIC(18,2) NOT NULL DEFAULT 0,
+        uuid VARCHAR(36),
+        CHECK (cargo >= 0 AND abono >= 0)
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_poliza_movimientos_cuenta ON poliza_movimientos(cuenta_id);
+
+    CREATE TABLE IF NOT EXISTS periodos_contables (
+        empresa_id INTEGER REFERENCES empresas(id) ON DELETE CASCADE,
+        ejercicio INTEGER NOT NULL,
+        mes INTEGER NOT NULL CHECK (mes BETWEEN 1 AND 12),
+        cerrado BOOLEAN NOT NULL DEFAULT false,
+        cerrado_at TIMESTAMP,
+        cerrado_por INTEGER REFERENCES users(id),
+        PRIMARY KEY (empresa_id, ejercicio, mes)
+    );
+
+    ALTER TABLE polizas ADD COLUMN IF NOT EXISTS cfdi_uuid VARCHAR(36);
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_polizas_cfdi ON polizas(empresa_id, cfdi_uuid) WHERE cfdi_uuid IS NOT NULL;
+
+    CREATE TABLE IF NOT EXISTS configuracion_polizas (
+        empresa_id INTEGER PRIMARY KEY REFERENCES empresas(id) ON DELETE CASCADE,
+        cuenta_clientes_id INTEGER REFERENCES cuentas_contables(id) ON DELETE SET NULL,
+        cuenta_proveedores_id INTEGER REFERENCES cuentas_contables(id) ON DELETE SET NULL,
+        cuenta_ingresos_id INTEGER REFERENCES cuentas_contables(id) ON DELETE SET NULL,
+        cuenta_gastos_id INTEGER REFERENCES cuentas_contables(id) ON DELETE SET NULL,
+        cuenta_iva_trasladado_id INTEGER REFERENCES cuentas_contables(id) ON DELETE SET NULL,
+        cuenta_iva_acreditable_id INTEGER REFERENCES cuentas_contables(id) ON DELETE SET NULL,
+        cuenta_iva_retenido_por_pagar_id INTEGER REFERENCES cuentas_contables(id) ON DELETE SET NULL,
+        cuenta_isr_retenido_por_pagar_id INTEGER REFERENCES cuentas_contables(id) ON DELETE SET NULL,
+        cuenta_iva_retenido_a_favor_id INTEGER REFERENCES cuentas_contables(id) ON DELETE SET NULL,
+        cuenta_isr_retenido_a_favor_id INTEGER REFERENCES cuentas_contables(id) ON DELETE SET NULL,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE TABLE IF NOT EXISTS reglas_poliza (
+        id SERIAL PRIMARY KEY,
+        empresa_id INTEGER REFERENCES empresas(id) ON DELETE CASCADE,
+        nombre VARCHAR(100) NOT NULL,
+        prioridad INTEGER NOT NULL DEFAULT 100,
+        aplica_a VARCHAR(10) NOT NULL CHECK (aplica_a IN ('emitidos', 'recibidos', 'ambos')),
+        rfc VARCHAR(13) NOT NULL DEFAULT '',
+        clave_prod_serv VARCHAR(8) NOT NULL DEFAULT '',
+        uso_cfdi VARCHAR(4) NOT NULL DEFAULT '',
+        cuenta_id INTEGER NOT NULL REFERENCES cuentas_contables(id) ON DELETE CASCADE,
+        is_active BOOLEAN DEFAULT true,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_reglas_poliza_empresa ON reglas_poliza(empresa_id, prioridad);
+
+    CREATE TABLE IF NOT EXISTS diot_proveedores (
+        id SERIAL PRIMARY KEY,
+        empresa_id INTEGER REFERENCES empresas(id) ON DELETE CASCADE,
+        rfc VARCHAR(13) NOT NULL,
+        nombre VARCHAR(300) NOT NULL DEFAULT '',
+        tipo_tercero VARCHAR(2) NOT NULL,
+        tipo_operacion VARCHAR(2) NOT NULL,
+        id_fiscal VARCHAR(40),
+        nombre_extranjero VARCHAR(43),
+        pais VARCHAR(2),
+        nacionalidad VARCHAR(40),
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE(empresa_id, rfc, nombre)
+    );
+
+    CREATE TABLE IF NOT EXISTS contactos (
+        id SERIAL PRIMARY KEY,
+        empresa_id INTEGER REFERENCES empresas(id) ON DELETE CASCADE,
+        tipo VARCHAR(10) NOT NULL DEFAULT 'cliente',
+        rfc VARCHAR(13) NOT NULL,
+        razon_social VARCHAR(300) NOT NULL,
+        regimen_fiscal VARCHAR(3) NOT NULL,
+        codigo_postal VARCHAR(5) NOT NULL,
+        uso_cfdi VARCHAR(4),
+        email VARCHAR(255),
+        is_active BOOLEAN DEFAULT true,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_contactos_rfc ON contactos(empresa_id, rfc,
+        (CASE WHEN rfc IN ('XAXX010101000', 'XEXX010101000') THEN UPPER(razon_social) ELSE '' END));
+
+    CREATE TABLE IF NOT EXISTS facturas (
+        id SERIAL PRIMARY KEY,
+        user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+        serie VARCHAR(25) NOT NULL DEFAULT '',
+        folio VARCHAR(40) NOT NULL DEFAULT '',
+        fecha TIMESTAMP NOT NULL,
+        emisor_rfc VARCHAR(13) NOT NULL,
+        receptor_rfc VARCHAR(13) NOT NULL,
+        receptor_nombre VARCHAR(300) NOT NULL DEFAULT '',
+        moneda VARCHAR(3) NOT NULL DEFAULT 'MXN',
+        subtotal NUMERIC(18,2) NOT NULL DEFAULT 0,
+        total NUMERIC(18,2) NOT NULL DEFAULT 0,
+        estado VARCHAR(20) NOT NULL DEFAULT 'borrador',
+        comprobante JSONB NOT NULL,
+        xml TEXT NOT NULL,
+        cadena_original TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    ALTER TABLE facturas ADD COLUMN IF NOT EXISTS no_certificado VARCHAR(20);
+    ALTER TABLE facturas ADD COLUMN IF NOT EXISTS uuid VARCHAR(36);
+    ALTER TABLE facturas ADD COLUMN IF NOT EXISTS fecha_timbrado TIMESTAMP;
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_facturas_uuid ON facturas(uuid) WHERE uuid IS NOT NULL;
+
+    CREATE TABLE IF NOT EXISTS cancelaciones (
+        id SERIAL PRIMARY KEY,
+        factura_id INTEGER REFERENCES facturas(id) ON DELETE CASCADE,
+        user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+        uuid VARCHAR(36) NOT NULL,
+        motivo VARCHAR(2) NOT NULL,
+        folio_sustitucion VARCHAR(36),
+        estado VARCHAR(30) NOT NULL,
+        mensaje TEXT,
+        acuse TEXT,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_cancelaciones_factura ON cancelaciones(factura_id);
+
+    CREATE TABLE IF NOT EXISTS cancelacion_eventos (
+        id SERIAL PRIMARY KEY,
+        cancelacion_id INTEGER REFERENCES cancelaciones(id) ON DELETE CASCADE,
+        estado VARCHAR(30) NOT NULL,
+        mensaje TEXT,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE TABLE IF NOT EXISTS csd_certificados (
+        id SERIAL PRIMARY KEY,
+        user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+        rfc VARCHAR(13) NOT NULL,
+        no_certificado VARCHAR(20) NOT NULL,
+        razon_social VARCHAR(300) NOT NULL DEFAULT '',
+        certificado BYTEA NOT NULL,
+        llave_cifrada BYTEA NOT NULL,
+        valido_desde TIMESTAMP NOT NULL,
+        valido_hasta TIMESTAMP NOT NULL,
+        activo BOOLEAN DEFAULT true,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE(user_id, no_certificado)
+    );
+
+    CREATE TABLE IF NOT EXISTS empresa_miembros (
+        id SERIAL PRIMARY KEY,
+        empresa_id INTEGER REFERENCES empresas(id) ON DELETE CASCADE,
+        user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+        rol VARCHAR(20) NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE(empresa_id, user_id)
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_empresa_miembros_user ON empresa_miembros(user_id);
+
+    ALTER TABLE cfdis ADD COLUMN IF NOT EXISTS empresa_id INTEGER REFERENCES empresas(id) ON DELETE CASCADE;
+    UPDATE cfdis c SET empresa_id = e.id FROM empresas e
+    WHERE c.empresa_id IS NULL AND e.user_id = c.user_id AND e.rfc IN (c.emisor_rfc, c.receptor_rfc);
+    UPDATE cfdis t SET empresa_id = (SELECT MIN(e.id) FROM empresas e WHERE e.user_id = t.user_id)
+    WHERE t.empresa_id IS NULL AND (SELECT COUNT(*) FROM empresas e WHERE e.user_id = t.user_id) = 1;
+    ALTER TABLE cfdis DROP CONSTRAINT IF EXISTS cfdis_user_id_uuid_key;
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_cfdis_empresa_uuid ON cfdis(empresa_id, uuid);
+
+    ALTER TABLE cfdi_pagos_doctos ADD COLUMN IF NOT EXISTS empresa_id INTEGER REFERENCES empresas(id) ON DELETE CASCADE;
+    UPDATE cfdi_pagos_doctos p SET empresa_id = c.empresa_id FROM cfdis c
+    WHERE p.empresa_id IS NULL AND c.id = p.pago_cfdi_id;
+    CREATE INDEX IF NOT EXISTS idx_cfdi_pagos_doctos_empresa ON cfdi_pagos_doctos(empresa_id, docto_uuid);
+
+    ALTER TABLE facturas ADD COLUMN IF NOT EXISTS empresa_id INTEGER REFERENCES empresas(id) ON DELETE CASCADE;
+    UPDATE facturas f SET empresa_id = e.id FROM empresas e
+    WHERE f.empresa_id IS NULL AND e.user_id = f.user_id AND e.rfc = f.emisor_rfc;
+    UPDATE facturas t SET empresa_id = (SELECT MIN(e.id) FROM empresas e WHERE e.user_id = t.user_id)
+    WHERE t.empresa_id IS NULL AND (SELECT COUNT(*) FROM empresas e WHERE e.user_id = t.user_id) = 1;
+    CREATE INDEX IF NOT EXISTS idx_facturas_empresa ON facturas(empresa_id, created_at);
+
+    ALTER TABLE cancelaciones ADD COLUMN IF NOT EXISTS empresa_id INTEGER REFERENCES empresas(id) ON DELETE CASCADE;
+    UPDATE cancelaciones c SET empresa_id = f.empresa_id FROM facturas f
+    WHERE c.empresa_id IS NULL AND f.id = c.factura_id;
+
+    ALTER TABLE csd_certificados ADD COLUMN IF NOT EXISTS empresa_id INTEGER REFERENCES empresas(id) ON DELETE CASCADE;
+    UPDATE csd_certificados s SET empresa_id = e.id FROM empresas e
+    WHERE s.empresa_id IS NULL AND e.user_id = s.user_id AND e.rfc = s.rfc;
+    UPDATE csd_certificados t SET empresa_id = (SELECT MIN(e.id) FROM empresas e WHERE e.user_id = t.user_id)
+    WHERE t.empresa_id IS NULL AND (SELECT COUNT(*) FROM empresas e WHERE e.user_id = t.user_id) = 1;
+    ALTER TABLE csd_certificados DROP CONSTRAINT IF EXISTS csd_certificados_user_id_no_certificado_key;
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_csd_empresa_certificado ON csd_certificados(empresa_id, no_certificado);
+
+    ALTER TABLE product_licenses ADD COLUMN IF NOT EXISTS empresa_id INTEGER REFERENCES empresas(id) ON DELETE SET NULL;
+
+    ALTER TABLE sessions ADD COLUMN IF NOT EXISTS family_id VARCHAR(64);
+    ALTER TABLE sessions ADD COLUMN IF NOT EXISTS user_agent TEXT;
+    ALTER TABLE sessions ADD COLUMN IF NOT EXISTS used_at TIMESTAMP;
+    ALTER TABLE sessions ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMP;
+    DELETE FROM sessions WHERE family_id IS NULL;
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_token ON sessions(token);
+    CREATE INDEX IF NOT EXISTS idx_sessions_family ON sessions(family_id);
+
+    CREATE TABLE IF NOT EXISTS password_resets (
+        id SERIAL PRIMARY KEY,
+        user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+        token VARCHAR(64) UNIQUE NOT NULL,
+        ip_address VARCHAR(45),
+        expires_at TIMESTAMP NOT NULL,
+        used_at TIMESTAMP,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE TABLE IF NOT EXISTS email_verifications (
+        id SERIAL PRIMARY KEY,
+        user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+        token VARCHAR(64) UNIQUE NOT NULL,
+        expires_at TIMESTAMP NOT NULL,
+        used_at TIMESTAMP,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE TABLE IF NOT EXISTS user_mfa (
+        user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+        secret VARCHAR(64) NOT NULL,
+        confirmed_at TIMESTAMP,
+        last_step BIGINT NOT NULL DEFAULT 0,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+        id SERIAL PRIMARY KEY,
+        user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+        code_hash VARCHAR(64) NOT NULL,
+        used_at TIMESTAMP,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user ON mfa_recovery_codes(user_id);
+
+    CREATE TABLE IF NOT EXISTS mail_outbox (
+        id SERIAL PRIMARY KEY,
+        para VARCHAR(255) NOT NULL,
+        asunto VARCHAR(255) NOT NULL,
+        texto TEXT NOT NULL,
+        html TEXT,
+        estado VARCHAR(20) NOT NULL DEFAULT 'pendiente',
+        intentos INTEGER NOT NULL DEFAULT 0,
+        ultimo_error TEXT,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        enviado_at TIMESTAMP
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_mail_outbox_pendientes ON mail_outbox(created_at) WHERE estado = 'pendiente';
+
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_logins INTEGER NOT NULL DEFAULT 0;
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS last_failed_login TIMESTAMP;
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+
+    CREATE TABLE IF NOT EXISTS rate_limits (
+        clave VARCHAR(255) PRIMARY KEY,
+        fallos INTEGER NOT NULL DEFAULT 0,
+        ultimo_fallo TIMESTAMP NOT NULL,
+        bloqueado_hasta TIMESTAMP
+    );
+
+    CREATE TABLE IF NOT EXISTS roles (
+        id SERIAL PRIMARY KEY,
+        name VARCHAR(50) UNIQUE NOT NULL,
+        description VARCHAR(255),
+        is_system BOOLEAN NOT NULL DEFAULT false,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE TABLE IF NOT EXISTS role_permissions (
+        role_id INTEGER REFERENCES roles(id) ON DELETE CASCADE,
+        permission VARCHAR(50) NOT NULL,
+        PRIMARY KEY (role_id, permission)
+    );
+
+    CREATE TABLE IF NOT EXISTS user_roles (
+        user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+        role_id INTEGER REFERENCES roles(id) ON DELETE CASCADE,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (user_id, role_id)
+    );
+
+    INSERT INTO roles (name, description, is_system)
+    VALUES ('super_admin', 'Acceso total al panel de administración', true)
+    ON CONFLICT (name) DO NOTHING;
+
+    INSERT INTO role_permissions (role_id, permission)
+    SELECT id, '*' FROM roles WHERE name = 'super_admin'
+    ON CONFLICT DO NOTHING;
+
+    CREATE TABLE IF NOT EXISTS api_keys (
+        id SERIAL PRIMARY KEY,
+        user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        empresa_id INTEGER REFERENCES empresas(id) ON DELETE CASCADE,
+        name VARCHAR(100) NOT NULL,
+        prefix VARCHAR(16) UNIQUE NOT NULL,
+        secret_hash VARCHAR(64) NOT NULL,
+        scopes TEXT[] NOT NULL DEFAULT '{}',
+        expires_at TIMESTAMP,
+        last_used_at TIMESTAMP,
+        last_used_ip VARCHAR(45),
+        revoked_at TIMESTAMP,
+        created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id);
+
+    CREATE TABLE IF NOT EXISTS audit_log (
+        id BIGSERIAL PRIMARY KEY,
+        actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+        actor_email VARCHAR(255),
+        api_key_id INTEGER REFERENCES api_keys(id) ON DELETE SET NULL,
+        ip_address VARCHAR(45),
+        action VARCHAR(50) NOT NULL,
+        entity_type VARCHAR(50) NOT NULL,
+        entity_id INTEGER NOT NULL,
+        before_data JSONB,
+        after_data JSONB,
+        changes JSONB,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at);
+    CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id);
+    CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id);
+
+    ALTER TABLE product_licenses ADD COLUMN IF NOT EXISTS contacto_id INTEGER REFERENCES contactos(id) ON DELETE SET NULL;
+
+    -- Una sola solicitud de cancelación en curso por factura; de las duplicadas
+    -- que haya de antes se conserva la más reciente
+    UPDATE cancelaciones c SET estado = 'error', mensaje = 'Solicitud duplicada', updated_at = CURRENT_TIMESTAMP
+    WHERE c.estado IN ('en_proceso', 'cancelable_con_aceptacion') AND EXISTS (
+        SELECT 1 FROM cancelaciones d
+        WHERE d.factura_id = c.factura_id AND d.id > c.id AND d.estado IN ('en_proceso', 'cancelable_con_aceptacion')
+    );
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_cancelaciones_pendiente ON cancelaciones(factura_id)
+    WHERE estado IN ('en_proceso', 'cancelable_con_aceptacion');
     `
 
+	// Las empresas anteriores a los miembros pasan a su creador como owner sólo
+	// al crear la tabla: después, quitar al creador no se debe deshacer al reiniciar
+	var miembrosExists bool
+	if err := db.QueryRow(`
+        SELECT EXISTS (
+            SELECT 1 FROM information_schema.tables
+            WHERE table_name='empresa_miembros'
+        )
+    `).Scan(&miembrosExists); err != nil {
+		return err
+	}
+
 	_, err := db.Exec(schema)
 	if err != nil {
 		return err
 	}
 
+	if !miembrosExists {
+		_, err = db.Exec(`
+            INSERT INTO empresa_miembros (empresa_id, user_id, rol)
+            SELECT id, user_id, 'owner' FROM empresas
+            ON CONFLICT (empresa_id, user_id) DO NOTHING
+        `)
+		if err != nil {
+			log.Println("⚠️  Error migrando los propietarios de las empresas:", err)
+		} else {
+			log.Println("✅ Propietarios de las empresas migrados a empresa_miembros")
+		}
+	}
+	logOrphanedRows(db)
+
+	// is_admin se reemplazó por roles: los admins existentes pasan a super_admin
 	var columnExists bool
 	err = db.QueryRow(`
         SELECT EXISTS (
@@ -246,12 +1026,40 @@ func createTables(db *sql.DB) error {
         )
     `).Scan(&columnExists)
 
+	if err == nil && columnExists {
+		_, err = db.Exec(`
+            INSERT INTO user_roles (user_id, role_id)
+            SELECT u.id, r.id FROM users u, roles r
+            WHERE u.is_admin AND r.name = 'super_admin'
+            ON CONFLICT DO NOTHING;
+            ALTER TABLE users DROP COLUMN is_admin;
+        `)
+		if err != nil {
+			log.Println("⚠️  Error migrando is_admin a roles:", err)
+		} else {
+			log.Println("✅ Administradores migrados al rol super_admin")
+		}
+	}
+
+	db.Exec(`ALTER TABLE users ADD COLUMN IF NOT EXISTS rfc VARCHAR(13)`)
+
+	// Las cuentas creadas antes de la verificación de email se dan por verificadas
+	err = db.QueryRow(`
+        SELECT EXISTS (
+            SELECT 1 FROM information_schema.columns
+            WHERE table_name='users' AND column_name='email_verified_at'
+        )
+    `).Scan(&columnExists)
+
 	if err == nil && !columnExists {
-		_, err = db.Exec(`ALTER TABLE users ADD COLUMN is_admin BOOLEAN DEFAULT false`)
+		_, err = db.Exec(`
+            ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+            UPDATE users SET email_verified_at = created_at;
+        `)
 		if err != nil {
-			log.Println("⚠️  Error agregando columna is_admin:", err)
+			log.Println("⚠️  Error agregando columna email_verified_at:", err)
 		} else {
-			log.Println("✅ Columna is_admin agregada")
+			log.Println("✅ Columna email_verified_at agregada")
 		}
 	}
 
@@ -260,26 +1068,20 @@ func createTables(db *sql.DB) error {
 	db.Exec(`ALTER TABLE product_licenses DROP COLUMN IF EXISTS product_name`)
 	db.Exec(`ALTER TABLE product_licenses DROP COLUMN IF EXISTS last_check`)
 
-	// Crear o actualizar usuario admin con contraseña fija
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("admin123"), bcrypt.DefaultCost)
+	// Crear el usuario admin sólo si no existe, con la contraseña de
+	// ADMIN_PASSWORD. Una cuenta existente no se toca: su contraseña, estado y
+	// roles se administran desde la aplicación.
 	var adminExists bool
 	db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = 'admin@jhvc.com')").Scan(&adminExists)
 
 	if !adminExists {
-		// Crear nuevo usuario admin
-		db.Exec(`
-            INSERT INTO users (email, password_hash, full_name, is_admin, is_active)
-            VALUES ('admin@jhvc.com', $1, 'Administrador', true, true)
-        `, string(hashedPassword))
-		log.Println("✅ Usuario admin creado: admin@jhvc.com / admin123")
-	} else {
-		// Actualizar usuario admin existente (contraseña + permisos)
-		db.Exec(`
-			UPDATE users 
-			SET password_hash = $1, is_admin = true, is_active = true
-			WHERE email = 'admin@jhvc.com'
-		`, string(hashedPassword))
-		log.Println("✅ Usuario admin actualizado: admin@jhvc.com / admin123")
+		if adminPassword == "" {
+			log.Println("⚠️  Usuario admin no creado: define ADMIN_PASSWORD")
+		} else if err := createAdmin(db, adminPassword); err != nil {
+			log.Println("⚠️  Error creando usuario admin:", err)
+		} else {
+			log.Println("✅ Usuario admin creado: admin@jhvc.com")
+		}
 	}
 
 	defaultProducts := []struct {
@@ -305,3 +1107,40 @@ func createTables(db *sql.DB) error {
 	log.Println("✅ Tablas verificadas/creadas correctamente")
 	return nil
 }
+
+// createAdmin crea admin@jhvc.com con el rol super_admin
+func createAdmin(db *sql.DB, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+        WITH admin AS (
+            INSERT INTO users (email, password_hash, full_name, is_active, email_verified_at)
+            VALUES ('admin@jhvc.com', $1, 'Administrador', true, CURRENT_TIMESTAMP)
+            ON CONFLICT (email) DO NOTHING
+            RETURNING id
+        )
+        INSERT INTO user_roles (user_id, role_id)
+        SELECT admin.id, r.id FROM admin, roles r WHERE r.name = 'super_admin'
+    `, string(hashedPassword))
+	return err
+}
+
+// logOrphanedRows reporta los CFDI, facturas y CSD anteriores a las empresas
+// que no se pudieron asignar a una (el RFC no coincide y el usuario tiene
+// varias empresas). Quedan fuera de todas las rutas hasta reasignarlos a mano.
+func logOrphanedRows(db *sql.DB) {
+	for _, table := range []string{"cfdis", "facturas", "csd_certificados"} {
+		var ids []int64
+		err := db.QueryRow(`SELECT COALESCE(array_agg(id ORDER BY id), '{}') FROM ` + table + ` WHERE empresa_id IS NULL`).
+			Scan(pq.Array(&ids))
+		if err != nil {
+			log.Printf("⚠️  Error buscando %s sin empresa: %v", table, err)
+			continue
+		}
+		if len(ids) > 0 {
+			log.Printf("⚠️  %d registros de %s sin empresa; asigna empresa_id a mano. IDs: %v", len(ids), table, ids)
+		}
+	}
+}
@@ -3,17 +3,69 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
 )
 
 type Config struct {
 	Port      string
 	JWTSecret string
+	// AdminRequireMFA bloquea el panel admin a las cuentas sin 2FA
+	AdminRequireMFA bool
+	// AdminPassword es la contraseña de admin@jhvc.com al crearlo en una base
+	// vacía; sin ella no se crea
+	AdminPassword string
+	// RateLimitStore: "memory" (una instancia) o "postgres" (varias instancias)
+	RateLimitStore string
+	// IP del cliente para los límites de intentos. TrustedProxies (CIDR o IP
+	// separados por comas) son los proxies cuyo X-Forwarded-For se acepta; sin
+	// ellos se usa la IP de la conexión. TrustedPlatform es el encabezado con
+	// la IP real que pone la plataforma (p. ej. CF-Connecting-IP, X-Real-IP).
+	TrustedProxies  []string
+	TrustedPlatform string
+	// CSDKey cifra en reposo las llaves privadas de los CSD
+	CSDKey string
+	// PAC de timbrado: "local" (pruebas sin conexión) o "sw" (SW Sapien)
+	PACProvider string
+	PACURL      string
+	PACToken    string
+	// AppURL es la URL pública con la que se arman los enlaces de los correos
+	AppURL string
+	// Correo: sin SMTPHost los mensajes se guardan en MailDir. MailConsole
+	// los escribe en el log y sólo se acepta con AppURL en localhost.
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+	MailFrom     string
+	MailDir      string
+	MailConsole  bool
 }
 
 func Load() *Config {
 	return &Config{
 		Port:      getEnv("PORT", "8080"),
 		JWTSecret: getEnv("JWT_SECRET", "secret-key"),
+		CSDKey:    os.Getenv("CSD_ENCRYPTION_KEY"),
+
+		AdminPassword:   os.Getenv("ADMIN_PASSWORD"),
+		AdminRequireMFA: getEnv("ADMIN_REQUIRE_MFA", "true") != "false",
+		RateLimitStore:  getEnv("RATE_LIMIT_STORE", "memory"),
+		TrustedProxies:  splitList(os.Getenv("TRUSTED_PROXIES")),
+		TrustedPlatform: os.Getenv("TRUSTED_PLATFORM"),
+
+		PACProvider: getEnv("PAC_PROVIDER", "local"),
+		PACURL:      getEnv("PAC_URL", "https://services.test.sw.com.mx"),
+		PACToken:    os.Getenv("PAC_TOKEN"),
+
+		AppURL: getEnv("APP_URL", "http://localhost:8080"),
+
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUser:     os.Getenv("SMTP_USER"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		MailFrom:     getEnv("MAIL_FROM", "JHVC Tech Solutions <no-reply@jhvc.com>"),
+		MailDir:      os.Getenv("MAIL_DIR"),
+		MailConsole:  os.Getenv("MAIL_CONSOLE") == "true",
 	}
 }
 
@@ -33,3 +85,13 @@ func getEnv(key, def string) string {
 	}
 	return def
 }
+
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,233 @@
+// internal/exportar/pdf.go
+package exportar
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Medidas en puntos de una hoja carta; con más de cinco columnas se usa horizontal
+const (
+	cartaAncho = 612.0
+	cartaAlto  = 792.0
+	margen     = 36.0
+
+	tamTitulo = 12.0
+	tamTexto  = 8.0
+	altoFila  = 12.0
+	relleno   = 3.0
+)
+
+// anchosHelvetica son las anchuras (milésimas de em) de los caracteres ASCII
+// 32-126 de Helvetica; los demás se aproximan con el ancho de un dígito
+var anchosHelvetica = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// PDF genera el reporte con las fuentes estándar Helvetica, sin incrustar
+// fuentes; el texto se codifica en WinAnsi, que cubre los acentos del español.
+// Los encabezados de columna se repiten en cada página.
+func PDF(t Tabla) ([]byte, error) {
+	ancho, alto := cartaAncho, cartaAlto
+	if len(t.Columnas) > 5 {
+		ancho, alto = alto, ancho
+	}
+
+	util := ancho - 2*margen
+	escala := util / t.anchoTotal()
+	anchos := make([]float64, len(t.Columnas))
+	for i, c := range t.Columnas {
+		anchos[i] = anchoColumna(c) * escala
+	}
+
+	// Reparte las filas en páginas; la primera lleva título y subtítulos
+	var paginas [][]Fila
+	disponible := func(primera bool) int {
+		espacio := alto - 2*margen - 2*altoFila // encabezados y pie
+		if primera {
+			espacio -= tamTitulo + 6 + float64(len(t.Subtitulos))*altoFila + altoFila
+		}
+		return int(espacio / altoFila)
+	}
+	filas := t.Filas
+	for len(paginas) == 0 || len(filas) > 0 {
+		n := disponible(len(paginas) == 0)
+		if n > len(filas) {
+			n = len(filas)
+		}
+		paginas = append(paginas, filas[:n])
+		filas = filas[n:]
+	}
+
+	var contenidos []string
+	for i, filasPagina := range paginas {
+		var c strings.Builder
+		y := alto - margen
+
+		if i == 0 {
+			y -= tamTitulo
+			texto(&c, "F2", tamTitulo, margen, y, t.Titulo)
+			y -= 6
+			for _, s := range t.Subtitulos {
+				y -= altoFila
+				texto(&c, "F1", tamTexto, margen, y, s)
+			}
+			y -= altoFila
+		}
+
+		encabezados := make([]interface{}, len(t.Columnas))
+		for j, col := range t.Columnas {
+			encabezados[j] = col.Titulo
+		}
+		y -= altoFila
+		fila(&c, anchos, y, Fila{Valores: encabezados, Negrita: true})
+		fmt.Fprintf(&c, "0.5 w %.2f %.2f m %.2f %.2f l S\n", margen, y-3, ancho-margen, y-3)
+
+		for _, f := range filasPagina {
+			y -= altoFila
+			fila(&c, anchos, y, f)
+		}
+
+		pie := fmt.Sprintf("Página %d de %d", i+1, len(paginas))
+		texto(&c, "F1", tamTexto, ancho-margen-anchoTexto(pie, tamTexto), margen-altoFila, pie)
+		contenidos = append(contenidos, c.String())
+	}
+
+	return armarPDF(ancho, alto, contenidos), nil
+}
+
+// fila escribe los valores; los importes se alinean a la derecha de su columna
+func fila(c *strings.Builder, anchos []float64, y float64, f Fila) {
+	fuente := "F1"
+	if f.Negrita {
+		fuente = "F2"
+	}
+
+	x := margen
+	for i, v := range f.Valores {
+		if i >= len(anchos) {
+			break
+		}
+		switch v := v.(type) {
+		case float64:
+			s := formatearImporte(v)
+			texto(c, fuente, tamTexto, x+anchos[i]-relleno-anchoTexto(s, tamTexto), y, s)
+		case string:
+			sangria := 0.0
+			if i == 0 {
+				sangria = float64(f.Sangria) * 8
+			}
+			s := recortarTexto(v, anchos[i]-2*relleno-sangria, tamTexto)
+			texto(c, fuente, tamTexto, x+relleno+sangria, y, s)
+		}
+		x += anchos[i]
+	}
+}
+
+func texto(c *strings.Builder, fuente string, tam, x, y float64, s string) {
+	if s == "" {
+		return
+	}
+	fmt.Fprintf(c, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", fuente, tam, x, y, escaparPDF(s))
+}
+
+// armarPDF escribe los objetos y la tabla de referencias cruzadas
+func armarPDF(ancho, alto float64, contenidos []string) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+	objeto := func(cuerpo string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), cuerpo)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1 catálogo, 2 páginas, 3-4 fuentes, luego página y contenido por hoja
+	kids := make([]string, len(contenidos))
+	for i := range contenidos {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objeto("<< /Type /Catalog /Pages 2 0 R >>")
+	objeto(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(contenidos)))
+	objeto("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	objeto("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, contenido := range contenidos {
+		objeto(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", ancho, alto, 6+2*i))
+		objeto(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(contenido), contenido))
+	}
+
+	inicioXref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, inicioXref)
+	return buf.Bytes()
+}
+
+// escaparPDF convierte a WinAnsi y escapa los delimitadores de cadena
+func escaparPDF(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r >= 32 && r < 127, r >= 0xA0 && r <= 0xFF:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+func anchoTexto(s string, tam float64) float64 {
+	total := 0
+	for _, r := range s {
+		if r >= 32 && r <= 126 {
+			total += anchosHelvetica[r-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * tam / 1000
+}
+
+// recortarTexto corta con puntos suspensivos lo que no cabe en la columna
+func recortarTexto(s string, max, tam float64) string {
+	if anchoTexto(s, tam) <= max {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && anchoTexto(string(r)+"...", tam) > max {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "..."
+}
+
+// formatearImporte usa separador de miles y dos decimales: 1,234,567.89
+func formatearImporte(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	signo := ""
+	if strings.HasPrefix(s, "-") {
+		signo, s = "-", s[1:]
+	}
+	entero, decimales := s[:len(s)-3], s[len(s)-3:]
+	for i := len(entero) - 3; i > 0; i -= 3 {
+		entero = entero[:i] + "," + entero[i:]
+	}
+	if signo == "-" && strings.Trim(entero+decimales, "0.,") == "" {
+		signo = ""
+	}
+	return signo + entero + decimales
+}
@@ -0,0 +1,41 @@
+// internal/exportar/tabla.go
+package exportar
+
+// Tabla es un reporte tabular que se puede exportar a PDF o XLSX. Los valores
+// de cada fila son string o float64; los float64 se presentan como importes.
+type Tabla struct {
+	Titulo     string
+	Subtitulos []string
+	Columnas   []Columna
+	Filas      []Fila
+}
+
+// Columna define el encabezado y el ancho relativo de una columna
+type Columna struct {
+	Titulo string
+	Ancho  float64
+}
+
+// Fila es un renglón del reporte. Sangria desplaza la primera columna de
+// texto para mostrar niveles (cuentas de detalle bajo su cuenta de mayor).
+type Fila struct {
+	Valores []interface{}
+	Negrita bool
+	Sangria int
+}
+
+// anchoTotal suma los anchos relativos; una columna sin ancho cuenta como 1
+func (t *Tabla) anchoTotal() float64 {
+	var total float64
+	for _, c := range t.Columnas {
+		total += anchoColumna(c)
+	}
+	return total
+}
+
+func anchoColumna(c Columna) float64 {
+	if c.Ancho <= 0 {
+		return 1
+	}
+	return c.Ancho
+}
@@ -0,0 +1,171 @@
+// internal/exportar/xlsx.go
+package exportar
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Partes fijas del paquete OOXML mínimo: un libro con una hoja y dos estilos
+// además del normal (1 = negrita, 2 = importe, 3 = importe en negrita)
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`
+
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<numFmts count="1"><numFmt numFmtId="164" formatCode="#,##0.00;-#,##0.00"/></numFmts>` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="4">` +
+		`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+		`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`<xf numFmtId="164" fontId="1" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/>` +
+		`</cellXfs></styleSheet>`
+)
+
+// XLSX genera un libro de Excel de una hoja con el reporte. Las cadenas van
+// en línea (inlineStr) para no mantener una tabla de cadenas compartidas.
+func XLSX(t Tabla) ([]byte, error) {
+	hoja := nombreHoja(t.Titulo)
+
+	var sheet strings.Builder
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+
+	sheet.WriteString(`<cols>`)
+	for i, c := range t.Columnas {
+		fmt.Fprintf(&sheet, `<col min="%d" max="%d" width="%.1f" customWidth="1"/>`, i+1, i+1, 12*anchoColumna(c))
+	}
+	sheet.WriteString(`</cols><sheetData>`)
+
+	fila := 0
+	escribirFila := func(valores []interface{}, negrita bool, sangria int) {
+		fila++
+		fmt.Fprintf(&sheet, `<row r="%d">`, fila)
+		for i, v := range valores {
+			ref := columnaXLSX(i) + strconv.Itoa(fila)
+			switch v := v.(type) {
+			case float64:
+				estilo := 2
+				if negrita {
+					estilo = 3
+				}
+				fmt.Fprintf(&sheet, `<c r="%s" s="%d"><v>%s</v></c>`, ref, estilo, strconv.FormatFloat(v, 'f', 2, 64))
+			case string:
+				if v == "" {
+					continue
+				}
+				if i == 0 && sangria > 0 {
+					v = strings.Repeat("  ", sangria) + v
+				}
+				estilo := 0
+				if negrita {
+					estilo = 1
+				}
+				fmt.Fprintf(&sheet, `<c r="%s" t="inlineStr" s="%d"><is><t xml:space="preserve">%s</t></is></c>`, ref, estilo, escaparXML(v))
+			}
+		}
+		sheet.WriteString(`</row>`)
+	}
+
+	escribirFila([]interface{}{t.Titulo}, true, 0)
+	for _, s := range t.Subtitulos {
+		escribirFila([]interface{}{s}, false, 0)
+	}
+	fila++
+
+	encabezados := make([]interface{}, len(t.Columnas))
+	for i, c := range t.Columnas {
+		encabezados[i] = c.Titulo
+	}
+	escribirFila(encabezados, true, 0)
+	for _, f := range t.Filas {
+		escribirFila(f.Valores, f.Negrita, f.Sangria)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	workbook := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + escaparXML(hoja) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	partes := []struct{ nombre, contenido string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", workbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+		{"xl/worksheets/sheet1.xml", sheet.String()},
+	}
+	for _, p := range partes {
+		w, err := zw.Create(p.nombre)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(p.contenido)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// columnaXLSX convierte el índice 0, 1, ... 26 en A, B, ... AA
+func columnaXLSX(i int) string {
+	nombre := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		nombre = string(rune('A'+(i-1)%26)) + nombre
+	}
+	return nombre
+}
+
+// nombreHoja respeta las reglas de Excel: hasta 31 caracteres y sin []:*?/\
+func nombreHoja(titulo string) string {
+	nombre := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return ' '
+		}
+		return r
+	}, titulo)
+	if r := []rune(nombre); len(r) > 31 {
+		nombre = string(r[:31])
+	}
+	if strings.TrimSpace(nombre) == "" {
+		return "Reporte"
+	}
+	return nombre
+}
+
+func escaparXML(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,64 @@
+// Package mail arma y envía los correos del sistema. Los mensajes se encolan
+// en la tabla mail_outbox y un proceso en segundo plano los entrega con el
+// Sender configurado: SMTP en producción, archivos .eml o consola en desarrollo.
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"time"
+)
+
+// Mensaje es un correo con versión de texto y, opcionalmente, HTML
+type Mensaje struct {
+	Para   string
+	Asunto string
+	Texto  string
+	HTML   string
+}
+
+// Sender entrega un mensaje ya armado
+type Sender interface {
+	Enviar(m Mensaje) error
+}
+
+// construirMIME arma el correo completo (encabezados y cuerpo
+// multipart/alternative) tal como se entrega por SMTP o se guarda en un .eml
+func construirMIME(de string, m Mensaje) ([]byte, error) {
+	var cuerpo bytes.Buffer
+	w := multipart.NewWriter(&cuerpo)
+
+	partes := []struct{ tipo, contenido string }{{"text/plain", m.Texto}}
+	if m.HTML != "" {
+		partes = append(partes, struct{ tipo, contenido string }{"text/html", m.HTML})
+	}
+	for _, p := range partes {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Type", p.tipo+"; charset=UTF-8")
+		h.Set("Content-Transfer-Encoding", "8bit")
+		pw, err := w.CreatePart(h)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := pw.Write([]byte(p.contenido)); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", de)
+	fmt.Fprintf(&msg, "To: %s\r\n", m.Para)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Asunto))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", w.Boundary())
+	msg.Write(cuerpo.Bytes())
+
+	return msg.Bytes(), nil
+}
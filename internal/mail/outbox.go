@@ -0,0 +1,130 @@
+package mail
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// maxIntentos antes de dar un correo por fallido
+const maxIntentos = 5
+
+// Outbox encola los correos en la base de datos para que una falla del
+// servidor SMTP no afecte la petición que los genera
+type Outbox struct {
+	db         *sql.DB
+	plantillas *Plantillas
+	sender     Sender
+}
+
+func NewOutbox(db *sql.DB, plantillas *Plantillas, sender Sender) *Outbox {
+	return &Outbox{db: db, plantillas: plantillas, sender: sender}
+}
+
+// Encolar guarda el mensaje como pendiente
+func (o *Outbox) Encolar(m Mensaje) error {
+	_, err := o.db.Exec(`
+        INSERT INTO mail_outbox (para, asunto, texto, html)
+        VALUES ($1, $2, $3, NULLIF($4, ''))
+    `, m.Para, m.Asunto, m.Texto, m.HTML)
+	return err
+}
+
+// EnviarPlantilla arma el correo con la plantilla y lo encola
+func (o *Outbox) EnviarPlantilla(para, plantilla string, datos interface{}) error {
+	m, err := o.plantillas.Render(plantilla, para, datos)
+	if err != nil {
+		return err
+	}
+	return o.Encolar(*m)
+}
+
+// Procesar entrega los correos pendientes. Los bloquea con SKIP LOCKED para
+// que varias instancias no envíen el mismo.
+func (o *Outbox) Procesar(limite int) (int, error) {
+	tx, err := o.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+        SELECT id, para, asunto, texto, COALESCE(html, '')
+        FROM mail_outbox
+        WHERE estado = 'pendiente'
+        ORDER BY created_at
+        LIMIT $1
+        FOR UPDATE SKIP LOCKED
+    `, limite)
+	if err != nil {
+		return 0, err
+	}
+
+	type pendiente struct {
+		id int
+		m  Mensaje
+	}
+	var pendientes []pendiente
+	for rows.Next() {
+		var p pendiente
+		if err := rows.Scan(&p.id, &p.m.Para, &p.m.Asunto, &p.m.Texto, &p.m.HTML); err != nil {
+			continue
+		}
+		pendientes = append(pendientes, p)
+	}
+	rows.Close()
+
+	enviados := 0
+	for _, p := range pendientes {
+		if err := o.sender.Enviar(p.m); err != nil {
+			_, err = tx.Exec(`
+                UPDATE mail_outbox
+                SET intentos = intentos + 1, ultimo_error = $1,
+                    estado = CASE WHEN intentos + 1 >= $2 THEN 'fallido' ELSE estado END
+                WHERE id = $3
+            `, err.Error(), maxIntentos, p.id)
+			if err != nil {
+				return enviados, err
+			}
+			continue
+		}
+		if _, consola := o.sender.(*ConsolaSender); consola {
+			// Sólo quedó en el log: no cuenta como entregado
+			if _, err := tx.Exec(`
+                UPDATE mail_outbox SET estado = 'consola', intentos = intentos + 1
+                WHERE id = $1
+            `, p.id); err != nil {
+				return enviados, err
+			}
+			continue
+		}
+		_, err := tx.Exec(`
+            UPDATE mail_outbox SET estado = 'enviado', intentos = intentos + 1, enviado_at = CURRENT_TIMESTAMP
+            WHERE id = $1
+        `, p.id)
+		if err != nil {
+			return enviados, err
+		}
+		enviados++
+	}
+
+	return enviados, tx.Commit()
+}
+
+// Iniciar procesa la cola cada intervalo hasta que se cancele el contexto
+func (o *Outbox) Iniciar(ctx context.Context, intervalo time.Duration) {
+	ticker := time.NewTicker(intervalo)
+	defer ticker.Stop()
+
+	for {
+		if _, err := o.Procesar(20); err != nil {
+			log.Println("⚠️  Error procesando correos:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
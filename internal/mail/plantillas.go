@@ -0,0 +1,88 @@
+package mail
+
+import (
+	"bytes"
+	"errors"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+)
+
+var ErrPlantillaNoEncontrada = errors.New("plantilla de correo no encontrada")
+
+// Plantillas son los correos de un directorio: <nombre>.txt (text/template,
+// con un bloque "asunto") y opcionalmente <nombre>.html (html/template)
+type Plantillas struct {
+	texto map[string]*texttemplate.Template
+	html  map[string]*htmltemplate.Template
+}
+
+func CargarPlantillas(dir string) (*Plantillas, error) {
+	p := &Plantillas{
+		texto: make(map[string]*texttemplate.Template),
+		html:  make(map[string]*htmltemplate.Template),
+	}
+
+	archivos, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	for _, archivo := range archivos {
+		nombre := strings.TrimSuffix(filepath.Base(archivo), ".txt")
+
+		t, err := texttemplate.ParseFiles(archivo)
+		if err != nil {
+			return nil, err
+		}
+		if t.Lookup("asunto") == nil {
+			return nil, errors.New(archivo + ": falta el bloque \"asunto\"")
+		}
+		p.texto[nombre] = t
+
+		archivoHTML := filepath.Join(dir, nombre+".html")
+		if _, err := os.Stat(archivoHTML); err != nil {
+			continue
+		}
+		h, err := htmltemplate.ParseFiles(archivoHTML)
+		if err != nil {
+			return nil, err
+		}
+		p.html[nombre] = h
+	}
+
+	return p, nil
+}
+
+// Render arma el mensaje de la plantilla con los datos dados
+func (p *Plantillas) Render(nombre, para string, datos interface{}) (*Mensaje, error) {
+	t, ok := p.texto[nombre]
+	if !ok {
+		return nil, ErrPlantillaNoEncontrada
+	}
+
+	var asunto, texto bytes.Buffer
+	if err := t.ExecuteTemplate(&asunto, "asunto", datos); err != nil {
+		return nil, err
+	}
+	if err := t.Execute(&texto, datos); err != nil {
+		return nil, err
+	}
+
+	m := &Mensaje{
+		Para:   para,
+		Asunto: strings.TrimSpace(asunto.String()),
+		Texto:  strings.TrimSpace(texto.String()) + "\n",
+	}
+
+	if h, ok := p.html[nombre]; ok {
+		var html bytes.Buffer
+		if err := h.Execute(&html, datos); err != nil {
+			return nil, err
+		}
+		m.HTML = html.String()
+	}
+
+	return m, nil
+}
@@ -0,0 +1,92 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SMTPSender entrega los correos a un servidor SMTP. Si hay usuario se
+// autentica con PLAIN, que net/smtp sólo permite sobre TLS o localhost.
+type SMTPSender struct {
+	host     string
+	port     string
+	usuario  string
+	password string
+	de       string
+}
+
+func NewSMTPSender(host, port, usuario, password, de string) *SMTPSender {
+	return &SMTPSender{host: host, port: port, usuario: usuario, password: password, de: de}
+}
+
+func (s *SMTPSender) Enviar(m Mensaje) error {
+	data, err := construirMIME(s.de, m)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.usuario != "" {
+		auth = smtp.PlainAuth("", s.usuario, s.password, s.host)
+	}
+	return smtp.SendMail(net.JoinHostPort(s.host, s.port), auth, direccion(s.de), []string{m.Para}, data)
+}
+
+// ArchivoSender guarda cada correo como .eml en un directorio. Sirve para
+// desarrollo y pruebas sin SMTP.
+type ArchivoSender struct {
+	dir string
+	de  string
+}
+
+func NewArchivoSender(dir, de string) *ArchivoSender {
+	return &ArchivoSender{dir: dir, de: de}
+}
+
+func (s *ArchivoSender) Enviar(m Mensaje) error {
+	data, err := construirMIME(s.de, m)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	nombre := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102T150405.000000000"), nombreSeguro(m.Para))
+	return os.WriteFile(filepath.Join(s.dir, nombre), data, 0o644)
+}
+
+// ConsolaSender escribe el correo completo en el log, enlaces incluidos. Sólo
+// para desarrollo local: la cola no lo da por enviado.
+type ConsolaSender struct{}
+
+func NewConsolaSender() *ConsolaSender {
+	return &ConsolaSender{}
+}
+
+func (s *ConsolaSender) Enviar(m Mensaje) error {
+	log.Printf("📧 Correo para %s: %s\n%s", m.Para, m.Asunto, m.Texto)
+	return nil
+}
+
+// direccion extrae el correo de un remitente con nombre ("Nombre <a@b.mx>")
+func direccion(de string) string {
+	if i := strings.LastIndex(de, "<"); i >= 0 {
+		return strings.TrimSuffix(de[i+1:], ">")
+	}
+	return de
+}
+
+func nombreSeguro(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, s)
+}
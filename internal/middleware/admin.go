@@ -1,22 +1,43 @@
 package middleware
 
 import (
-	"strings"
-
 	"github.com/gin-gonic/gin"
 	"github.com/jhvc/backend/internal/modules/auth"
 )
 
+// AdminMiddleware va después de AuthMiddleware y deja pasar a quien tenga
+// algún rol administrativo; cada ruta exige además su permiso con
+// RequirePermission. Con la política de 2FA para admins, la cuenta sin 2FA
+// sólo puede usar sus rutas de cuenta hasta activarlo.
 func AdminMiddleware(service *auth.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token := c.GetHeader("Authorization")
-		token = strings.TrimPrefix(token, "Bearer ")
-		isAdmin, err := service.IsAdmin(token)
-		if err != nil || !isAdmin {
+		user, ok := CurrentUser(c)
+		if !ok || !user.IsAdmin {
 			c.JSON(403, gin.H{"error": "Acceso denegado - Solo administradores"})
 			c.Abort()
 			return
 		}
+		// La política de 2FA es para el login de personas; una API key ya la
+		// creó un admin que pasó por ella
+		if _, byKey := APIKey(c); !byKey && service.AdminMFAPending(user) {
+			c.JSON(403, gin.H{"error": "Activa la verificación en dos pasos para usar el panel de administración", "code": "mfa_setup_required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission va después de AuthMiddleware y revisa el permiso en los
+// roles vigentes del usuario
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := CurrentUser(c)
+		if !ok || !user.HasPermission(permission) {
+			c.JSON(403, gin.H{"error": "Acceso denegado - Requiere el permiso " + permission})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
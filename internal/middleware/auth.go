@@ -7,17 +7,94 @@ import (
 	"github.com/jhvc/backend/internal/modules/auth"
 )
 
+// AuthMiddleware valida el token y resuelve el usuario vigente (con caché de
+// corta duración); deja en el contexto "user", "userID" y "sessionID". También
+// acepta una API key (X-API-Key o Authorization: Bearer jhvc_...): entonces
+// deja "apiKey" y el usuario sólo con los permisos de los alcances de la llave.
 func AuthMiddleware(service *auth.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := c.GetHeader("Authorization")
 		token = strings.TrimPrefix(token, "Bearer ")
-		userID, err := service.ValidateToken(token)
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			token = key
+		}
+
+		if auth.IsAPIKey(token) {
+			user, key, err := service.AuthenticateAPIKey(token, c.ClientIP())
+			if err != nil {
+				c.JSON(401, gin.H{"error": "No autorizado"})
+				c.Abort()
+				return
+			}
+			c.Set("user", user)
+			c.Set("userID", user.ID)
+			c.Set("apiKey", key)
+			c.Next()
+			return
+		}
+
+		userID, sessionID, err := service.ValidateToken(token)
 		if err != nil {
 			c.JSON(401, gin.H{"error": "No autorizado"})
 			c.Abort()
 			return
 		}
+		user, err := service.CurrentUser(userID)
+		if err != nil || !user.IsActive {
+			c.JSON(401, gin.H{"error": "No autorizado"})
+			c.Abort()
+			return
+		}
+		c.Set("user", user)
 		c.Set("userID", userID)
+		c.Set("sessionID", sessionID)
 		c.Next()
 	}
 }
+
+// VerifiedMiddleware va después de AuthMiddleware y limita a las cuentas que
+// aún no confirman su email a las rutas de su propia cuenta
+func VerifiedMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := CurrentUser(c)
+		if !ok || !user.EmailVerified {
+			c.JSON(403, gin.H{"error": "Verifica tu email para continuar", "code": "email_not_verified"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession rechaza las API keys en rutas que sólo tienen sentido para
+// una persona con sesión (perfil, sesiones, 2FA)
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := APIKey(c); ok {
+			c.JSON(403, gin.H{"error": "Esta ruta no acepta API keys"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// APIKey devuelve la llave con la que se autenticó la petición, si la hay
+func APIKey(c *gin.Context) (*auth.APIKey, bool) {
+	v, ok := c.Get("apiKey")
+	if !ok {
+		return nil, false
+	}
+	key, ok := v.(*auth.APIKey)
+	return key, ok
+}
+
+// CurrentUser devuelve el usuario que dejó AuthMiddleware en el contexto
+func CurrentUser(c *gin.Context) (*auth.User, bool) {
+	v, ok := c.Get("user")
+	if !ok {
+		return nil, false
+	}
+	user, ok := v.(*auth.User)
+	return user, ok
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jhvc/backend/internal/modules/auth"
+	"github.com/jhvc/backend/internal/modules/empresas"
+)
+
+// EmpresaMiddleware resuelve la empresa de la petición, de la ruta (:empresaId)
+// o del encabezado X-Company-ID, verifica que el usuario autenticado sea
+// miembro y la deja en el contexto como "empresaID" junto con su rol
+// ("rolEmpresa"). Los lectores sólo pueden hacer consultas y salir de la
+// empresa. Con API key, la llave debe ser de esa empresa y tener el alcance de
+// lectura o escritura.
+func EmpresaMiddleware(service *empresas.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		valor := c.Param("empresaId")
+		if valor == "" {
+			valor = c.GetHeader("X-Company-ID")
+		}
+		if valor == "" {
+			c.JSON(400, gin.H{"error": "Indique la empresa en el encabezado X-Company-ID"})
+			c.Abort()
+			return
+		}
+		empresaID, err := strconv.Atoi(valor)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Empresa inválida"})
+			c.Abort()
+			return
+		}
+		empresa, err := service.GetEmpresa(c.GetInt("userID"), empresaID)
+		if err != nil {
+			c.JSON(404, gin.H{"error": "Empresa no encontrada"})
+			c.Abort()
+			return
+		}
+		lectura := c.Request.Method == "GET" || c.Request.Method == "HEAD"
+		if key, ok := APIKey(c); ok {
+			scope := auth.ScopeEmpresaWrite
+			if lectura {
+				scope = auth.ScopeEmpresaRead
+			}
+			if key.EmpresaID == nil || *key.EmpresaID != empresaID || !(key.HasScope(scope) || key.HasScope(auth.ScopeEmpresaWrite)) {
+				c.JSON(403, gin.H{"error": "La API key no tiene acceso a esta empresa"})
+				c.Abort()
+				return
+			}
+		}
+		if !empresas.PuedeEscribir(empresa.Rol) && !lectura && !esSalida(c) {
+			c.JSON(403, gin.H{"error": "Acceso de sólo lectura a esta empresa"})
+			c.Abort()
+			return
+		}
+		c.Set("empresaID", empresaID)
+		c.Set("rolEmpresa", empresa.Rol)
+		c.Next()
+	}
+}
+
+// esSalida indica si el usuario se está quitando a sí mismo de la empresa
+func esSalida(c *gin.Context) bool {
+	return c.Request.Method == "DELETE" &&
+		strings.HasSuffix(c.FullPath(), "/miembros/:userId") &&
+		c.Param("userId") == strconv.Itoa(c.GetInt("userID"))
+}
+
+// RequiereRol limita la ruta a los roles indicados; va después de
+// EmpresaMiddleware
+func RequiereRol(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rol := c.GetString("rolEmpresa")
+		for _, r := range roles {
+			if r == rol {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(403, gin.H{"error": "Acceso denegado para el rol " + rol})
+		c.Abort()
+	}
+}
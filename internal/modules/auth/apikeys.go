@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+// Formato de la llave: jhvc_<prefijo>_<secreto>. El prefijo identifica la
+// llave en la base y en los listados; el secreto sólo se guarda como hash.
+const apiKeyPrefix = "jhvc_"
+
+// Alcances de las llaves de empresa; las demás llevan permisos del catálogo
+const (
+	ScopeEmpresaRead  = "empresa:read"
+	ScopeEmpresaWrite = "empresa:write"
+)
+
+var (
+	ErrAPIKeyInvalid      = errors.New("API key inválida, vencida o revocada")
+	ErrAPIKeyNotFound     = errors.New("API key no encontrada")
+	ErrAPIKeyScope        = errors.New("alcance de API key desconocido")
+	ErrAPIKeyEmpresaScope = errors.New("los alcances de empresa requieren una llave de empresa")
+	ErrNotEmpresaMember   = errors.New("el usuario no es miembro de la empresa")
+	ErrAPIKeyEmpresaOwner = errors.New("las llaves de empresa sólo se pueden crear para uno mismo")
+)
+
+// IsAPIKey distingue una llave de un JWT en el encabezado Authorization
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
+
+// HasScope revisa si la llave tiene el alcance indicado
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPIKey genera una llave para el usuario indicado (o para quien la
+// crea). Quien la crea debe tener los permisos que otorga; las llaves de
+// empresa sólo son para uno mismo y en una empresa de la que es miembro.
+func (s *Service) CreateAPIKey(actor Actor, req CreateAPIKeyRequest) (*CreatedAPIKey, error) {
+	ownerID := req.UserID
+	if ownerID == 0 {
+		ownerID = actor.UserID
+	}
+	if _, err := s.repo.GetUserByID(ownerID); err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Una llave de empresa actúa como su dueño en esa empresa; crearla para
+	// otro usuario daría acceso a sus datos fiscales
+	if req.EmpresaID != nil && ownerID != actor.UserID {
+		return nil, ErrAPIKeyEmpresaOwner
+	}
+
+	scopes, err := s.validateScopes(actor, req.Scopes, req.EmpresaID != nil)
+	if err != nil {
+		return nil, err
+	}
+	if req.EmpresaID != nil {
+		member, err := s.repo.IsEmpresaMember(ownerID, *req.EmpresaID)
+		if err != nil {
+			return nil, err
+		}
+		if !member {
+			return nil, ErrNotEmpresaMember
+		}
+	}
+
+	prefix, err := randomToken(6)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &APIKey{
+		UserID:     ownerID,
+		EmpresaID:  req.EmpresaID,
+		Name:       strings.TrimSpace(req.Name),
+		Prefix:     prefix,
+		SecretHash: hashToken(secret),
+		Scopes:     scopes,
+		CreatedBy:  actor.UserID,
+	}
+	if req.DaysValid > 0 {
+		exp := time.Now().Add(time.Duration(req.DaysValid) * 24 * time.Hour)
+		key.ExpiresAt = &exp
+	}
+	if err := s.repo.CreateAPIKey(key); err != nil {
+		return nil, err
+	}
+	s.Audit(actor, "apikey.create", auditAPIKey, key.ID, nil, key)
+
+	return &CreatedAPIKey{APIKey: *key, Key: apiKeyPrefix + prefix + "_" + secret}, nil
+}
+
+// validateScopes acepta permisos del catálogo que tenga quien crea la llave y,
+// sólo en llaves de empresa, los alcances empresa:read y empresa:write
+func (s *Service) validateScopes(actor Actor, scopes []string, empresaKey bool) ([]string, error) {
+	var permissions, valid []string
+	seen := make(map[string]bool)
+	for _, scope := range scopes {
+		if seen[scope] {
+			continue
+		}
+		seen[scope] = true
+
+		switch {
+		case scope == ScopeEmpresaRead || scope == ScopeEmpresaWrite:
+			if !empresaKey {
+				return nil, ErrAPIKeyEmpresaScope
+			}
+		case isKnownPermission(scope):
+			permissions = append(permissions, scope)
+		default:
+			return nil, ErrAPIKeyScope
+		}
+		valid = append(valid, scope)
+	}
+
+	if err := s.checkGrantable(actor, permissions); err != nil {
+		return nil, err
+	}
+	return valid, nil
+}
+
+func (s *Service) GetAPIKeys(userID int) ([]APIKey, error) {
+	return s.repo.GetAPIKeys(userID)
+}
+
+func (s *Service) RevokeAPIKey(actor Actor, id int) error {
+	before, _ := s.repo.GetAPIKey(id)
+	revoked, err := s.repo.RevokeAPIKey(id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	after, _ := s.repo.GetAPIKey(id)
+	s.Audit(actor, "apikey.revoke", auditAPIKey, id, before, after)
+	return nil
+}
+
+// AuthenticateAPIKey valida la llave y devuelve su usuario con los permisos
+// reducidos a los alcances de la llave
+func (s *Service) AuthenticateAPIKey(raw, ipAddress string) (*User, *APIKey, error) {
+	rest := strings.TrimPrefix(raw, apiKeyPrefix)
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" || secret == "" {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	key, err := s.repo.GetAPIKeyByPrefix(prefix)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(key.SecretHash), []byte(hashToken(secret))) != 1 {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt)) {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	owner, err := s.CurrentUser(key.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !owner.IsActive {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	if err := s.repo.TouchAPIKey(key.ID, ipAddress); err != nil {
+		log.Println("⚠️  Error registrando uso de API key:", err)
+	}
+
+	// Copia del usuario: el de la caché conserva sus permisos completos
+	user := *owner
+	user.Permissions = nil
+	for _, scope := range key.Scopes {
+		if isKnownPermission(scope) && owner.HasPermission(scope) {
+			user.Permissions = append(user.Permissions, scope)
+		}
+	}
+	return &user, key, nil
+}
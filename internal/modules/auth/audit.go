@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"reflect"
+	"strconv"
+)
+
+const (
+	auditPageSize    = 50
+	auditMaxPageSize = 500
+)
+
+// Tipos de entidad de la bitácora
+const (
+	auditUser           = "user"
+	auditInvitationCode = "invitation_code"
+	auditLicense        = "product_license"
+	auditLicenseDevice  = "license_device"
+	auditRole           = "role"
+	auditAPIKey         = "api_key"
+)
+
+// Actor es quien hace una acción administrativa: el usuario, la llave con la
+// que entró (si fue con API key) y su IP. User es el usuario de la petición;
+// con API key sólo tiene los permisos de los alcances de la llave.
+type Actor struct {
+	UserID    int
+	User      *User
+	APIKeyID  *int
+	IPAddress string
+}
+
+type auditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Audit registra una acción administrativa con la entidad antes y después
+// (nil al crear o eliminar). Un error al registrarla no deshace la acción.
+func (s *Service) Audit(actor Actor, action, entityType string, entityID int, before, after interface{}) {
+	entry := &AuditEntry{
+		APIKeyID:   actor.APIKeyID,
+		IPAddress:  actor.IPAddress,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+	if actor.UserID > 0 {
+		entry.ActorID = &actor.UserID
+		if user, err := s.CurrentUser(actor.UserID); err == nil {
+			entry.ActorEmail = user.Email
+		}
+	}
+
+	var err error
+	if entry.Before, entry.After, entry.Changes, err = auditDiff(before, after); err == nil {
+		err = s.repo.CreateAuditEntry(entry)
+	}
+	if err != nil {
+		log.Printf("⚠️  No se pudo registrar %s de %s %d en la bitácora: %v", action, entityType, entityID, err)
+	}
+}
+
+// auditDiff serializa ambas versiones y compara campo por campo
+func auditDiff(before, after interface{}) (beforeJSON, afterJSON, changesJSON []byte, err error) {
+	if beforeJSON, err = auditSnapshot(before); err != nil {
+		return
+	}
+	if afterJSON, err = auditSnapshot(after); err != nil {
+		return
+	}
+
+	var beforeFields, afterFields map[string]interface{}
+	if len(beforeJSON) > 0 {
+		if err = json.Unmarshal(beforeJSON, &beforeFields); err != nil {
+			return
+		}
+	}
+	if len(afterJSON) > 0 {
+		if err = json.Unmarshal(afterJSON, &afterFields); err != nil {
+			return
+		}
+	}
+
+	changes := make(map[string]auditChange)
+	for field, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[field]) {
+			changes[field] = auditChange{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			changes[field] = auditChange{After: value}
+		}
+	}
+	if len(changes) > 0 {
+		changesJSON, err = json.Marshal(changes)
+	}
+	return
+}
+
+// auditSnapshot devuelve nil para una entidad ausente, aunque llegue como
+// puntero nil con tipo
+func auditSnapshot(v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetAuditLog pagina la bitácora; page empieza en 1
+func (s *Service) GetAuditLog(filter AuditFilter) (*AuditPage, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = auditPageSize
+	}
+	if filter.PageSize > auditMaxPageSize {
+		filter.PageSize = auditMaxPageSize
+	}
+
+	entries, total, err := s.repo.GetAuditLog(filter, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []AuditEntry{}
+	}
+	return &AuditPage{Entries: entries, Total: total, Page: filter.Page, PageSize: filter.PageSize}, nil
+}
+
+// auditColumns es el encabezado del CSV de la bitácora
+var auditColumns = []string{"id", "created_at", "actor_id", "actor_email", "api_key_id", "ip_address", "action", "entity_type", "entity_id", "changes"}
+
+// ExportAuditCSV escribe todas las entradas que cumplen el filtro, sin paginar
+func (s *Service) ExportAuditCSV(filter AuditFilter) ([]byte, error) {
+	entries, _, err := s.repo.GetAuditLog(filter, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	// BOM para que Excel abra el archivo como UTF-8
+	buf.WriteString("\ufeff")
+	w := csv.NewWriter(&buf)
+	if err := w.Write(auditColumns); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		record := []string{
+			strconv.FormatInt(e.ID, 10),
+			e.CreatedAt.Format("2006-01-02 15:04:05"),
+			optionalID(e.ActorID),
+			e.ActorEmail,
+			optionalID(e.APIKeyID),
+			e.IPAddress,
+			e.Action,
+			e.EntityType,
+			strconv.Itoa(e.EntityID),
+			string(e.Changes),
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func optionalID(id *int) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.Itoa(*id)
+}
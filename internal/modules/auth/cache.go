@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// userCacheTTL limita cuánto puede tardar en notarse un cambio hecho fuera de
+// este proceso (otra instancia o la base directamente)
+const userCacheTTL = 30 * time.Second
+
+type cachedUser struct {
+	user      User
+	expiresAt time.Time
+}
+
+// userCache guarda el registro del usuario que resuelve el middleware en cada
+// petición
+type userCache struct {
+	mu    sync.Mutex
+	users map[int]cachedUser
+}
+
+func newUserCache() *userCache {
+	return &userCache{users: make(map[int]cachedUser)}
+}
+
+func (c *userCache) get(id int) (*User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.users[id]
+	if !ok || time.Now().After(entry.expiresAt) {
+		delete(c.users, id)
+		return nil, false
+	}
+	user := entry.user
+	return &user, true
+}
+
+func (c *userCache) set(user *User) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.users[user.ID] = cachedUser{user: *user, expiresAt: time.Now().Add(userCacheTTL)}
+}
+
+func (c *userCache) invalidate(id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.users, id)
+}
+
+// clear descarta todo; se usa cuando cambian los permisos de un rol
+func (c *userCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.users = make(map[int]cachedUser)
+}
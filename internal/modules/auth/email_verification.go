@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+const emailVerificationTTL = 48 * time.Hour
+
+var (
+	ErrVerificationTokenInvalid = errors.New("el enlace de verificación es inválido o ya venció")
+	ErrEmailAlreadyVerified     = errors.New("el email ya está verificado")
+)
+
+// sendVerification genera un enlace nuevo (invalidando los anteriores) y lo
+// encola por correo
+func (s *Service) sendVerification(user *User) error {
+	if s.mailer == nil {
+		return errors.New("el envío de correos no está configurado")
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.CreateEmailVerification(user.ID, hashToken(token), time.Now().Add(emailVerificationTTL)); err != nil {
+		return err
+	}
+
+	return s.mailer.EnviarPlantilla(user.Email, "verificar_email", map[string]string{
+		"Nombre":   user.FullName,
+		"Enlace":   s.appURL + "/verify-email?token=" + token,
+		"Vigencia": "48 horas",
+	})
+}
+
+// ResendVerification envía otro enlace a un usuario que aún no verifica su email
+func (s *Service) ResendVerification(userID int) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+	if err := s.sendVerification(user); err != nil {
+		log.Printf("⚠️  No se pudo encolar la verificación para el usuario %d: %v", user.ID, err)
+		return err
+	}
+	return nil
+}
+
+// VerifyEmail consume el enlace y habilita la cuenta por completo
+func (s *Service) VerifyEmail(token string) error {
+	userID, err := s.repo.VerifyEmail(hashToken(token))
+	if err == sql.ErrNoRows {
+		return ErrVerificationTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+	s.users.invalidate(userID)
+	return nil
+}
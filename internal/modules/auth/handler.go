@@ -1,10 +1,12 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jhvc/backend/internal/ratelimit"
 )
 
 type Handler struct {
@@ -22,7 +24,7 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.service.Register(req)
+	resp, err := h.service.Register(req, sessionInfo(c))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
 		return
@@ -42,9 +44,9 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.service.Login(req)
+	resp, err := h.service.Login(req, sessionInfo(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		respondLoginError(c, err)
 		return
 	}
 
@@ -55,6 +57,257 @@ func (h *Handler) Login(c *gin.Context) {
 	})
 }
 
+// Refresh cambia un refresh token vigente por un par de tokens nuevo
+func (h *Handler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	resp, err := h.service.Refresh(req.RefreshToken, sessionInfo(c))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    resp,
+	})
+}
+
+// Logout cierra la sesión del token en uso; el refresh token deja de servir
+func (h *Handler) Logout(c *gin.Context) {
+	if err := h.service.Logout(c.GetString("sessionID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Sesión cerrada",
+	})
+}
+
+func (h *Handler) GetSessions(c *gin.Context) {
+	sessions, err := h.service.GetSessions(c.GetInt("userID"), c.GetString("sessionID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    sessions,
+	})
+}
+
+func (h *Handler) RevokeSession(c *gin.Context) {
+	sessionID, _ := strconv.Atoi(c.Param("id"))
+
+	if err := h.service.RevokeSession(c.GetInt("userID"), sessionID); err != nil {
+		status := http.StatusInternalServerError
+		if err == ErrSessionNotFound {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Sesión cerrada",
+	})
+}
+
+// RevokeAllSessions cierra la sesión en todos los dispositivos
+func (h *Handler) RevokeAllSessions(c *gin.Context) {
+	if err := h.service.RevokeAllSessions(c.GetInt("userID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Sesiones cerradas",
+	})
+}
+
+// RevokeUserSessions (admin) cierra todas las sesiones de un usuario
+func (h *Handler) RevokeUserSessions(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	if err := h.service.RevokeUserSessions(ContextActor(c), userID); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Sesiones del usuario cerradas",
+	})
+}
+
+// ForgotPassword responde igual exista o no la cuenta, para no revelar emails
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := h.service.ForgotPassword(req.Email, sessionInfo(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": "No se pudo enviar el correo, intenta más tarde"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Si el email está registrado, recibirás un enlace para restablecer tu contraseña",
+	})
+}
+
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := h.service.ResetPassword(req.Token, req.Password); err != nil {
+		status := http.StatusInternalServerError
+		if err == ErrResetTokenInvalid {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Contraseña actualizada; inicia sesión de nuevo",
+	})
+}
+
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := h.service.VerifyEmail(req.Token); err != nil {
+		status := http.StatusInternalServerError
+		if err == ErrVerificationTokenInvalid {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Email verificado",
+	})
+}
+
+// ResendVerification envía un enlace nuevo al email del usuario autenticado
+func (h *Handler) ResendVerification(c *gin.Context) {
+	if err := h.service.ResendVerification(c.GetInt("userID")); err != nil {
+		status := http.StatusInternalServerError
+		if err == ErrEmailAlreadyVerified {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Enlace de verificación enviado",
+	})
+}
+
+// UnlockUser (admin) quita el bloqueo por intentos fallidos de una cuenta
+func (h *Handler) UnlockUser(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	if err := h.service.UnlockUser(ContextActor(c), userID); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Cuenta desbloqueada",
+	})
+}
+
+func respondLoginError(c *gin.Context, err error) {
+	if respondThrottled(c, err) {
+		return
+	}
+	status := http.StatusUnauthorized
+	if err == ErrAccountLocked {
+		status = http.StatusLocked
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
+
+// respondThrottled responde 429 con Retry-After si err es un bloqueo del limitador
+func respondThrottled(c *gin.Context, err error) bool {
+	var bloqueo *ratelimit.Bloqueo
+	if !errors.As(err, &bloqueo) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(bloqueo.Segundos()))
+	c.JSON(http.StatusTooManyRequests, gin.H{"success": false, "error": err.Error()})
+	return true
+}
+
+func sessionInfo(c *gin.Context) SessionInfo {
+	return SessionInfo{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// ContextActor identifica para la bitácora a quien hace la petición; lo usan
+// también los módulos que registran sus acciones administrativas
+func ContextActor(c *gin.Context) Actor {
+	a := Actor{UserID: c.GetInt("userID"), IPAddress: c.ClientIP()}
+	if user, ok := c.Get("user"); ok {
+		a.User, _ = user.(*User)
+	}
+	if key, ok := c.Get("apiKey"); ok {
+		if key, ok := key.(*APIKey); ok {
+			a.APIKeyID = &key.ID
+		}
+	}
+	return a
+}
+
+func respondLicenseError(c *gin.Context, err error) {
+	if respondNotFound(c, err) {
+		return
+	}
+	status := http.StatusInternalServerError
+	if err == ErrContactWithoutEmpresa {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
+
+func respondNotFound(c *gin.Context, err error) bool {
+	switch err {
+	case ErrUserNotFound, ErrInvitationCodeNotFound, ErrLicenseNotFound, ErrDeviceNotFound, ErrModuleNotFound, ErrContactNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return true
+	}
+	return false
+}
+
 func (h *Handler) GetProfile(c *gin.Context) {
 	userID := c.GetInt("userID")
 	user, err := h.service.GetProfile(userID)
@@ -94,8 +347,8 @@ func (h *Handler) UpdateUserStatus(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.UpdateUserStatus(userID, req.IsActive); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+	if err := h.service.UpdateUserStatus(ContextActor(c), userID, req.IsActive); err != nil {
+		respondRoleError(c, err)
 		return
 	}
 
@@ -106,8 +359,6 @@ func (h *Handler) UpdateUserStatus(c *gin.Context) {
 }
 
 func (h *Handler) CreateInvitationCode(c *gin.Context) {
-	adminID := c.GetInt("userID")
-
 	var req struct {
 		MaxUses   int `json:"max_uses" binding:"required"`
 		DaysValid int `json:"days_valid"`
@@ -118,7 +369,7 @@ func (h *Handler) CreateInvitationCode(c *gin.Context) {
 		return
 	}
 
-	code, err := h.service.CreateInvitationCode(adminID, req.MaxUses, req.DaysValid)
+	code, err := h.service.CreateInvitationCode(ContextActor(c), req.MaxUses, req.DaysValid)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
@@ -155,7 +406,10 @@ func (h *Handler) UpdateCodeStatus(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.UpdateCodeStatus(codeID, req.IsActive); err != nil {
+	if err := h.service.UpdateCodeStatus(ContextActor(c), codeID, req.IsActive); err != nil {
+		if respondNotFound(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
 	}
@@ -173,9 +427,9 @@ func (h *Handler) CreateProductLicense(c *gin.Context) {
 		return
 	}
 
-	licenseCode, err := h.service.CreateProductLicense(req)
+	licenseCode, err := h.service.CreateProductLicense(ContextActor(c), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		respondLicenseError(c, err)
 		return
 	}
 
@@ -186,8 +440,26 @@ func (h *Handler) CreateProductLicense(c *gin.Context) {
 	})
 }
 
+// GetAllProductLicenses lista todas las licencias; ?empresa_id= filtra por empresa
 func (h *Handler) GetAllProductLicenses(c *gin.Context) {
-	licenses, err := h.service.GetAllProductLicenses()
+	empresaID, _ := strconv.Atoi(c.Query("empresa_id"))
+
+	licenses, err := h.service.GetAllProductLicenses(empresaID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    licenses,
+	})
+}
+
+// GetEmpresaLicenses lista las licencias asignadas a la empresa del contexto
+// @Router /empresas/{empresaId}/licencias [get]
+func (h *Handler) GetEmpresaLicenses(c *gin.Context) {
+	licenses, err := h.service.GetAllProductLicenses(c.GetInt("empresaID"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
@@ -211,7 +483,10 @@ func (h *Handler) UpdateProductLicenseStatus(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.UpdateProductLicenseStatus(licenseID, req.IsActive); err != nil {
+	if err := h.service.UpdateProductLicenseStatus(ContextActor(c), licenseID, req.IsActive); err != nil {
+		if respondNotFound(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
 	}
@@ -225,7 +500,10 @@ func (h *Handler) UpdateProductLicenseStatus(c *gin.Context) {
 func (h *Handler) DeleteProductLicense(c *gin.Context) {
 	licenseID, _ := strconv.Atoi(c.Param("id"))
 
-	if err := h.service.DeleteProductLicense(licenseID); err != nil {
+	if err := h.service.DeleteProductLicense(ContextActor(c), licenseID); err != nil {
+		if respondNotFound(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
 	}
@@ -245,8 +523,8 @@ func (h *Handler) UpdateProductLicense(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.UpdateProductLicense(licenseID, req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+	if err := h.service.UpdateProductLicense(ContextActor(c), licenseID, req); err != nil {
+		respondLicenseError(c, err)
 		return
 	}
 
@@ -263,8 +541,11 @@ func (h *Handler) VerifyProductLicense(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.service.VerifyProductLicense(req)
+	resp, err := h.service.VerifyProductLicense(req, c.ClientIP())
 	if err != nil {
+		if respondThrottled(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
 	}
@@ -297,7 +578,10 @@ func (h *Handler) GetLicenseDevices(c *gin.Context) {
 func (h *Handler) RemoveLicenseDevice(c *gin.Context) {
 	deviceID, _ := strconv.Atoi(c.Param("deviceId"))
 
-	if err := h.service.RemoveLicenseDevice(deviceID); err != nil {
+	if err := h.service.RemoveLicenseDevice(ContextActor(c), deviceID); err != nil {
+		if respondNotFound(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
 	}
@@ -336,7 +620,10 @@ func (h *Handler) AddLicenseModule(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.AddLicenseModule(licenseID, req.ModuleName); err != nil {
+	if err := h.service.AddLicenseModule(ContextActor(c), licenseID, req.ModuleName); err != nil {
+		if respondNotFound(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
 	}
@@ -350,7 +637,10 @@ func (h *Handler) AddLicenseModule(c *gin.Context) {
 func (h *Handler) RemoveLicenseModule(c *gin.Context) {
 	moduleID, _ := strconv.Atoi(c.Param("moduleId"))
 
-	if err := h.service.RemoveLicenseModule(moduleID); err != nil {
+	if err := h.service.RemoveLicenseModule(ContextActor(c), moduleID); err != nil {
+		if respondNotFound(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
 	}
@@ -377,3 +667,353 @@ func (h *Handler) GetProducts(c *gin.Context) {
 		"data":    products,
 	})
 }
+
+// LoginMFA es el segundo paso del login para cuentas con 2FA
+func (h *Handler) LoginMFA(c *gin.Context) {
+	var req MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	resp, err := h.service.LoginMFA(req, sessionInfo(c))
+	if err != nil {
+		respondLoginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Login exitoso",
+		"data":    resp,
+	})
+}
+
+// SetupMFA genera la llave TOTP y el URI para el código QR
+func (h *Handler) SetupMFA(c *gin.Context) {
+	setup, err := h.service.SetupMFA(c.GetInt("userID"))
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    setup,
+	})
+}
+
+// ConfirmMFA activa 2FA y devuelve los códigos de recuperación (sólo esta vez)
+func (h *Handler) ConfirmMFA(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	codes, err := h.service.ConfirmMFA(c.GetInt("userID"), req.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Verificación en dos pasos activada",
+		"data":    codes,
+	})
+}
+
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(c.GetInt("userID"), req.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    codes,
+	})
+}
+
+func (h *Handler) DisableMFA(c *gin.Context) {
+	var req MFADisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := h.service.DisableMFA(c.GetInt("userID"), req.Password, req.Code); err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Verificación en dos pasos desactivada",
+	})
+}
+
+// ResetUserMFA (admin) quita 2FA a un usuario que perdió su dispositivo
+func (h *Handler) ResetUserMFA(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	if err := h.service.ResetUserMFA(ContextActor(c), userID); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Verificación en dos pasos reiniciada",
+	})
+}
+
+func respondMFAError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch err {
+	case ErrMFACodeInvalid, ErrMFASetupNotStarted:
+		status = http.StatusBadRequest
+	case ErrMFAAlreadyEnabled, ErrMFANotEnabled:
+		status = http.StatusConflict
+	case ErrMFARequiredForAdmin:
+		status = http.StatusForbidden
+	case ErrPasswordIncorrect:
+		status = http.StatusUnauthorized
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
+
+// ============================================
+// ROLES Y PERMISOS
+// ============================================
+
+func (h *Handler) GetRoles(c *gin.Context) {
+	roles, err := h.service.GetRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    roles,
+	})
+}
+
+// GetPermissions devuelve el catálogo de permisos asignables
+func (h *Handler) GetPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    Permissions,
+	})
+}
+
+func (h *Handler) CreateRole(c *gin.Context) {
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	role, err := h.service.CreateRole(ContextActor(c), req)
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    role,
+	})
+}
+
+func (h *Handler) UpdateRole(c *gin.Context) {
+	roleID, _ := strconv.Atoi(c.Param("id"))
+
+	var req UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	role, err := h.service.UpdateRole(ContextActor(c), roleID, req)
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    role,
+	})
+}
+
+func (h *Handler) DeleteRole(c *gin.Context) {
+	roleID, _ := strconv.Atoi(c.Param("id"))
+
+	if err := h.service.DeleteRole(ContextActor(c), roleID); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Rol eliminado",
+	})
+}
+
+// SetUserRoles reemplaza los roles de un usuario
+func (h *Handler) SetUserRoles(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	var req UserRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := h.service.SetUserRoles(ContextActor(c), userID, req.RoleIDs); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Roles actualizados",
+	})
+}
+
+func respondRoleError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch err {
+	case ErrRoleNotFound, ErrUserNotFound:
+		status = http.StatusNotFound
+	case ErrPermissionUnknown:
+		status = http.StatusBadRequest
+	case ErrRoleDuplicate, ErrLastSuperAdmin:
+		status = http.StatusConflict
+	case ErrRoleSystem, ErrPermissionEscalation:
+		status = http.StatusForbidden
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
+
+// ============================================
+// API KEYS
+// ============================================
+
+// CreateAPIKey devuelve la llave completa; no se vuelve a mostrar
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(ContextActor(c), req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err {
+		case ErrUserNotFound:
+			status = http.StatusNotFound
+		case ErrAPIKeyScope, ErrAPIKeyEmpresaScope, ErrNotEmpresaMember:
+			status = http.StatusBadRequest
+		case ErrPermissionEscalation, ErrAPIKeyEmpresaOwner:
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Guarda la llave: no se volverá a mostrar",
+		"data":    key,
+	})
+}
+
+// GetAPIKeys lista las llaves, opcionalmente de un usuario (?user_id=)
+func (h *Handler) GetAPIKeys(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Query("user_id"))
+
+	keys, err := h.service.GetAPIKeys(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    keys,
+	})
+}
+
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	keyID, _ := strconv.Atoi(c.Param("id"))
+
+	if err := h.service.RevokeAPIKey(ContextActor(c), keyID); err != nil {
+		status := http.StatusInternalServerError
+		if err == ErrAPIKeyNotFound {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "API key revocada",
+	})
+}
+
+// ============================================
+// AUDIT LOG
+// ============================================
+
+// GetAuditLog lista la bitácora de acciones administrativas con filtros
+// (actor_id, action, entity_type, entity_id, from, to) y paginación
+// (page, page_size)
+func (h *Handler) GetAuditLog(c *gin.Context) {
+	var filter AuditFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	page, err := h.service.GetAuditLog(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    page,
+	})
+}
+
+// ExportAuditLog descarga en CSV las entradas que cumplen los mismos filtros
+func (h *Handler) ExportAuditLog(c *gin.Context) {
+	var filter AuditFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	data, err := h.service.ExportAuditCSV(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="bitacora.csv"`)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+}
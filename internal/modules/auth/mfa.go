@@ -0,0 +1,307 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// mfaChallengeTTL es el tiempo para capturar el código después de la contraseña
+	mfaChallengeTTL   = 5 * time.Minute
+	mfaIssuer         = "JHVC Tech Solutions"
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrMFAChallengeInvalid = errors.New("la verificación en dos pasos expiró; inicia sesión de nuevo")
+	ErrMFACodeInvalid      = errors.New("código de verificación inválido")
+	ErrMFAAlreadyEnabled   = errors.New("la verificación en dos pasos ya está activa")
+	ErrMFANotEnabled       = errors.New("la verificación en dos pasos no está activa")
+	ErrMFASetupNotStarted  = errors.New("primero genera la llave de verificación en dos pasos")
+	ErrMFARequiredForAdmin = errors.New("los administradores deben mantener la verificación en dos pasos")
+	ErrPasswordIncorrect   = errors.New("contraseña incorrecta")
+)
+
+// RequireAdminMFA activa la política que exige 2FA a todas las cuentas admin
+func (s *Service) RequireAdminMFA(required bool) {
+	s.requireAdminMFA = required
+}
+
+// AdminMFAPending indica si la política bloquea al admin hasta que active 2FA
+func (s *Service) AdminMFAPending(user *User) bool {
+	return s.requireAdminMFA && user.IsAdmin && !user.MFAEnabled
+}
+
+// mfaChallenge firma el token del primer paso del login. No lleva sid, así que
+// no sirve como token de acceso.
+func (s *Service) mfaChallenge(user *User, remember bool) (*AuthResponse, error) {
+	claims := jwt.MapClaims{
+		"user_id":  user.ID,
+		"typ":      "mfa",
+		"remember": remember,
+		"exp":      time.Now().Add(mfaChallengeTTL).Unix(),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthResponse{MFARequired: true, MFAToken: token}, nil
+}
+
+// LoginMFA completa el login con el código TOTP o un código de recuperación
+func (s *Service) LoginMFA(req MFALoginRequest, info SessionInfo) (*AuthResponse, error) {
+	token, err := jwt.Parse(req.MFAToken, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("método de firma inválido")
+		}
+		return s.jwtSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrMFAChallengeInvalid
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != "mfa" {
+		return nil, ErrMFAChallengeInvalid
+	}
+	userIDFloat, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, ErrMFAChallengeInvalid
+	}
+
+	user, err := s.repo.GetUserByID(int(userIDFloat))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.limits.allowLogin(info.IPAddress, user.Email); err != nil {
+		return nil, err
+	}
+	if isLocked(user) {
+		return nil, ErrAccountLocked
+	}
+	if !user.IsActive {
+		return nil, errors.New("cuenta desactivada")
+	}
+
+	// Los códigos fallidos cuentan igual que las contraseñas fallidas
+	if err := s.verifyMFA(user.ID, req.Code); err != nil {
+		if err == ErrMFACodeInvalid {
+			s.limits.failLogin(info.IPAddress, user.Email)
+			s.recordFailedLogin(user)
+		}
+		return nil, err
+	}
+	s.limits.clearLogin(user.Email)
+	s.repo.ResetFailedLogins(user.ID)
+
+	duration := refreshTokenTTL
+	if remember, _ := claims["remember"].(bool); remember {
+		duration = rememberTokenTTL
+	}
+	return s.startSession(user, info, duration)
+}
+
+// SetupMFA genera una llave nueva pendiente de confirmar
+func (s *Service) SetupMFA(userID int) (*MFASetup, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	saved, err := s.repo.SaveMFASecret(userID, secret)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	return &MFASetup{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(mfaIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFA activa 2FA con el primer código de la app y entrega los códigos
+// de recuperación
+func (s *Service) ConfirmMFA(userID int, code string) (*MFARecoveryCodes, error) {
+	secret, confirmed, err := s.repo.GetMFASecret(userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrMFASetupNotStarted
+	}
+	if err != nil {
+		return nil, err
+	}
+	if confirmed {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if err := s.useTOTP(userID, secret, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ConfirmMFA(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	s.users.invalidate(userID)
+	return &MFARecoveryCodes{Codes: codes}, nil
+}
+
+// RegenerateRecoveryCodes invalida los códigos anteriores; pide un código de
+// la app para que no baste con una sesión abierta
+func (s *Service) RegenerateRecoveryCodes(userID int, code string) (*MFARecoveryCodes, error) {
+	secret, confirmed, err := s.repo.GetMFASecret(userID)
+	if err == sql.ErrNoRows || (err == nil && !confirmed) {
+		return nil, ErrMFANotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.useTOTP(userID, secret, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return &MFARecoveryCodes{Codes: codes}, nil
+}
+
+// DisableMFA quita 2FA de la propia cuenta con la contraseña y un código
+func (s *Service) DisableMFA(userID int, password, code string) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return ErrMFANotEnabled
+	}
+	if s.requireAdminMFA && user.IsAdmin {
+		return ErrMFARequiredForAdmin
+	}
+
+	_, passwordHash, err := s.repo.GetUserByEmail(user.Email)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)); err != nil {
+		return ErrPasswordIncorrect
+	}
+	if err := s.verifyMFA(userID, code); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteMFA(userID); err != nil {
+		return err
+	}
+	s.users.invalidate(userID)
+	return nil
+}
+
+// ResetUserMFA (admin) quita 2FA a un usuario que perdió su dispositivo y
+// cierra sus sesiones
+func (s *Service) ResetUserMFA(actor Actor, userID int) error {
+	before, err := s.repo.GetUserByID(userID)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.checkManageable(actor, before); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteMFA(userID); err != nil {
+		return err
+	}
+	s.users.invalidate(userID)
+	log.Printf("⚠️  2FA reiniciado por un administrador para el usuario %d", userID)
+	after, _ := s.repo.GetUserByID(userID)
+	s.Audit(actor, "user.mfa_reset", auditUser, userID, before, after)
+	return s.repo.RevokeUserSessions(userID)
+}
+
+// verifyMFA acepta un código TOTP de 6 dígitos o un código de recuperación
+func (s *Service) verifyMFA(userID int, code string) error {
+	code = strings.TrimSpace(code)
+
+	if len(code) == totpDigits && strings.Trim(code, "0123456789") == "" {
+		secret, confirmed, err := s.repo.GetMFASecret(userID)
+		if err == sql.ErrNoRows || (err == nil && !confirmed) {
+			return ErrMFANotEnabled
+		}
+		if err != nil {
+			return err
+		}
+		return s.useTOTP(userID, secret, code)
+	}
+
+	used, err := s.repo.UseRecoveryCode(userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrMFACodeInvalid
+	}
+	log.Printf("ℹ️  Código de recuperación usado por el usuario %d", userID)
+	return nil
+}
+
+// useTOTP valida el código y marca su periodo como usado
+func (s *Service) useTOTP(userID int, secret, code string) error {
+	step, ok := validateTOTP(secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return ErrMFACodeInvalid
+	}
+	fresh, err := s.repo.UseMFAStep(userID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrMFACodeInvalid
+	}
+	return nil
+}
+
+// newRecoveryCodes genera los códigos en claro (xxxxx-xxxxx) y sus hashes
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw, err := randomToken(5)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
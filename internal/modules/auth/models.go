@@ -1,16 +1,25 @@
 package auth
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type User struct {
-	ID          int       `json:"id"`
-	Email       string    `json:"email"`
-	FullName    string    `json:"full_name"`
-	CompanyName string    `json:"company_name,omitempty"`
-	Phone       string    `json:"phone,omitempty"`
-	IsActive    bool      `json:"is_active"`
-	IsAdmin     bool      `json:"is_admin"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID            int        `json:"id"`
+	Email         string     `json:"email"`
+	FullName      string     `json:"full_name"`
+	CompanyName   string     `json:"company_name,omitempty"`
+	RFC           string     `json:"rfc,omitempty"`
+	Phone         string     `json:"phone,omitempty"`
+	IsActive      bool       `json:"is_active"`
+	IsAdmin       bool       `json:"is_admin"` // Tiene algún rol administrativo
+	Roles         []string   `json:"roles"`
+	Permissions   []string   `json:"permissions,omitempty"` // Sólo en el perfil y el usuario actual
+	EmailVerified bool       `json:"email_verified"`
+	MFAEnabled    bool       `json:"mfa_enabled"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"` // Bloqueo por intentos fallidos
+	CreatedAt     time.Time  `json:"created_at"`
 }
 
 type RegisterRequest struct {
@@ -19,6 +28,7 @@ type RegisterRequest struct {
 	FullName       string `json:"fullName" binding:"required"`
 	Phone          string `json:"phone"`
 	CompanyName    string `json:"company"`
+	RFC            string `json:"rfc" binding:"omitempty,rfc"`
 	InvitationCode string `json:"invitationCode" binding:"required"`
 }
 
@@ -28,9 +38,152 @@ type LoginRequest struct {
 	Remember bool   `json:"remember"`
 }
 
+// AuthResponse - Tokens de la sesión. Si la cuenta tiene 2FA, el login sólo
+// devuelve MFARequired y MFAToken, que se canjea en /login/mfa con el código.
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token            string `json:"token,omitempty"`
+	RefreshToken     string `json:"refresh_token,omitempty"`
+	ExpiresIn        int    `json:"expires_in,omitempty"` // Segundos de vida del token de acceso
+	User             *User  `json:"user,omitempty"`
+	MFARequired      bool   `json:"mfa_required,omitempty"`
+	MFAToken         string `json:"mfa_token,omitempty"`
+	MFASetupRequired bool   `json:"mfa_setup_required,omitempty"` // Admin sin 2FA con la política activa
+}
+
+// MFALoginRequest - Segundo paso del login: código TOTP o código de recuperación
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type MFADisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFASetup - Llave pendiente de confirmar; ProvisioningURI se muestra como QR
+type MFASetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// MFARecoveryCodes se muestran una sola vez; en la base sólo queda su hash
+type MFARecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// SessionInfo identifica al cliente que abre o renueva una sesión
+type SessionInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// ActiveSession - Sesión vigente (familia de refresh tokens) para el listado
+// del usuario; LastUsedAt es la última renovación
+type ActiveSession struct {
+	ID         int       `json:"id"`
+	FamilyID   string    `json:"-"`
+	Current    bool      `json:"current"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Session - Refresh token emitido. Cada rotación crea un registro nuevo en la
+// misma familia (FamilyID), que es la sesión que abrió el login.
+type Session struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	FamilyID  string     `json:"-"`
+	TokenHash string     `json:"-"`
+	IPAddress string     `json:"ip_address,omitempty"`
+	UserAgent string     `json:"user_agent,omitempty"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Role - Conjunto de permisos asignable a usuarios; los de sistema no se editan
+type Role struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	IsSystem    bool      `json:"is_system"`
+	Permissions []string  `json:"permissions"`
+	Users       int       `json:"users"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type RoleRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+type UpdateRoleRequest struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+type UserRolesRequest struct {
+	RoleIDs []int `json:"role_ids"`
+}
+
+// APIKey - Llave para integraciones servidor a servidor. Actúa como su usuario
+// limitada a Scopes; con EmpresaID sólo sirve para esa empresa. El secreto
+// sólo se guarda como hash.
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	EmpresaID  *int       `json:"empresa_id,omitempty"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	SecretHash string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedBy  int        `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string   `json:"name" binding:"required"`
+	UserID    int      `json:"user_id"`    // Opcional: por omisión, quien la crea
+	EmpresaID *int     `json:"empresa_id"` // Opcional: llave de una empresa
+	Scopes    []string `json:"scopes" binding:"required"`
+	DaysValid int      `json:"days_valid"` // 0 = sin vencimiento
+}
+
+// CreatedAPIKey incluye la llave completa; sólo se muestra al crearla
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
 }
 
 type InvitationCode struct {
@@ -47,6 +200,8 @@ type InvitationCode struct {
 // ProductLicense - Licencia principal (sin machine_id, current_devices, product_name)
 type ProductLicense struct {
 	ID          int        `json:"id"`
+	EmpresaID   *int       `json:"empresa_id,omitempty"`
+	ContactoID  *int       `json:"contacto_id,omitempty"`
 	LicenseCode string     `json:"license_code"`
 	ClientName  string     `json:"client_name"`
 	ClientEmail string     `json:"client_email,omitempty"`
@@ -86,7 +241,9 @@ type Product struct {
 }
 
 type CreateProductLicenseRequest struct {
-	ClientName  string   `json:"client_name" binding:"required"`
+	EmpresaID   *int     `json:"empresa_id"`  // Opcional: empresa a la que se asigna
+	ContactoID  *int     `json:"contacto_id"` // Opcional: cliente del directorio de esa empresa
+	ClientName  string   `json:"client_name" binding:"required_without=ContactoID"`
 	ClientEmail string   `json:"client_email"`
 	MaxDevices  int      `json:"max_devices"`
 	DaysValid   int      `json:"days_valid"`
@@ -95,7 +252,9 @@ type CreateProductLicenseRequest struct {
 }
 
 type UpdateProductLicenseRequest struct {
-	ClientName  string `json:"client_name" binding:"required"`
+	EmpresaID   *int   `json:"empresa_id"`
+	ContactoID  *int   `json:"contacto_id"`
+	ClientName  string `json:"client_name" binding:"required_without=ContactoID"`
 	ClientEmail string `json:"client_email"`
 	MaxDevices  int    `json:"max_devices"`
 	DaysValid   int    `json:"days_valid"`
@@ -128,3 +287,40 @@ type ProductLicenseWithDetails struct {
 	CurrentDevices int      `json:"current_devices"`
 	Modules        []string `json:"modules"`
 }
+
+// AuditEntry - Acción administrativa registrada. Before/After son la entidad
+// antes y después del cambio; Changes sólo los campos que cambiaron.
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	ActorID    *int            `json:"actor_id,omitempty"`
+	ActorEmail string          `json:"actor_email,omitempty"`
+	APIKeyID   *int            `json:"api_key_id,omitempty"`
+	IPAddress  string          `json:"ip_address,omitempty"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   int             `json:"entity_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	Changes    json.RawMessage `json:"changes,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditFilter - Filtros de la bitácora; from y to son fechas (YYYY-MM-DD)
+// inclusivas
+type AuditFilter struct {
+	ActorID    int       `form:"actor_id"`
+	Action     string    `form:"action"`
+	EntityType string    `form:"entity_type"`
+	EntityID   int       `form:"entity_id"`
+	From       time.Time `form:"from" time_format:"2006-01-02"`
+	To         time.Time `form:"to" time_format:"2006-01-02"`
+	Page       int       `form:"page" binding:"min=0"`
+	PageSize   int       `form:"page_size" binding:"min=0"`
+}
+
+type AuditPage struct {
+	Entries  []AuditEntry `json:"entries"`
+	Total    int          `json:"total"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+}
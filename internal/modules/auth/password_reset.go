@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const passwordResetTTL = time.Hour
+
+var ErrResetTokenInvalid = errors.New("el enlace para restablecer la contraseña es inválido o ya venció")
+
+// Mailer encola correos a partir de una plantilla; lo implementa mail.Outbox
+type Mailer interface {
+	EnviarPlantilla(para, plantilla string, datos interface{}) error
+}
+
+// UseMailer conecta el envío de correos y la URL pública con la que se arman
+// los enlaces de los correos
+func (s *Service) UseMailer(m Mailer, appURL string) {
+	s.mailer = m
+	s.appURL = strings.TrimSuffix(appURL, "/")
+}
+
+// ForgotPassword envía un enlace para restablecer la contraseña. No indica si
+// el email existe: el resultado es el mismo para cualquier dirección.
+func (s *Service) ForgotPassword(email string, info SessionInfo) error {
+	if s.mailer == nil {
+		return errors.New("el envío de correos no está configurado")
+	}
+
+	user, _, err := s.repo.GetUserByEmail(strings.TrimSpace(email))
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.CreatePasswordReset(user.ID, hashToken(token), info.IPAddress, time.Now().Add(passwordResetTTL)); err != nil {
+		return err
+	}
+
+	err = s.mailer.EnviarPlantilla(user.Email, "restablecer_password", map[string]string{
+		"Nombre":   user.FullName,
+		"Enlace":   s.appURL + "/reset-password?token=" + token,
+		"Vigencia": "1 hora",
+	})
+	if err != nil {
+		log.Printf("⚠️  No se pudo encolar el correo de recuperación para el usuario %d: %v", user.ID, err)
+		return err
+	}
+	return nil
+}
+
+// ResetPassword consume el token, cambia la contraseña y cierra todas las
+// sesiones del usuario
+func (s *Service) ResetPassword(token, password string) error {
+	userID, err := s.repo.UsePasswordReset(hashToken(token))
+	if err == sql.ErrNoRows {
+		return ErrResetTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePassword(userID, string(hashedPassword)); err != nil {
+		return err
+	}
+
+	s.users.invalidate(userID)
+	return s.repo.RevokeUserSessions(userID)
+}
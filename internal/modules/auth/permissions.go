@@ -0,0 +1,69 @@
+package auth
+
+// Permisos del panel de administración. Se guardan como texto en
+// role_permissions; PermAll sólo lo tiene el rol super_admin.
+const (
+	PermAll            = "*"
+	PermUsersRead      = "users:read"
+	PermUsersManage    = "users:manage"
+	PermRolesManage    = "roles:manage"
+	PermCodesRead      = "codes:read"
+	PermCodesCreate    = "codes:create"
+	PermLicensesRead   = "licenses:read"
+	PermLicensesWrite  = "licenses:write"
+	PermCatalogsManage = "catalogs:manage"
+	PermReportsRead    = "reports:read"
+	PermAPIKeysManage  = "apikeys:manage"
+)
+
+// SuperAdminRole es el rol de sistema con todos los permisos; los admins de
+// antes de los roles se migraron a él
+const SuperAdminRole = "super_admin"
+
+// Permission describe un permiso para el catálogo del panel
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Permissions es el catálogo de permisos asignables a un rol
+var Permissions = []Permission{
+	{PermUsersRead, "Ver usuarios"},
+	{PermUsersManage, "Activar, desbloquear y cerrar sesiones de usuarios"},
+	{PermRolesManage, "Crear roles y asignarlos a usuarios"},
+	{PermCodesRead, "Ver códigos de invitación"},
+	{PermCodesCreate, "Crear y desactivar códigos de invitación"},
+	{PermLicensesRead, "Ver licencias, dispositivos y productos"},
+	{PermLicensesWrite, "Crear, editar y eliminar licencias"},
+	{PermCatalogsManage, "Importar y activar catálogos del SAT"},
+	{PermReportsRead, "Ver reportes y bitácoras"},
+	{PermAPIKeysManage, "Crear, listar y revocar API keys"},
+}
+
+func isKnownPermission(name string) bool {
+	for _, p := range Permissions {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *User) isSuperAdmin() bool {
+	for _, name := range u.Roles {
+		if name == SuperAdminRole {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission revisa los permisos cargados por CurrentUser
+func (u *User) HasPermission(permission string) bool {
+	for _, p := range u.Permissions {
+		if p == permission || p == PermAll {
+			return true
+		}
+	}
+	return false
+}
@@ -3,6 +3,8 @@ package auth
 import (
 	"database/sql"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type Repository struct {
@@ -13,68 +15,84 @@ func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) CreateUser(email, passwordHash, fullName, companyName, phone string) (int64, error) {
+func (r *Repository) CreateUser(email, passwordHash, fullName, companyName, rfc, phone string) (int64, error) {
 	var id int64
 	err := r.db.QueryRow(`
-        INSERT INTO users (email, password_hash, full_name, company_name, phone)
-        VALUES ($1, $2, $3, $4, $5)
+        INSERT INTO users (email, password_hash, full_name, company_name, rfc, phone)
+        VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
         RETURNING id
-    `, email, passwordHash, fullName, companyName, phone).Scan(&id)
+    `, email, passwordHash, fullName, companyName, rfc, phone).Scan(&id)
 	if err != nil {
 		return 0, err
 	}
 	return id, nil
 }
 
-func (r *Repository) GetUserByEmail(email string) (*User, string, error) {
+// userColumns son las columnas que lee scanUser. IsAdmin se deriva de los roles:
+// cualquier rol da acceso al panel y los permisos deciden qué puede hacer.
+const userColumns = `
+        id, email, full_name, company_name, rfc, phone, is_active,
+        email_verified_at IS NOT NULL,
+        EXISTS(SELECT 1 FROM user_mfa m WHERE m.user_id = users.id AND m.confirmed_at IS NOT NULL),
+        CASE WHEN locked_until > CURRENT_TIMESTAMP THEN locked_until END,
+        ARRAY(
+            SELECT ro.name FROM user_roles ur JOIN roles ro ON ro.id = ur.role_id
+            WHERE ur.user_id = users.id ORDER BY ro.name
+        ),
+        created_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser lee userColumns; extra recibe las columnas que la consulta agregue al final
+func scanUser(row rowScanner, extra ...interface{}) (*User, error) {
 	var user User
-	var passwordHash string
-	var companyName, phone sql.NullString
+	var companyName, rfc, phone sql.NullString
+	var lockedUntil sql.NullTime
 
-	err := r.db.QueryRow(`
-        SELECT id, email, password_hash, full_name, company_name, phone, is_active, is_admin, created_at
-        FROM users WHERE email = $1
-    `, email).Scan(
-		&user.ID, &user.Email, &passwordHash, &user.FullName,
-		&companyName, &phone, &user.IsActive, &user.IsAdmin, &user.CreatedAt,
-	)
-	if err != nil {
-		return nil, "", err
+	dest := []interface{}{
+		&user.ID, &user.Email, &user.FullName, &companyName, &rfc, &phone, &user.IsActive,
+		&user.EmailVerified, &user.MFAEnabled, &lockedUntil, pq.Array(&user.Roles), &user.CreatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
 	}
 
 	if companyName.Valid {
 		user.CompanyName = companyName.String
 	}
+	if rfc.Valid {
+		user.RFC = rfc.String
+	}
 	if phone.Valid {
 		user.Phone = phone.String
 	}
+	if lockedUntil.Valid {
+		user.LockedUntil = &lockedUntil.Time
+	}
+	user.IsAdmin = len(user.Roles) > 0
 
-	return &user, passwordHash, nil
+	return &user, nil
 }
 
-func (r *Repository) GetUserByID(id int) (*User, error) {
-	var user User
-	var companyName, phone sql.NullString
-
-	err := r.db.QueryRow(`
-        SELECT id, email, full_name, company_name, phone, is_active, is_admin, created_at
-        FROM users WHERE id = $1
-    `, id).Scan(
-		&user.ID, &user.Email, &user.FullName,
-		&companyName, &phone, &user.IsActive, &user.IsAdmin, &user.CreatedAt,
-	)
+func (r *Repository) GetUserByEmail(email string) (*User, string, error) {
+	var passwordHash string
+	user, err := scanUser(r.db.QueryRow(`
+        SELECT `+userColumns+`, password_hash
+        FROM users WHERE email = $1
+    `, email), &passwordHash)
 	if err != nil {
-		return nil, err
-	}
-
-	if companyName.Valid {
-		user.CompanyName = companyName.String
-	}
-	if phone.Valid {
-		user.Phone = phone.String
+		return nil, "", err
 	}
+	return user, passwordHash, nil
+}
 
-	return &user, nil
+func (r *Repository) GetUserByID(id int) (*User, error) {
+	return scanUser(r.db.QueryRow(`
+        SELECT `+userColumns+`
+        FROM users WHERE id = $1
+    `, id))
 }
 
 func (r *Repository) EmailExists(email string) (bool, error) {
@@ -83,14 +101,6 @@ func (r *Repository) EmailExists(email string) (bool, error) {
 	return exists, err
 }
 
-func (r *Repository) SaveSession(userID int, token, ipAddress string, expiresAt string) error {
-	_, err := r.db.Exec(`
-        INSERT INTO sessions (user_id, token, ip_address, expires_at)
-        VALUES ($1, $2, $3, $4)
-    `, userID, token, ipAddress, expiresAt)
-	return err
-}
-
 func (r *Repository) ValidateInvitationCode(code string) (*InvitationCode, error) {
 	var inv InvitationCode
 	var expiresAt sql.NullTime
@@ -134,8 +144,8 @@ func (r *Repository) RecordCodeUsage(codeID, userID int) error {
 
 func (r *Repository) GetAllUsers() ([]User, error) {
 	rows, err := r.db.Query(`
-        SELECT id, email, full_name, company_name, phone, is_active, is_admin, created_at
-        FROM users 
+        SELECT ` + userColumns + `
+        FROM users
         ORDER BY created_at DESC
     `)
 	if err != nil {
@@ -145,22 +155,11 @@ func (r *Repository) GetAllUsers() ([]User, error) {
 
 	var users []User
 	for rows.Next() {
-		var u User
-		var companyName, phone sql.NullString
-
-		err := rows.Scan(&u.ID, &u.Email, &u.FullName, &companyName, &phone, &u.IsActive, &u.IsAdmin, &u.CreatedAt)
+		u, err := scanUser(rows)
 		if err != nil {
 			continue
 		}
-
-		if companyName.Valid {
-			u.CompanyName = companyName.String
-		}
-		if phone.Valid {
-			u.Phone = phone.String
-		}
-
-		users = append(users, u)
+		users = append(users, *u)
 	}
 
 	return users, nil
@@ -171,12 +170,52 @@ func (r *Repository) UpdateUserStatus(userID int, isActive bool) error {
 	return err
 }
 
-func (r *Repository) CreateInvitationCode(code string, maxUses int, createdBy int, expiresAt *time.Time) error {
+// RecordFailedLogin suma un intento fallido (la cuenta se reinicia si el
+// anterior fue hace más de window) y bloquea la cuenta al llegar a maxFailed;
+// devuelve si quedó bloqueada
+func (r *Repository) RecordFailedLogin(userID, maxFailed int, window, lockout time.Duration) (bool, error) {
+	var failed int
+	err := r.db.QueryRow(`
+        UPDATE users SET
+            failed_logins = CASE
+                WHEN last_failed_login IS NULL OR last_failed_login < CURRENT_TIMESTAMP - $2 * INTERVAL '1 second' THEN 1
+                ELSE failed_logins + 1
+            END,
+            last_failed_login = CURRENT_TIMESTAMP
+        WHERE id = $1
+        RETURNING failed_logins
+    `, userID, int(window.Seconds())).Scan(&failed)
+	if err != nil {
+		return false, err
+	}
+	if failed < maxFailed {
+		return false, nil
+	}
+
+	_, err = r.db.Exec(`
+        UPDATE users SET failed_logins = 0, locked_until = CURRENT_TIMESTAMP + $2 * INTERVAL '1 second'
+        WHERE id = $1
+    `, userID, int(lockout.Seconds()))
+	return err == nil, err
+}
+
+// ResetFailedLogins limpia el contador y el bloqueo (login exitoso o desbloqueo manual)
+func (r *Repository) ResetFailedLogins(userID int) error {
 	_, err := r.db.Exec(`
+        UPDATE users SET failed_logins = 0, last_failed_login = NULL, locked_until = NULL
+        WHERE id = $1 AND (failed_logins > 0 OR locked_until IS NOT NULL)
+    `, userID)
+	return err
+}
+
+func (r *Repository) CreateInvitationCode(code string, maxUses int, createdBy int, expiresAt *time.Time) (int, error) {
+	var id int
+	err := r.db.QueryRow(`
         INSERT INTO invitation_codes (code, max_uses, created_by, expires_at)
         VALUES ($1, $2, $3, $4)
-    `, code, maxUses, createdBy, expiresAt)
-	return err
+        RETURNING id
+    `, code, maxUses, createdBy, expiresAt).Scan(&id)
+	return id, err
 }
 
 func (r *Repository) GetAllInvitationCodes() ([]InvitationCode, error) {
@@ -215,6 +254,30 @@ func (r *Repository) GetAllInvitationCodes() ([]InvitationCode, error) {
 	return codes, nil
 }
 
+func (r *Repository) GetInvitationCode(id int) (*InvitationCode, error) {
+	var c InvitationCode
+	var expiresAt sql.NullTime
+	var createdBy sql.NullInt64
+
+	err := r.db.QueryRow(`
+        SELECT id, code, max_uses, current_uses, is_active, created_by, created_at, expires_at
+        FROM invitation_codes
+        WHERE id = $1
+    `, id).Scan(&c.ID, &c.Code, &c.MaxUses, &c.CurrentUses, &c.IsActive, &createdBy, &c.CreatedAt, &expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if createdBy.Valid {
+		c.CreatedBy = int(createdBy.Int64)
+	}
+	if expiresAt.Valid {
+		c.ExpiresAt = &expiresAt.Time
+	}
+
+	return &c, nil
+}
+
 func (r *Repository) UpdateCodeStatus(codeID int, isActive bool) error {
 	_, err := r.db.Exec("UPDATE invitation_codes SET is_active = $1 WHERE id = $2", isActive, codeID)
 	return err
@@ -224,7 +287,7 @@ func (r *Repository) UpdateCodeStatus(codeID int, isActive bool) error {
 // PRODUCT LICENSES
 // ============================================
 
-func (r *Repository) CreateProductLicense(licenseCode, clientName, clientEmail string, maxDevices, daysValid int, notes string) (int64, error) {
+func (r *Repository) CreateProductLicense(empresaID, contactoID *int, licenseCode, clientName, clientEmail string, maxDevices, daysValid int, notes string) (int64, error) {
 	var expiresAt *time.Time
 	if daysValid > 0 {
 		exp := time.Now().Add(time.Duration(daysValid) * 24 * time.Hour)
@@ -233,19 +296,21 @@ func (r *Repository) CreateProductLicense(licenseCode, clientName, clientEmail s
 
 	var id int64
 	err := r.db.QueryRow(`
-        INSERT INTO product_licenses (license_code, client_name, client_email, max_devices, expires_at, notes)
-        VALUES ($1, $2, $3, $4, $5, $6)
+        INSERT INTO product_licenses (empresa_id, license_code, client_name, client_email, max_devices, expires_at, notes, contacto_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
         RETURNING id
-    `, licenseCode, clientName, clientEmail, maxDevices, expiresAt, notes).Scan(&id)
+    `, empresaID, licenseCode, clientName, clientEmail, maxDevices, expiresAt, notes, contactoID).Scan(&id)
 	return id, err
 }
 
-func (r *Repository) GetAllProductLicenses() ([]ProductLicenseWithDetails, error) {
+// GetAllProductLicenses lista las licencias; con empresaID > 0 sólo las de esa empresa
+func (r *Repository) GetAllProductLicenses(empresaID int) ([]ProductLicenseWithDetails, error) {
 	rows, err := r.db.Query(`
-        SELECT id, license_code, client_name, client_email, is_active, max_devices, created_at, expires_at, notes
-        FROM product_licenses 
+        SELECT id, empresa_id, contacto_id, license_code, client_name, client_email, is_active, max_devices, created_at, expires_at, notes
+        FROM product_licenses
+        WHERE ($1 = 0 OR empresa_id = $1)
         ORDER BY created_at DESC
-    `)
+    `, empresaID)
 	if err != nil {
 		return nil, err
 	}
@@ -256,13 +321,22 @@ func (r *Repository) GetAllProductLicenses() ([]ProductLicenseWithDetails, error
 		var lic ProductLicenseWithDetails
 		var clientEmail, notes sql.NullString
 		var expiresAt sql.NullTime
+		var licEmpresaID, contactoID sql.NullInt64
 
-		err := rows.Scan(&lic.ID, &lic.LicenseCode, &lic.ClientName, &clientEmail,
+		err := rows.Scan(&lic.ID, &licEmpresaID, &contactoID, &lic.LicenseCode, &lic.ClientName, &clientEmail,
 			&lic.IsActive, &lic.MaxDevices, &lic.CreatedAt, &expiresAt, &notes)
 		if err != nil {
 			continue
 		}
 
+		if licEmpresaID.Valid {
+			id := int(licEmpresaID.Int64)
+			lic.EmpresaID = &id
+		}
+		if contactoID.Valid {
+			id := int(contactoID.Int64)
+			lic.ContactoID = &id
+		}
 		if clientEmail.Valid {
 			lic.ClientEmail = clientEmail.String
 		}
@@ -303,7 +377,7 @@ func (r *Repository) DeleteProductLicense(id int) error {
 	return err
 }
 
-func (r *Repository) UpdateProductLicense(id int, clientName, clientEmail string, maxDevices, daysValid int, notes string, isActive bool) error {
+func (r *Repository) UpdateProductLicense(id int, empresaID, contactoID *int, clientName, clientEmail string, maxDevices, daysValid int, notes string, isActive bool) error {
 	var expiresAt *time.Time
 	if daysValid > 0 {
 		exp := time.Now().Add(time.Duration(daysValid) * 24 * time.Hour)
@@ -313,12 +387,23 @@ func (r *Repository) UpdateProductLicense(id int, clientName, clientEmail string
 	_, err := r.db.Exec(`
         UPDATE product_licenses 
         SET client_name = $1, client_email = $2, max_devices = $3, 
-            expires_at = $4, notes = $5, is_active = $6
-        WHERE id = $7
-    `, clientName, clientEmail, maxDevices, expiresAt, notes, isActive, id)
+            expires_at = $4, notes = $5, is_active = $6, empresa_id = $7, contacto_id = $8
+        WHERE id = $9
+    `, clientName, clientEmail, maxDevices, expiresAt, notes, isActive, empresaID, contactoID, id)
 	return err
 }
 
+// GetContact devuelve el nombre y el email de un contacto del directorio de
+// la empresa
+func (r *Repository) GetContact(empresaID, contactoID int) (string, string, error) {
+	var name string
+	var email sql.NullString
+	err := r.db.QueryRow(`
+        SELECT razon_social, email FROM contactos WHERE empresa_id = $1 AND id = $2
+    `, empresaID, contactoID).Scan(&name, &email)
+	return name, email.String, err
+}
+
 func (r *Repository) GetProductLicenseByCode(licenseCode string) (*ProductLicense, error) {
 	var lic ProductLicense
 	var clientEmail, notes sql.NullString
@@ -350,6 +435,50 @@ func (r *Repository) GetProductLicenseByCode(licenseCode string) (*ProductLicens
 	return &lic, nil
 }
 
+// GetProductLicense devuelve la licencia con sus módulos y el número de
+// dispositivos registrados
+func (r *Repository) GetProductLicense(id int) (*ProductLicenseWithDetails, error) {
+	var lic ProductLicenseWithDetails
+	var clientEmail, notes sql.NullString
+	var expiresAt sql.NullTime
+	var empresaID, contactoID sql.NullInt64
+
+	err := r.db.QueryRow(`
+        SELECT id, empresa_id, contacto_id, license_code, client_name, client_email, is_active, max_devices, created_at, expires_at, notes,
+               (SELECT COUNT(*) FROM license_devices WHERE license_id = product_licenses.id),
+               ARRAY(SELECT module_name FROM license_modules WHERE license_id = product_licenses.id ORDER BY module_name)
+        FROM product_licenses
+        WHERE id = $1
+    `, id).Scan(
+		&lic.ID, &empresaID, &contactoID, &lic.LicenseCode, &lic.ClientName, &clientEmail,
+		&lic.IsActive, &lic.MaxDevices, &lic.CreatedAt, &expiresAt, &notes,
+		&lic.CurrentDevices, pq.Array(&lic.Modules),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if empresaID.Valid {
+		id := int(empresaID.Int64)
+		lic.EmpresaID = &id
+	}
+	if contactoID.Valid {
+		id := int(contactoID.Int64)
+		lic.ContactoID = &id
+	}
+	if clientEmail.Valid {
+		lic.ClientEmail = clientEmail.String
+	}
+	if expiresAt.Valid {
+		lic.ExpiresAt = &expiresAt.Time
+	}
+	if notes.Valid {
+		lic.Notes = notes.String
+	}
+
+	return &lic, nil
+}
+
 // ============================================
 // LICENSE DEVICES
 // ============================================
@@ -434,6 +563,29 @@ func (r *Repository) GetLicenseDevices(licenseID int) ([]LicenseDevice, error) {
 	return devices, nil
 }
 
+func (r *Repository) GetLicenseDevice(deviceID int) (*LicenseDevice, error) {
+	var device LicenseDevice
+	var deviceName sql.NullString
+
+	err := r.db.QueryRow(`
+        SELECT id, license_id, machine_id, device_name, first_activation, last_check
+        FROM license_devices
+        WHERE id = $1
+    `, deviceID).Scan(
+		&device.ID, &device.LicenseID, &device.MachineID, &deviceName,
+		&device.FirstActivation, &device.LastCheck,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if deviceName.Valid {
+		device.DeviceName = deviceName.String
+	}
+
+	return &device, nil
+}
+
 func (r *Repository) DeleteLicenseDevice(deviceID int) error {
 	_, err := r.db.Exec("DELETE FROM license_devices WHERE id = $1", deviceID)
 	return err
@@ -485,6 +637,19 @@ func (r *Repository) GetLicenseModules(licenseID int) ([]LicenseModule, error) {
 	return modules, nil
 }
 
+func (r *Repository) GetLicenseModule(moduleID int) (*LicenseModule, error) {
+	var module LicenseModule
+	err := r.db.QueryRow(`
+        SELECT id, license_id, module_name, added_at
+        FROM license_modules
+        WHERE id = $1
+    `, moduleID).Scan(&module.ID, &module.LicenseID, &module.ModuleName, &module.AddedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &module, nil
+}
+
 func (r *Repository) DeleteLicenseModule(moduleID int) error {
 	_, err := r.db.Exec("DELETE FROM license_modules WHERE id = $1", moduleID)
 	return err
@@ -526,3 +691,732 @@ func (r *Repository) GetAllProducts() ([]Product, error) {
 
 	return products, nil
 }
+
+// ============================================
+// SESSIONS
+// ============================================
+
+// CreateSession guarda un refresh token (sólo su hash) de la familia indicada
+func (r *Repository) CreateSession(sess *Session) error {
+	return r.db.QueryRow(`
+        INSERT INTO sessions (user_id, family_id, token, ip_address, user_agent, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at
+    `, sess.UserID, sess.FamilyID, sess.TokenHash, sess.IPAddress, sess.UserAgent, sess.ExpiresAt).Scan(&sess.ID, &sess.CreatedAt)
+}
+
+func (r *Repository) GetSessionByToken(tokenHash string) (*Session, error) {
+	var sess Session
+	var ipAddress, userAgent sql.NullString
+	var usedAt, revokedAt sql.NullTime
+
+	err := r.db.QueryRow(`
+        SELECT id, user_id, family_id, token, ip_address, user_agent, expires_at, used_at, revoked_at, created_at
+        FROM sessions WHERE token = $1
+    `, tokenHash).Scan(
+		&sess.ID, &sess.UserID, &sess.FamilyID, &sess.TokenHash, &ipAddress, &userAgent,
+		&sess.ExpiresAt, &usedAt, &revokedAt, &sess.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sess.IPAddress = ipAddress.String
+	sess.UserAgent = userAgent.String
+	if usedAt.Valid {
+		sess.UsedAt = &usedAt.Time
+	}
+	if revokedAt.Valid {
+		sess.RevokedAt = &revokedAt.Time
+	}
+
+	return &sess, nil
+}
+
+// MarkSessionUsed marca el refresh token como rotado. Devuelve false si otra
+// solicitud ya lo había usado o la sesión fue revocada.
+func (r *Repository) MarkSessionUsed(id int) (bool, error) {
+	res, err := r.db.Exec(`
+        UPDATE sessions SET used_at = CURRENT_TIMESTAMP
+        WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL
+    `, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RevokeSessionFamily revoca todos los refresh tokens derivados del mismo login
+func (r *Repository) RevokeSessionFamily(familyID string) error {
+	_, err := r.db.Exec(`
+        UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+        WHERE family_id = $1 AND revoked_at IS NULL
+    `, familyID)
+	return err
+}
+
+// IsSessionActive indica si la familia tiene un refresh token vigente sin revocar
+func (r *Repository) IsSessionActive(familyID string) (bool, error) {
+	var active bool
+	err := r.db.QueryRow(`
+        SELECT EXISTS(
+            SELECT 1 FROM sessions
+            WHERE family_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+        )
+    `, familyID).Scan(&active)
+	return active, err
+}
+
+// GetActiveSessions devuelve una fila por sesión vigente del usuario. El ID es
+// el del primer refresh token de la familia, que no cambia al rotar.
+func (r *Repository) GetActiveSessions(userID int) ([]ActiveSession, error) {
+	rows, err := r.db.Query(`
+        SELECT f.id, s.family_id, s.ip_address, s.user_agent, f.created_at, s.created_at, s.expires_at
+        FROM sessions s
+        JOIN (
+            SELECT family_id, MIN(id) AS id, MIN(created_at) AS created_at
+            FROM sessions WHERE user_id = $1
+            GROUP BY family_id
+        ) f ON f.family_id = s.family_id
+        WHERE s.user_id = $1 AND s.used_at IS NULL AND s.revoked_at IS NULL
+          AND s.expires_at > CURRENT_TIMESTAMP
+        ORDER BY s.created_at DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []ActiveSession
+	for rows.Next() {
+		var sess ActiveSession
+		var ipAddress, userAgent sql.NullString
+
+		err := rows.Scan(&sess.ID, &sess.FamilyID, &ipAddress, &userAgent,
+			&sess.CreatedAt, &sess.LastUsedAt, &sess.ExpiresAt)
+		if err != nil {
+			continue
+		}
+
+		sess.IPAddress = ipAddress.String
+		sess.UserAgent = userAgent.String
+		sessions = append(sessions, sess)
+	}
+
+	return sessions, nil
+}
+
+// RevokeUserSession revoca la familia a la que pertenece el refresh token id,
+// sólo si es del usuario
+func (r *Repository) RevokeUserSession(userID, id int) (bool, error) {
+	res, err := r.db.Exec(`
+        UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+        WHERE user_id = $1 AND revoked_at IS NULL
+          AND family_id = (SELECT family_id FROM sessions WHERE id = $2 AND user_id = $1)
+    `, userID, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RevokeUserSessions cierra todas las sesiones del usuario
+func (r *Repository) RevokeUserSessions(userID int) error {
+	_, err := r.db.Exec(`
+        UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+        WHERE user_id = $1 AND revoked_at IS NULL
+    `, userID)
+	return err
+}
+
+// ============================================
+// PASSWORD RESETS
+// ============================================
+
+// CreatePasswordReset guarda el hash del token e invalida los anteriores del usuario
+func (r *Repository) CreatePasswordReset(userID int, tokenHash, ipAddress string, expiresAt time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE password_resets SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL`, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO password_resets (user_id, token, ip_address, expires_at)
+        VALUES ($1, $2, NULLIF($3, ''), $4)
+    `, userID, tokenHash, ipAddress, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UsePasswordReset marca el token como usado si sigue vigente y devuelve su
+// usuario; sql.ErrNoRows si no existe, ya se usó o venció
+func (r *Repository) UsePasswordReset(tokenHash string) (int, error) {
+	var userID int
+	err := r.db.QueryRow(`
+        UPDATE password_resets SET used_at = CURRENT_TIMESTAMP
+        WHERE token = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+        RETURNING user_id
+    `, tokenHash).Scan(&userID)
+	return userID, err
+}
+
+func (r *Repository) UpdatePassword(userID int, passwordHash string) error {
+	_, err := r.db.Exec(`UPDATE users SET password_hash = $1 WHERE id = $2`, passwordHash, userID)
+	return err
+}
+
+// ============================================
+// EMAIL VERIFICATION
+// ============================================
+
+// CreateEmailVerification guarda el hash del token e invalida los anteriores del usuario
+func (r *Repository) CreateEmailVerification(userID int, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE email_verifications SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL`, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO email_verifications (user_id, token, expires_at)
+        VALUES ($1, $2, $3)
+    `, userID, tokenHash, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// VerifyEmail consume el token vigente y marca el email del usuario como
+// verificado; sql.ErrNoRows si el token no existe, ya se usó o venció
+func (r *Repository) VerifyEmail(tokenHash string) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	err = tx.QueryRow(`
+        UPDATE email_verifications SET used_at = CURRENT_TIMESTAMP
+        WHERE token = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+        RETURNING user_id
+    `, tokenHash).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+        UPDATE users SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+        WHERE id = $1
+    `, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, tx.Commit()
+}
+
+// ============================================
+// MFA (TOTP)
+// ============================================
+
+// SaveMFASecret deja una llave pendiente de confirmar; reemplaza otra pendiente
+// pero nunca una ya confirmada
+func (r *Repository) SaveMFASecret(userID int, secret string) (bool, error) {
+	result, err := r.db.Exec(`
+        INSERT INTO user_mfa (user_id, secret)
+        VALUES ($1, $2)
+        ON CONFLICT (user_id) DO UPDATE
+        SET secret = EXCLUDED.secret, last_step = 0, created_at = CURRENT_TIMESTAMP
+        WHERE user_mfa.confirmed_at IS NULL
+    `, userID, secret)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// GetMFASecret devuelve la llave del usuario y si ya está confirmada;
+// sql.ErrNoRows si nunca inició la configuración
+func (r *Repository) GetMFASecret(userID int) (string, bool, error) {
+	var secret string
+	var confirmed bool
+	err := r.db.QueryRow(`
+        SELECT secret, confirmed_at IS NOT NULL FROM user_mfa WHERE user_id = $1
+    `, userID).Scan(&secret, &confirmed)
+	return secret, confirmed, err
+}
+
+// UseMFAStep registra el periodo TOTP usado; false si ése o uno posterior ya
+// se había usado (el mismo código no sirve dos veces)
+func (r *Repository) UseMFAStep(userID int, step int64) (bool, error) {
+	result, err := r.db.Exec(`
+        UPDATE user_mfa SET last_step = $2 WHERE user_id = $1 AND last_step < $2
+    `, userID, step)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// ConfirmMFA activa la llave y reemplaza los códigos de recuperación
+func (r *Repository) ConfirmMFA(userID int, recoveryHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE user_mfa SET confirmed_at = CURRENT_TIMESTAMP WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) ReplaceRecoveryCodes(userID int, recoveryHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int, recoveryHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryHashes {
+		_, err := tx.Exec(`
+            INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)
+        `, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode consume un código de recuperación; false si no existe o ya se usó
+func (r *Repository) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	result, err := r.db.Exec(`
+        UPDATE mfa_recovery_codes SET used_at = CURRENT_TIMESTAMP
+        WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+    `, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// DeleteMFA quita la llave y los códigos de recuperación del usuario
+func (r *Repository) DeleteMFA(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ============================================
+// ROLES
+// ============================================
+
+// GetUserPermissions devuelve la unión de los permisos de los roles del usuario
+func (r *Repository) GetUserPermissions(userID int) ([]string, error) {
+	rows, err := r.db.Query(`
+        SELECT DISTINCT rp.permission
+        FROM user_roles ur
+        JOIN role_permissions rp ON rp.role_id = ur.role_id
+        WHERE ur.user_id = $1
+        ORDER BY rp.permission
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			continue
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, nil
+}
+
+const roleColumns = `
+        ro.id, ro.name, COALESCE(ro.description, ''), ro.is_system,
+        ARRAY(SELECT rp.permission FROM role_permissions rp WHERE rp.role_id = ro.id ORDER BY rp.permission),
+        (SELECT COUNT(*) FROM user_roles ur WHERE ur.role_id = ro.id),
+        ro.created_at`
+
+func scanRole(row rowScanner) (*Role, error) {
+	var role Role
+	err := row.Scan(&role.ID, &role.Name, &role.Description, &role.IsSystem,
+		pq.Array(&role.Permissions), &role.Users, &role.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *Repository) GetRoles() ([]Role, error) {
+	rows, err := r.db.Query(`SELECT ` + roleColumns + ` FROM roles ro ORDER BY ro.is_system DESC, ro.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []Role
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			continue
+		}
+		roles = append(roles, *role)
+	}
+	return roles, nil
+}
+
+func (r *Repository) GetRole(id int) (*Role, error) {
+	return scanRole(r.db.QueryRow(`SELECT `+roleColumns+` FROM roles ro WHERE ro.id = $1`, id))
+}
+
+// CreateRole devuelve sql.ErrNoRows si ya existe un rol con ese nombre
+func (r *Repository) CreateRole(name, description string, permissions []string) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`
+        INSERT INTO roles (name, description) VALUES ($1, NULLIF($2, ''))
+        ON CONFLICT (name) DO NOTHING
+        RETURNING id
+    `, name, description).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	if err := setRolePermissions(tx, id, permissions); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+func (r *Repository) UpdateRole(id int, description string, permissions []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE roles SET description = NULLIF($2, '') WHERE id = $1`, id, description)
+	if err != nil {
+		return err
+	}
+	if err := setRolePermissions(tx, id, permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func setRolePermissions(tx *sql.Tx, roleID int, permissions []string) error {
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role_id = $1`, roleID); err != nil {
+		return err
+	}
+	for _, p := range permissions {
+		_, err := tx.Exec(`
+            INSERT INTO role_permissions (role_id, permission) VALUES ($1, $2)
+            ON CONFLICT DO NOTHING
+        `, roleID, p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) DeleteRole(id int) error {
+	_, err := r.db.Exec(`DELETE FROM roles WHERE id = $1 AND NOT is_system`, id)
+	return err
+}
+
+// SetUserRoles reemplaza los roles del usuario
+func (r *Repository) SetUserRoles(userID int, roleIDs []int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_roles WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, roleID := range roleIDs {
+		_, err := tx.Exec(`
+            INSERT INTO user_roles (user_id, role_id) VALUES ($1, $2)
+            ON CONFLICT DO NOTHING
+        `, userID, roleID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CountActiveSuperAdmins cuenta los usuarios activos con el rol super_admin
+func (r *Repository) CountActiveSuperAdmins() (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+        SELECT COUNT(DISTINCT u.id)
+        FROM users u
+        JOIN user_roles ur ON ur.user_id = u.id
+        JOIN roles ro ON ro.id = ur.role_id
+        WHERE ro.name = $1 AND u.is_active
+    `, SuperAdminRole).Scan(&count)
+	return count, err
+}
+
+// ============================================
+// API KEYS
+// ============================================
+
+const apiKeyColumns = `
+        id, user_id, empresa_id, name, prefix, secret_hash, scopes, expires_at,
+        last_used_at, COALESCE(last_used_ip, ''), revoked_at, COALESCE(created_by, 0), created_at`
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	var key APIKey
+	var empresaID sql.NullInt64
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+
+	err := row.Scan(&key.ID, &key.UserID, &empresaID, &key.Name, &key.Prefix, &key.SecretHash,
+		pq.Array(&key.Scopes), &expiresAt, &lastUsedAt, &key.LastUsedIP, &revokedAt, &key.CreatedBy, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if empresaID.Valid {
+		id := int(empresaID.Int64)
+		key.EmpresaID = &id
+	}
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return &key, nil
+}
+
+func (r *Repository) CreateAPIKey(key *APIKey) error {
+	return r.db.QueryRow(`
+        INSERT INTO api_keys (user_id, empresa_id, name, prefix, secret_hash, scopes, expires_at, created_by)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id, created_at
+    `, key.UserID, key.EmpresaID, key.Name, key.Prefix, key.SecretHash, pq.Array(key.Scopes),
+		key.ExpiresAt, key.CreatedBy).Scan(&key.ID, &key.CreatedAt)
+}
+
+func (r *Repository) GetAPIKeyByPrefix(prefix string) (*APIKey, error) {
+	return scanAPIKey(r.db.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE prefix = $1`, prefix))
+}
+
+func (r *Repository) GetAPIKey(id int) (*APIKey, error) {
+	return scanAPIKey(r.db.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id))
+}
+
+// GetAPIKeys lista las llaves; userID 0 = todas
+func (r *Repository) GetAPIKeys(userID int) ([]APIKey, error) {
+	rows, err := r.db.Query(`
+        SELECT `+apiKeyColumns+` FROM api_keys
+        WHERE $1 = 0 OR user_id = $1
+        ORDER BY created_at DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, *key)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey devuelve false si la llave no existe o ya estaba revocada
+func (r *Repository) RevokeAPIKey(id int) (bool, error) {
+	result, err := r.db.Exec(`
+        UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP
+        WHERE id = $1 AND revoked_at IS NULL
+    `, id)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// TouchAPIKey registra el último uso; se escribe a lo más una vez por minuto
+// por llave para no convertir cada petición en un UPDATE
+func (r *Repository) TouchAPIKey(id int, ipAddress string) error {
+	_, err := r.db.Exec(`
+        UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP, last_used_ip = NULLIF($2, '')
+        WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')
+    `, id, ipAddress)
+	return err
+}
+
+func (r *Repository) IsEmpresaMember(userID, empresaID int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`
+        SELECT EXISTS(SELECT 1 FROM empresa_miembros WHERE user_id = $1 AND empresa_id = $2)
+    `, userID, empresaID).Scan(&exists)
+	return exists, err
+}
+
+// ============================================
+// AUDIT LOG
+// ============================================
+
+func (r *Repository) CreateAuditEntry(e *AuditEntry) error {
+	return r.db.QueryRow(`
+        INSERT INTO audit_log (actor_id, actor_email, api_key_id, ip_address, action, entity_type, entity_id,
+                               before_data, after_data, changes)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        RETURNING id, created_at
+    `, e.ActorID, e.ActorEmail, e.APIKeyID, e.IPAddress, e.Action, e.EntityType, e.EntityID,
+		nullJSON(e.Before), nullJSON(e.After), nullJSON(e.Changes)).Scan(&e.ID, &e.CreatedAt)
+}
+
+// GetAuditLog devuelve una página de la bitácora, de la más reciente a la más
+// antigua, y el total de entradas que cumplen el filtro. limit 0 = todas.
+func (r *Repository) GetAuditLog(f AuditFilter, limit, offset int) ([]AuditEntry, int, error) {
+	var from, to *time.Time
+	if !f.From.IsZero() {
+		from = &f.From
+	}
+	if !f.To.IsZero() {
+		end := f.To.AddDate(0, 0, 1)
+		to = &end
+	}
+	args := []interface{}{f.ActorID, f.Action, f.EntityType, f.EntityID, from, to}
+	where := `
+        WHERE ($1 = 0 OR actor_id = $1)
+          AND ($2 = '' OR action = $2)
+          AND ($3 = '' OR entity_type = $3)
+          AND ($4 = 0 OR entity_id = $4)
+          AND ($5::timestamp IS NULL OR created_at >= $5)
+          AND ($6::timestamp IS NULL OR created_at < $6)`
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM audit_log`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(`
+        SELECT id, actor_id, actor_email, api_key_id, ip_address, action, entity_type, entity_id,
+               before_data, after_data, changes, created_at
+        FROM audit_log`+where+`
+        ORDER BY created_at DESC, id DESC
+        LIMIT NULLIF($7, 0) OFFSET $8
+    `, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		var actorID, apiKeyID sql.NullInt64
+		var actorEmail, ipAddress sql.NullString
+		var before, after, changes []byte
+
+		err := rows.Scan(&e.ID, &actorID, &actorEmail, &apiKeyID, &ipAddress, &e.Action, &e.EntityType, &e.EntityID,
+			&before, &after, &changes, &e.CreatedAt)
+		if err != nil {
+			continue
+		}
+
+		if actorID.Valid {
+			id := int(actorID.Int64)
+			e.ActorID = &id
+		}
+		if apiKeyID.Valid {
+			id := int(apiKeyID.Int64)
+			e.APIKeyID = &id
+		}
+		e.ActorEmail = actorEmail.String
+		e.IPAddress = ipAddress.String
+		e.Before, e.After, e.Changes = before, after, changes
+
+		entries = append(entries, e)
+	}
+
+	return entries, total, nil
+}
+
+// nullJSON guarda NULL en lugar de un documento vacío
+func nullJSON(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
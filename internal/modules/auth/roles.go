@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrUserNotFound         = errors.New("usuario no encontrado")
+	ErrRoleNotFound         = errors.New("rol no encontrado")
+	ErrRoleDuplicate        = errors.New("ya existe un rol con ese nombre")
+	ErrRoleSystem           = errors.New("los roles del sistema no se pueden modificar")
+	ErrPermissionUnknown    = errors.New("permiso desconocido")
+	ErrPermissionEscalation = errors.New("no puedes otorgar ni quitar permisos que no tienes")
+	ErrLastSuperAdmin       = errors.New("debe quedar al menos un super administrador activo")
+)
+
+func (s *Service) GetRoles() ([]Role, error) {
+	return s.repo.GetRoles()
+}
+
+// CreateRole crea un rol con permisos del catálogo; quien lo crea debe tener
+// cada uno de ellos
+func (s *Service) CreateRole(actor Actor, req RoleRequest) (*Role, error) {
+	permissions, err := validatePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkGrantable(actor, permissions); err != nil {
+		return nil, err
+	}
+
+	id, err := s.repo.CreateRole(strings.ToLower(strings.TrimSpace(req.Name)), req.Description, permissions)
+	if err == sql.ErrNoRows {
+		return nil, ErrRoleDuplicate
+	}
+	if err != nil {
+		return nil, err
+	}
+	role, err := s.repo.GetRole(id)
+	if err != nil {
+		return nil, err
+	}
+	s.Audit(actor, "role.create", auditRole, id, nil, role)
+	return role, nil
+}
+
+func (s *Service) UpdateRole(actor Actor, id int, req UpdateRoleRequest) (*Role, error) {
+	role, err := s.getEditableRole(id)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := validatePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkGrantable(actor, append(permissions, role.Permissions...)); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateRole(id, req.Description, permissions); err != nil {
+		return nil, err
+	}
+	s.users.clear()
+	updated, err := s.repo.GetRole(id)
+	if err != nil {
+		return nil, err
+	}
+	s.Audit(actor, "role.update", auditRole, id, role, updated)
+	return updated, nil
+}
+
+func (s *Service) DeleteRole(actor Actor, id int) error {
+	role, err := s.getEditableRole(id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkGrantable(actor, role.Permissions); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteRole(id); err != nil {
+		return err
+	}
+	s.users.clear()
+	s.Audit(actor, "role.delete", auditRole, id, role, nil)
+	return nil
+}
+
+func (s *Service) getEditableRole(id int) (*Role, error) {
+	role, err := s.repo.GetRole(id)
+	if err == sql.ErrNoRows {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if role.IsSystem {
+		return nil, ErrRoleSystem
+	}
+	return role, nil
+}
+
+// SetUserRoles reemplaza los roles del usuario. Quien los asigna debe tener
+// los permisos de los roles que agrega y de los que quita.
+func (s *Service) SetUserRoles(actor Actor, userID int, roleIDs []int) error {
+	target, err := s.repo.GetUserByID(userID)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	roles, err := s.repo.GetRoles()
+	if err != nil {
+		return err
+	}
+	byID := make(map[int]Role)
+	byName := make(map[string]Role)
+	for _, role := range roles {
+		byID[role.ID] = role
+		byName[role.Name] = role
+	}
+
+	var affected []string
+	keepsSuperAdmin := false
+	for _, id := range roleIDs {
+		role, ok := byID[id]
+		if !ok {
+			return ErrRoleNotFound
+		}
+		affected = append(affected, role.Permissions...)
+		keepsSuperAdmin = keepsSuperAdmin || role.Name == SuperAdminRole
+	}
+	wasSuperAdmin := false
+	for _, name := range target.Roles {
+		affected = append(affected, byName[name].Permissions...)
+		wasSuperAdmin = wasSuperAdmin || name == SuperAdminRole
+	}
+	if err := s.checkGrantable(actor, affected); err != nil {
+		return err
+	}
+
+	if wasSuperAdmin && !keepsSuperAdmin {
+		if err := s.checkNotLastSuperAdmin(target); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.SetUserRoles(userID, roleIDs); err != nil {
+		return err
+	}
+	s.users.invalidate(userID)
+	after, _ := s.repo.GetUserByID(userID)
+	s.Audit(actor, "user.roles", auditUser, userID, target, after)
+	return nil
+}
+
+// checkGrantable revisa que quien hace la petición tenga cada permiso que
+// pretende otorgar o quitar. Con API key cuentan sólo los alcances de la
+// llave, no todos los permisos de su dueño.
+func (s *Service) checkGrantable(actor Actor, permissions []string) error {
+	grantor := actor.User
+	if grantor == nil {
+		if actor.APIKeyID != nil {
+			return ErrPermissionEscalation
+		}
+		user, err := s.CurrentUser(actor.UserID)
+		if err != nil {
+			return err
+		}
+		grantor = user
+	}
+	for _, p := range permissions {
+		if !grantor.HasPermission(p) {
+			return ErrPermissionEscalation
+		}
+	}
+	return nil
+}
+
+// checkManageable impide que quien no es super administrador modifique la
+// cuenta de uno (estado, 2FA, bloqueo o sesiones)
+func (s *Service) checkManageable(actor Actor, target *User) error {
+	if !target.isSuperAdmin() {
+		return nil
+	}
+	return s.checkGrantable(actor, []string{PermAll})
+}
+
+// checkNotLastSuperAdmin rechaza dejar sin super administradores activos
+func (s *Service) checkNotLastSuperAdmin(target *User) error {
+	if !target.isSuperAdmin() || !target.IsActive {
+		return nil
+	}
+	count, err := s.repo.CountActiveSuperAdmins()
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return ErrLastSuperAdmin
+	}
+	return nil
+}
+
+// validatePermissions descarta duplicados y rechaza lo que no esté en el
+// catálogo, incluido PermAll, que es exclusivo de super_admin
+func validatePermissions(permissions []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var valid []string
+	for _, p := range permissions {
+		if !isKnownPermission(p) {
+			return nil, ErrPermissionUnknown
+		}
+		if !seen[p] {
+			seen[p] = true
+			valid = append(valid, p)
+		}
+	}
+	return valid, nil
+}
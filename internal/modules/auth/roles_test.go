@@ -0,0 +1,30 @@
+package auth
+
+import "testing"
+
+func TestCheckManageable(t *testing.T) {
+	superAdmin := &User{ID: 1, Roles: []string{SuperAdminRole}, Permissions: []string{PermAll}}
+	soporte := &User{ID: 2, Roles: []string{"soporte"}, Permissions: []string{PermUsersRead, PermUsersManage}}
+	cliente := &User{ID: 3}
+	llave := 7
+
+	casos := []struct {
+		nombre string
+		actor  Actor
+		target *User
+		err    error
+	}{
+		{"super admin sobre super admin", Actor{UserID: 1, User: superAdmin}, superAdmin, nil},
+		{"super admin sobre cliente", Actor{UserID: 1, User: superAdmin}, cliente, nil},
+		{"soporte sobre cliente", Actor{UserID: 2, User: soporte}, cliente, nil},
+		{"soporte sobre super admin", Actor{UserID: 2, User: soporte}, superAdmin, ErrPermissionEscalation},
+		{"API key sobre super admin", Actor{UserID: 1, APIKeyID: &llave}, superAdmin, ErrPermissionEscalation},
+	}
+
+	s := &Service{}
+	for _, c := range casos {
+		if err := s.checkManageable(c.actor, c.target); err != c.err {
+			t.Errorf("%s: error = %v, se esperaba %v", c.nombre, err, c.err)
+		}
+	}
+}
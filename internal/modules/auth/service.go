@@ -5,26 +5,44 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"errors"
+	"log"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jhvc/backend/internal/validacion"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvitationCodeNotFound = errors.New("código de invitación no encontrado")
+	ErrLicenseNotFound        = errors.New("licencia no encontrada")
+	ErrDeviceNotFound         = errors.New("dispositivo no encontrado")
+	ErrModuleNotFound         = errors.New("módulo no encontrado")
+	ErrContactNotFound        = errors.New("el contacto no existe en el directorio de la empresa")
+	ErrContactWithoutEmpresa  = errors.New("para usar un contacto indica la empresa de la licencia")
+)
+
 type Service struct {
 	repo      *Repository
 	jwtSecret []byte
+	users     *userCache
+	mailer    Mailer
+	appURL    string
+	// requireAdminMFA exige 2FA a las cuentas admin (ver RequireAdminMFA)
+	requireAdminMFA bool
+	limits          *rateLimits
 }
 
 func NewService(repo *Repository, jwtSecret string) *Service {
 	return &Service{
 		repo:      repo,
 		jwtSecret: []byte(jwtSecret),
+		users:     newUserCache(),
 	}
 }
 
-func (s *Service) Register(req RegisterRequest) (*AuthResponse, error) {
+func (s *Service) Register(req RegisterRequest, info SessionInfo) (*AuthResponse, error) {
 	invCode, err := s.repo.ValidateInvitationCode(req.InvitationCode)
 	if err != nil {
 		return nil, errors.New("código de invitación inválido")
@@ -51,7 +69,12 @@ func (s *Service) Register(req RegisterRequest) (*AuthResponse, error) {
 		return nil, err
 	}
 
-	userID, err := s.repo.CreateUser(req.Email, string(hashedPassword), req.FullName, req.CompanyName, req.Phone)
+	rfc := ""
+	if req.RFC != "" {
+		rfc = validacion.NormalizarRFC(req.RFC)
+	}
+
+	userID, err := s.repo.CreateUser(req.Email, string(hashedPassword), req.FullName, req.CompanyName, rfc, req.Phone)
 	if err != nil {
 		return nil, err
 	}
@@ -64,27 +87,36 @@ func (s *Service) Register(req RegisterRequest) (*AuthResponse, error) {
 		return nil, err
 	}
 
-	token, err := s.generateToken(user.ID, user.Email, user.IsAdmin, 24*time.Hour)
-	if err != nil {
-		return nil, err
+	// La cuenta queda limitada hasta confirmar el email; si el correo falla se
+	// puede pedir otro enlace después
+	if err := s.sendVerification(user); err != nil {
+		log.Printf("⚠️  No se pudo encolar la verificación para el usuario %d: %v", user.ID, err)
 	}
 
-	return &AuthResponse{
-		Token: token,
-		User:  *user,
-	}, nil
+	return s.startSession(user, info, refreshTokenTTL)
 }
 
-func (s *Service) Login(req LoginRequest) (*AuthResponse, error) {
+func (s *Service) Login(req LoginRequest, info SessionInfo) (*AuthResponse, error) {
+	if err := s.limits.allowLogin(info.IPAddress, req.Email); err != nil {
+		return nil, err
+	}
+
 	user, passwordHash, err := s.repo.GetUserByEmail(req.Email)
 	if err == sql.ErrNoRows {
+		s.limits.failLogin(info.IPAddress, req.Email)
 		return nil, errors.New("credenciales inválidas")
 	}
 	if err != nil {
 		return nil, err
 	}
 
+	if isLocked(user) {
+		return nil, ErrAccountLocked
+	}
+
 	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)); err != nil {
+		s.limits.failLogin(info.IPAddress, req.Email)
+		s.recordFailedLogin(user)
 		return nil, errors.New("credenciales inválidas")
 	}
 
@@ -92,38 +124,65 @@ func (s *Service) Login(req LoginRequest) (*AuthResponse, error) {
 		return nil, errors.New("cuenta desactivada")
 	}
 
-	duration := 24 * time.Hour
+	// Con 2FA la contraseña sólo abre el segundo paso (LoginMFA)
+	if user.MFAEnabled {
+		return s.mfaChallenge(user, req.Remember)
+	}
+
+	duration := refreshTokenTTL
 	if req.Remember {
-		duration = 30 * 24 * time.Hour
+		duration = rememberTokenTTL
 	}
 
-	token, err := s.generateToken(user.ID, user.Email, user.IsAdmin, duration)
+	s.limits.clearLogin(user.Email)
+	s.repo.ResetFailedLogins(user.ID)
+
+	resp, err := s.startSession(user, info, duration)
 	if err != nil {
 		return nil, err
 	}
-
-	return &AuthResponse{
-		Token: token,
-		User:  *user,
-	}, nil
+	resp.MFASetupRequired = s.AdminMFAPending(user)
+	return resp, nil
 }
 
 func (s *Service) GetProfile(userID int) (*User, error) {
-	return s.repo.GetUserByID(userID)
+	return s.CurrentUser(userID)
 }
 
-func (s *Service) generateToken(userID int, email string, isAdmin bool, duration time.Duration) (string, error) {
+// CurrentUser devuelve el registro vigente del usuario para autorizar la
+// petición. Los permisos salen de aquí y no de los claims del token.
+func (s *Service) CurrentUser(userID int) (*User, error) {
+	if user, ok := s.users.get(userID); ok {
+		return user, nil
+	}
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Permissions, err = s.repo.GetUserPermissions(userID); err != nil {
+		return nil, err
+	}
+	s.users.set(user)
+	return user, nil
+}
+
+// generateToken firma un token de acceso de vida corta; sid es la familia de
+// refresh tokens de la que depende. Los roles no viajan en el token: se leen
+// del usuario en cada petición para que un cambio aplique de inmediato.
+func (s *Service) generateToken(userID int, email string, sessionID string, duration time.Duration) (string, error) {
 	claims := jwt.MapClaims{
-		"user_id":  userID,
-		"email":    email,
-		"is_admin": isAdmin,
-		"exp":      time.Now().Add(duration).Unix(),
+		"user_id": userID,
+		"email":   email,
+		"sid":     sessionID,
+		"exp":     time.Now().Add(duration).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(s.jwtSecret)
 }
 
-func (s *Service) ValidateToken(tokenString string) (int, error) {
+// ValidateToken devuelve el usuario y la sesión (familia de refresh tokens) del
+// token de acceso
+func (s *Service) ValidateToken(tokenString string) (int, string, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("método de firma inválido")
@@ -131,50 +190,40 @@ func (s *Service) ValidateToken(tokenString string) (int, error) {
 		return s.jwtSecret, nil
 	})
 	if err != nil {
-		return 0, err
+		return 0, "", err
 	}
 	if !token.Valid {
-		return 0, errors.New("token inválido")
+		return 0, "", errors.New("token inválido")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return 0, errors.New("claims inválidos")
+		return 0, "", errors.New("claims inválidos")
+	}
+
+	if typ, _ := claims["typ"].(string); typ != "" {
+		return 0, "", errors.New("token inválido")
 	}
 
 	userIDFloat, ok := claims["user_id"].(float64)
 	if !ok {
-		return 0, errors.New("user_id inválido en token")
+		return 0, "", errors.New("user_id inválido en token")
 	}
 
-	return int(userIDFloat), nil
-}
-
-func (s *Service) IsAdmin(tokenString string) (bool, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("método de firma inválido")
-		}
-		return s.jwtSecret, nil
-	})
-	if err != nil {
-		return false, err
+	// El token de acceso deja de valer en cuanto se revoca su sesión
+	sessionID, _ := claims["sid"].(string)
+	if sessionID == "" {
+		return 0, "", ErrSessionRevoked
 	}
-	if !token.Valid {
-		return false, errors.New("token inválido")
-	}
-
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return false, errors.New("claims inválidos")
+	active, err := s.repo.IsSessionActive(sessionID)
+	if err != nil {
+		return 0, "", err
 	}
-
-	isAdmin, ok := claims["is_admin"].(bool)
-	if !ok {
-		return false, nil
+	if !active {
+		return 0, "", ErrSessionRevoked
 	}
 
-	return isAdmin, nil
+	return int(userIDFloat), sessionID, nil
 }
 
 func (s *Service) GenerateInvitationCode() string {
@@ -183,7 +232,7 @@ func (s *Service) GenerateInvitationCode() string {
 	return hex.EncodeToString(bytes)
 }
 
-func (s *Service) CreateInvitationCode(adminID, maxUses int, daysValid int) (string, error) {
+func (s *Service) CreateInvitationCode(actor Actor, maxUses int, daysValid int) (string, error) {
 	code := s.GenerateInvitationCode()
 
 	var expiresAt *time.Time
@@ -192,24 +241,67 @@ func (s *Service) CreateInvitationCode(adminID, maxUses int, daysValid int) (str
 		expiresAt = &exp
 	}
 
-	err := s.repo.CreateInvitationCode(code, maxUses, adminID, expiresAt)
-	return code, err
+	id, err := s.repo.CreateInvitationCode(code, maxUses, actor.UserID, expiresAt)
+	if err != nil {
+		return "", err
+	}
+	after, _ := s.repo.GetInvitationCode(id)
+	s.Audit(actor, "code.create", auditInvitationCode, id, nil, after)
+	return code, nil
 }
 
 func (s *Service) GetAllUsers() ([]User, error) {
 	return s.repo.GetAllUsers()
 }
 
-func (s *Service) UpdateUserStatus(userID int, isActive bool) error {
-	return s.repo.UpdateUserStatus(userID, isActive)
+// UpdateUserStatus activa o desactiva la cuenta; al desactivarla se cierran
+// todas sus sesiones. No se puede desactivar al último super administrador.
+func (s *Service) UpdateUserStatus(actor Actor, userID int, isActive bool) error {
+	before, err := s.repo.GetUserByID(userID)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.checkManageable(actor, before); err != nil {
+		return err
+	}
+	if !isActive {
+		if err := s.checkNotLastSuperAdmin(before); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.UpdateUserStatus(userID, isActive); err != nil {
+		return err
+	}
+	s.users.invalidate(userID)
+	after, _ := s.repo.GetUserByID(userID)
+	s.Audit(actor, "user.status", auditUser, userID, before, after)
+	if !isActive {
+		return s.repo.RevokeUserSessions(userID)
+	}
+	return nil
 }
 
 func (s *Service) GetAllInvitationCodes() ([]InvitationCode, error) {
 	return s.repo.GetAllInvitationCodes()
 }
 
-func (s *Service) UpdateCodeStatus(codeID int, isActive bool) error {
-	return s.repo.UpdateCodeStatus(codeID, isActive)
+func (s *Service) UpdateCodeStatus(actor Actor, codeID int, isActive bool) error {
+	before, err := s.repo.GetInvitationCode(codeID)
+	if err == sql.ErrNoRows {
+		return ErrInvitationCodeNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdateCodeStatus(codeID, isActive); err != nil {
+		return err
+	}
+	after, _ := s.repo.GetInvitationCode(codeID)
+	s.Audit(actor, "code.status", auditInvitationCode, codeID, before, after)
+	return nil
 }
 
 func (s *Service) GenerateProductLicenseCode() string {
@@ -218,14 +310,18 @@ func (s *Service) GenerateProductLicenseCode() string {
 	return strings.ToUpper(hex.EncodeToString(bytes))
 }
 
-func (s *Service) CreateProductLicense(req CreateProductLicenseRequest) (string, error) {
+func (s *Service) CreateProductLicense(actor Actor, req CreateProductLicenseRequest) (string, error) {
 	licenseCode := s.GenerateProductLicenseCode()
 
 	if req.MaxDevices == 0 {
 		req.MaxDevices = 1
 	}
 
-	licenseID, err := s.repo.CreateProductLicense(licenseCode, req.ClientName, req.ClientEmail, req.MaxDevices, req.DaysValid, req.Notes)
+	if err := s.fillLicenseClient(req.EmpresaID, req.ContactoID, &req.ClientName, &req.ClientEmail); err != nil {
+		return "", err
+	}
+
+	licenseID, err := s.repo.CreateProductLicense(req.EmpresaID, req.ContactoID, licenseCode, req.ClientName, req.ClientEmail, req.MaxDevices, req.DaysValid, req.Notes)
 	if err != nil {
 		return "", err
 	}
@@ -235,36 +331,105 @@ func (s *Service) CreateProductLicense(req CreateProductLicenseRequest) (string,
 		s.repo.AddLicenseModule(int(licenseID), strings.ToUpper(strings.TrimSpace(moduleName)))
 	}
 
+	after, _ := s.repo.GetProductLicense(int(licenseID))
+	s.Audit(actor, "license.create", auditLicense, int(licenseID), nil, after)
+
 	return licenseCode, nil
 }
 
-func (s *Service) GetAllProductLicenses() ([]ProductLicenseWithDetails, error) {
-	return s.repo.GetAllProductLicenses()
+// GetAllProductLicenses lista las licencias; con empresaID > 0 sólo las de esa empresa
+func (s *Service) GetAllProductLicenses(empresaID int) ([]ProductLicenseWithDetails, error) {
+	return s.repo.GetAllProductLicenses(empresaID)
 }
 
-func (s *Service) UpdateProductLicenseStatus(id int, isActive bool) error {
-	return s.repo.UpdateProductLicenseStatus(id, isActive)
+func (s *Service) UpdateProductLicenseStatus(actor Actor, id int, isActive bool) error {
+	before, err := s.getProductLicense(id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdateProductLicenseStatus(id, isActive); err != nil {
+		return err
+	}
+	after, _ := s.repo.GetProductLicense(id)
+	s.Audit(actor, "license.status", auditLicense, id, before, after)
+	return nil
 }
 
-func (s *Service) DeleteProductLicense(id int) error {
-	return s.repo.DeleteProductLicense(id)
+func (s *Service) DeleteProductLicense(actor Actor, id int) error {
+	before, err := s.getProductLicense(id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteProductLicense(id); err != nil {
+		return err
+	}
+	s.Audit(actor, "license.delete", auditLicense, id, before, nil)
+	return nil
 }
 
-func (s *Service) UpdateProductLicense(id int, req UpdateProductLicenseRequest) error {
+func (s *Service) UpdateProductLicense(actor Actor, id int, req UpdateProductLicenseRequest) error {
 	if req.MaxDevices == 0 {
 		req.MaxDevices = 1
 	}
-	return s.repo.UpdateProductLicense(id, req.ClientName, req.ClientEmail, req.MaxDevices, req.DaysValid, req.Notes, req.IsActive)
+	if err := s.fillLicenseClient(req.EmpresaID, req.ContactoID, &req.ClientName, &req.ClientEmail); err != nil {
+		return err
+	}
+	before, err := s.getProductLicense(id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdateProductLicense(id, req.EmpresaID, req.ContactoID, req.ClientName, req.ClientEmail, req.MaxDevices, req.DaysValid, req.Notes, req.IsActive); err != nil {
+		return err
+	}
+	after, _ := s.repo.GetProductLicense(id)
+	s.Audit(actor, "license.update", auditLicense, id, before, after)
+	return nil
+}
+
+// fillLicenseClient toma el nombre y el email del cliente del contacto del
+// directorio, que debe ser de la empresa de la licencia
+func (s *Service) fillLicenseClient(empresaID, contactoID *int, clientName, clientEmail *string) error {
+	if contactoID == nil {
+		return nil
+	}
+	if empresaID == nil {
+		return ErrContactWithoutEmpresa
+	}
+	name, email, err := s.repo.GetContact(*empresaID, *contactoID)
+	if err == sql.ErrNoRows {
+		return ErrContactNotFound
+	}
+	if err != nil {
+		return err
+	}
+	*clientName = name
+	if *clientEmail == "" {
+		*clientEmail = email
+	}
+	return nil
+}
+
+func (s *Service) getProductLicense(id int) (*ProductLicenseWithDetails, error) {
+	license, err := s.repo.GetProductLicense(id)
+	if err == sql.ErrNoRows {
+		return nil, ErrLicenseNotFound
+	}
+	return license, err
 }
 
 // ============================================
 // VERIFY PRODUCT LICENSE - LÓGICA CORREGIDA
 // ============================================
 
-func (s *Service) VerifyProductLicense(req VerifyLicenseRequest) (VerifyLicenseResponse, error) {
+func (s *Service) VerifyProductLicense(req VerifyLicenseRequest, ipAddress string) (VerifyLicenseResponse, error) {
+	if err := s.limits.allowLicense(ipAddress, req.LicenseCode); err != nil {
+		return VerifyLicenseResponse{}, err
+	}
+
 	// 1. Buscar licencia
 	license, err := s.repo.GetProductLicenseByCode(req.LicenseCode)
 	if err != nil {
+		s.limits.failLicense(ipAddress, req.LicenseCode)
 		return VerifyLicenseResponse{
 			Valid:   false,
 			Message: "Licencia no encontrada",
@@ -306,6 +471,7 @@ func (s *Service) VerifyProductLicense(req VerifyLicenseRequest) (VerifyLicenseR
 		// Device NUEVO → Verificar si hay cupo
 		currentDevices, _ := s.repo.CountDevicesByLicense(license.ID)
 		if currentDevices >= license.MaxDevices {
+			s.limits.failLicense(ipAddress, req.LicenseCode)
 			return VerifyLicenseResponse{
 				Valid:   false,
 				Message: "Límite de dispositivos alcanzado (" + string(rune(license.MaxDevices)) + " máx.)",
@@ -322,6 +488,8 @@ func (s *Service) VerifyProductLicense(req VerifyLicenseRequest) (VerifyLicenseR
 		}
 	}
 
+	s.limits.clearLicense(req.LicenseCode)
+
 	// 6. Obtener módulos para respuesta
 	modules, _ := s.repo.GetLicenseModules(license.ID)
 	var moduleNames []string
@@ -355,8 +523,19 @@ func (s *Service) GetLicenseDevices(licenseID int) ([]LicenseDevice, error) {
 	return s.repo.GetLicenseDevices(licenseID)
 }
 
-func (s *Service) RemoveLicenseDevice(deviceID int) error {
-	return s.repo.DeleteLicenseDevice(deviceID)
+func (s *Service) RemoveLicenseDevice(actor Actor, deviceID int) error {
+	before, err := s.repo.GetLicenseDevice(deviceID)
+	if err == sql.ErrNoRows {
+		return ErrDeviceNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteLicenseDevice(deviceID); err != nil {
+		return err
+	}
+	s.Audit(actor, "license.device_remove", auditLicenseDevice, deviceID, before, nil)
+	return nil
 }
 
 // ============================================
@@ -367,12 +546,36 @@ func (s *Service) GetLicenseModules(licenseID int) ([]LicenseModule, error) {
 	return s.repo.GetLicenseModules(licenseID)
 }
 
-func (s *Service) AddLicenseModule(licenseID int, moduleName string) error {
-	return s.repo.AddLicenseModule(licenseID, strings.ToUpper(strings.TrimSpace(moduleName)))
+// Los cambios de módulos se registran en la bitácora sobre la licencia, con
+// la lista de módulos antes y después
+func (s *Service) AddLicenseModule(actor Actor, licenseID int, moduleName string) error {
+	before, err := s.getProductLicense(licenseID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.AddLicenseModule(licenseID, strings.ToUpper(strings.TrimSpace(moduleName))); err != nil {
+		return err
+	}
+	after, _ := s.repo.GetProductLicense(licenseID)
+	s.Audit(actor, "license.module_add", auditLicense, licenseID, before, after)
+	return nil
 }
 
-func (s *Service) RemoveLicenseModule(moduleID int) error {
-	return s.repo.DeleteLicenseModule(moduleID)
+func (s *Service) RemoveLicenseModule(actor Actor, moduleID int) error {
+	module, err := s.repo.GetLicenseModule(moduleID)
+	if err == sql.ErrNoRows {
+		return ErrModuleNotFound
+	}
+	if err != nil {
+		return err
+	}
+	before, _ := s.repo.GetProductLicense(module.LicenseID)
+	if err := s.repo.DeleteLicenseModule(moduleID); err != nil {
+		return err
+	}
+	after, _ := s.repo.GetProductLicense(module.LicenseID)
+	s.Audit(actor, "license.module_remove", auditLicense, module.LicenseID, before, after)
+	return nil
 }
 
 // ============================================
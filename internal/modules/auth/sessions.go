@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	accessTokenTTL   = 15 * time.Minute
+	refreshTokenTTL  = 24 * time.Hour
+	rememberTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token inválido")
+	ErrRefreshTokenExpired = errors.New("refresh token expirado")
+	ErrRefreshTokenReused  = errors.New("refresh token reutilizado; la sesión se cerró por seguridad")
+	ErrSessionRevoked      = errors.New("sesión revocada")
+	ErrSessionNotFound     = errors.New("sesión no encontrada")
+)
+
+// startSession abre una familia de refresh tokens y emite el primer par de tokens
+func (s *Service) startSession(user *User, info SessionInfo, duration time.Duration) (*AuthResponse, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(user, familyID, info, time.Now().Add(duration))
+}
+
+// Refresh rota el refresh token: el presentado queda usado y se emite otro en
+// la misma familia con la misma expiración. Presentar un token ya rotado
+// indica que fue copiado, así que se revoca la familia completa.
+func (s *Service) Refresh(refreshToken string, info SessionInfo) (*AuthResponse, error) {
+	sess, err := s.repo.GetSessionByToken(hashToken(refreshToken))
+	if err == sql.ErrNoRows {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRefreshable(sess, time.Now()); err == ErrRefreshTokenReused {
+		return nil, s.revokeReused(sess)
+	} else if err != nil {
+		return nil, err
+	}
+
+	rotated, err := s.repo.MarkSessionUsed(sess.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Otra solicitud lo rotó primero con el mismo token
+		return nil, s.revokeReused(sess)
+	}
+
+	user, err := s.repo.GetUserByID(sess.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		s.repo.RevokeSessionFamily(sess.FamilyID)
+		return nil, errors.New("cuenta desactivada")
+	}
+
+	return s.issueTokens(user, sess.FamilyID, info, sess.ExpiresAt)
+}
+
+// checkRefreshable decide si el refresh token guardado todavía se puede
+// rotar. ErrRefreshTokenReused indica que hay que revocar la familia.
+func checkRefreshable(sess *Session, now time.Time) error {
+	switch {
+	case sess.RevokedAt != nil:
+		return ErrSessionRevoked
+	case sess.UsedAt != nil:
+		return ErrRefreshTokenReused
+	case now.After(sess.ExpiresAt):
+		return ErrRefreshTokenExpired
+	}
+	return nil
+}
+
+func (s *Service) revokeReused(sess *Session) error {
+	log.Printf("⚠️  Refresh token reutilizado (usuario %d, sesión %d); se revoca la familia", sess.UserID, sess.ID)
+	if err := s.repo.RevokeSessionFamily(sess.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// issueTokens guarda un refresh token nuevo de la familia y firma el token de
+// acceso ligado a ella
+func (s *Service) issueTokens(user *User, familyID string, info SessionInfo, expiresAt time.Time) (*AuthResponse, error) {
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	sess := &Session{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		IPAddress: info.IPAddress,
+		UserAgent: info.UserAgent,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.CreateSession(sess); err != nil {
+		return nil, err
+	}
+
+	token, err := s.generateToken(user.ID, user.Email, familyID, accessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+		User:         user,
+	}, nil
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken es lo que se guarda en sessions.token; el refresh token en claro
+// sólo lo conoce el cliente
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Logout revoca la sesión del token de acceso en uso
+func (s *Service) Logout(sessionID string) error {
+	return s.repo.RevokeSessionFamily(sessionID)
+}
+
+// GetSessions lista las sesiones vigentes del usuario marcando la actual
+func (s *Service) GetSessions(userID int, currentSessionID string) ([]ActiveSession, error) {
+	sessions, err := s.repo.GetActiveSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].FamilyID == currentSessionID
+	}
+	return sessions, nil
+}
+
+func (s *Service) RevokeSession(userID, id int) error {
+	revoked, err := s.repo.RevokeUserSession(userID, id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAllSessions cierra todas las sesiones del usuario, incluida la actual
+func (s *Service) RevokeAllSessions(userID int) error {
+	return s.repo.RevokeUserSessions(userID)
+}
+
+// RevokeUserSessions (admin) cierra todas las sesiones de otro usuario
+func (s *Service) RevokeUserSessions(actor Actor, userID int) error {
+	target, err := s.repo.GetUserByID(userID)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.checkManageable(actor, target); err != nil {
+		return err
+	}
+	if err := s.repo.RevokeUserSessions(userID); err != nil {
+		return err
+	}
+	s.Audit(actor, "user.sessions_revoke", auditUser, userID, nil, nil)
+	return nil
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckRefreshable(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Minute)
+
+	casos := []struct {
+		nombre string
+		sess   Session
+		err    error
+	}{
+		{"vigente", Session{ExpiresAt: now.Add(time.Hour)}, nil},
+		{"expirado", Session{ExpiresAt: now.Add(-time.Second)}, ErrRefreshTokenExpired},
+		{"ya rotado", Session{ExpiresAt: now.Add(time.Hour), UsedAt: &earlier}, ErrRefreshTokenReused},
+		{"revocado", Session{ExpiresAt: now.Add(time.Hour), RevokedAt: &earlier}, ErrSessionRevoked},
+		// Un token rotado de una familia ya revocada no vuelve a revocarla
+		{"rotado y revocado", Session{ExpiresAt: now.Add(time.Hour), UsedAt: &earlier, RevokedAt: &earlier}, ErrSessionRevoked},
+		// Reusar un token rotado se detecta aunque ya haya expirado
+		{"rotado y expirado", Session{ExpiresAt: earlier, UsedAt: &earlier}, ErrRefreshTokenReused},
+	}
+
+	for _, c := range casos {
+		if err := checkRefreshable(&c.sess, now); err != c.err {
+			t.Errorf("%s: error = %v, se esperaba %v", c.nombre, err, c.err)
+		}
+	}
+}
+
+func TestRefreshTokenHash(t *testing.T) {
+	a, err := randomToken(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := randomToken(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 64 || a == b {
+		t.Errorf("randomToken = %q, %q", a, b)
+	}
+
+	// Sólo el hash se guarda: es estable y nunca es el token en claro
+	if hashToken(a) != hashToken(a) || hashToken(a) == hashToken(b) || hashToken(a) == a {
+		t.Error("hashToken no identifica al token de forma estable")
+	}
+	if h := hashToken("abc"); h != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Errorf("hashToken(abc) = %s", h)
+	}
+}
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jhvc/backend/internal/ratelimit"
+)
+
+// Bloqueo de cuenta: se guarda en users y aplica aunque el atacante cambie de IP
+const (
+	maxFailedLogins    = 5
+	failedLoginWindow  = 15 * time.Minute
+	accountLockoutTime = 15 * time.Minute
+)
+
+var ErrAccountLocked = errors.New("cuenta bloqueada temporalmente por intentos fallidos; intenta más tarde o contacta al administrador")
+
+// rateLimits agrupa los limitadores de login y de verificación de licencias.
+// Sin UseRateLimits no se limita nada (el bloqueo de cuenta sigue activo).
+type rateLimits struct {
+	loginIP     *ratelimit.Limiter
+	loginEmail  *ratelimit.Limiter
+	licenseIP   *ratelimit.Limiter
+	licenseCode *ratelimit.Limiter
+}
+
+// UseRateLimits activa los limitadores con el store indicado (memoria o Postgres)
+func (s *Service) UseRateLimits(store ratelimit.Store) {
+	s.limits = &rateLimits{
+		loginIP: ratelimit.New(store, "login-ip", ratelimit.Regla{
+			Libres: 20, Base: time.Second, Max: 15 * time.Minute, Ventana: time.Hour,
+		}),
+		loginEmail: ratelimit.New(store, "login-email", ratelimit.Regla{
+			Libres: 3, Base: 2 * time.Second, Max: 5 * time.Minute, Ventana: 15 * time.Minute,
+		}),
+		licenseIP: ratelimit.New(store, "license-ip", ratelimit.Regla{
+			Libres: 10, Base: time.Second, Max: 15 * time.Minute, Ventana: time.Hour,
+		}),
+		licenseCode: ratelimit.New(store, "license-code", ratelimit.Regla{
+			Libres: 10, Base: time.Second, Max: 5 * time.Minute, Ventana: 15 * time.Minute,
+		}),
+	}
+}
+
+func emailKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (l *rateLimits) allowLogin(ip, email string) error {
+	if l == nil {
+		return nil
+	}
+	if err := l.loginIP.Permitir(ip); err != nil {
+		return err
+	}
+	return l.loginEmail.Permitir(emailKey(email))
+}
+
+func (l *rateLimits) failLogin(ip, email string) {
+	if l == nil {
+		return
+	}
+	if err := l.loginIP.Fallo(ip); err != nil {
+		log.Println("⚠️  Error registrando intento fallido:", err)
+	}
+	if err := l.loginEmail.Fallo(emailKey(email)); err != nil {
+		log.Println("⚠️  Error registrando intento fallido:", err)
+	}
+}
+
+// clearLogin limpia sólo el email: un login válido no debe perdonar a la IP
+// los intentos contra otras cuentas
+func (l *rateLimits) clearLogin(email string) {
+	if l == nil {
+		return
+	}
+	l.loginEmail.Limpiar(emailKey(email))
+}
+
+func (l *rateLimits) allowLicense(ip, code string) error {
+	if l == nil {
+		return nil
+	}
+	if err := l.licenseIP.Permitir(ip); err != nil {
+		return err
+	}
+	return l.licenseCode.Permitir(code)
+}
+
+func (l *rateLimits) failLicense(ip, code string) {
+	if l == nil {
+		return
+	}
+	if err := l.licenseIP.Fallo(ip); err != nil {
+		log.Println("⚠️  Error registrando intento fallido:", err)
+	}
+	if err := l.licenseCode.Fallo(code); err != nil {
+		log.Println("⚠️  Error registrando intento fallido:", err)
+	}
+}
+
+func (l *rateLimits) clearLicense(code string) {
+	if l == nil {
+		return
+	}
+	l.licenseCode.Limpiar(code)
+}
+
+func isLocked(user *User) bool {
+	return user.LockedUntil != nil && time.Now().Before(*user.LockedUntil)
+}
+
+// recordFailedLogin suma el fallo a la cuenta y la bloquea al llegar al límite
+func (s *Service) recordFailedLogin(user *User) {
+	locked, err := s.repo.RecordFailedLogin(user.ID, maxFailedLogins, failedLoginWindow, accountLockoutTime)
+	if err != nil {
+		log.Println("⚠️  Error registrando intento fallido:", err)
+		return
+	}
+	if locked {
+		s.users.invalidate(user.ID)
+		log.Printf("⚠️  Cuenta %d bloqueada por %d intentos fallidos", user.ID, maxFailedLogins)
+	}
+}
+
+// UnlockUser (admin) quita el bloqueo por intentos fallidos de la cuenta
+func (s *Service) UnlockUser(actor Actor, userID int) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.checkManageable(actor, user); err != nil {
+		return err
+	}
+	if err := s.repo.ResetFailedLogins(userID); err != nil {
+		return err
+	}
+	s.limits.clearLogin(user.Email)
+	s.users.invalidate(userID)
+	after, _ := s.repo.GetUserByID(userID)
+	s.Audit(actor, "user.unlock", auditUser, userID, user, after)
+	return nil
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret es la llave ASCII "12345678901234567890" de los vectores de
+// prueba de RFC 4226 y RFC 6238, en base32
+var rfcSecret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC4226(t *testing.T) {
+	esperados := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for contador, codigo := range esperados {
+		if c := totpCode([]byte("12345678901234567890"), int64(contador)); c != codigo {
+			t.Errorf("totpCode(%d) = %s, se esperaba %s", contador, c, codigo)
+		}
+	}
+}
+
+// Los vectores SHA-1 de RFC 6238 tienen 8 dígitos; a 6 dígitos son los últimos 6
+func TestValidateTOTPRFC6238(t *testing.T) {
+	casos := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, codigo := range casos {
+		now := time.Unix(unix, 0)
+		step, ok := validateTOTP(rfcSecret, codigo, now)
+		if !ok {
+			t.Errorf("validateTOTP(%s) en %d rechazado", codigo, unix)
+			continue
+		}
+		if step != unix/totpPeriod {
+			t.Errorf("validateTOTP(%s) en %d = periodo %d", codigo, unix, step)
+		}
+	}
+}
+
+func TestValidateTOTPVentana(t *testing.T) {
+	key := []byte("12345678901234567890")
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+
+	for _, delta := range []int64{-1, 0, 1} {
+		if step, ok := validateTOTP(rfcSecret, totpCode(key, current+delta), now); !ok || step != current+delta {
+			t.Errorf("código del periodo %+d: (%d, %v)", delta, step, ok)
+		}
+	}
+	for _, delta := range []int64{-2, 2} {
+		if _, ok := validateTOTP(rfcSecret, totpCode(key, current+delta), now); ok {
+			t.Errorf("se aceptó el código del periodo %+d, fuera de la ventana", delta)
+		}
+	}
+
+	for _, codigo := range []string{"", "00592", "0059240", "abcdef"} {
+		if _, ok := validateTOTP(rfcSecret, codigo, now); ok {
+			t.Errorf("se aceptó el código %q", codigo)
+		}
+	}
+	if _, ok := validateTOTP("no-es-base32!", "005924", now); ok {
+		t.Error("se aceptó un secreto inválido")
+	}
+	// La app puede mostrar el secreto en minúsculas
+	if _, ok := validateTOTP(strings.ToLower(rfcSecret), "005924", now); !ok {
+		t.Error("se rechazó el secreto en minúsculas")
+	}
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	secret, err := newTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Errorf("newTOTPSecret = %q (%d bytes, %v)", secret, len(key), err)
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := totpProvisioningURI("Mi Empresa", "ana@example.com", rfcSecret)
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Mi Empresa:ana@example.com" {
+		t.Errorf("URI = %s", uri)
+	}
+	if strings.Contains(uri, "+") {
+		t.Errorf("URI con espacios como \"+\": %s", uri)
+	}
+	q := u.Query()
+	if q.Get("secret") != rfcSecret || q.Get("issuer") != "Mi Empresa" || q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Errorf("parámetros = %v", q)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jhvc/backend/internal/modules/directorio"
 )
 
 // Handler maneja las peticiones HTTP para la calculadora fiscal
@@ -27,6 +28,7 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 		calc.GET("/configuraciones", h.GetConfiguraciones)
 		calc.GET("/calcular", h.Calcular)
 		calc.POST("/calcular", h.CalcularPost)
+		calc.POST("/conceptos", h.CalcularConceptos)
 	}
 }
 
@@ -158,3 +160,44 @@ func (h *Handler) CalcularPost(c *gin.Context) {
 		"data":    resultado,
 	})
 }
+
+// CalcularConceptos calcula una operación de varios conceptos. Con
+// contacto_id se usa la ruta de la empresa (/empresas/{empresaId}/calculadora/conceptos)
+// @Summary Calcula varios conceptos
+// @Description Cada concepto usa su propia configuración fiscal; los totales suman los importes redondeados.
+// @Description Con contacto_id, un receptor persona física no lleva retenciones.
+// @Tags calculadora
+// @Accept json
+// @Produce json
+// @Param request body CalculoMultipleRequest true "Conceptos a calcular"
+// @Success 200 {object} CalculoMultiple
+// @Failure 400 {object} map[string]interface{}
+// @Router /calculadora/conceptos [post]
+func (h *Handler) CalcularConceptos(c *gin.Context) {
+	var req CalculoMultipleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Datos inválidos: " + err.Error(),
+		})
+		return
+	}
+
+	resultado, err := h.service.CalcularOperacion(c.GetInt("empresaID"), req)
+	if err == directorio.ErrContactoNoEncontrado {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    resultado,
+	})
+}
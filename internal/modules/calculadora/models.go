@@ -31,3 +31,49 @@ type CalculoRequest struct {
 	ConfigIndex       int     `form:"config" binding:"required,gte=0"`
 	RetencionEspecial float64 `form:"retencion_especial"`
 }
+
+// ConceptoCalculo es un concepto dentro de un cálculo de varios conceptos
+type ConceptoCalculo struct {
+	Descripcion       string  `json:"descripcion"`
+	Cantidad          float64 `json:"cantidad" binding:"required,gt=0"`
+	ValorUnitario     float64 `json:"valor_unitario" binding:"required,gt=0"`
+	Descuento         float64 `json:"descuento" binding:"gte=0"`
+	ConfigIndex       int     `json:"config" binding:"gte=0"`
+	RetencionEspecial float64 `json:"retencion_especial"`
+}
+
+// CalculoConcepto es el resultado de un concepto con las tasas aplicadas
+type CalculoConcepto struct {
+	Descripcion      string  `json:"descripcion"`
+	Cantidad         float64 `json:"cantidad"`
+	ValorUnitario    float64 `json:"valor_unitario"`
+	Importe          float64 `json:"importe"`
+	Descuento        float64 `json:"descuento"`
+	Subtotal         float64 `json:"subtotal"`
+	IVARate          float64 `json:"iva_rate"`
+	ISRRate          float64 `json:"isr_rate"`
+	ISHRate          float64 `json:"ish_rate"`
+	RetencionIVARate float64 `json:"retencion_iva_rate"`
+	CalculoFiscal
+}
+
+// CalculoMultiple suma los conceptos de un cálculo de varios conceptos
+type CalculoMultiple struct {
+	Conceptos    []CalculoConcepto `json:"conceptos"`
+	Importe      float64           `json:"importe"`
+	Descuento    float64           `json:"descuento"`
+	Subtotal     float64           `json:"subtotal"`
+	IVA          float64           `json:"iva"`
+	ISH          float64           `json:"ish"`
+	RetencionISR float64           `json:"retencion_isr"`
+	RetencionIVA float64           `json:"retencion_iva"`
+	Total        float64           `json:"total"`
+	// TipoPersonaReceptor es el del contacto, cuando el cálculo lo indica
+	TipoPersonaReceptor string `json:"tipo_persona_receptor,omitempty"`
+}
+
+// CalculoMultipleRequest representa la petición de cálculo de varios conceptos
+type CalculoMultipleRequest struct {
+	Conceptos  []ConceptoCalculo `json:"conceptos" binding:"required,min=1,dive"`
+	ContactoID int               `json:"contacto_id"` // Opcional: receptor del directorio de la empresa
+}
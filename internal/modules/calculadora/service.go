@@ -1,16 +1,23 @@
 // internal/calculadora/service.go
 package calculadora
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/jhvc/backend/internal/modules/directorio"
+	"github.com/jhvc/backend/internal/validacion"
+)
 
 var (
-	ErrConfigNotFound = errors.New("configuración no encontrada")
-	ErrInvalidAmount  = errors.New("monto inválido")
+	ErrConfigNotFound     = errors.New("configuración no encontrada")
+	ErrInvalidAmount      = errors.New("monto inválido")
+	ErrContactoSinEmpresa = errors.New("contacto_id requiere calcular desde una empresa")
 )
 
 // Service maneja la lógica de negocio de cálculos fiscales
 type Service struct {
 	configuraciones []ConfigFiscal
+	directorio      *directorio.Service
 }
 
 // NewService crea una nueva instancia del servicio
@@ -20,6 +27,13 @@ func NewService() *Service {
 	}
 }
 
+// UsarDirectorio conecta el directorio de contactos para calcular con el
+// receptor de un contacto. Se separa del constructor porque facturación usa la
+// calculadora sin contactos.
+func (s *Service) UsarDirectorio(d *directorio.Service) {
+	s.directorio = d
+}
+
 // GetConfiguraciones devuelve todas las configuraciones disponibles
 func (s *Service) GetConfiguraciones() []ConfigFiscal {
 	return s.configuraciones
@@ -97,6 +111,108 @@ func (s *Service) CalcularInverso(total float64, config ConfigFiscal, retencionE
 	}
 }
 
+// CalcularOperacion calcula los conceptos de la petición. Con contacto_id, el
+// receptor sale del directorio de la empresa: si es persona física (o el RFC
+// genérico) no retiene ISR ni IVA, así que las retenciones no se aplican.
+func (s *Service) CalcularOperacion(empresaID int, req CalculoMultipleRequest) (*CalculoMultiple, error) {
+	if req.ContactoID == 0 {
+		return s.CalcularConceptos(req.Conceptos)
+	}
+	if empresaID == 0 || s.directorio == nil {
+		return nil, ErrContactoSinEmpresa
+	}
+
+	receptor, err := s.directorio.Receptor(empresaID, req.ContactoID)
+	if err != nil {
+		return nil, err
+	}
+	info, err := validacion.ValidarRFC(receptor.Rfc)
+	if err != nil {
+		return nil, err
+	}
+
+	resultado, err := s.calcularConceptos(req.Conceptos, info.Tipo == validacion.TipoPersonaMoral)
+	if err != nil {
+		return nil, err
+	}
+	resultado.TipoPersonaReceptor = info.Tipo
+	return resultado, nil
+}
+
+// CalcularConceptos calcula varios conceptos, cada uno con su configuración,
+// redondeando por concepto y sumando los importes ya redondeados
+func (s *Service) CalcularConceptos(conceptos []ConceptoCalculo) (*CalculoMultiple, error) {
+	return s.calcularConceptos(conceptos, true)
+}
+
+// calcularConceptos sin retenciones ignora las de la configuración y la
+// retención especial de cada concepto
+func (s *Service) calcularConceptos(conceptos []ConceptoCalculo, retenciones bool) (*CalculoMultiple, error) {
+	resultado := &CalculoMultiple{}
+
+	for _, c := range conceptos {
+		config, err := s.GetConfiguracion(c.ConfigIndex)
+		if err != nil {
+			return nil, err
+		}
+		if !retenciones {
+			sinRetencion := *config
+			sinRetencion.ISRRate = 0
+			sinRetencion.IVARetencion = false
+			config = &sinRetencion
+			c.RetencionEspecial = 0
+		}
+
+		importe := roundTo2Decimals(c.Cantidad * c.ValorUnitario)
+		subtotal := importe - c.Descuento
+		if subtotal <= 0 {
+			return nil, ErrInvalidAmount
+		}
+
+		var retencionIVARate float64
+		if config.IVARetencion {
+			retencionIVARate = config.IVARate * (2.0 / 3.0)
+		} else if c.RetencionEspecial > 0 {
+			retencionIVARate = c.RetencionEspecial
+		}
+
+		calc := s.CalcularDirecto(subtotal, *config, c.RetencionEspecial)
+		resultado.Conceptos = append(resultado.Conceptos, CalculoConcepto{
+			Descripcion:      c.Descripcion,
+			Cantidad:         c.Cantidad,
+			ValorUnitario:    c.ValorUnitario,
+			Importe:          importe,
+			Descuento:        roundTo2Decimals(c.Descuento),
+			Subtotal:         roundTo2Decimals(subtotal),
+			IVARate:          config.IVARate,
+			ISRRate:          config.ISRRate,
+			ISHRate:          config.ISHRate,
+			RetencionIVARate: retencionIVARate,
+			CalculoFiscal:    calc,
+		})
+
+		resultado.Importe += importe
+		resultado.Descuento += c.Descuento
+		resultado.Subtotal += calc.Subtotal
+		resultado.IVA += calc.IVA
+		resultado.ISH += calc.ISH
+		resultado.RetencionISR += calc.RetencionISR
+		resultado.RetencionIVA += calc.RetencionIVA
+	}
+
+	resultado.Importe = roundTo2Decimals(resultado.Importe)
+	resultado.Descuento = roundTo2Decimals(resultado.Descuento)
+	resultado.Subtotal = roundTo2Decimals(resultado.Subtotal)
+	resultado.IVA = roundTo2Decimals(resultado.IVA)
+	resultado.ISH = roundTo2Decimals(resultado.ISH)
+	resultado.RetencionISR = roundTo2Decimals(resultado.RetencionISR)
+	resultado.RetencionIVA = roundTo2Decimals(resultado.RetencionIVA)
+	resultado.Total = roundTo2Decimals(resultado.Subtotal + resultado.IVA + resultado.ISH -
+		resultado.RetencionISR - resultado.RetencionIVA)
+
+	return resultado, nil
+}
+
 // loadDefaultConfigs carga las configuraciones predeterminadas
 func loadDefaultConfigs() []ConfigFiscal {
 	return []ConfigFiscal{
@@ -0,0 +1,167 @@
+// internal/modules/catalogos/defaults.go
+package catalogos
+
+// VersionBase identifica los catálogos precargados desde el código
+const VersionBase = "base"
+
+// Regímenes que aceptan los usos de gastos e inversiones (G01-G03, I01-I08)
+const regimenesGastos = "601,603,606,612,620,621,622,623,624,625,626"
+
+// Regímenes de personas físicas que aceptan deducciones personales (D01-D10)
+const regimenesDeducciones = "605,606,608,611,612,614,607,615,625"
+
+// Regímenes que aceptan "Sin efectos fiscales" y "Pagos"
+const regimenesTodos = "601,603,605,606,608,610,611,612,614,616,620,621,622,623,624,607,615,625,626"
+
+// catalogosBase son los catálogos pequeños que se cargan al iniciar si aún no
+// se ha importado ninguna versión. ClaveProdServ, ClaveUnidad y CodigoPostal
+// son demasiado grandes y deben importarse desde el archivo del SAT.
+var catalogosBase = map[string][]Entrada{
+	RegimenFiscal: {
+		regimen("601", "General de Ley Personas Morales", false, true),
+		regimen("603", "Personas Morales con Fines no Lucrativos", false, true),
+		regimen("605", "Sueldos y Salarios e Ingresos Asimilados a Salarios", true, false),
+		regimen("606", "Arrendamiento", true, false),
+		regimen("607", "Régimen de Enajenación o Adquisición de Bienes", true, false),
+		regimen("608", "Demás ingresos", true, false),
+		regimen("610", "Residentes en el Extranjero sin Establecimiento Permanente en México", true, true),
+		regimen("611", "Ingresos por Dividendos (socios y accionistas)", true, false),
+		regimen("612", "Personas Físicas con Actividades Empresariales y Profesionales", true, false),
+		regimen("614", "Ingresos por intereses", true, false),
+		regimen("615", "Régimen de los ingresos por obtención de premios", true, false),
+		regimen("616", "Sin obligaciones fiscales", true, false),
+		regimen("620", "Sociedades Cooperativas de Producción que optan por diferir sus ingresos", false, true),
+		regimen("621", "Incorporación Fiscal", true, false),
+		regimen("622", "Actividades Agrícolas, Ganaderas, Silvícolas y Pesqueras", false, true),
+		regimen("623", "Opcional para Grupos de Sociedades", false, true),
+		regimen("624", "Coordinados", false, true),
+		regimen("625", "Régimen de las Actividades Empresariales con ingresos a través de Plataformas Tecnológicas", true, false),
+		regimen("626", "Régimen Simplificado de Confianza", true, true),
+	},
+	UsoCFDI: {
+		uso("G01", "Adquisición de mercancías", true, true, regimenesGastos),
+		uso("G02", "Devoluciones, descuentos o bonificaciones", true, true, regimenesGastos),
+		uso("G03", "Gastos en general", true, true, regimenesGastos),
+		uso("I01", "Construcciones", true, true, regimenesGastos),
+		uso("I02", "Mobiliario y equipo de oficina por inversiones", true, true, regimenesGastos),
+		uso("I03", "Equipo de transporte", true, true, regimenesGastos),
+		uso("I04", "Equipo de computo y accesorios", true, true, regimenesGastos),
+		uso("I05", "Dados, troqueles, moldes, matrices y herramental", true, true, regimenesGastos),
+		uso("I06", "Comunicaciones telefónicas", true, true, regimenesGastos),
+		uso("I07", "Comunicaciones satelitales", true, true, regimenesGastos),
+		uso("I08", "Otra maquinaria y equipo", true, true, regimenesGastos),
+		uso("D01", "Honorarios médicos, dentales y gastos hospitalarios", true, false, regimenesDeducciones),
+		uso("D02", "Gastos médicos por incapacidad o discapacidad", true, false, regimenesDeducciones),
+		uso("D03", "Gastos funerales", true, false, regimenesDeducciones),
+		uso("D04", "Donativos", true, false, regimenesDeducciones),
+		uso("D05", "Intereses reales efectivamente pagados por créditos hipotecarios (casa habitación)", true, false, regimenesDeducciones),
+		uso("D06", "Aportaciones voluntarias al SAR", true, false, regimenesDeducciones),
+		uso("D07", "Primas por seguros de gastos médicos", true, false, regimenesDeducciones),
+		uso("D08", "Gastos de transportación escolar obligatoria", true, false, regimenesDeducciones),
+		uso("D09", "Depósitos en cuentas para el ahorro, primas que tengan como base planes de pensiones", true, false, regimenesDeducciones),
+		uso("D10", "Pagos por servicios educativos (colegiaturas)", true, false, regimenesDeducciones),
+		uso("S01", "Sin efectos fiscales", true, true, regimenesTodos),
+		uso("CP01", "Pagos", true, true, regimenesTodos),
+		uso("CN01", "Nómina", true, false, "605"),
+	},
+	FormaPago: {
+		{Clave: "01", Descripcion: "Efectivo"},
+		{Clave: "02", Descripcion: "Cheque nominativo"},
+		{Clave: "03", Descripcion: "Transferencia electrónica de fondos"},
+		{Clave: "04", Descripcion: "Tarjeta de crédito"},
+		{Clave: "05", Descripcion: "Monedero electrónico"},
+		{Clave: "06", Descripcion: "Dinero electrónico"},
+		{Clave: "08", Descripcion: "Vales de despensa"},
+		{Clave: "12", Descripcion: "Dación en pago"},
+		{Clave: "13", Descripcion: "Pago por subrogación"},
+		{Clave: "14", Descripcion: "Pago por consignación"},
+		{Clave: "15", Descripcion: "Condonación"},
+		{Clave: "17", Descripcion: "Compensación"},
+		{Clave: "23", Descripcion: "Novación"},
+		{Clave: "24", Descripcion: "Confusión"},
+		{Clave: "25", Descripcion: "Remisión de deuda"},
+		{Clave: "26", Descripcion: "Prescripción o caducidad"},
+		{Clave: "27", Descripcion: "A satisfacción del acreedor"},
+		{Clave: "28", Descripcion: "Tarjeta de débito"},
+		{Clave: "29", Descripcion: "Tarjeta de servicios"},
+		{Clave: "30", Descripcion: "Aplicación de anticipos"},
+		{Clave: "31", Descripcion: "Intermediario pagos"},
+		{Clave: "99", Descripcion: "Por definir"},
+	},
+	MetodoPago: {
+		{Clave: "PUE", Descripcion: "Pago en una sola exhibición"},
+		{Clave: "PPD", Descripcion: "Pago en parcialidades o diferido"},
+	},
+	TipoDeComprobante: {
+		{Clave: "I", Descripcion: "Ingreso"},
+		{Clave: "E", Descripcion: "Egreso"},
+		{Clave: "T", Descripcion: "Traslado"},
+		{Clave: "N", Descripcion: "Nómina"},
+		{Clave: "P", Descripcion: "Pago"},
+	},
+	ObjetoImp: {
+		{Clave: "01", Descripcion: "No objeto de impuesto"},
+		{Clave: "02", Descripcion: "Sí objeto de impuesto"},
+		{Clave: "03", Descripcion: "Sí objeto del impuesto y no obligado al desglose"},
+		{Clave: "04", Descripcion: "Sí objeto del impuesto y no causa impuesto"},
+		{Clave: "05", Descripcion: "Sí objeto del impuesto, IVA crédito PODEBI"},
+	},
+	Exportacion: {
+		{Clave: "01", Descripcion: "No aplica"},
+		{Clave: "02", Descripcion: "Definitiva con clave A1"},
+		{Clave: "03", Descripcion: "Temporal"},
+		{Clave: "04", Descripcion: "Definitiva con clave distinta a A1 o cuando no existe enajenación en términos del CFF"},
+	},
+	Moneda: {
+		{Clave: "MXN", Descripcion: "Peso Mexicano"},
+		{Clave: "USD", Descripcion: "Dolar americano"},
+		{Clave: "EUR", Descripcion: "Euro"},
+		{Clave: "XXX", Descripcion: "Los códigos asignados para las transacciones en que intervenga ninguna moneda"},
+	},
+	Impuesto: {
+		{Clave: "001", Descripcion: "ISR"},
+		{Clave: "002", Descripcion: "IVA"},
+		{Clave: "003", Descripcion: "IEPS"},
+	},
+	TipoFactor: {
+		{Clave: "Tasa", Descripcion: "Tasa"},
+		{Clave: "Cuota", Descripcion: "Cuota"},
+		{Clave: "Exento", Descripcion: "Exento"},
+	},
+	TipoRelacion: {
+		{Clave: "01", Descripcion: "Nota de crédito de los documentos relacionados"},
+		{Clave: "02", Descripcion: "Nota de débito de los documentos relacionados"},
+		{Clave: "03", Descripcion: "Devolución de mercancía sobre facturas o traslados previos"},
+		{Clave: "04", Descripcion: "Sustitución de los CFDI previos"},
+		{Clave: "05", Descripcion: "Traslados de mercancías facturados previamente"},
+		{Clave: "06", Descripcion: "Factura generada por los traslados previos"},
+		{Clave: "07", Descripcion: "CFDI por aplicación de anticipo"},
+	},
+}
+
+func regimen(clave, descripcion string, fisica, moral bool) Entrada {
+	return Entrada{
+		Clave:       clave,
+		Descripcion: descripcion,
+		Datos:       map[string]string{DatoFisica: siNo(fisica), DatoMoral: siNo(moral)},
+	}
+}
+
+func uso(clave, descripcion string, fisica, moral bool, regimenes string) Entrada {
+	return Entrada{
+		Clave:       clave,
+		Descripcion: descripcion,
+		Datos: map[string]string{
+			DatoFisica:            siNo(fisica),
+			DatoMoral:             siNo(moral),
+			DatoRegimenesReceptor: regimenes,
+		},
+	}
+}
+
+func siNo(b bool) string {
+	if b {
+		return "Sí"
+	}
+	return "No"
+}
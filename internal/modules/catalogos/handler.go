@@ -0,0 +1,181 @@
+// internal/modules/catalogos/handler.go
+package catalogos
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jhvc/backend/internal/modules/auth"
+	"github.com/jhvc/backend/internal/validacion"
+)
+
+// Handler maneja las peticiones HTTP de los catálogos del SAT
+type Handler struct {
+	service *Service
+}
+
+// NewHandler crea una nueva instancia del handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetCatalogos lista los catálogos soportados y su versión vigente
+// @Router /catalogos [get]
+func (h *Handler) GetCatalogos(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.service.Resumen(),
+	})
+}
+
+// Buscar busca claves en un catálogo
+// @Param q query string false "Texto o prefijo de clave"
+// @Param limit query int false "Máximo de resultados"
+// @Router /catalogos/{catalogo} [get]
+func (h *Handler) Buscar(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	entradas, err := h.service.Buscar(c.Param("catalogo"), c.Query("q"), limit)
+	if err == ErrCatalogoNoSoportado {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    entradas,
+	})
+}
+
+// Obtener devuelve una clave de un catálogo
+// @Router /catalogos/{catalogo}/{clave} [get]
+func (h *Handler) Obtener(c *gin.Context) {
+	entrada, err := h.service.Obtener(c.Param("catalogo"), c.Param("clave"))
+	if err == ErrCatalogoNoSoportado || err == ErrClaveNoEncontrada {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    entrada,
+	})
+}
+
+// CompatibilidadUsoCFDI valida un UsoCFDI contra el régimen del receptor, o lista
+// los usos permitidos si no se indica `uso`. El tipo de persona se toma de
+// `tipo_persona` o se deduce del `rfc` del receptor.
+// @Param regimen query string true "RegimenFiscalReceptor"
+// @Param uso query string false "UsoCFDI"
+// @Param tipo_persona query string false "fisica o moral"
+// @Param rfc query string false "RFC del receptor"
+// @Router /catalogos/compatibilidad/uso-cfdi [get]
+func (h *Handler) CompatibilidadUsoCFDI(c *gin.Context) {
+	regimen := c.Query("regimen")
+	if regimen == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "regimen requerido"})
+		return
+	}
+
+	tipoPersona := c.Query("tipo_persona")
+	if rfc := c.Query("rfc"); rfc != "" && tipoPersona == "" {
+		info, err := validacion.ValidarRFC(rfc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		tipoPersona = info.Tipo
+	}
+
+	if uso := c.Query("uso"); uso != "" {
+		res, err := h.service.ValidarUsoCFDI(uso, regimen, tipoPersona)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": res})
+		return
+	}
+
+	usos, err := h.service.UsosCFDIPermitidos(regimen, tipoPersona)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    usos,
+	})
+}
+
+// Importar carga una nueva versión de un catálogo desde un CSV (campo `archivo`)
+// @Accept multipart/form-data
+// @Param version formData string true "Versión del catálogo (p. ej. fecha de publicación)"
+// @Router /admin/catalogos/{catalogo}/importar [post]
+func (h *Handler) Importar(c *gin.Context) {
+	file, err := c.FormFile("archivo")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "archivo requerido"})
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer f.Close()
+
+	version, err := h.service.Importar(auth.ContextActor(c), c.Param("catalogo"), c.PostForm("version"), f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Catálogo importado",
+		"data":    version,
+	})
+}
+
+// GetVersiones lista las versiones importadas de un catálogo
+// @Router /admin/catalogos/{catalogo}/versiones [get]
+func (h *Handler) GetVersiones(c *gin.Context) {
+	versiones, err := h.service.GetVersiones(c.Param("catalogo"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    versiones,
+	})
+}
+
+// ActivarVersion marca una versión importada como vigente
+// @Router /admin/catalogos/{catalogo}/versiones/{id}/activar [put]
+func (h *Handler) ActivarVersion(c *gin.Context) {
+	versionID, _ := strconv.Atoi(c.Param("id"))
+
+	if err := h.service.ActivarVersion(auth.ContextActor(c), c.Param("catalogo"), versionID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Versión activada",
+	})
+}
@@ -0,0 +1,134 @@
+// internal/modules/catalogos/loader.go
+package catalogos
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var ErrArchivoVacio = errors.New("el archivo no contiene registros")
+
+var formatosFecha = []string{"2006-01-02", "02/01/2006", "2/1/2006", "2006-01-02 15:04:05", "02-01-2006"}
+
+// ParseCSV lee una hoja del catálogo del SAT exportada a CSV. Se ignoran los
+// renglones de título hasta encontrar el encabezado cuya primera columna es
+// el nombre del catálogo (p. ej. "c_UsoCFDI"); si no existe, se toma el primer renglón.
+func ParseCSV(catalogo string, r io.Reader) ([]Entrada, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	registros, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(registros) == 0 {
+		return nil, ErrArchivoVacio
+	}
+
+	inicio := 0
+	for i, reg := range registros {
+		if len(reg) > 0 && strings.EqualFold(limpiarCelda(reg[0]), catalogo) {
+			inicio = i
+			break
+		}
+	}
+
+	encabezado := make([]string, len(registros[inicio]))
+	for i, col := range registros[inicio] {
+		encabezado[i] = normalizarEncabezado(col)
+	}
+
+	var entradas []Entrada
+	vistas := make(map[string]bool)
+	for _, reg := range registros[inicio+1:] {
+		if len(reg) == 0 {
+			continue
+		}
+		clave := normalizarClave(catalogo, limpiarCelda(reg[0]))
+		if clave == "" || vistas[clave] {
+			continue
+		}
+		vistas[clave] = true
+
+		e := Entrada{Clave: clave, Datos: make(map[string]string)}
+		if len(reg) > 1 {
+			e.Descripcion = limpiarCelda(reg[1])
+		}
+
+		for i := 2; i < len(reg) && i < len(encabezado); i++ {
+			valor := limpiarCelda(reg[i])
+			if valor == "" || encabezado[i] == "" {
+				continue
+			}
+			switch {
+			case strings.Contains(encabezado[i], "inicio_de_vigencia"):
+				e.VigenciaDesde = parseFecha(valor)
+			case strings.Contains(encabezado[i], "fin_de_vigencia"):
+				e.VigenciaHasta = parseFecha(valor)
+			default:
+				e.Datos[claveDato(encabezado[i])] = valor
+			}
+		}
+
+		entradas = append(entradas, e)
+	}
+
+	if len(entradas) == 0 {
+		return nil, ErrArchivoVacio
+	}
+	return entradas, nil
+}
+
+// claveDato unifica los nombres de columna que usan las reglas de compatibilidad
+func claveDato(encabezado string) string {
+	switch {
+	case strings.HasSuffix(encabezado, "fisica"):
+		return DatoFisica
+	case strings.HasSuffix(encabezado, "moral"):
+		return DatoMoral
+	case strings.HasPrefix(encabezado, "regimen_fiscal_receptor"):
+		return DatoRegimenesReceptor
+	}
+	return encabezado
+}
+
+func normalizarClave(catalogo, clave string) string {
+	ancho, ok := anchoClave[catalogo]
+	if !ok || len(clave) >= ancho || strings.TrimFunc(clave, unicode.IsDigit) != "" {
+		return clave
+	}
+	return strings.Repeat("0", ancho-len(clave)) + clave
+}
+
+// normalizarEncabezado convierte "Aplica para tipo persona Física" en "aplica_para_tipo_persona_fisica"
+func normalizarEncabezado(s string) string {
+	s = quitarAcentos(strings.ToLower(limpiarCelda(s)))
+	campos := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(campos, "_")
+}
+
+var acentos = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u")
+
+func quitarAcentos(s string) string {
+	return acentos.Replace(s)
+}
+
+func limpiarCelda(s string) string {
+	return strings.TrimSpace(strings.TrimPrefix(s, "\ufeff"))
+}
+
+func parseFecha(s string) *time.Time {
+	for _, f := range formatosFecha {
+		if t, err := time.Parse(f, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// internal/modules/catalogos/models.go
+package catalogos
+
+import "time"
+
+// Nombres de los catálogos del Anexo 20 soportados
+const (
+	RegimenFiscal     = "c_RegimenFiscal"
+	UsoCFDI           = "c_UsoCFDI"
+	FormaPago         = "c_FormaPago"
+	MetodoPago        = "c_MetodoPago"
+	TipoDeComprobante = "c_TipoDeComprobante"
+	ObjetoImp         = "c_ObjetoImp"
+	Exportacion       = "c_Exportacion"
+	Moneda            = "c_Moneda"
+	Impuesto          = "c_Impuesto"
+	TipoFactor        = "c_TipoFactor"
+	TasaOCuota        = "c_TasaOCuota"
+	TipoRelacion      = "c_TipoRelacion"
+	Periodicidad      = "c_Periodicidad"
+	Meses             = "c_Meses"
+	Pais              = "c_Pais"
+	CodigoPostal      = "c_CodigoPostal"
+	ClaveUnidad       = "c_ClaveUnidad"
+	ClaveProdServ     = "c_ClaveProdServ"
+
+	// CodigoAgrupador es el código agrupador de cuentas del Anexo 24 (contabilidad electrónica)
+	CodigoAgrupador = "c_CodigoAgrupador"
+)
+
+// CatalogosSoportados lista los catálogos que se pueden importar y consultar
+var CatalogosSoportados = []string{
+	RegimenFiscal, UsoCFDI, FormaPago, MetodoPago, TipoDeComprobante, ObjetoImp,
+	Exportacion, Moneda, Impuesto, TipoFactor, TasaOCuota, TipoRelacion,
+	Periodicidad, Meses, Pais, CodigoPostal, ClaveUnidad, ClaveProdServ,
+	CodigoAgrupador,
+}
+
+// anchoClave indica la longitud de las claves numéricas que las hojas de cálculo
+// suelen exportar sin ceros a la izquierda
+var anchoClave = map[string]int{
+	RegimenFiscal: 3,
+	FormaPago:     2,
+	ObjetoImp:     2,
+	Exportacion:   2,
+	Impuesto:      3,
+	TipoRelacion:  2,
+	Periodicidad:  2,
+	Meses:         2,
+	CodigoPostal:  5,
+	ClaveProdServ: 8,
+}
+
+// Columnas conocidas dentro de Entrada.Datos
+const (
+	DatoFisica            = "fisica"
+	DatoMoral             = "moral"
+	DatoRegimenesReceptor = "regimen_fiscal_receptor"
+	DatoPalabrasSimilares = "palabras_similares"
+)
+
+// CatalogoVersion es una importación de un catálogo del SAT
+type CatalogoVersion struct {
+	ID         int       `json:"id"`
+	Catalogo   string    `json:"catalogo"`
+	Version    string    `json:"version"`
+	Registros  int       `json:"registros"`
+	IsCurrent  bool      `json:"is_current"`
+	ImportedBy int       `json:"imported_by,omitempty"`
+	ImportedAt time.Time `json:"imported_at"`
+}
+
+// Entrada es un registro de un catálogo
+type Entrada struct {
+	Clave         string            `json:"clave"`
+	Descripcion   string            `json:"descripcion"`
+	Datos         map[string]string `json:"datos,omitempty"`
+	VigenciaDesde *time.Time        `json:"vigencia_desde,omitempty"`
+	VigenciaHasta *time.Time        `json:"vigencia_hasta,omitempty"`
+}
+
+// Vigente indica si la entrada está vigente en la fecha dada
+func (e Entrada) Vigente(fecha time.Time) bool {
+	if e.VigenciaDesde != nil && fecha.Before(*e.VigenciaDesde) {
+		return false
+	}
+	if e.VigenciaHasta != nil && fecha.After(e.VigenciaHasta.Add(24*time.Hour)) {
+		return false
+	}
+	return true
+}
+
+// ResumenCatalogo describe la versión vigente de un catálogo
+type ResumenCatalogo struct {
+	Catalogo string           `json:"catalogo"`
+	Vigente  *CatalogoVersion `json:"vigente,omitempty"`
+	Cargado  bool             `json:"cargado"`
+}
+
+// CompatibilidadUsoCFDI es el resultado de validar un UsoCFDI contra el receptor
+type CompatibilidadUsoCFDI struct {
+	UsoCFDI       string `json:"uso_cfdi"`
+	RegimenFiscal string `json:"regimen_fiscal"`
+	TipoPersona   string `json:"tipo_persona,omitempty"`
+	Permitido     bool   `json:"permitido"`
+	Motivo        string `json:"motivo,omitempty"`
+}
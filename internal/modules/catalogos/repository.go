@@ -0,0 +1,234 @@
+// internal/modules/catalogos/repository.go
+package catalogos
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// ImportarVersion guarda una nueva versión del catálogo y la marca como vigente
+// en una sola transacción
+func (r *Repository) ImportarVersion(catalogo, version string, importedBy int, entradas []Entrada) (*CatalogoVersion, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var v CatalogoVersion
+	err = tx.QueryRow(`
+        INSERT INTO sat_catalogo_versiones (catalogo, version, registros, imported_by)
+        VALUES ($1, $2, $3, NULLIF($4, 0))
+        RETURNING id, catalogo, version, registros, imported_at
+    `, catalogo, version, len(entradas), importedBy).Scan(&v.ID, &v.Catalogo, &v.Version, &v.Registros, &v.ImportedAt)
+	if err != nil {
+		return nil, err
+	}
+	v.ImportedBy = importedBy
+
+	stmt, err := tx.Prepare(pq.CopyIn("sat_catalogo_entradas",
+		"version_id", "catalogo", "clave", "descripcion", "datos", "vigencia_desde", "vigencia_hasta"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entradas {
+		datos, err := json.Marshal(e.Datos)
+		if err != nil {
+			stmt.Close()
+			return nil, err
+		}
+		if _, err := stmt.Exec(v.ID, catalogo, e.Clave, e.Descripcion, string(datos), e.VigenciaDesde, e.VigenciaHasta); err != nil {
+			stmt.Close()
+			return nil, err
+		}
+	}
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return nil, err
+	}
+	if err := stmt.Close(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`
+        UPDATE sat_catalogo_versiones SET is_current = (id = $1) WHERE catalogo = $2
+    `, v.ID, catalogo); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	v.IsCurrent = true
+	return &v, nil
+}
+
+func (r *Repository) GetVersionVigente(catalogo string) (*CatalogoVersion, error) {
+	var v CatalogoVersion
+	var importedBy sql.NullInt64
+
+	err := r.db.QueryRow(`
+        SELECT id, catalogo, version, registros, is_current, imported_by, imported_at
+        FROM sat_catalogo_versiones
+        WHERE catalogo = $1 AND is_current = true
+    `, catalogo).Scan(&v.ID, &v.Catalogo, &v.Version, &v.Registros, &v.IsCurrent, &importedBy, &v.ImportedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if importedBy.Valid {
+		v.ImportedBy = int(importedBy.Int64)
+	}
+	return &v, nil
+}
+
+func (r *Repository) GetVersiones(catalogo string) ([]CatalogoVersion, error) {
+	rows, err := r.db.Query(`
+        SELECT id, catalogo, version, registros, is_current, imported_by, imported_at
+        FROM sat_catalogo_versiones
+        WHERE catalogo = $1
+        ORDER BY imported_at DESC
+    `, catalogo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versiones []CatalogoVersion
+	for rows.Next() {
+		var v CatalogoVersion
+		var importedBy sql.NullInt64
+
+		if err := rows.Scan(&v.ID, &v.Catalogo, &v.Version, &v.Registros, &v.IsCurrent, &importedBy, &v.ImportedAt); err != nil {
+			continue
+		}
+		if importedBy.Valid {
+			v.ImportedBy = int(importedBy.Int64)
+		}
+		versiones = append(versiones, v)
+	}
+
+	return versiones, nil
+}
+
+// ActivarVersion permite regresar a una versión anterior de un catálogo
+func (r *Repository) ActivarVersion(catalogo string, versionID int) error {
+	res, err := r.db.Exec(`
+        UPDATE sat_catalogo_versiones SET is_current = (id = $1)
+        WHERE catalogo = $2 AND EXISTS (
+            SELECT 1 FROM sat_catalogo_versiones WHERE id = $1 AND catalogo = $2
+        )
+    `, versionID, catalogo)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *Repository) GetEntrada(catalogo, clave string) (*Entrada, error) {
+	row := r.db.QueryRow(`
+        SELECT e.clave, e.descripcion, e.datos, e.vigencia_desde, e.vigencia_hasta
+        FROM sat_catalogo_entradas e
+        JOIN sat_catalogo_versiones v ON v.id = e.version_id
+        WHERE v.catalogo = $1 AND v.is_current = true AND e.clave = $2
+    `, catalogo, clave)
+	return scanEntrada(row)
+}
+
+// BuscarTexto usa búsqueda de texto completo en español sobre la descripción
+// y las palabras similares; también acepta prefijos de clave.
+func (r *Repository) BuscarTexto(catalogo, q string, limit int) ([]Entrada, error) {
+	rows, err := r.db.Query(`
+        SELECT e.clave, e.descripcion, e.datos, e.vigencia_desde, e.vigencia_hasta
+        FROM sat_catalogo_entradas e
+        JOIN sat_catalogo_versiones v ON v.id = e.version_id
+        WHERE v.catalogo = $1 AND v.is_current = true
+          AND (
+            to_tsvector('spanish', e.descripcion || ' ' || COALESCE(e.datos->>'palabras_similares', ''))
+                @@ plainto_tsquery('spanish', $2)
+            OR e.clave LIKE $2 || '%'
+          )
+        ORDER BY (e.clave LIKE $2 || '%') DESC,
+            ts_rank(
+                to_tsvector('spanish', e.descripcion || ' ' || COALESCE(e.datos->>'palabras_similares', '')),
+                plainto_tsquery('spanish', $2)
+            ) DESC,
+            e.clave
+        LIMIT $3
+    `, catalogo, q, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanEntradas(rows)
+}
+
+// Buscar filtra por clave o descripción (ILIKE); con q vacío lista el catálogo
+func (r *Repository) Buscar(catalogo, q string, limit int) ([]Entrada, error) {
+	rows, err := r.db.Query(`
+        SELECT e.clave, e.descripcion, e.datos, e.vigencia_desde, e.vigencia_hasta
+        FROM sat_catalogo_entradas e
+        JOIN sat_catalogo_versiones v ON v.id = e.version_id
+        WHERE v.catalogo = $1 AND v.is_current = true
+          AND ($2 = '' OR e.clave ILIKE $2 || '%' OR e.descripcion ILIKE '%' || $2 || '%')
+        ORDER BY e.clave
+        LIMIT $3
+    `, catalogo, q, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanEntradas(rows)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEntrada(row rowScanner) (*Entrada, error) {
+	var e Entrada
+	var datos []byte
+	var desde, hasta sql.NullTime
+
+	if err := row.Scan(&e.Clave, &e.Descripcion, &datos, &desde, &hasta); err != nil {
+		return nil, err
+	}
+
+	if len(datos) > 0 {
+		json.Unmarshal(datos, &e.Datos)
+	}
+	if desde.Valid {
+		e.VigenciaDesde = &desde.Time
+	}
+	if hasta.Valid {
+		e.VigenciaHasta = &hasta.Time
+	}
+	return &e, nil
+}
+
+func scanEntradas(rows *sql.Rows) ([]Entrada, error) {
+	defer rows.Close()
+
+	var entradas []Entrada
+	for rows.Next() {
+		e, err := scanEntrada(rows)
+		if err != nil {
+			continue
+		}
+		entradas = append(entradas, *e)
+	}
+
+	return entradas, nil
+}
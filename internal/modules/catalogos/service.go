@@ -0,0 +1,308 @@
+// internal/modules/catalogos/service.go
+package catalogos
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/jhvc/backend/internal/modules/auth"
+	"github.com/jhvc/backend/internal/validacion"
+)
+
+var (
+	ErrCatalogoNoSoportado = errors.New("catálogo no soportado")
+	ErrCatalogoNoCargado   = errors.New("catálogo no cargado")
+	ErrClaveNoEncontrada   = errors.New("clave no encontrada en el catálogo")
+	ErrVersionRequerida    = errors.New("versión requerida")
+)
+
+const (
+	limiteBusqueda       = 20
+	limiteBusquedaMaximo = 200
+)
+
+// Tipo de entidad de los catálogos en la bitácora
+const auditVersion = "catalog_version"
+
+// Bitacora registra las acciones administrativas sobre los catálogos
+type Bitacora interface {
+	Audit(actor auth.Actor, action, entityType string, entityID int, before, after interface{})
+}
+
+// Service maneja la consulta e importación de los catálogos del SAT
+type Service struct {
+	repo     *Repository
+	bitacora Bitacora
+}
+
+// NewService crea una nueva instancia del servicio
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// UsarBitacora conecta la bitácora de auditoría; sin ella la importación y la
+// activación de versiones no se registran
+func (s *Service) UsarBitacora(b Bitacora) {
+	s.bitacora = b
+}
+
+func (s *Service) audit(actor auth.Actor, action string, entityID int, before, after interface{}) {
+	if s.bitacora != nil {
+		s.bitacora.Audit(actor, action, auditVersion, entityID, before, after)
+	}
+}
+
+// NormalizarCatalogo acepta el nombre sin importar mayúsculas ni el prefijo "c_"
+func NormalizarCatalogo(nombre string) (string, error) {
+	nombre = strings.TrimSpace(nombre)
+	for _, c := range CatalogosSoportados {
+		if strings.EqualFold(c, nombre) || strings.EqualFold(strings.TrimPrefix(c, "c_"), nombre) {
+			return c, nil
+		}
+	}
+	return "", ErrCatalogoNoSoportado
+}
+
+// CargarBase precarga los catálogos pequeños que todavía no tienen versión vigente
+func (s *Service) CargarBase() error {
+	for catalogo, entradas := range catalogosBase {
+		if _, err := s.repo.GetVersionVigente(catalogo); err == nil {
+			continue
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+
+		if _, err := s.repo.ImportarVersion(catalogo, VersionBase, 0, entradas); err != nil {
+			return fmt.Errorf("%s: %w", catalogo, err)
+		}
+		log.Printf("✅ Catálogo %s precargado (%d registros)", catalogo, len(entradas))
+	}
+	return nil
+}
+
+// Importar carga una nueva versión de un catálogo desde un CSV exportado del archivo del SAT
+func (s *Service) Importar(actor auth.Actor, catalogo, version string, r io.Reader) (*CatalogoVersion, error) {
+	catalogo, err := NormalizarCatalogo(catalogo)
+	if err != nil {
+		return nil, err
+	}
+
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return nil, ErrVersionRequerida
+	}
+
+	entradas, err := ParseCSV(catalogo, r)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := s.repo.ImportarVersion(catalogo, version, actor.UserID, entradas)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(actor, "catalog.import", v.ID, nil, v)
+	return v, nil
+}
+
+// Resumen lista los catálogos soportados y su versión vigente
+func (s *Service) Resumen() []ResumenCatalogo {
+	resumen := make([]ResumenCatalogo, 0, len(CatalogosSoportados))
+	for _, c := range CatalogosSoportados {
+		r := ResumenCatalogo{Catalogo: c}
+		if v, err := s.repo.GetVersionVigente(c); err == nil {
+			r.Vigente = v
+			r.Cargado = true
+		}
+		resumen = append(resumen, r)
+	}
+	return resumen
+}
+
+func (s *Service) GetVersiones(catalogo string) ([]CatalogoVersion, error) {
+	catalogo, err := NormalizarCatalogo(catalogo)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetVersiones(catalogo)
+}
+
+// ActivarVersion marca una versión como vigente; la bitácora guarda la
+// versión vigente antes y después del cambio
+func (s *Service) ActivarVersion(actor auth.Actor, catalogo string, versionID int) error {
+	catalogo, err := NormalizarCatalogo(catalogo)
+	if err != nil {
+		return err
+	}
+
+	before, err := s.repo.GetVersionVigente(catalogo)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err := s.repo.ActivarVersion(catalogo, versionID); err != nil {
+		return err
+	}
+
+	after, err := s.repo.GetVersionVigente(catalogo)
+	if err != nil {
+		return err
+	}
+	s.audit(actor, "catalog.activate", versionID, before, after)
+	return nil
+}
+
+// Buscar busca en la versión vigente; ClaveProdServ usa búsqueda de texto completo
+func (s *Service) Buscar(catalogo, q string, limit int) ([]Entrada, error) {
+	catalogo, err := NormalizarCatalogo(catalogo)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = limiteBusqueda
+	}
+	if limit > limiteBusquedaMaximo {
+		limit = limiteBusquedaMaximo
+	}
+
+	q = strings.TrimSpace(q)
+	if q != "" && (catalogo == ClaveProdServ || catalogo == ClaveUnidad) {
+		return s.repo.BuscarTexto(catalogo, q, limit)
+	}
+	return s.repo.Buscar(catalogo, q, limit)
+}
+
+// Obtener devuelve una clave de la versión vigente del catálogo
+func (s *Service) Obtener(catalogo, clave string) (*Entrada, error) {
+	catalogo, err := NormalizarCatalogo(catalogo)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := s.repo.GetEntrada(catalogo, normalizarClave(catalogo, strings.TrimSpace(clave)))
+	if err == sql.ErrNoRows {
+		return nil, ErrClaveNoEncontrada
+	}
+	return e, err
+}
+
+// Cargado indica si el catálogo tiene una versión vigente
+func (s *Service) Cargado(catalogo string) bool {
+	_, err := s.repo.GetVersionVigente(catalogo)
+	return err == nil
+}
+
+// ValidarClave verifica que la clave exista en la versión vigente del catálogo
+func (s *Service) ValidarClave(catalogo, clave string) error {
+	if !s.Cargado(catalogo) {
+		return fmt.Errorf("%w: %s", ErrCatalogoNoCargado, catalogo)
+	}
+	if _, err := s.Obtener(catalogo, clave); err != nil {
+		if err == ErrClaveNoEncontrada {
+			return fmt.Errorf("%s: clave %q no encontrada", catalogo, clave)
+		}
+		return err
+	}
+	return nil
+}
+
+// RegimenAplica verifica que el régimen fiscal exista y aplique al tipo de persona
+// ("fisica" o "moral"; vacío omite esa revisión)
+func (s *Service) RegimenAplica(regimen, tipoPersona string) (bool, error) {
+	e, err := s.Obtener(RegimenFiscal, regimen)
+	if err != nil {
+		return false, err
+	}
+	return aplicaATipoPersona(*e, tipoPersona), nil
+}
+
+// ValidarUsoCFDI revisa las reglas del catálogo c_UsoCFDI: el uso debe existir,
+// aplicar al tipo de persona del receptor y listar su régimen fiscal
+func (s *Service) ValidarUsoCFDI(usoCFDI, regimenReceptor, tipoPersona string) (*CompatibilidadUsoCFDI, error) {
+	res := &CompatibilidadUsoCFDI{
+		UsoCFDI:       strings.ToUpper(strings.TrimSpace(usoCFDI)),
+		RegimenFiscal: normalizarClave(RegimenFiscal, strings.TrimSpace(regimenReceptor)),
+		TipoPersona:   tipoPersona,
+	}
+
+	uso, err := s.Obtener(UsoCFDI, res.UsoCFDI)
+	if err == ErrClaveNoEncontrada {
+		res.Motivo = "UsoCFDI no existe en el catálogo"
+		return res, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	regimen, err := s.Obtener(RegimenFiscal, res.RegimenFiscal)
+	if err == ErrClaveNoEncontrada {
+		res.Motivo = "RegimenFiscal no existe en el catálogo"
+		return res, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !aplicaATipoPersona(*regimen, tipoPersona) {
+		res.Motivo = "El régimen fiscal no aplica para persona " + tipoPersona
+		return res, nil
+	}
+	if !aplicaATipoPersona(*uso, tipoPersona) {
+		res.Motivo = "El UsoCFDI no aplica para persona " + tipoPersona
+		return res, nil
+	}
+	if !contieneRegimen(uso.Datos[DatoRegimenesReceptor], res.RegimenFiscal) {
+		res.Motivo = "El UsoCFDI no es compatible con el régimen fiscal del receptor"
+		return res, nil
+	}
+
+	res.Permitido = true
+	return res, nil
+}
+
+// UsosCFDIPermitidos lista los usos de CFDI compatibles con el régimen del receptor
+func (s *Service) UsosCFDIPermitidos(regimenReceptor, tipoPersona string) ([]Entrada, error) {
+	usos, err := s.repo.Buscar(UsoCFDI, "", limiteBusquedaMaximo)
+	if err != nil {
+		return nil, err
+	}
+
+	regimenReceptor = normalizarClave(RegimenFiscal, strings.TrimSpace(regimenReceptor))
+	var permitidos []Entrada
+	for _, u := range usos {
+		if aplicaATipoPersona(u, tipoPersona) && contieneRegimen(u.Datos[DatoRegimenesReceptor], regimenReceptor) {
+			permitidos = append(permitidos, u)
+		}
+	}
+	return permitidos, nil
+}
+
+func aplicaATipoPersona(e Entrada, tipoPersona string) bool {
+	var valor string
+	switch tipoPersona {
+	case validacion.TipoPersonaFisica:
+		valor = e.Datos[DatoFisica]
+	case validacion.TipoPersonaMoral:
+		valor = e.Datos[DatoMoral]
+	default:
+		return true
+	}
+	// Las columnas del SAT usan "Sí"/"No"; si no vienen se asume que aplica
+	return valor == "" || !strings.EqualFold(valor, "no")
+}
+
+func contieneRegimen(lista, regimen string) bool {
+	if strings.TrimSpace(lista) == "" {
+		return true
+	}
+	for _, r := range strings.Split(lista, ",") {
+		if strings.TrimSpace(r) == regimen {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+// internal/modules/cfdi/handler.go
+package cfdi
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tamanoMaximoXML limita el tamaño de cada CFDI recibido
+const tamanoMaximoXML = 5 << 20
+
+// Handler maneja las peticiones HTTP de CFDIs y complementos de pago
+type Handler struct {
+	service *Service
+}
+
+// NewHandler crea una nueva instancia del handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Ingerir recibe uno o varios XML en el campo multipart `archivos`, o un XML
+// directo en el cuerpo con Content-Type application/xml
+// @Router /cfdi [post]
+func (h *Handler) Ingerir(c *gin.Context) {
+	empresaID, userID := c.GetInt("empresaID"), c.GetInt("userID")
+
+	if c.ContentType() == "application/xml" || c.ContentType() == "text/xml" {
+		data, err := io.ReadAll(io.LimitReader(c.Request.Body, tamanoMaximoXML))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+
+		res, err := h.service.Ingerir(empresaID, userID, data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"success": true, "data": res})
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["archivos"]) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "archivos requeridos"})
+		return
+	}
+
+	var resultados []ResultadoIngesta
+	for _, fh := range form.File["archivos"] {
+		res := ResultadoIngesta{Archivo: fh.Filename}
+
+		f, err := fh.Open()
+		if err != nil {
+			res.Error = err.Error()
+			resultados = append(resultados, res)
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(f, tamanoMaximoXML))
+		f.Close()
+		if err != nil {
+			res.Error = err.Error()
+			resultados = append(resultados, res)
+			continue
+		}
+
+		r, err := h.service.Ingerir(empresaID, userID, data)
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			r.Archivo = fh.Filename
+			res = *r
+		}
+		resultados = append(resultados, res)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    resultados,
+	})
+}
+
+// GetCFDIs lista los CFDIs almacenados
+// @Param tipo query string false "TipoDeComprobante (I, E, P...)"
+// @Param rfc query string false "RFC emisor o receptor"
+// @Param desde query string false "Fecha inicial AAAA-MM-DD"
+// @Param hasta query string false "Fecha final AAAA-MM-DD (inclusive)"
+// @Router /cfdi [get]
+func (h *Handler) GetCFDIs(c *gin.Context) {
+	f, err := filtroDesdeQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	cfdis, err := h.service.GetCFDIs(c.GetInt("empresaID"), f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    cfdis,
+	})
+}
+
+// GetCFDI devuelve los datos de un CFDI
+// @Router /cfdi/{uuid} [get]
+func (h *Handler) GetCFDI(c *gin.Context) {
+	cfdi, err := h.service.GetCFDI(c.GetInt("empresaID"), c.Param("uuid"))
+	if err == ErrCFDINoEncontrado {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    cfdi,
+	})
+}
+
+// GetXML descarga el XML original de un CFDI
+// @Router /cfdi/{uuid}/xml [get]
+func (h *Handler) GetXML(c *gin.Context) {
+	data, err := h.service.GetXML(c.GetInt("empresaID"), c.Param("uuid"))
+	if err == ErrCFDINoEncontrado {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+NormalizarUUID(c.Param("uuid"))+`.xml"`)
+	c.Data(http.StatusOK, "application/xml", data)
+}
+
+// GetHistorialPagos devuelve las parcialidades y el saldo insoluto de una factura
+// @Router /cfdi/{uuid}/pagos [get]
+func (h *Handler) GetHistorialPagos(c *gin.Context) {
+	historial, err := h.service.GetHistorialPagos(c.GetInt("empresaID"), c.Param("uuid"))
+	if err == ErrCFDINoEncontrado {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    historial,
+	})
+}
+
+// GetPendientesPPD reporta las facturas PPD con saldo pendiente o sin REP
+// @Param sin_rep query bool false "Sólo facturas sin ningún complemento de pago"
+// @Router /cfdi/ppd/pendientes [get]
+func (h *Handler) GetPendientesPPD(c *gin.Context) {
+	f, err := filtroDesdeQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	pendientes, err := h.service.GetPendientesPPD(c.GetInt("empresaID"), f, c.Query("sin_rep") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    pendientes,
+	})
+}
+
+func filtroDesdeQuery(c *gin.Context) (FiltroCFDI, error) {
+	f := FiltroCFDI{Tipo: c.Query("tipo"), RFC: c.Query("rfc")}
+
+	if desde := c.Query("desde"); desde != "" {
+		t, err := time.Parse("2006-01-02", desde)
+		if err != nil {
+			return f, err
+		}
+		f.Desde = &t
+	}
+	if hasta := c.Query("hasta"); hasta != "" {
+		t, err := time.Parse("2006-01-02", hasta)
+		if err != nil {
+			return f, err
+		}
+		t = t.AddDate(0, 0, 1)
+		f.Hasta = &t
+	}
+
+	return f, nil
+}
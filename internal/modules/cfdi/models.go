@@ -0,0 +1,194 @@
+// internal/modules/cfdi/models.go
+package cfdi
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Tipos de comprobante y métodos de pago usados en las consultas
+const (
+	TipoIngreso = "I"
+	TipoEgreso  = "E"
+	TipoPago    = "P"
+
+	MetodoPUE = "PUE"
+	MetodoPPD = "PPD"
+)
+
+// Comprobante es la estructura mínima de un CFDI 3.3/4.0 que se lee al ingerirlo.
+// Las etiquetas no llevan namespace para aceptar cualquier prefijo (cfdi:, pago20:, tfd:).
+type Comprobante struct {
+	XMLName           xml.Name    `xml:"Comprobante"`
+	Version           string      `xml:"Version,attr"`
+	Serie             string      `xml:"Serie,attr"`
+	Folio             string      `xml:"Folio,attr"`
+	Fecha             string      `xml:"Fecha,attr"`
+	FormaPago         string      `xml:"FormaPago,attr"`
+	SubTotal          float64     `xml:"SubTotal,attr"`
+	Descuento         float64     `xml:"Descuento,attr"`
+	Moneda            string      `xml:"Moneda,attr"`
+	TipoCambio        float64     `xml:"TipoCambio,attr"`
+	Total             float64     `xml:"Total,attr"`
+	TipoDeComprobante string      `xml:"TipoDeComprobante,attr"`
+	MetodoPago        string      `xml:"MetodoPago,attr"`
+	LugarExpedicion   string      `xml:"LugarExpedicion,attr"`
+	Emisor            Emisor      `xml:"Emisor"`
+	Receptor          Receptor    `xml:"Receptor"`
+	Conceptos         []Concepto  `xml:"Conceptos>Concepto"`
+	Impuestos         *Impuestos  `xml:"Impuestos"`
+	Complemento       Complemento `xml:"Complemento"`
+}
+
+type Emisor struct {
+	Rfc           string `xml:"Rfc,attr"`
+	Nombre        string `xml:"Nombre,attr"`
+	RegimenFiscal string `xml:"RegimenFiscal,attr"`
+}
+
+type Receptor struct {
+	Rfc                     string `xml:"Rfc,attr"`
+	Nombre                  string `xml:"Nombre,attr"`
+	DomicilioFiscalReceptor string `xml:"DomicilioFiscalReceptor,attr"`
+	RegimenFiscalReceptor   string `xml:"RegimenFiscalReceptor,attr"`
+	UsoCFDI                 string `xml:"UsoCFDI,attr"`
+}
+
+type Concepto struct {
+	ClaveProdServ string             `xml:"ClaveProdServ,attr"`
+	Cantidad      float64            `xml:"Cantidad,attr"`
+	ClaveUnidad   string             `xml:"ClaveUnidad,attr"`
+	Descripcion   string             `xml:"Descripcion,attr"`
+	ValorUnitario float64            `xml:"ValorUnitario,attr"`
+	Importe       float64            `xml:"Importe,attr"`
+	Descuento     float64            `xml:"Descuento,attr"`
+	ObjetoImp     string             `xml:"ObjetoImp,attr"`
+	Traslados     []ImpuestoConcepto `xml:"Impuestos>Traslados>Traslado"`
+	Retenciones   []ImpuestoConcepto `xml:"Impuestos>Retenciones>Retencion"`
+}
+
+type ImpuestoConcepto struct {
+	Base       float64 `xml:"Base,attr"`
+	Impuesto   string  `xml:"Impuesto,attr"`
+	TipoFactor string  `xml:"TipoFactor,attr"`
+	TasaOCuota float64 `xml:"TasaOCuota,attr"`
+	Importe    float64 `xml:"Importe,attr"`
+}
+
+type Impuestos struct {
+	TotalImpuestosTrasladados float64 `xml:"TotalImpuestosTrasladados,attr"`
+	TotalImpuestosRetenidos   float64 `xml:"TotalImpuestosRetenidos,attr"`
+}
+
+type Complemento struct {
+	TimbreFiscalDigital *TimbreFiscalDigital `xml:"TimbreFiscalDigital"`
+	Pagos               *Pagos               `xml:"Pagos"`
+}
+
+type TimbreFiscalDigital struct {
+	UUID             string `xml:"UUID,attr"`
+	FechaTimbrado    string `xml:"FechaTimbrado,attr"`
+	RfcProvCertif    string `xml:"RfcProvCertif,attr"`
+	NoCertificadoSAT string `xml:"NoCertificadoSAT,attr"`
+	SelloSAT         string `xml:"SelloSAT,attr"`
+}
+
+// Pagos es el complemento para recepción de pagos (REP) 2.0; también lee la 1.0
+type Pagos struct {
+	Version string `xml:"Version,attr"`
+	Pago    []Pago `xml:"Pago"`
+}
+
+type Pago struct {
+	FechaPago        string             `xml:"FechaPago,attr"`
+	FormaDePagoP     string             `xml:"FormaDePagoP,attr"`
+	MonedaP          string             `xml:"MonedaP,attr"`
+	TipoCambioP      float64            `xml:"TipoCambioP,attr"`
+	Monto            float64            `xml:"Monto,attr"`
+	NumOperacion     string             `xml:"NumOperacion,attr"`
+	DoctoRelacionado []DoctoRelacionado `xml:"DoctoRelacionado"`
+}
+
+type DoctoRelacionado struct {
+	IdDocumento      string  `xml:"IdDocumento,attr"`
+	Serie            string  `xml:"Serie,attr"`
+	Folio            string  `xml:"Folio,attr"`
+	MonedaDR         string  `xml:"MonedaDR,attr"`
+	EquivalenciaDR   float64 `xml:"EquivalenciaDR,attr"`
+	NumParcialidad   int     `xml:"NumParcialidad,attr"`
+	ImpSaldoAnt      float64 `xml:"ImpSaldoAnt,attr"`
+	ImpPagado        float64 `xml:"ImpPagado,attr"`
+	ImpSaldoInsoluto float64 `xml:"ImpSaldoInsoluto,attr"`
+	ObjetoImpDR      string  `xml:"ObjetoImpDR,attr"`
+}
+
+// CFDI es un comprobante timbrado almacenado en una empresa; UserID es quien
+// lo cargó
+type CFDI struct {
+	ID              int       `json:"id"`
+	EmpresaID       int       `json:"empresa_id"`
+	UserID          int       `json:"user_id"`
+	UUID            string    `json:"uuid"`
+	Version         string    `json:"version"`
+	TipoComprobante string    `json:"tipo_comprobante"`
+	Serie           string    `json:"serie,omitempty"`
+	Folio           string    `json:"folio,omitempty"`
+	Fecha           time.Time `json:"fecha"`
+	EmisorRFC       string    `json:"emisor_rfc"`
+	EmisorNombre    string    `json:"emisor_nombre,omitempty"`
+	ReceptorRFC     string    `json:"receptor_rfc"`
+	ReceptorNombre  string    `json:"receptor_nombre,omitempty"`
+	UsoCFDI         string    `json:"uso_cfdi,omitempty"`
+	MetodoPago      string    `json:"metodo_pago,omitempty"`
+	FormaPago       string    `json:"forma_pago,omitempty"`
+	Moneda          string    `json:"moneda"`
+	TipoCambio      float64   `json:"tipo_cambio,omitempty"`
+	SubTotal        float64   `json:"subtotal"`
+	Total           float64   `json:"total"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// PagoAplicado es un DoctoRelacionado de un REP ligado al CFDI que paga
+type PagoAplicado struct {
+	ID               int       `json:"id"`
+	PagoUUID         string    `json:"pago_uuid"`
+	DoctoUUID        string    `json:"docto_uuid"`
+	DoctoCFDIID      *int      `json:"docto_cfdi_id,omitempty"`
+	FechaPago        time.Time `json:"fecha_pago"`
+	FormaPago        string    `json:"forma_pago,omitempty"`
+	MonedaDR         string    `json:"moneda_dr,omitempty"`
+	EquivalenciaDR   float64   `json:"equivalencia_dr,omitempty"`
+	NumParcialidad   int       `json:"num_parcialidad"`
+	ImpSaldoAnt      float64   `json:"imp_saldo_ant"`
+	ImpPagado        float64   `json:"imp_pagado"`
+	ImpSaldoInsoluto float64   `json:"imp_saldo_insoluto"`
+}
+
+// SaldoPPD resume los pagos recibidos de una factura PPD
+type SaldoPPD struct {
+	CFDI          CFDI           `json:"cfdi"`
+	Pagado        float64        `json:"pagado"`
+	Saldo         float64        `json:"saldo"`
+	Parcialidades int            `json:"parcialidades"`
+	UltimoPago    *time.Time     `json:"ultimo_pago,omitempty"`
+	SinREP        bool           `json:"sin_rep"`
+	Pagos         []PagoAplicado `json:"pagos,omitempty"`
+}
+
+// FiltroCFDI filtra las consultas de comprobantes
+type FiltroCFDI struct {
+	Tipo  string
+	RFC   string
+	Desde *time.Time
+	Hasta *time.Time
+}
+
+// ResultadoIngesta informa qué pasó con cada archivo recibido
+type ResultadoIngesta struct {
+	Archivo   string `json:"archivo,omitempty"`
+	UUID      string `json:"uuid,omitempty"`
+	Tipo      string `json:"tipo,omitempty"`
+	Duplicado bool   `json:"duplicado,omitempty"`
+	Pagos     int    `json:"pagos,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
@@ -0,0 +1,64 @@
+// internal/modules/cfdi/parser.go
+package cfdi
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrXMLInvalido = errors.New("XML de CFDI inválido")
+	ErrSinTimbre   = errors.New("el CFDI no está timbrado (sin TimbreFiscalDigital)")
+	ErrSinPagos    = errors.New("el CFDI de tipo P no contiene el complemento de pagos")
+	ErrFecha       = errors.New("fecha del CFDI inválida")
+)
+
+// formatoFechaCFDI es el formato ISO 8601 sin zona horaria que usa el Anexo 20
+const formatoFechaCFDI = "2006-01-02T15:04:05"
+
+// Parse lee un CFDI timbrado y valida los datos mínimos para almacenarlo
+func Parse(data []byte) (*Comprobante, error) {
+	var comp Comprobante
+	if err := xml.Unmarshal(data, &comp); err != nil {
+		return nil, ErrXMLInvalido
+	}
+
+	if comp.Version == "" || comp.Emisor.Rfc == "" || comp.Receptor.Rfc == "" {
+		return nil, ErrXMLInvalido
+	}
+	if comp.Complemento.TimbreFiscalDigital == nil || comp.Complemento.TimbreFiscalDigital.UUID == "" {
+		return nil, ErrSinTimbre
+	}
+	if comp.TipoDeComprobante == TipoPago && comp.Complemento.Pagos == nil {
+		return nil, ErrSinPagos
+	}
+	if _, err := ParseFecha(comp.Fecha); err != nil {
+		return nil, err
+	}
+
+	return &comp, nil
+}
+
+// UUID devuelve el folio fiscal en mayúsculas
+func (c *Comprobante) UUID() string {
+	if c.Complemento.TimbreFiscalDigital == nil {
+		return ""
+	}
+	return NormalizarUUID(c.Complemento.TimbreFiscalDigital.UUID)
+}
+
+// NormalizarUUID unifica el formato para comparar folios fiscales
+func NormalizarUUID(uuid string) string {
+	return strings.ToUpper(strings.TrimSpace(uuid))
+}
+
+// ParseFecha interpreta las fechas del CFDI y de los complementos
+func ParseFecha(s string) (time.Time, error) {
+	t, err := time.Parse(formatoFechaCFDI, strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, ErrFecha
+	}
+	return t, nil
+}
@@ -0,0 +1,260 @@
+// internal/modules/cfdi/repository.go
+package cfdi
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const columnasCFDI = `id, empresa_id, user_id, uuid, version, tipo_comprobante, serie, folio, fecha,
+        emisor_rfc, emisor_nombre, receptor_rfc, receptor_nombre, uso_cfdi,
+        metodo_pago, forma_pago, moneda, tipo_cambio, subtotal, total, created_at`
+
+// GuardarCFDI guarda el comprobante y, si es un REP, sus documentos relacionados.
+// Devuelve duplicado=true si el UUID ya existía en la empresa.
+func (r *Repository) GuardarCFDI(c *CFDI, xmlData []byte, pagos []PagoAplicado) (id int, duplicado bool, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+        INSERT INTO cfdis (empresa_id, user_id, uuid, version, tipo_comprobante, serie, folio, fecha,
+            emisor_rfc, emisor_nombre, receptor_rfc, receptor_nombre, uso_cfdi,
+            metodo_pago, forma_pago, moneda, tipo_cambio, subtotal, total, xml)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+        ON CONFLICT (empresa_id, uuid) DO NOTHING
+        RETURNING id
+    `, c.EmpresaID, c.UserID, c.UUID, c.Version, c.TipoComprobante, c.Serie, c.Folio, c.Fecha,
+		c.EmisorRFC, c.EmisorNombre, c.ReceptorRFC, c.ReceptorNombre, c.UsoCFDI,
+		c.MetodoPago, c.FormaPago, c.Moneda, c.TipoCambio, c.SubTotal, c.Total, string(xmlData)).Scan(&id)
+	if err == sql.ErrNoRows {
+		err = tx.QueryRow(`SELECT id FROM cfdis WHERE empresa_id = $1 AND uuid = $2`, c.EmpresaID, c.UUID).Scan(&id)
+		return id, true, err
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	for _, p := range pagos {
+		_, err = tx.Exec(`
+            INSERT INTO cfdi_pagos_doctos (empresa_id, user_id, pago_cfdi_id, pago_uuid, docto_uuid, docto_cfdi_id,
+                fecha_pago, forma_pago, moneda_dr, equivalencia_dr, num_parcialidad,
+                imp_saldo_ant, imp_pagado, imp_saldo_insoluto)
+            VALUES ($1, $2, $3, $4, $5,
+                (SELECT id FROM cfdis WHERE empresa_id = $1 AND uuid = $5),
+                $6, $7, $8, $9, $10, $11, $12, $13)
+            ON CONFLICT (pago_cfdi_id, docto_uuid, num_parcialidad) DO NOTHING
+        `, c.EmpresaID, c.UserID, id, c.UUID, p.DoctoUUID, p.FechaPago, p.FormaPago, p.MonedaDR,
+			p.EquivalenciaDR, p.NumParcialidad, p.ImpSaldoAnt, p.ImpPagado, p.ImpSaldoInsoluto)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	// Ligar pagos recibidos antes que la factura que pagan
+	_, err = tx.Exec(`
+        UPDATE cfdi_pagos_doctos SET docto_cfdi_id = $1
+        WHERE empresa_id = $2 AND docto_uuid = $3 AND docto_cfdi_id IS NULL
+    `, id, c.EmpresaID, c.UUID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return id, false, tx.Commit()
+}
+
+func (r *Repository) GetCFDIs(empresaID int, f FiltroCFDI) ([]CFDI, error) {
+	rows, err := r.db.Query(`
+        SELECT `+columnasCFDI+`
+        FROM cfdis
+        WHERE empresa_id = $1
+          AND ($2 = '' OR tipo_comprobante = $2)
+          AND ($3 = '' OR emisor_rfc = $3 OR receptor_rfc = $3)
+          AND ($4::timestamp IS NULL OR fecha >= $4)
+          AND ($5::timestamp IS NULL OR fecha < $5)
+        ORDER BY fecha DESC
+    `, empresaID, f.Tipo, f.RFC, f.Desde, f.Hasta)
+	if err != nil {
+		return nil, err
+	}
+	return scanCFDIs(rows)
+}
+
+func (r *Repository) GetCFDIByUUID(empresaID int, uuid string) (*CFDI, error) {
+	row := r.db.QueryRow(`
+        SELECT `+columnasCFDI+`
+        FROM cfdis WHERE empresa_id = $1 AND uuid = $2
+    `, empresaID, uuid)
+	return scanCFDI(row)
+}
+
+func (r *Repository) GetXML(empresaID int, uuid string) ([]byte, error) {
+	var data string
+	err := r.db.QueryRow(`SELECT xml FROM cfdis WHERE empresa_id = $1 AND uuid = $2`, empresaID, uuid).Scan(&data)
+	return []byte(data), err
+}
+
+// GetPagosDeDocumento devuelve las parcialidades pagadas de una factura en orden
+func (r *Repository) GetPagosDeDocumento(empresaID int, doctoUUID string) ([]PagoAplicado, error) {
+	rows, err := r.db.Query(`
+        SELECT id, pago_uuid, docto_uuid, docto_cfdi_id, fecha_pago, forma_pago, moneda_dr,
+            equivalencia_dr, num_parcialidad, imp_saldo_ant, imp_pagado, imp_saldo_insoluto
+        FROM cfdi_pagos_doctos
+        WHERE empresa_id = $1 AND docto_uuid = $2
+        ORDER BY num_parcialidad, fecha_pago
+    `, empresaID, doctoUUID)
+	if err != nil {
+		return nil, err
+	}
+	return scanPagos(rows)
+}
+
+// GetSaldosPPD calcula, para las facturas de ingreso PPD, lo pagado según sus REP.
+// El saldo es el ImpSaldoInsoluto de la última parcialidad o el total si no hay pagos.
+func (r *Repository) GetSaldosPPD(empresaID int, f FiltroCFDI) ([]SaldoPPD, error) {
+	rows, err := r.db.Query(`
+        SELECT c.id, c.empresa_id, c.user_id, c.uuid, c.version, c.tipo_comprobante, c.serie, c.folio, c.fecha,
+            c.emisor_rfc, c.emisor_nombre, c.receptor_rfc, c.receptor_nombre, c.uso_cfdi,
+            c.metodo_pago, c.forma_pago, c.moneda, c.tipo_cambio, c.subtotal, c.total, c.created_at,
+            COALESCE(SUM(p.imp_pagado), 0),
+            COALESCE((
+                SELECT u.imp_saldo_insoluto FROM cfdi_pagos_doctos u
+                WHERE u.empresa_id = c.empresa_id AND u.docto_uuid = c.uuid
+                ORDER BY u.num_parcialidad DESC, u.fecha_pago DESC LIMIT 1
+            ), c.total),
+            COUNT(p.id),
+            MAX(p.fecha_pago)
+        FROM cfdis c
+        LEFT JOIN cfdi_pagos_doctos p ON p.empresa_id = c.empresa_id AND p.docto_uuid = c.uuid
+        WHERE c.empresa_id = $1 AND c.tipo_comprobante = 'I' AND c.metodo_pago = 'PPD'
+          AND ($2 = '' OR c.emisor_rfc = $2 OR c.receptor_rfc = $2)
+          AND ($3::timestamp IS NULL OR c.fecha >= $3)
+          AND ($4::timestamp IS NULL OR c.fecha < $4)
+        GROUP BY c.id
+        ORDER BY c.fecha
+    `, empresaID, f.RFC, f.Desde, f.Hasta)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var saldos []SaldoPPD
+	for rows.Next() {
+		var s SaldoPPD
+		var ultimoPago sql.NullTime
+
+		dest := append(camposCFDI(&s.CFDI), &s.Pagado, &s.Saldo, &s.Parcialidades, &ultimoPago)
+		if err := rows.Scan(dest...); err != nil {
+			continue
+		}
+
+		if ultimoPago.Valid {
+			s.UltimoPago = &ultimoPago.Time
+		}
+		s.SinREP = s.Parcialidades == 0
+		saldos = append(saldos, s)
+	}
+
+	return saldos, nil
+}
+
+// GetRFCEmpresa devuelve el RFC de la empresa dueña de los comprobantes
+func (r *Repository) GetRFCEmpresa(empresaID int) (string, error) {
+	var rfc string
+	err := r.db.QueryRow(`SELECT rfc FROM empresas WHERE id = $1`, empresaID).Scan(&rfc)
+	return rfc, err
+}
+
+// GetPagosRecibidos devuelve las parcialidades pagadas entre desde y hasta
+// (exclusivo) de facturas cuyo receptor es el RFC indicado
+func (r *Repository) GetPagosRecibidos(empresaID int, receptorRFC string, desde, hasta time.Time) ([]PagoAplicado, error) {
+	rows, err := r.db.Query(`
+        SELECT p.id, p.pago_uuid, p.docto_uuid, p.docto_cfdi_id, p.fecha_pago, p.forma_pago, p.moneda_dr,
+            p.equivalencia_dr, p.num_parcialidad, p.imp_saldo_ant, p.imp_pagado, p.imp_saldo_insoluto
+        FROM cfdi_pagos_doctos p
+        JOIN cfdis c ON c.id = p.docto_cfdi_id
+        WHERE p.empresa_id = $1 AND c.receptor_rfc = $2
+          AND p.fecha_pago >= $3 AND p.fecha_pago < $4
+        ORDER BY p.fecha_pago, p.docto_uuid, p.num_parcialidad
+    `, empresaID, receptorRFC, desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+	return scanPagos(rows)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// camposCFDI devuelve los destinos de Scan en el orden de columnasCFDI
+func camposCFDI(c *CFDI) []interface{} {
+	return []interface{}{
+		&c.ID, &c.EmpresaID, &c.UserID, &c.UUID, &c.Version, &c.TipoComprobante, &c.Serie, &c.Folio, &c.Fecha,
+		&c.EmisorRFC, &c.EmisorNombre, &c.ReceptorRFC, &c.ReceptorNombre, &c.UsoCFDI,
+		&c.MetodoPago, &c.FormaPago, &c.Moneda, &c.TipoCambio, &c.SubTotal, &c.Total, &c.CreatedAt,
+	}
+}
+
+func scanCFDI(row rowScanner) (*CFDI, error) {
+	var c CFDI
+	if err := row.Scan(camposCFDI(&c)...); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func scanCFDIs(rows *sql.Rows) ([]CFDI, error) {
+	defer rows.Close()
+
+	var cfdis []CFDI
+	for rows.Next() {
+		c, err := scanCFDI(rows)
+		if err != nil {
+			continue
+		}
+		cfdis = append(cfdis, *c)
+	}
+
+	return cfdis, nil
+}
+
+func scanPagos(rows *sql.Rows) ([]PagoAplicado, error) {
+	defer rows.Close()
+
+	var pagos []PagoAplicado
+	for rows.Next() {
+		var p PagoAplicado
+		var doctoCFDIID sql.NullInt64
+		var formaPago, monedaDR sql.NullString
+		var equivalencia sql.NullFloat64
+
+		err := rows.Scan(&p.ID, &p.PagoUUID, &p.DoctoUUID, &doctoCFDIID, &p.FechaPago, &formaPago, &monedaDR,
+			&equivalencia, &p.NumParcialidad, &p.ImpSaldoAnt, &p.ImpPagado, &p.ImpSaldoInsoluto)
+		if err != nil {
+			continue
+		}
+
+		if doctoCFDIID.Valid {
+			id := int(doctoCFDIID.Int64)
+			p.DoctoCFDIID = &id
+		}
+		p.FormaPago = formaPago.String
+		p.MonedaDR = monedaDR.String
+		p.EquivalenciaDR = equivalencia.Float64
+
+		pagos = append(pagos, p)
+	}
+
+	return pagos, nil
+}
@@ -0,0 +1,200 @@
+// internal/modules/cfdi/service.go
+package cfdi
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jhvc/backend/internal/validacion"
+)
+
+var (
+	ErrCFDINoEncontrado = errors.New("CFDI no encontrado")
+	ErrCFDIAjeno        = errors.New("el RFC de la empresa no es emisor ni receptor del CFDI")
+)
+
+// Service maneja el almacenamiento de CFDIs y el seguimiento de pagos PPD
+type Service struct {
+	repo *Repository
+}
+
+// NewService crea una nueva instancia del servicio
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Ingerir valida y guarda un CFDI timbrado. Si es un REP, cada DoctoRelacionado
+// se liga a la factura original por su UUID. La empresa debe ser emisor o
+// receptor del comprobante.
+func (s *Service) Ingerir(empresaID, userID int, data []byte) (*ResultadoIngesta, error) {
+	comp, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	rfc, err := s.repo.GetRFCEmpresa(empresaID)
+	if err != nil {
+		return nil, err
+	}
+
+	fecha, _ := ParseFecha(comp.Fecha)
+	c := &CFDI{
+		EmpresaID:       empresaID,
+		UserID:          userID,
+		UUID:            comp.UUID(),
+		Version:         comp.Version,
+		TipoComprobante: comp.TipoDeComprobante,
+		Serie:           comp.Serie,
+		Folio:           comp.Folio,
+		Fecha:           fecha,
+		EmisorRFC:       validacion.NormalizarRFC(comp.Emisor.Rfc),
+		EmisorNombre:    comp.Emisor.Nombre,
+		ReceptorRFC:     validacion.NormalizarRFC(comp.Receptor.Rfc),
+		ReceptorNombre:  comp.Receptor.Nombre,
+		UsoCFDI:         comp.Receptor.UsoCFDI,
+		MetodoPago:      comp.MetodoPago,
+		FormaPago:       comp.FormaPago,
+		Moneda:          comp.Moneda,
+		TipoCambio:      comp.TipoCambio,
+		SubTotal:        comp.SubTotal,
+		Total:           comp.Total,
+	}
+
+	if c.EmisorRFC != rfc && c.ReceptorRFC != rfc {
+		return nil, ErrCFDIAjeno
+	}
+
+	pagos, err := pagosAplicados(comp)
+	if err != nil {
+		return nil, err
+	}
+
+	_, duplicado, err := s.repo.GuardarCFDI(c, data, pagos)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResultadoIngesta{
+		UUID:      c.UUID,
+		Tipo:      c.TipoComprobante,
+		Duplicado: duplicado,
+		Pagos:     len(pagos),
+	}, nil
+}
+
+// pagosAplicados aplana los Pago/DoctoRelacionado del complemento de pagos
+func pagosAplicados(comp *Comprobante) ([]PagoAplicado, error) {
+	if comp.Complemento.Pagos == nil {
+		return nil, nil
+	}
+
+	var pagos []PagoAplicado
+	for _, p := range comp.Complemento.Pagos.Pago {
+		fechaPago, err := ParseFecha(p.FechaPago)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dr := range p.DoctoRelacionado {
+			pagos = append(pagos, PagoAplicado{
+				PagoUUID:         comp.UUID(),
+				DoctoUUID:        NormalizarUUID(dr.IdDocumento),
+				FechaPago:        fechaPago,
+				FormaPago:        p.FormaDePagoP,
+				MonedaDR:         dr.MonedaDR,
+				EquivalenciaDR:   dr.EquivalenciaDR,
+				NumParcialidad:   dr.NumParcialidad,
+				ImpSaldoAnt:      dr.ImpSaldoAnt,
+				ImpPagado:        dr.ImpPagado,
+				ImpSaldoInsoluto: dr.ImpSaldoInsoluto,
+			})
+		}
+	}
+	return pagos, nil
+}
+
+func (s *Service) GetCFDIs(empresaID int, f FiltroCFDI) ([]CFDI, error) {
+	f.RFC = validacion.NormalizarRFC(f.RFC)
+	f.Tipo = strings.ToUpper(strings.TrimSpace(f.Tipo))
+	return s.repo.GetCFDIs(empresaID, f)
+}
+
+func (s *Service) GetCFDI(empresaID int, uuid string) (*CFDI, error) {
+	c, err := s.repo.GetCFDIByUUID(empresaID, NormalizarUUID(uuid))
+	if err == sql.ErrNoRows {
+		return nil, ErrCFDINoEncontrado
+	}
+	return c, err
+}
+
+func (s *Service) GetXML(empresaID int, uuid string) ([]byte, error) {
+	data, err := s.repo.GetXML(empresaID, NormalizarUUID(uuid))
+	if err == sql.ErrNoRows {
+		return nil, ErrCFDINoEncontrado
+	}
+	return data, err
+}
+
+// GetHistorialPagos devuelve las parcialidades de una factura y su saldo insoluto
+func (s *Service) GetHistorialPagos(empresaID int, uuid string) (*SaldoPPD, error) {
+	c, err := s.GetCFDI(empresaID, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	pagos, err := s.repo.GetPagosDeDocumento(empresaID, c.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	saldo := &SaldoPPD{CFDI: *c, Saldo: c.Total, Pagos: pagos, Parcialidades: len(pagos)}
+	for i, p := range pagos {
+		saldo.Pagado += p.ImpPagado
+		if saldo.UltimoPago == nil || p.FechaPago.After(*saldo.UltimoPago) {
+			saldo.UltimoPago = &pagos[i].FechaPago
+		}
+	}
+	if len(pagos) > 0 {
+		saldo.Saldo = pagos[len(pagos)-1].ImpSaldoInsoluto
+	}
+	saldo.Pagado = redondear(saldo.Pagado)
+	saldo.SinREP = c.MetodoPago == MetodoPPD && len(pagos) == 0
+
+	return saldo, nil
+}
+
+// GetPagosRecibidos devuelve los pagos hechos en el rango [desde, hasta) a
+// facturas PPD recibidas por el RFC
+func (s *Service) GetPagosRecibidos(empresaID int, rfc string, desde, hasta time.Time) ([]PagoAplicado, error) {
+	return s.repo.GetPagosRecibidos(empresaID, validacion.NormalizarRFC(rfc), desde, hasta)
+}
+
+// GetPendientesPPD lista las facturas PPD con saldo insoluto; con soloSinREP
+// se limita a las que aún no tienen ningún complemento de pago
+func (s *Service) GetPendientesPPD(empresaID int, f FiltroCFDI, soloSinREP bool) ([]SaldoPPD, error) {
+	f.RFC = validacion.NormalizarRFC(f.RFC)
+	saldos, err := s.repo.GetSaldosPPD(empresaID, f)
+	if err != nil {
+		return nil, err
+	}
+
+	var pendientes []SaldoPPD
+	for _, saldo := range saldos {
+		if soloSinREP && !saldo.SinREP {
+			continue
+		}
+		if saldo.Saldo <= 0.005 {
+			continue
+		}
+		saldo.Pagado = redondear(saldo.Pagado)
+		pendientes = append(pendientes, saldo)
+	}
+	return pendientes, nil
+}
+
+func redondear(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,86 @@
+// internal/modules/contabilidad/balanza.go
+package contabilidad
+
+import (
+	"time"
+)
+
+// Balanza calcula la balanza de comprobación de un mes con las pólizas registradas
+func (s *Service) Balanza(empresaID, ejercicio, mes int) ([]SaldoCuenta, error) {
+	if err := validarPeriodo(ejercicio, mes); err != nil {
+		return nil, err
+	}
+	desde, hasta := limitesMes(ejercicio, mes)
+	return s.BalanzaRango(empresaID, desde, hasta)
+}
+
+// BalanzaRango calcula saldos iniciales, cargos, abonos y saldos finales de
+// todas las cuentas entre dos fechas. Los importes de las cuentas de último
+// nivel se acumulan en sus cuentas padre antes de aplicar la naturaleza de
+// cada una, así una cuenta complementaria (p. ej. depreciación acumulada)
+// resta correctamente de su cuenta de mayor.
+func (s *Service) BalanzaRango(empresaID int, desde, hasta time.Time) ([]SaldoCuenta, error) {
+	cuentas, err := s.repo.GetCuentas(empresaID)
+	if err != nil {
+		return nil, err
+	}
+	acumulados, err := s.repo.GetAcumulados(empresaID, desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+
+	padres := make(map[int]*int, len(cuentas))
+	for _, c := range cuentas {
+		padres[c.ID] = c.PadreID
+	}
+
+	// Saldos deudores (cargos - abonos) acumulados hacia arriba en el árbol
+	totales := make(map[int]*acumulado, len(cuentas))
+	for id, a := range acumulados {
+		for actual := &id; actual != nil; actual = padres[*actual] {
+			t, ok := totales[*actual]
+			if !ok {
+				t = &acumulado{}
+				totales[*actual] = t
+			}
+			t.cargosPrevios += a.cargosPrevios
+			t.abonosPrevios += a.abonosPrevios
+			t.cargos += a.cargos
+			t.abonos += a.abonos
+		}
+	}
+
+	balanza := make([]SaldoCuenta, 0, len(cuentas))
+	for _, c := range cuentas {
+		saldo := SaldoCuenta{
+			CuentaID:        c.ID,
+			Codigo:          c.Codigo,
+			Nombre:          c.Nombre,
+			Naturaleza:      c.Naturaleza,
+			Nivel:           c.Nivel,
+			PadreID:         c.PadreID,
+			CodigoAgrupador: c.CodigoAgrupador,
+			EsHoja:          c.EsHoja,
+			Activa:          c.IsActive,
+		}
+		if t, ok := totales[c.ID]; ok {
+			signo := 1.0
+			if c.Naturaleza == NaturalezaAcreedora {
+				signo = -1
+			}
+			saldo.SaldoInicial = redondear(signo * (t.cargosPrevios - t.abonosPrevios))
+			saldo.Cargos = redondear(t.cargos)
+			saldo.Abonos = redondear(t.abonos)
+			saldo.SaldoFinal = redondear(saldo.SaldoInicial + signo*(t.cargos-t.abonos))
+		}
+		balanza = append(balanza, saldo)
+	}
+
+	return balanza, nil
+}
+
+// limitesMes devuelve el primer y el último día del mes
+func limitesMes(ejercicio, mes int) (time.Time, time.Time) {
+	desde := time.Date(ejercicio, time.Month(mes), 1, 0, 0, 0, 0, time.UTC)
+	return desde, desde.AddDate(0, 1, -1)
+}
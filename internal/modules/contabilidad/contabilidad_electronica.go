@@ -0,0 +1,370 @@
+// internal/modules/contabilidad/contabilidad_electronica.go
+package contabilidad
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSinCatalogo = errors.New("la empresa no tiene catálogo de cuentas")
+	ErrSinPolizas  = errors.New("el periodo no tiene pólizas registradas; el XML de pólizas requiere al menos una")
+	ErrFechaModBal = errors.New("la balanza complementaria requiere fecha_mod_bal (AAAA-MM-DD)")
+	ErrNumOrden    = errors.New("tipo_solicitud AF o FC requiere num_orden")
+	ErrNumTramite  = errors.New("tipo_solicitud DE o CO requiere num_tramite")
+)
+
+// letrasTipoPoliza identifican el tipo en NumUnIdenPol (I-1, E-3, D-12)
+var letrasTipoPoliza = map[string]string{
+	TipoIngreso: "I",
+	TipoEgreso:  "E",
+	TipoDiario:  "D",
+}
+
+// ArchivoXML es un documento de contabilidad electrónica ya validado
+type ArchivoXML struct {
+	Nombre    string
+	Contenido []byte
+}
+
+// PaqueteContabilidad son los XML de un periodo y las advertencias que no
+// impiden entregarlos (periodo abierto, CFDI no encontrados)
+type PaqueteContabilidad struct {
+	RFC          string
+	Ejercicio    int
+	Mes          int
+	Archivos     []ArchivoXML
+	Advertencias []string
+}
+
+// GenerarContabilidadElectronica arma catálogo, balanza y, si hay tipo de
+// solicitud, pólizas del periodo. Cada documento pasa por la revisión parcial
+// del esquema 1.3 (ver esquemas.go) antes de incluirse.
+func (s *Service) GenerarContabilidadElectronica(empresaID, ejercicio, mes int, op OpcionesContabilidadElectronica) (*PaqueteContabilidad, error) {
+	if err := validarPeriodo(ejercicio, mes); err != nil {
+		return nil, err
+	}
+	if err := validarOpciones(&op); err != nil {
+		return nil, err
+	}
+
+	rfc, err := s.repo.GetRFCEmpresa(empresaID)
+	if err != nil {
+		return nil, err
+	}
+
+	paquete := &PaqueteContabilidad{RFC: rfc, Ejercicio: ejercicio, Mes: mes}
+	if cerrado, err := s.periodoCerrado(empresaID, ejercicio, mes); err != nil {
+		return nil, err
+	} else if !cerrado {
+		paquete.Advertencias = append(paquete.Advertencias, "el periodo no está cerrado; los saldos aún pueden cambiar")
+	}
+
+	balanza, err := s.Balanza(empresaID, ejercicio, mes)
+	if err != nil {
+		return nil, err
+	}
+	if len(balanza) == 0 {
+		return nil, ErrSinCatalogo
+	}
+	prefijo := fmt.Sprintf("%s%04d%02d", rfc, ejercicio, mes)
+	mesXML := fmt.Sprintf("%02d", mes)
+
+	catalogo := construirCatalogoXML(rfc, mesXML, ejercicio, balanza)
+	if err := s.agregarXML(paquete, prefijo+"CT.xml", catalogo, revisarCatalogoXML(catalogo)); err != nil {
+		return nil, err
+	}
+
+	bal := construirBalanzaXML(rfc, mesXML, ejercicio, op, balanza)
+	if err := s.agregarXML(paquete, prefijo+"B"+op.TipoEnvio+".xml", bal, revisarBalanzaXML(bal)); err != nil {
+		return nil, err
+	}
+
+	if op.TipoSolicitud != "" {
+		polizas, err := s.construirPolizasXML(empresaID, rfc, ejercicio, mes, op, paquete)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.agregarXML(paquete, prefijo+"PL.xml", polizas, revisarPolizasXML(polizas)); err != nil {
+			return nil, err
+		}
+	}
+
+	return paquete, nil
+}
+
+func (s *Service) agregarXML(p *PaqueteContabilidad, nombre string, doc interface{}, errValidacion error) error {
+	if errValidacion != nil {
+		return errValidacion
+	}
+	contenido, err := serializarXML(doc)
+	if err != nil {
+		return err
+	}
+	p.Archivos = append(p.Archivos, ArchivoXML{Nombre: nombre, Contenido: contenido})
+	return nil
+}
+
+func (s *Service) periodoCerrado(empresaID, ejercicio, mes int) (bool, error) {
+	periodos, err := s.repo.GetPeriodos(empresaID, ejercicio)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range periodos {
+		if p.Mes == mes {
+			return p.Cerrado, nil
+		}
+	}
+	return false, nil
+}
+
+// validarOpciones aplica los valores por omisión y las combinaciones que
+// exige el SAT antes de consultar la contabilidad
+func validarOpciones(op *OpcionesContabilidadElectronica) error {
+	op.TipoEnvio = strings.ToUpper(strings.TrimSpace(op.TipoEnvio))
+	if op.TipoEnvio == "" {
+		op.TipoEnvio = EnvioNormal
+	}
+	op.TipoSolicitud = strings.ToUpper(strings.TrimSpace(op.TipoSolicitud))
+	op.NumOrden = strings.ToUpper(strings.TrimSpace(op.NumOrden))
+	op.NumTramite = strings.ToUpper(strings.TrimSpace(op.NumTramite))
+
+	if op.TipoEnvio == EnvioComplementario {
+		if _, err := time.Parse("2006-01-02", strings.TrimSpace(op.FechaModBal)); err != nil {
+			return ErrFechaModBal
+		}
+	} else {
+		op.FechaModBal = ""
+	}
+
+	switch op.TipoSolicitud {
+	case SolicitudActoFiscalizacion, SolicitudFiscalizacionCompulsa:
+		if op.NumOrden == "" {
+			return ErrNumOrden
+		}
+		op.NumTramite = ""
+	case SolicitudDevolucion, SolicitudCompensacion:
+		if op.NumTramite == "" {
+			return ErrNumTramite
+		}
+		op.NumOrden = ""
+	}
+	return nil
+}
+
+// incluirCuenta deja fuera las cuentas inactivas que no tienen saldo ni
+// movimientos en el periodo, para que catálogo y balanza coincidan
+func incluirCuenta(saldo SaldoCuenta) bool {
+	return saldo.Activa || saldo.SaldoInicial != 0 || saldo.Cargos != 0 || saldo.Abonos != 0 || saldo.SaldoFinal != 0
+}
+
+func construirCatalogoXML(rfc, mes string, ejercicio int, balanza []SaldoCuenta) *CatalogoXML {
+	doc := &CatalogoXML{
+		Xmlns:          NamespaceCatalogo,
+		XmlnsXSI:       NamespaceXSI,
+		SchemaLocation: SchemaLocationCatalogo,
+		Version:        VersionContabilidadE,
+		RFC:            rfc,
+		Mes:            mes,
+		Anio:           ejercicio,
+	}
+
+	codigos := make(map[int]string, len(balanza))
+	for _, c := range balanza {
+		codigos[c.CuentaID] = c.Codigo
+	}
+
+	for _, c := range balanza {
+		if !incluirCuenta(c) {
+			continue
+		}
+		cta := CuentaCatalogo{
+			CodAgrup: c.CodigoAgrupador,
+			NumCta:   c.Codigo,
+			Desc:     recortar(c.Nombre, 400),
+			Nivel:    c.Nivel,
+			Natur:    c.Naturaleza,
+		}
+		if c.PadreID != nil {
+			cta.SubCtaDe = codigos[*c.PadreID]
+		}
+		doc.Ctas = append(doc.Ctas, cta)
+	}
+
+	return doc
+}
+
+func construirBalanzaXML(rfc, mes string, ejercicio int, op OpcionesContabilidadElectronica, balanza []SaldoCuenta) *BalanzaXML {
+	doc := &BalanzaXML{
+		Xmlns:          NamespaceBalanza,
+		XmlnsXSI:       NamespaceXSI,
+		SchemaLocation: SchemaLocationBalanza,
+		Version:        VersionContabilidadE,
+		RFC:            rfc,
+		Mes:            mes,
+		Anio:           ejercicio,
+		TipoEnvio:      op.TipoEnvio,
+		FechaModBal:    strings.TrimSpace(op.FechaModBal),
+	}
+
+	for _, saldo := range balanza {
+		if !incluirCuenta(saldo) {
+			continue
+		}
+		doc.Ctas = append(doc.Ctas, CuentaBalanza{
+			NumCta:   saldo.Codigo,
+			SaldoIni: formatearImporte(saldo.SaldoInicial),
+			Debe:     formatearImporte(saldo.Cargos),
+			Haber:    formatearImporte(saldo.Abonos),
+			SaldoFin: formatearImporte(saldo.SaldoFinal),
+		})
+	}
+
+	return doc
+}
+
+// construirPolizasXML incluye las pólizas registradas del mes. Los
+// movimientos con UUID se relacionan con el CFDI guardado en el repositorio;
+// si el CFDI no está, la transacción se envía sin CompNal y se advierte.
+func (s *Service) construirPolizasXML(empresaID int, rfc string, ejercicio, mes int, op OpcionesContabilidadElectronica, paquete *PaqueteContabilidad) (*PolizasXML, error) {
+	doc := &PolizasXML{
+		Xmlns:          NamespacePolizas,
+		XmlnsXSI:       NamespaceXSI,
+		SchemaLocation: SchemaLocationPolizas,
+		Version:        VersionContabilidadE,
+		RFC:            rfc,
+		Mes:            fmt.Sprintf("%02d", mes),
+		Anio:           ejercicio,
+		TipoSolicitud:  op.TipoSolicitud,
+		NumOrden:       op.NumOrden,
+		NumTramite:     op.NumTramite,
+	}
+
+	polizas, err := s.repo.GetPolizas(empresaID, FiltroPolizas{Ejercicio: ejercicio, Mes: mes, Estado: EstadoRegistrada})
+	if err != nil {
+		return nil, err
+	}
+	if len(polizas) == 0 {
+		return nil, ErrSinPolizas
+	}
+
+	comprobantes := make(map[string]*comprobanteNacional)
+	for _, p := range polizas {
+		movimientos, err := s.repo.GetMovimientos(p.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		pol := PolizaSAT{
+			NumUnIdenPol: fmt.Sprintf("%s-%d", letrasTipoPoliza[p.Tipo], p.Numero),
+			Fecha:        p.Fecha.Format("2006-01-02"),
+			Concepto:     recortar(p.Concepto, 300),
+		}
+
+		for _, m := range movimientos {
+			t := TransaccionSAT{
+				NumCta:   m.CuentaCodigo,
+				DesCta:   recortar(m.CuentaNombre, 100),
+				Concepto: recortar(m.Concepto, 200),
+				Debe:     formatearImporte(m.Cargo),
+				Haber:    formatearImporte(m.Abono),
+			}
+
+			if m.UUID != "" {
+				comp, ok := comprobantes[m.UUID]
+				if !ok {
+					comp, err = s.repo.GetComprobante(empresaID, m.UUID)
+					if err != nil && err != sql.ErrNoRows {
+						return nil, err
+					}
+					comprobantes[m.UUID] = comp
+				}
+				if comp == nil {
+					paquete.Advertencias = append(paquete.Advertencias,
+						fmt.Sprintf("póliza %s: el CFDI %s no está en el repositorio; se omitió CompNal", pol.NumUnIdenPol, m.UUID))
+				} else {
+					t.CompNal = append(t.CompNal, compNal(m.UUID, rfc, comp))
+				}
+			}
+
+			pol.Transacciones = append(pol.Transacciones, t)
+		}
+
+		doc.Polizas = append(doc.Polizas, pol)
+	}
+
+	return doc, nil
+}
+
+// compNal usa el RFC de la contraparte: el receptor si la empresa emitió el
+// CFDI, el emisor si lo recibió
+func compNal(uuid, rfc string, c *comprobanteNacional) CompNalSAT {
+	nodo := CompNalSAT{
+		UUID:       uuid,
+		RFC:        c.emisorRFC,
+		MontoTotal: formatearImporte(c.total),
+	}
+	if strings.EqualFold(c.emisorRFC, rfc) {
+		nodo.RFC = c.receptorRFC
+	}
+	if c.moneda != "" && c.moneda != "MXN" {
+		nodo.Moneda = c.moneda
+		nodo.TipCamb = strconv.FormatFloat(c.tipoCambio, 'f', 5, 64)
+	}
+	return nodo
+}
+
+// ZIP empaqueta los XML del periodo (y las advertencias, si las hay)
+func (p *PaqueteContabilidad) ZIP() ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	escribir := func(nombre string, contenido []byte) error {
+		w, err := zw.Create(nombre)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(contenido)
+		return err
+	}
+
+	for _, a := range p.Archivos {
+		if err := escribir(a.Nombre, a.Contenido); err != nil {
+			return nil, err
+		}
+	}
+	if len(p.Advertencias) > 0 {
+		if err := escribir("advertencias.txt", []byte(strings.Join(p.Advertencias, "\n")+"\n")); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// NombreZIP sigue la nomenclatura de los XML: RFC, año y mes
+func (p *PaqueteContabilidad) NombreZIP() string {
+	return fmt.Sprintf("%s%04d%02d.zip", p.RFC, p.Ejercicio, p.Mes)
+}
+
+func formatearImporte(v float64) string {
+	return strconv.FormatFloat(redondear(v), 'f', 2, 64)
+}
+
+// recortar limita el texto a la longitud máxima del atributo en el esquema
+func recortar(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > max {
+		return strings.TrimSpace(string(r[:max]))
+	}
+	return s
+}
@@ -0,0 +1,222 @@
+// internal/modules/contabilidad/esquemas.go
+package contabilidad
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Revisión parcial de los XSD 1.3 de contabilidad electrónica. La biblioteca
+// estándar no valida XSD, así que aquí sólo se reproducen los patrones y
+// longitudes de los atributos, los atributos condicionales y los nodos que el
+// esquema exige al menos una vez. El orden de los elementos no se revisa: lo
+// fijan las estructuras de xml_sat.go. Un documento que pasa la revisión
+// todavía puede ser rechazado por el validador del SAT.
+var (
+	patronRFC        = regexp.MustCompile(`^[A-ZÑ&]{3,4}[0-9]{2}[0-1][0-9][0-3][0-9][A-Z0-9]?[A-Z0-9]?[0-9A-Z]?$`)
+	patronImporte    = regexp.MustCompile(`^-?[0-9]{1,20}(\.[0-9]{1,2})?$`)
+	patronTipoCambio = regexp.MustCompile(`^[0-9]{1,14}(\.[0-9]{1,5})?$`)
+	patronUUID       = regexp.MustCompile(`^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-[a-fA-F0-9]{4}-[a-fA-F0-9]{4}-[a-fA-F0-9]{12}$`)
+	patronNumOrden   = regexp.MustCompile(`^[A-Z]{3}[0-9]{7}/[0-9]{2}$`)
+	patronNumTramite = regexp.MustCompile(`^[A-Z]{2}[0-9]{12}$`)
+	patronMoneda     = regexp.MustCompile(`^[A-Z]{3}$`)
+	patronMes        = regexp.MustCompile(`^(0[1-9]|1[0-2])$`)
+)
+
+// ErroresEsquema agrupa las restricciones revisadas del XSD que incumple un documento
+type ErroresEsquema []string
+
+func (e ErroresEsquema) Error() string {
+	return "XML no cumple las reglas revisadas del esquema 1.3: " + strings.Join(e, "; ")
+}
+
+// validador acumula errores con el nombre del documento como prefijo
+type validador struct {
+	documento string
+	errs      ErroresEsquema
+}
+
+func (v *validador) errorf(formato string, args ...interface{}) {
+	v.errs = append(v.errs, v.documento+"."+fmt.Sprintf(formato, args...))
+}
+
+func (v *validador) texto(campo, valor string, min, max int) {
+	if n := utf8.RuneCountInString(valor); n < min || n > max {
+		v.errorf("%s: longitud entre %d y %d", campo, min, max)
+	}
+}
+
+func (v *validador) patron(campo, valor string, re *regexp.Regexp) {
+	if !re.MatchString(valor) {
+		v.errorf("%s: valor %q con formato inválido", campo, valor)
+	}
+}
+
+func (v *validador) importe(campo, valor string, negativo bool) {
+	v.patron(campo, valor, patronImporte)
+	if !negativo && strings.HasPrefix(valor, "-") {
+		v.errorf("%s: no admite negativos", campo)
+	}
+}
+
+func (v *validador) fecha(campo, valor string) {
+	if _, err := time.Parse("2006-01-02", valor); err != nil {
+		v.errorf("%s: fecha inválida %q", campo, valor)
+	}
+}
+
+// encabezado valida los atributos comunes a los tres documentos
+func (v *validador) encabezado(version, rfc, mes string, anio int) {
+	if version != VersionContabilidadE {
+		v.errorf("Version: debe ser %s", VersionContabilidadE)
+	}
+	v.texto("RFC", rfc, 12, 13)
+	v.patron("RFC", rfc, patronRFC)
+	if !patronMes.MatchString(mes) {
+		v.errorf("Mes: debe ser 01 a 12")
+	}
+	if anio < 2015 || anio > 2099 {
+		v.errorf("Anio: debe estar entre 2015 y 2099")
+	}
+}
+
+func (v *validador) resultado() error {
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+func revisarCatalogoXML(c *CatalogoXML) error {
+	v := &validador{documento: "Catalogo"}
+	v.encabezado(c.Version, c.RFC, c.Mes, c.Anio)
+
+	if len(c.Ctas) == 0 {
+		v.errorf("Ctas: se requiere al menos una cuenta")
+	}
+	cuentas := make(map[string]bool, len(c.Ctas))
+	for _, cta := range c.Ctas {
+		cuentas[cta.NumCta] = true
+	}
+	for i, cta := range c.Ctas {
+		campo := fmt.Sprintf("Ctas[%d]", i)
+		v.texto(campo+".CodAgrup", cta.CodAgrup, 1, 12)
+		v.texto(campo+".NumCta", cta.NumCta, 1, 100)
+		v.texto(campo+".Desc", cta.Desc, 1, 400)
+		if cta.Nivel < 1 {
+			v.errorf("%s.Nivel: debe ser mayor a cero", campo)
+		}
+		if cta.Natur != NaturalezaDeudora && cta.Natur != NaturalezaAcreedora {
+			v.errorf("%s.Natur: debe ser D o A", campo)
+		}
+		if cta.SubCtaDe != "" {
+			v.texto(campo+".SubCtaDe", cta.SubCtaDe, 1, 100)
+			if !cuentas[cta.SubCtaDe] {
+				v.errorf("%s.SubCtaDe: la cuenta %s no está en el catálogo", campo, cta.SubCtaDe)
+			}
+		} else if cta.Nivel > 1 {
+			v.errorf("%s.SubCtaDe: requerida en cuentas de nivel %d", campo, cta.Nivel)
+		}
+	}
+
+	return v.resultado()
+}
+
+func revisarBalanzaXML(b *BalanzaXML) error {
+	v := &validador{documento: "Balanza"}
+	v.encabezado(b.Version, b.RFC, b.Mes, b.Anio)
+
+	switch b.TipoEnvio {
+	case EnvioNormal:
+		if b.FechaModBal != "" {
+			v.errorf("FechaModBal: sólo aplica a balanzas complementarias")
+		}
+	case EnvioComplementario:
+		if b.FechaModBal == "" {
+			v.errorf("FechaModBal: requerida en balanzas complementarias")
+		} else {
+			v.fecha("FechaModBal", b.FechaModBal)
+		}
+	default:
+		v.errorf("TipoEnvio: debe ser N o C")
+	}
+
+	if len(b.Ctas) == 0 {
+		v.errorf("Ctas: se requiere al menos una cuenta")
+	}
+	for i, cta := range b.Ctas {
+		campo := fmt.Sprintf("Ctas[%d]", i)
+		v.texto(campo+".NumCta", cta.NumCta, 1, 100)
+		v.importe(campo+".SaldoIni", cta.SaldoIni, true)
+		v.importe(campo+".Debe", cta.Debe, false)
+		v.importe(campo+".Haber", cta.Haber, false)
+		v.importe(campo+".SaldoFin", cta.SaldoFin, true)
+	}
+
+	return v.resultado()
+}
+
+func revisarPolizasXML(p *PolizasXML) error {
+	v := &validador{documento: "Polizas"}
+	v.encabezado(p.Version, p.RFC, p.Mes, p.Anio)
+
+	switch p.TipoSolicitud {
+	case SolicitudActoFiscalizacion, SolicitudFiscalizacionCompulsa:
+		if p.NumOrden == "" {
+			v.errorf("NumOrden: requerido con TipoSolicitud %s", p.TipoSolicitud)
+		}
+	case SolicitudDevolucion, SolicitudCompensacion:
+		if p.NumTramite == "" {
+			v.errorf("NumTramite: requerido con TipoSolicitud %s", p.TipoSolicitud)
+		}
+	default:
+		v.errorf("TipoSolicitud: debe ser AF, FC, DE o CO")
+	}
+	if p.NumOrden != "" {
+		v.patron("NumOrden", p.NumOrden, patronNumOrden)
+	}
+	if p.NumTramite != "" {
+		v.patron("NumTramite", p.NumTramite, patronNumTramite)
+	}
+
+	if len(p.Polizas) == 0 {
+		v.errorf("Poliza: se requiere al menos una póliza")
+	}
+	for i, pol := range p.Polizas {
+		campo := fmt.Sprintf("Poliza[%d]", i)
+		v.texto(campo+".NumUnIdenPol", pol.NumUnIdenPol, 1, 50)
+		v.fecha(campo+".Fecha", pol.Fecha)
+		v.texto(campo+".Concepto", pol.Concepto, 1, 300)
+		if len(pol.Transacciones) == 0 {
+			v.errorf("%s: se requiere al menos una transacción", campo)
+		}
+
+		for j, t := range pol.Transacciones {
+			campoT := fmt.Sprintf("%s.Transaccion[%d]", campo, j)
+			v.texto(campoT+".NumCta", t.NumCta, 1, 100)
+			v.texto(campoT+".DesCta", t.DesCta, 1, 100)
+			v.texto(campoT+".Concepto", t.Concepto, 1, 200)
+			v.importe(campoT+".Debe", t.Debe, false)
+			v.importe(campoT+".Haber", t.Haber, false)
+
+			for k, comp := range t.CompNal {
+				campoC := fmt.Sprintf("%s.CompNal[%d]", campoT, k)
+				v.patron(campoC+".UUID_CFDI", comp.UUID, patronUUID)
+				v.texto(campoC+".RFC", comp.RFC, 12, 13)
+				v.patron(campoC+".RFC", comp.RFC, patronRFC)
+				v.importe(campoC+".MontoTotal", comp.MontoTotal, false)
+				if comp.Moneda != "" {
+					v.patron(campoC+".Moneda", comp.Moneda, patronMoneda)
+				}
+				if comp.TipCamb != "" {
+					v.patron(campoC+".TipCamb", comp.TipCamb, patronTipoCambio)
+				}
+			}
+		}
+	}
+
+	return v.resultado()
+}
@@ -0,0 +1,631 @@
+// internal/modules/contabilidad/handler.go
+package contabilidad
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jhvc/backend/internal/exportar"
+	"github.com/jhvc/backend/internal/modules/cfdi"
+)
+
+// Handler maneja las peticiones HTTP de contabilidad. La empresa llega en el
+// contexto como "empresaID" (ver middleware.EmpresaMiddleware).
+type Handler struct {
+	service *Service
+}
+
+// NewHandler crea una nueva instancia del handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetCuentas lista el catálogo de cuentas; con ?arbol=true lo anida por nivel
+// @Router /empresas/{empresaId}/cuentas [get]
+func (h *Handler) GetCuentas(c *gin.Context) {
+	cuentas, err := h.service.GetCuentas(c.GetInt("empresaID"), c.Query("arbol") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    cuentas,
+	})
+}
+
+// GetCuenta devuelve una cuenta
+// @Router /empresas/{empresaId}/cuentas/{cuentaId} [get]
+func (h *Handler) GetCuenta(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("cuentaId"))
+
+	cuenta, err := h.service.GetCuenta(c.GetInt("empresaID"), id)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    cuenta,
+	})
+}
+
+// CrearCuenta agrega una cuenta o subcuenta al catálogo
+// @Router /empresas/{empresaId}/cuentas [post]
+func (h *Handler) CrearCuenta(c *gin.Context) {
+	var req CuentaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	cuenta, err := h.service.Crear(c.GetInt("empresaID"), req)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Cuenta creada",
+		"data":    cuenta,
+	})
+}
+
+// ActualizarCuenta modifica nombre, naturaleza, código agrupador o estado
+// @Router /empresas/{empresaId}/cuentas/{cuentaId} [put]
+func (h *Handler) ActualizarCuenta(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("cuentaId"))
+
+	var req ActualizarCuentaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	cuenta, err := h.service.Actualizar(c.GetInt("empresaID"), id, req)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Cuenta actualizada",
+		"data":    cuenta,
+	})
+}
+
+// EliminarCuenta borra una cuenta sin subcuentas
+// @Router /empresas/{empresaId}/cuentas/{cuentaId} [delete]
+func (h *Handler) EliminarCuenta(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("cuentaId"))
+
+	if err := h.service.Eliminar(c.GetInt("empresaID"), id); err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Cuenta eliminada",
+	})
+}
+
+// ImportarPlantilla carga el catálogo base en una empresa sin cuentas
+// @Router /empresas/{empresaId}/cuentas/plantilla [post]
+func (h *Handler) ImportarPlantilla(c *gin.Context) {
+	total, err := h.service.ImportarPlantilla(c.GetInt("empresaID"))
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Catálogo de cuentas importado",
+		"data":    gin.H{"cuentas": total},
+	})
+}
+
+// GetPolizas lista pólizas con filtros ?ejercicio=&mes=&tipo=&estado=
+// @Router /empresas/{empresaId}/polizas [get]
+func (h *Handler) GetPolizas(c *gin.Context) {
+	ejercicio, _ := strconv.Atoi(c.Query("ejercicio"))
+	mes, _ := strconv.Atoi(c.Query("mes"))
+
+	polizas, err := h.service.GetPolizas(c.GetInt("empresaID"), FiltroPolizas{
+		Ejercicio: ejercicio,
+		Mes:       mes,
+		Tipo:      c.Query("tipo"),
+		Estado:    c.Query("estado"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    polizas,
+	})
+}
+
+// GetPoliza devuelve la póliza con sus movimientos
+// @Router /empresas/{empresaId}/polizas/{polizaId} [get]
+func (h *Handler) GetPoliza(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("polizaId"))
+
+	poliza, err := h.service.GetPoliza(c.GetInt("empresaID"), id)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    poliza,
+	})
+}
+
+// CrearPoliza captura una póliza en borrador
+// @Router /empresas/{empresaId}/polizas [post]
+func (h *Handler) CrearPoliza(c *gin.Context) {
+	var req PolizaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	poliza, err := h.service.CrearPoliza(c.GetInt("empresaID"), c.GetInt("userID"), req)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Póliza guardada",
+		"data":    poliza,
+	})
+}
+
+// ActualizarPoliza reemplaza un borrador
+// @Router /empresas/{empresaId}/polizas/{polizaId} [put]
+func (h *Handler) ActualizarPoliza(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("polizaId"))
+
+	var req PolizaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	poliza, err := h.service.ActualizarPoliza(c.GetInt("empresaID"), id, req)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Póliza actualizada",
+		"data":    poliza,
+	})
+}
+
+// EliminarPoliza descarta un borrador
+// @Router /empresas/{empresaId}/polizas/{polizaId} [delete]
+func (h *Handler) EliminarPoliza(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("polizaId"))
+
+	if err := h.service.EliminarPoliza(c.GetInt("empresaID"), id); err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Póliza eliminada",
+	})
+}
+
+// RegistrarPoliza valida la partida doble y registra la póliza
+// @Router /empresas/{empresaId}/polizas/{polizaId}/registrar [post]
+func (h *Handler) RegistrarPoliza(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("polizaId"))
+
+	poliza, err := h.service.RegistrarPoliza(c.GetInt("empresaID"), id)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Póliza registrada",
+		"data":    poliza,
+	})
+}
+
+// RevertirPoliza registra la póliza de reversión de una póliza registrada
+// @Router /empresas/{empresaId}/polizas/{polizaId}/revertir [post]
+func (h *Handler) RevertirPoliza(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("polizaId"))
+
+	var req RevertirRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	poliza, err := h.service.RevertirPoliza(c.GetInt("empresaID"), id, c.GetInt("userID"), req)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Póliza revertida",
+		"data":    poliza,
+	})
+}
+
+// GetPeriodos lista el estado de los meses de un ejercicio (?ejercicio=, por omisión el actual)
+// @Router /empresas/{empresaId}/periodos [get]
+func (h *Handler) GetPeriodos(c *gin.Context) {
+	ejercicio, err := strconv.Atoi(c.Query("ejercicio"))
+	if err != nil {
+		ejercicio = time.Now().Year()
+	}
+
+	periodos, err := h.service.GetPeriodos(c.GetInt("empresaID"), ejercicio)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    periodos,
+	})
+}
+
+// CerrarPeriodo cierra un mes; sus pólizas registradas ya no cambian
+// @Router /empresas/{empresaId}/periodos/{ejercicio}/{mes}/cerrar [post]
+func (h *Handler) CerrarPeriodo(c *gin.Context) {
+	ejercicio, _ := strconv.Atoi(c.Param("ejercicio"))
+	mes, _ := strconv.Atoi(c.Param("mes"))
+
+	if err := h.service.CerrarPeriodo(c.GetInt("empresaID"), ejercicio, mes, c.GetInt("userID")); err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Periodo cerrado",
+	})
+}
+
+// ReabrirPeriodo vuelve a abrir un mes cerrado
+// @Router /empresas/{empresaId}/periodos/{ejercicio}/{mes}/reabrir [post]
+func (h *Handler) ReabrirPeriodo(c *gin.Context) {
+	ejercicio, _ := strconv.Atoi(c.Param("ejercicio"))
+	mes, _ := strconv.Atoi(c.Param("mes"))
+
+	if err := h.service.ReabrirPeriodo(c.GetInt("empresaID"), ejercicio, mes); err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Periodo reabierto",
+	})
+}
+
+func responderError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, ErrCuentaNoEncontrada), errors.Is(err, ErrPolizaNoEncontrada), errors.Is(err, ErrReglaNoEncontrada),
+		errors.Is(err, cfdi.ErrCFDINoEncontrado):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrCodigoDuplicado), errors.Is(err, ErrCatalogoExistente), errors.Is(err, ErrCuentaConSubcuentas),
+		errors.Is(err, ErrCuentaConMovimientos), errors.Is(err, ErrPolizaRegistrada), errors.Is(err, ErrPolizaRevertida),
+		errors.Is(err, ErrPeriodoCerrado), errors.Is(err, ErrPeriodoConBorradores):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
+
+// DescargarContabilidadElectronica entrega un ZIP con catálogo de cuentas y
+// balanza del mes en XML 1.3; con ?tipo_solicitud= incluye las pólizas.
+// Balanza complementaria: ?tipo_envio=C&fecha_mod_bal=AAAA-MM-DD.
+// @Router /empresas/{empresaId}/contabilidad-electronica/{ejercicio}/{mes} [get]
+func (h *Handler) DescargarContabilidadElectronica(c *gin.Context) {
+	var op OpcionesContabilidadElectronica
+	if err := c.ShouldBindQuery(&op); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	ejercicio, _ := strconv.Atoi(c.Param("ejercicio"))
+	mes, _ := strconv.Atoi(c.Param("mes"))
+
+	paquete, err := h.service.GenerarContabilidadElectronica(c.GetInt("empresaID"), ejercicio, mes, op)
+	if err != nil {
+		if errs, ok := err.(ErroresEsquema); ok {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"success": false, "error": err.Error(), "errores": errs})
+			return
+		}
+		responderError(c, err)
+		return
+	}
+
+	data, err := paquete.ZIP()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+paquete.NombreZIP()+`"`)
+	c.Data(http.StatusOK, "application/zip", data)
+}
+
+// RegistrarPolizas registra varias pólizas en borrador; informa cuáles no se pudieron registrar
+// @Router /empresas/{empresaId}/polizas/registrar [post]
+func (h *Handler) RegistrarPolizas(c *gin.Context) {
+	var req RegistrarPolizasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	resultado := h.service.RegistrarPolizas(c.GetInt("empresaID"), req.PolizaIDs)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": len(resultado.Errores) == 0,
+		"message": strconv.Itoa(len(resultado.Registradas)) + " pólizas registradas",
+		"data":    resultado,
+	})
+}
+
+// GetConfiguracionPolizas devuelve las cuentas por omisión de las pólizas automáticas
+// @Router /empresas/{empresaId}/polizas-automaticas/configuracion [get]
+func (h *Handler) GetConfiguracionPolizas(c *gin.Context) {
+	conf, err := h.service.GetConfiguracionPolizas(c.GetInt("empresaID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    conf,
+	})
+}
+
+// GuardarConfiguracionPolizas reemplaza las cuentas por omisión; las omitidas quedan vacías
+// @Router /empresas/{empresaId}/polizas-automaticas/configuracion [put]
+func (h *Handler) GuardarConfiguracionPolizas(c *gin.Context) {
+	var req ConfiguracionPolizas
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	conf, err := h.service.GuardarConfiguracionPolizas(c.GetInt("empresaID"), req)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Configuración guardada",
+		"data":    conf,
+	})
+}
+
+// GenerarPolizasCFDI crea pólizas en borrador desde los CFDI emitidos y recibidos
+// @Router /empresas/{empresaId}/polizas-automaticas/generar [post]
+func (h *Handler) GenerarPolizasCFDI(c *gin.Context) {
+	var req GenerarPolizasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	resultado, err := h.service.GenerarPolizasCFDI(c.GetInt("empresaID"), c.GetInt("userID"), req)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": strconv.Itoa(len(resultado.Generadas)) + " pólizas generadas",
+		"data":    resultado,
+	})
+}
+
+// GetReglas lista las reglas de pólizas automáticas en orden de evaluación
+// @Router /empresas/{empresaId}/reglas-poliza [get]
+func (h *Handler) GetReglas(c *gin.Context) {
+	reglas, err := h.service.GetReglas(c.GetInt("empresaID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    reglas,
+	})
+}
+
+// CrearRegla agrega una regla por RFC, ClaveProdServ o UsoCFDI
+// @Router /empresas/{empresaId}/reglas-poliza [post]
+func (h *Handler) CrearRegla(c *gin.Context) {
+	var req ReglaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	regla, err := h.service.CrearRegla(c.GetInt("empresaID"), req)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Regla creada",
+		"data":    regla,
+	})
+}
+
+// ActualizarRegla modifica una regla
+// @Router /empresas/{empresaId}/reglas-poliza/{reglaId} [put]
+func (h *Handler) ActualizarRegla(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("reglaId"))
+
+	var req ReglaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	regla, err := h.service.ActualizarRegla(c.GetInt("empresaID"), id, req)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Regla actualizada",
+		"data":    regla,
+	})
+}
+
+// EliminarRegla borra una regla
+// @Router /empresas/{empresaId}/reglas-poliza/{reglaId} [delete]
+func (h *Handler) EliminarRegla(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("reglaId"))
+
+	if err := h.service.EliminarRegla(c.GetInt("empresaID"), id); err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Regla eliminada",
+	})
+}
+
+// BalanzaReporte devuelve la balanza de comprobación de un rango de fechas.
+// ?comparar=periodo_anterior|ejercicio_anterior agrega columnas comparativas y
+// ?formato=pdf|xlsx la descarga como archivo.
+// @Router /empresas/{empresaId}/reportes/balanza [get]
+func (h *Handler) BalanzaReporte(c *gin.Context) {
+	h.reporte(c, h.service.BalanzaComprobacion)
+}
+
+// BalanceGeneral devuelve el balance general a la fecha hasta
+// @Router /empresas/{empresaId}/reportes/balance-general [get]
+func (h *Handler) BalanceGeneral(c *gin.Context) {
+	h.reporte(c, h.service.BalanceGeneral)
+}
+
+// EstadoResultados devuelve el estado de resultados del rango
+// @Router /empresas/{empresaId}/reportes/estado-resultados [get]
+func (h *Handler) EstadoResultados(c *gin.Context) {
+	h.reporte(c, h.service.EstadoResultados)
+}
+
+// AuxiliarCuenta devuelve las pólizas que mueven una cuenta y sus subcuentas
+// en el rango, para revisar el detalle de un renglón de los reportes
+// @Router /empresas/{empresaId}/reportes/auxiliar/{cuentaId} [get]
+func (h *Handler) AuxiliarCuenta(c *gin.Context) {
+	var f FiltroReporte
+	if err := c.ShouldBindQuery(&f); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	id, _ := strconv.Atoi(c.Param("cuentaId"))
+
+	aux, err := h.service.Auxiliar(c.GetInt("empresaID"), id, f)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	responderReporte(c, f.Formato, "auxiliar-"+aux.Cuenta.Codigo+"-"+aux.Hasta, aux, aux.Tabla)
+}
+
+func (h *Handler) reporte(c *gin.Context, generar func(int, FiltroReporte) (*Reporte, error)) {
+	var f FiltroReporte
+	if err := c.ShouldBindQuery(&f); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	reporte, err := generar(c.GetInt("empresaID"), f)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	nombre := strings.ToLower(strings.ReplaceAll(reporte.Titulo, " ", "-")) + "-" + reporte.RFC + "-" + reporte.Hasta
+	responderReporte(c, f.Formato, nombre, reporte, reporte.Tabla)
+}
+
+// responderReporte entrega el reporte como JSON o como archivo PDF/XLSX
+func responderReporte(c *gin.Context, formato, nombre string, data interface{}, tabla func() exportar.Tabla) {
+	var (
+		archivo []byte
+		tipo    string
+		err     error
+	)
+	switch formato {
+	case "pdf":
+		archivo, err = exportar.PDF(tabla())
+		tipo = "application/pdf"
+	case "xlsx":
+		archivo, err = exportar.XLSX(tabla())
+		tipo = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": data})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+nombreArchivo(nombre)+"."+formato+`"`)
+	c.Data(http.StatusOK, tipo, archivo)
+}
+
+// nombreArchivo quita acentos y caracteres que no van en un nombre de archivo
+func nombreArchivo(s string) string {
+	acentos := strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ñ", "n")
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, acentos.Replace(s))
+}
@@ -0,0 +1,320 @@
+// internal/modules/contabilidad/models.go
+package contabilidad
+
+import "time"
+
+// Naturaleza de las cuentas, con las claves del catálogo XML del SAT
+const (
+	NaturalezaDeudora   = "D"
+	NaturalezaAcreedora = "A"
+)
+
+// Cuenta es una cuenta del catálogo de una empresa. Sólo las cuentas sin
+// subcuentas (hojas) reciben movimientos.
+type Cuenta struct {
+	ID              int       `json:"id"`
+	EmpresaID       int       `json:"empresa_id"`
+	Codigo          string    `json:"codigo"`
+	Nombre          string    `json:"nombre"`
+	Naturaleza      string    `json:"naturaleza"`
+	Nivel           int       `json:"nivel"`
+	PadreID         *int      `json:"padre_id,omitempty"`
+	CodigoAgrupador string    `json:"codigo_agrupador"`
+	IsActive        bool      `json:"is_active"`
+	EsHoja          bool      `json:"es_hoja"`
+	Subcuentas      []Cuenta  `json:"subcuentas,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type CuentaRequest struct {
+	Codigo          string `json:"codigo" binding:"required,max=30"`
+	Nombre          string `json:"nombre" binding:"required"`
+	Naturaleza      string `json:"naturaleza" binding:"omitempty,oneof=D A"`
+	PadreID         *int   `json:"padre_id"`
+	CodigoAgrupador string `json:"codigo_agrupador" binding:"required"`
+}
+
+type ActualizarCuentaRequest struct {
+	Nombre          string `json:"nombre" binding:"required"`
+	Naturaleza      string `json:"naturaleza" binding:"required,oneof=D A"`
+	CodigoAgrupador string `json:"codigo_agrupador" binding:"required"`
+	IsActive        *bool  `json:"is_active"`
+}
+
+// Tipos de póliza del catálogo de contabilidad electrónica
+const (
+	TipoIngreso = "ingreso"
+	TipoEgreso  = "egreso"
+	TipoDiario  = "diario"
+)
+
+// Estados de una póliza. Una póliza registrada no se modifica ni se borra:
+// se corrige con una póliza de reversión.
+const (
+	EstadoBorrador   = "borrador"
+	EstadoRegistrada = "registrada"
+)
+
+type Poliza struct {
+	ID           int          `json:"id"`
+	EmpresaID    int          `json:"empresa_id"`
+	Tipo         string       `json:"tipo"`
+	Numero       int          `json:"numero"`
+	Fecha        time.Time    `json:"fecha"`
+	Concepto     string       `json:"concepto"`
+	Estado       string       `json:"estado"`
+	RevierteA    *int         `json:"revierte_a,omitempty"`
+	RevertidaPor *int         `json:"revertida_por,omitempty"`
+	TotalCargos  float64      `json:"total_cargos"`
+	TotalAbonos  float64      `json:"total_abonos"`
+	CFDIUUID     string       `json:"cfdi_uuid,omitempty"`
+	Movimientos  []Movimiento `json:"movimientos,omitempty"`
+	CreatedBy    int          `json:"created_by"`
+	RegistradaAt *time.Time   `json:"registrada_at,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// Movimiento es una línea de la póliza; lleva cargo o abono, nunca ambos
+type Movimiento struct {
+	ID           int     `json:"id"`
+	PolizaID     int     `json:"poliza_id"`
+	CuentaID     int     `json:"cuenta_id"`
+	CuentaCodigo string  `json:"cuenta_codigo"`
+	CuentaNombre string  `json:"cuenta_nombre"`
+	Concepto     string  `json:"concepto"`
+	Cargo        float64 `json:"cargo"`
+	Abono        float64 `json:"abono"`
+	UUID         string  `json:"uuid,omitempty"`
+}
+
+type MovimientoRequest struct {
+	CuentaID int     `json:"cuenta_id" binding:"required"`
+	Concepto string  `json:"concepto"`
+	Cargo    float64 `json:"cargo" binding:"min=0"`
+	Abono    float64 `json:"abono" binding:"min=0"`
+	UUID     string  `json:"uuid"`
+}
+
+type PolizaRequest struct {
+	Tipo        string              `json:"tipo" binding:"required,oneof=ingreso egreso diario"`
+	Fecha       string              `json:"fecha" binding:"required"`
+	Concepto    string              `json:"concepto" binding:"required"`
+	Movimientos []MovimientoRequest `json:"movimientos" binding:"required,min=2,dive"`
+}
+
+type RevertirRequest struct {
+	Fecha string `json:"fecha"`
+}
+
+type FiltroPolizas struct {
+	Ejercicio int
+	Mes       int
+	Tipo      string
+	Estado    string
+}
+
+// Periodo es un mes contable; cerrado impide registrar o revertir pólizas en él
+type Periodo struct {
+	EmpresaID  int        `json:"empresa_id"`
+	Ejercicio  int        `json:"ejercicio"`
+	Mes        int        `json:"mes"`
+	Cerrado    bool       `json:"cerrado"`
+	CerradoAt  *time.Time `json:"cerrado_at,omitempty"`
+	CerradoPor *int       `json:"cerrado_por,omitempty"`
+}
+
+// SaldoCuenta es el renglón de una balanza de comprobación. Los saldos siguen
+// la naturaleza de la cuenta: positivos cuando la cuenta tiene saldo a su favor.
+type SaldoCuenta struct {
+	CuentaID        int     `json:"cuenta_id"`
+	Codigo          string  `json:"codigo"`
+	Nombre          string  `json:"nombre"`
+	Naturaleza      string  `json:"naturaleza"`
+	Nivel           int     `json:"nivel"`
+	PadreID         *int    `json:"padre_id,omitempty"`
+	CodigoAgrupador string  `json:"codigo_agrupador"`
+	EsHoja          bool    `json:"es_hoja"`
+	Activa          bool    `json:"activa"`
+	SaldoInicial    float64 `json:"saldo_inicial"`
+	Cargos          float64 `json:"cargos"`
+	Abonos          float64 `json:"abonos"`
+	SaldoFinal      float64 `json:"saldo_final"`
+}
+
+// OpcionesContabilidadElectronica controla el envío de la balanza y la
+// solicitud de pólizas. Las pólizas sólo se generan si hay TipoSolicitud.
+type OpcionesContabilidadElectronica struct {
+	TipoEnvio     string `form:"tipo_envio" binding:"omitempty,oneof=N C"`
+	FechaModBal   string `form:"fecha_mod_bal"`
+	TipoSolicitud string `form:"tipo_solicitud" binding:"omitempty,oneof=AF FC DE CO"`
+	NumOrden      string `form:"num_orden"`
+	NumTramite    string `form:"num_tramite"`
+}
+
+// Origen de los CFDI que procesa una regla de pólizas automáticas
+const (
+	AplicaEmitidos  = "emitidos"
+	AplicaRecibidos = "recibidos"
+	AplicaAmbos     = "ambos"
+)
+
+// ConfiguracionPolizas son las cuentas que usan las pólizas generadas desde
+// CFDI cuando ninguna regla indica otra cosa
+type ConfiguracionPolizas struct {
+	EmpresaID                   int       `json:"empresa_id"`
+	CuentaClientesID            *int      `json:"cuenta_clientes_id"`
+	CuentaProveedoresID         *int      `json:"cuenta_proveedores_id"`
+	CuentaIngresosID            *int      `json:"cuenta_ingresos_id"`
+	CuentaGastosID              *int      `json:"cuenta_gastos_id"`
+	CuentaIVATrasladadoID       *int      `json:"cuenta_iva_trasladado_id"`
+	CuentaIVAAcreditableID      *int      `json:"cuenta_iva_acreditable_id"`
+	CuentaIVARetenidoPorPagarID *int      `json:"cuenta_iva_retenido_por_pagar_id"`
+	CuentaISRRetenidoPorPagarID *int      `json:"cuenta_isr_retenido_por_pagar_id"`
+	CuentaIVARetenidoAFavorID   *int      `json:"cuenta_iva_retenido_a_favor_id"`
+	CuentaISRRetenidoAFavorID   *int      `json:"cuenta_isr_retenido_a_favor_id"`
+	UpdatedAt                   time.Time `json:"updated_at"`
+}
+
+// ReglaPoliza asigna la cuenta de ingreso o gasto de los conceptos de un CFDI.
+// Los criterios vacíos no filtran; ClaveProdServ admite prefijos (43 = familia).
+// Gana la regla activa de menor prioridad.
+type ReglaPoliza struct {
+	ID            int       `json:"id"`
+	EmpresaID     int       `json:"empresa_id"`
+	Nombre        string    `json:"nombre"`
+	Prioridad     int       `json:"prioridad"`
+	AplicaA       string    `json:"aplica_a"`
+	RFC           string    `json:"rfc,omitempty"`
+	ClaveProdServ string    `json:"clave_prod_serv,omitempty"`
+	UsoCFDI       string    `json:"uso_cfdi,omitempty"`
+	CuentaID      int       `json:"cuenta_id"`
+	CuentaCodigo  string    `json:"cuenta_codigo"`
+	CuentaNombre  string    `json:"cuenta_nombre"`
+	IsActive      bool      `json:"is_active"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type ReglaRequest struct {
+	Nombre        string `json:"nombre" binding:"required,max=100"`
+	Prioridad     int    `json:"prioridad"`
+	AplicaA       string `json:"aplica_a" binding:"required,oneof=emitidos recibidos ambos"`
+	RFC           string `json:"rfc" binding:"omitempty,rfc"`
+	ClaveProdServ string `json:"clave_prod_serv" binding:"omitempty,max=8,numeric"`
+	UsoCFDI       string `json:"uso_cfdi" binding:"omitempty,max=4"`
+	CuentaID      int    `json:"cuenta_id" binding:"required"`
+	IsActive      *bool  `json:"is_active"`
+}
+
+// GenerarPolizasRequest elige los CFDI a contabilizar: por rango de fechas
+// (AAAA-MM-DD, inclusive) o por UUID
+type GenerarPolizasRequest struct {
+	Desde   string   `json:"desde"`
+	Hasta   string   `json:"hasta"`
+	AplicaA string   `json:"aplica_a" binding:"omitempty,oneof=emitidos recibidos ambos"`
+	UUIDs   []string `json:"uuids"`
+}
+
+// CFDIOmitido explica por qué un CFDI no generó póliza
+type CFDIOmitido struct {
+	UUID   string `json:"uuid"`
+	Motivo string `json:"motivo"`
+}
+
+type ResultadoPolizasCFDI struct {
+	Generadas []Poliza      `json:"generadas"`
+	Omitidas  []CFDIOmitido `json:"omitidas,omitempty"`
+}
+
+type RegistrarPolizasRequest struct {
+	PolizaIDs []int `json:"poliza_ids" binding:"required,min=1"`
+}
+
+// ErrorRegistro es una póliza del lote que no se pudo registrar
+type ErrorRegistro struct {
+	PolizaID int    `json:"poliza_id"`
+	Error    string `json:"error"`
+}
+
+type ResultadoRegistro struct {
+	Registradas []int           `json:"registradas"`
+	Errores     []ErrorRegistro `json:"errores,omitempty"`
+}
+
+// Comparativos disponibles en los reportes
+const (
+	CompararPeriodoAnterior   = "periodo_anterior"
+	CompararEjercicioAnterior = "ejercicio_anterior"
+)
+
+// FiltroReporte son los parámetros comunes de los reportes financieros. Sin
+// desde, el reporte empieza el primer día del mes de hasta; nivel 0 muestra
+// todas las cuentas.
+type FiltroReporte struct {
+	Desde    string `form:"desde"`
+	Hasta    string `form:"hasta" binding:"required"`
+	Comparar string `form:"comparar" binding:"omitempty,oneof=periodo_anterior ejercicio_anterior"`
+	Nivel    int    `form:"nivel" binding:"min=0"`
+	Formato  string `form:"formato" binding:"omitempty,oneof=json pdf xlsx"`
+}
+
+// Reporte es un estado financiero con una columna por importe; los renglones
+// con cuenta_id llevan al auxiliar de la cuenta
+type Reporte struct {
+	Titulo      string           `json:"titulo"`
+	RFC         string           `json:"rfc"`
+	RazonSocial string           `json:"razon_social"`
+	Desde       string           `json:"desde"`
+	Hasta       string           `json:"hasta"`
+	Comparativo *RangoReporte    `json:"comparativo,omitempty"`
+	Columnas    []string         `json:"columnas"`
+	Secciones   []SeccionReporte `json:"secciones"`
+	Resumen     []RenglonReporte `json:"resumen,omitempty"`
+}
+
+type RangoReporte struct {
+	Desde string `json:"desde"`
+	Hasta string `json:"hasta"`
+}
+
+type SeccionReporte struct {
+	Nombre    string           `json:"nombre"`
+	Renglones []RenglonReporte `json:"renglones"`
+	Total     RenglonReporte   `json:"total"`
+}
+
+type RenglonReporte struct {
+	CuentaID int       `json:"cuenta_id,omitempty"`
+	Codigo   string    `json:"codigo,omitempty"`
+	Nombre   string    `json:"nombre"`
+	Nivel    int       `json:"nivel,omitempty"`
+	Importes []float64 `json:"importes"`
+}
+
+// Auxiliar son los movimientos registrados de una cuenta y sus subcuentas,
+// con el saldo después de cada uno
+type Auxiliar struct {
+	Cuenta       Cuenta               `json:"cuenta"`
+	Desde        string               `json:"desde"`
+	Hasta        string               `json:"hasta"`
+	SaldoInicial float64              `json:"saldo_inicial"`
+	Cargos       float64              `json:"cargos"`
+	Abonos       float64              `json:"abonos"`
+	SaldoFinal   float64              `json:"saldo_final"`
+	Movimientos  []MovimientoAuxiliar `json:"movimientos"`
+}
+
+type MovimientoAuxiliar struct {
+	PolizaID     int       `json:"poliza_id"`
+	Tipo         string    `json:"tipo"`
+	Numero       int       `json:"numero"`
+	Fecha        time.Time `json:"fecha"`
+	CuentaCodigo string    `json:"cuenta_codigo"`
+	Concepto     string    `json:"concepto"`
+	Cargo        float64   `json:"cargo"`
+	Abono        float64   `json:"abono"`
+	Saldo        float64   `json:"saldo"`
+	UUID         string    `json:"uuid,omitempty"`
+}
@@ -0,0 +1,121 @@
+// internal/modules/contabilidad/plantilla.go
+package contabilidad
+
+// cuentaPlantilla es una cuenta del catálogo base; padre es el código de la
+// cuenta de mayor nivel ("" para las de primer nivel)
+type cuentaPlantilla struct {
+	codigo, padre, nombre, naturaleza, agrupador string
+}
+
+// plantillaCuentas es un catálogo base para personas morales y físicas con
+// actividad empresarial, ligado al código agrupador del Anexo 24. Las cuentas
+// de cuarto nivel son las que reciben movimientos.
+var plantillaCuentas = []cuentaPlantilla{
+	{"1000", "", "Activo", "D", "100"},
+	{"1100", "1000", "Activo a corto plazo", "D", "100.01"},
+	{"1101", "1100", "Caja", "D", "101"},
+	{"1101-001", "1101", "Caja y efectivo", "D", "101.01"},
+	{"1102", "1100", "Bancos", "D", "102"},
+	{"1102-001", "1102", "Bancos nacionales", "D", "102.01"},
+	{"1102-002", "1102", "Bancos extranjeros", "D", "102.02"},
+	{"1103", "1100", "Inversiones", "D", "103"},
+	{"1103-001", "1103", "Inversiones temporales", "D", "103.01"},
+	{"1105", "1100", "Clientes", "D", "105"},
+	{"1105-001", "1105", "Clientes nacionales", "D", "105.01"},
+	{"1105-002", "1105", "Clientes extranjeros", "D", "105.02"},
+	{"1107", "1100", "Deudores diversos", "D", "107"},
+	{"1107-001", "1107", "Funcionarios y empleados", "D", "107.01"},
+	{"1107-005", "1107", "Otros deudores diversos", "D", "107.05"},
+	{"1113", "1100", "Impuestos a favor", "D", "113"},
+	{"1113-001", "1113", "IVA a favor", "D", "113.01"},
+	{"1113-002", "1113", "ISR a favor", "D", "113.02"},
+	{"1115", "1100", "Inventario", "D", "115"},
+	{"1115-001", "1115", "Inventario", "D", "115.01"},
+	{"1118", "1100", "Impuestos acreditables pagados", "D", "118"},
+	{"1118-001", "1118", "IVA acreditable pagado", "D", "118.01"},
+	{"1119", "1100", "Impuestos acreditables por pagar", "D", "119"},
+	{"1119-001", "1119", "IVA pendiente de pago", "D", "119.01"},
+	{"1120", "1100", "Anticipo a proveedores", "D", "120"},
+	{"1120-001", "1120", "Anticipo a proveedores nacional", "D", "120.01"},
+	{"1200", "1000", "Activo a largo plazo", "D", "100.02"},
+	{"1201", "1200", "Terrenos", "D", "151"},
+	{"1201-001", "1201", "Terrenos", "D", "151.01"},
+	{"1202", "1200", "Edificios", "D", "152"},
+	{"1202-001", "1202", "Edificios", "D", "152.01"},
+	{"1203", "1200", "Maquinaria y equipo", "D", "153"},
+	{"1203-001", "1203", "Maquinaria y equipo", "D", "153.01"},
+	{"1204", "1200", "Equipo de transporte", "D", "154"},
+	{"1204-001", "1204", "Automóviles, autobuses y camiones", "D", "154.01"},
+	{"1205", "1200", "Mobiliario y equipo de oficina", "D", "155"},
+	{"1205-001", "1205", "Mobiliario y equipo de oficina", "D", "155.01"},
+	{"1206", "1200", "Equipo de cómputo", "D", "156"},
+	{"1206-001", "1206", "Equipo de cómputo", "D", "156.01"},
+	{"1207", "1200", "Depreciación acumulada de activos fijos", "A", "171"},
+	{"1207-001", "1207", "Depreciación acumulada de edificios", "A", "171.01"},
+	{"1207-002", "1207", "Depreciación acumulada de maquinaria y equipo", "A", "171.02"},
+	{"1207-003", "1207", "Depreciación acumulada de equipo de transporte", "A", "171.03"},
+	{"1207-004", "1207", "Depreciación acumulada de mobiliario y equipo de oficina", "A", "171.04"},
+	{"1207-005", "1207", "Depreciación acumulada de equipo de cómputo", "A", "171.05"},
+	{"1208", "1200", "Depósitos en garantía", "D", "184"},
+	{"1208-001", "1208", "Depósitos de fianzas", "D", "184.01"},
+	{"1208-002", "1208", "Depósitos de arrendamiento de bienes inmuebles", "D", "184.02"},
+
+	{"2000", "", "Pasivo", "A", "200"},
+	{"2100", "2000", "Pasivo a corto plazo", "A", "200.01"},
+	{"2101", "2100", "Proveedores", "A", "201"},
+	{"2101-001", "2101", "Proveedores nacionales", "A", "201.01"},
+	{"2101-002", "2101", "Proveedores extranjeros", "A", "201.02"},
+	{"2102", "2100", "Acreedores diversos a corto plazo", "A", "205"},
+	{"2102-001", "2102", "Socios, accionistas o representante legal", "A", "205.01"},
+	{"2102-006", "2102", "Otros acreedores diversos a corto plazo", "A", "205.06"},
+	{"2103", "2100", "Impuestos trasladados cobrados", "A", "208"},
+	{"2103-001", "2103", "IVA trasladado cobrado", "A", "208.01"},
+	{"2104", "2100", "Impuestos trasladados no cobrados", "A", "209"},
+	{"2104-001", "2104", "IVA trasladado no cobrado", "A", "209.01"},
+	{"2105", "2100", "Provisión de sueldos y salarios por pagar", "A", "210"},
+	{"2105-001", "2105", "Provisión de sueldos y salarios por pagar", "A", "210.01"},
+	{"2106", "2100", "Provisión de contribuciones de seguridad social por pagar", "A", "211"},
+	{"2106-001", "2106", "Provisión de IMSS patronal por pagar", "A", "211.01"},
+	{"2107", "2100", "Impuestos y derechos por pagar", "A", "213"},
+	{"2107-001", "2107", "IVA por pagar", "A", "213.01"},
+	{"2107-003", "2107", "ISR por pagar", "A", "213.03"},
+	{"2108", "2100", "Impuestos retenidos", "A", "216"},
+	{"2108-001", "2108", "Impuestos retenidos de ISR por sueldos y salarios", "A", "216.01"},
+	{"2108-010", "2108", "Impuestos retenidos de IVA", "A", "216.10"},
+
+	{"3000", "", "Capital contable", "A", "300"},
+	{"3101", "3000", "Capital social", "A", "301"},
+	{"3101-001", "3101", "Capital fijo", "A", "301.01"},
+	{"3101-002", "3101", "Capital variable", "A", "301.02"},
+	{"3102", "3000", "Resultado de ejercicios anteriores", "A", "304"},
+	{"3102-001", "3102", "Utilidad de ejercicios anteriores", "A", "304.01"},
+	{"3102-002", "3102", "Pérdida de ejercicios anteriores", "D", "304.02"},
+	{"3103", "3000", "Resultado del ejercicio", "A", "305"},
+	{"3103-001", "3103", "Utilidad del ejercicio", "A", "305.01"},
+	{"3103-002", "3103", "Pérdida del ejercicio", "D", "305.02"},
+
+	{"4000", "", "Ingresos", "A", "400"},
+	{"4101", "4000", "Ingresos", "A", "401"},
+	{"4101-001", "4101", "Ventas y/o servicios gravados a la tasa general", "A", "401.01"},
+	{"4101-004", "4101", "Ventas y/o servicios gravados al 0%", "A", "401.04"},
+	{"4101-007", "4101", "Ventas y/o servicios exentos", "A", "401.07"},
+	{"4102", "4000", "Devoluciones, descuentos o bonificaciones sobre ingresos", "D", "402"},
+	{"4102-001", "4102", "Devoluciones, descuentos o bonificaciones sobre ventas", "D", "402.01"},
+	{"4103", "4000", "Otros ingresos", "A", "403"},
+	{"4103-004", "4103", "Otros ingresos", "A", "403.04"},
+
+	{"5000", "", "Costos", "D", "500"},
+	{"5101", "5000", "Costo de venta y/o servicio", "D", "501"},
+	{"5101-001", "5101", "Costo de venta", "D", "501.01"},
+
+	{"6000", "", "Gastos", "D", "600"},
+	{"6101", "6000", "Gastos generales", "D", "601"},
+	{"6101-001", "6101", "Sueldos y salarios", "D", "601.01"},
+	{"6101-084", "6101", "Otros gastos generales", "D", "601.84"},
+
+	{"7000", "", "Resultado integral de financiamiento", "D", "700"},
+	{"7101", "7000", "Gastos financieros", "D", "701"},
+	{"7101-001", "7101", "Pérdida cambiaria", "D", "701.01"},
+	{"7102", "7000", "Productos financieros", "A", "702"},
+	{"7102-001", "7102", "Utilidad cambiaria", "A", "702.01"},
+}
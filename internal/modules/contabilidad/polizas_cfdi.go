@@ -0,0 +1,444 @@
+// internal/modules/contabilidad/polizas_cfdi.go
+package contabilidad
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jhvc/backend/internal/modules/cfdi"
+	"github.com/jhvc/backend/internal/validacion"
+)
+
+var (
+	ErrReglaNoEncontrada = errors.New("regla no encontrada")
+	ErrRangoCFDI         = errors.New("indique desde y hasta (AAAA-MM-DD) o una lista de UUID")
+)
+
+// toleranciaCuadre absorbe diferencias de redondeo entre los impuestos por
+// concepto y el total del comprobante
+const toleranciaCuadre = 0.05
+
+// Claves de impuesto del Anexo 20
+const (
+	impuestoISR = "001"
+	impuestoIVA = "002"
+)
+
+func (s *Service) GetConfiguracionPolizas(empresaID int) (*ConfiguracionPolizas, error) {
+	return s.repo.GetConfiguracionPolizas(empresaID)
+}
+
+// GuardarConfiguracionPolizas exige que cada cuenta indicada pueda recibir movimientos
+func (s *Service) GuardarConfiguracionPolizas(empresaID int, conf ConfiguracionPolizas) (*ConfiguracionPolizas, error) {
+	conf.EmpresaID = empresaID
+	for _, campo := range cuentasConfiguracion(&conf) {
+		if *campo == nil {
+			continue
+		}
+		if _, err := s.ValidarCuentaAfectable(empresaID, **campo); err != nil {
+			return nil, fmt.Errorf("cuenta %d: %w", **campo, err)
+		}
+	}
+
+	if err := s.repo.GuardarConfiguracionPolizas(&conf); err != nil {
+		return nil, err
+	}
+	return s.repo.GetConfiguracionPolizas(empresaID)
+}
+
+func (s *Service) GetReglas(empresaID int) ([]ReglaPoliza, error) {
+	return s.repo.GetReglas(empresaID)
+}
+
+func (s *Service) CrearRegla(empresaID int, req ReglaRequest) (*ReglaPoliza, error) {
+	regla, err := s.armarRegla(empresaID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.repo.CrearRegla(regla)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetRegla(empresaID, id)
+}
+
+func (s *Service) ActualizarRegla(empresaID, id int, req ReglaRequest) (*ReglaPoliza, error) {
+	if _, err := s.getRegla(empresaID, id); err != nil {
+		return nil, err
+	}
+	regla, err := s.armarRegla(empresaID, req)
+	if err != nil {
+		return nil, err
+	}
+	regla.ID = id
+
+	if err := s.repo.ActualizarRegla(regla); err != nil {
+		return nil, err
+	}
+	return s.repo.GetRegla(empresaID, id)
+}
+
+func (s *Service) EliminarRegla(empresaID, id int) error {
+	if _, err := s.getRegla(empresaID, id); err != nil {
+		return err
+	}
+	return s.repo.EliminarRegla(empresaID, id)
+}
+
+func (s *Service) getRegla(empresaID, id int) (*ReglaPoliza, error) {
+	regla, err := s.repo.GetRegla(empresaID, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrReglaNoEncontrada
+	}
+	return regla, err
+}
+
+func (s *Service) armarRegla(empresaID int, req ReglaRequest) (*ReglaPoliza, error) {
+	if _, err := s.ValidarCuentaAfectable(empresaID, req.CuentaID); err != nil {
+		return nil, err
+	}
+
+	regla := &ReglaPoliza{
+		EmpresaID:     empresaID,
+		Nombre:        strings.TrimSpace(req.Nombre),
+		Prioridad:     req.Prioridad,
+		AplicaA:       req.AplicaA,
+		RFC:           validacion.NormalizarRFC(req.RFC),
+		ClaveProdServ: strings.TrimSpace(req.ClaveProdServ),
+		UsoCFDI:       strings.ToUpper(strings.TrimSpace(req.UsoCFDI)),
+		CuentaID:      req.CuentaID,
+		IsActive:      true,
+	}
+	if regla.Prioridad == 0 {
+		regla.Prioridad = 100
+	}
+	if req.IsActive != nil {
+		regla.IsActive = *req.IsActive
+	}
+	return regla, nil
+}
+
+// aplica indica si la regla corresponde al concepto de un CFDI
+func (g *ReglaPoliza) aplica(emitido bool, rfcContraparte, usoCFDI, claveProdServ string) bool {
+	if !g.IsActive {
+		return false
+	}
+	if (g.AplicaA == AplicaEmitidos && !emitido) || (g.AplicaA == AplicaRecibidos && emitido) {
+		return false
+	}
+	if g.RFC != "" && !strings.EqualFold(g.RFC, rfcContraparte) {
+		return false
+	}
+	if g.UsoCFDI != "" && g.UsoCFDI != strings.ToUpper(usoCFDI) {
+		return false
+	}
+	return g.ClaveProdServ == "" || strings.HasPrefix(claveProdServ, g.ClaveProdServ)
+}
+
+// GenerarPolizasCFDI crea pólizas de diario en borrador para los CFDI de
+// ingreso y egreso de la empresa que aún no tienen póliza. Cada CFDI que no
+// se puede contabilizar se informa con su motivo sin detener el resto.
+func (s *Service) GenerarPolizasCFDI(empresaID, userID int, req GenerarPolizasRequest) (*ResultadoPolizasCFDI, error) {
+	comprobantes, err := s.cfdisAContabilizar(empresaID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	rfc, err := s.repo.GetRFCEmpresa(empresaID)
+	if err != nil {
+		return nil, err
+	}
+	conf, err := s.repo.GetConfiguracionPolizas(empresaID)
+	if err != nil {
+		return nil, err
+	}
+	reglas, err := s.repo.GetReglas(empresaID)
+	if err != nil {
+		return nil, err
+	}
+	contabilizados, err := s.repo.UUIDsContabilizados(empresaID)
+	if err != nil {
+		return nil, err
+	}
+	resultado := &ResultadoPolizasCFDI{Generadas: []Poliza{}}
+	omitir := func(uuid, motivo string) {
+		resultado.Omitidas = append(resultado.Omitidas, CFDIOmitido{UUID: uuid, Motivo: motivo})
+	}
+
+	for _, c := range comprobantes {
+		if c.TipoComprobante != cfdi.TipoIngreso && c.TipoComprobante != cfdi.TipoEgreso {
+			omitir(c.UUID, "sólo se contabilizan CFDI de ingreso y egreso")
+			continue
+		}
+		emitido := strings.EqualFold(c.EmisorRFC, rfc)
+		if !emitido && !strings.EqualFold(c.ReceptorRFC, rfc) {
+			omitir(c.UUID, "el CFDI no fue emitido ni recibido por la empresa")
+			continue
+		}
+		if req.AplicaA == AplicaEmitidos && !emitido || req.AplicaA == AplicaRecibidos && emitido {
+			continue
+		}
+		if contabilizados[c.UUID] {
+			omitir(c.UUID, "el CFDI ya tiene póliza")
+			continue
+		}
+
+		data, err := s.cfdis.GetXML(empresaID, c.UUID)
+		if err != nil {
+			omitir(c.UUID, err.Error())
+			continue
+		}
+		comp, err := cfdi.Parse(data)
+		if err != nil {
+			omitir(c.UUID, err.Error())
+			continue
+		}
+
+		polizaReq, err := polizaDesdeCFDI(c, comp, emitido, conf, reglas)
+		if err != nil {
+			omitir(c.UUID, err.Error())
+			continue
+		}
+		poliza, err := s.armarPoliza(empresaID, *polizaReq)
+		if err != nil {
+			omitir(c.UUID, err.Error())
+			continue
+		}
+		poliza.CreatedBy = userID
+		poliza.CFDIUUID = c.UUID
+
+		id, err := s.repo.CrearPoliza(poliza)
+		if err != nil {
+			omitir(c.UUID, err.Error())
+			continue
+		}
+		contabilizados[c.UUID] = true
+
+		creada, err := s.GetPoliza(empresaID, id)
+		if err != nil {
+			return nil, err
+		}
+		resultado.Generadas = append(resultado.Generadas, *creada)
+	}
+
+	return resultado, nil
+}
+
+// cfdisAContabilizar busca los comprobantes por UUID o por rango de fechas
+func (s *Service) cfdisAContabilizar(empresaID int, req GenerarPolizasRequest) ([]cfdi.CFDI, error) {
+	if len(req.UUIDs) > 0 {
+		var comprobantes []cfdi.CFDI
+		for _, uuid := range req.UUIDs {
+			c, err := s.cfdis.GetCFDI(empresaID, uuid)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", uuid, err)
+			}
+			comprobantes = append(comprobantes, *c)
+		}
+		return comprobantes, nil
+	}
+
+	if req.Desde == "" || req.Hasta == "" {
+		return nil, ErrRangoCFDI
+	}
+	desde, err := parseFechaPoliza(req.Desde)
+	if err != nil {
+		return nil, err
+	}
+	hasta, err := parseFechaPoliza(req.Hasta)
+	if err != nil {
+		return nil, err
+	}
+	hasta = hasta.AddDate(0, 0, 1)
+
+	rfc, err := s.repo.GetRFCEmpresa(empresaID)
+	if err != nil {
+		return nil, err
+	}
+	return s.cfdis.GetCFDIs(empresaID, cfdi.FiltroCFDI{RFC: rfc, Desde: &desde, Hasta: &hasta})
+}
+
+// asiento acumula cargos y abonos por cuenta en el orden en que aparecen
+type asiento struct {
+	orden  []int
+	saldos map[int]float64
+}
+
+func (a *asiento) cargar(cuentaID int, importe float64) {
+	if _, ok := a.saldos[cuentaID]; !ok {
+		a.orden = append(a.orden, cuentaID)
+	}
+	a.saldos[cuentaID] += importe
+}
+
+func (a *asiento) abonar(cuentaID int, importe float64) {
+	a.cargar(cuentaID, -importe)
+}
+
+// polizaDesdeCFDI arma el asiento del comprobante en moneda nacional:
+//
+//	emitido:  cargo a clientes por el total; abono a ingresos e IVA trasladado;
+//	          cargo a las retenciones que hizo el cliente (a favor)
+//	recibido: cargo a gastos e IVA acreditable; abono a proveedores por el total;
+//	          abono a las retenciones que hizo la empresa (por pagar)
+//
+// Una nota de crédito (egreso) produce el asiento inverso. Los impuestos
+// distintos de IVA (p. ej. IEPS) se suman al importe del concepto.
+func polizaDesdeCFDI(c cfdi.CFDI, comp *cfdi.Comprobante, emitido bool, conf *ConfiguracionPolizas, reglas []ReglaPoliza) (*PolizaRequest, error) {
+	factor := 1.0
+	if c.Moneda != "" && c.Moneda != "MXN" && c.Moneda != "XXX" {
+		if c.TipoCambio <= 0 {
+			return nil, fmt.Errorf("CFDI en %s sin tipo de cambio", c.Moneda)
+		}
+		factor = c.TipoCambio
+	}
+
+	rfcContraparte, nombreContraparte := c.EmisorRFC, c.EmisorNombre
+	contraparteID, ivaID, ivaRetID, isrRetID := conf.CuentaProveedoresID, conf.CuentaIVAAcreditableID,
+		conf.CuentaIVARetenidoPorPagarID, conf.CuentaISRRetenidoPorPagarID
+	porDefectoID := conf.CuentaGastosID
+	if emitido {
+		rfcContraparte, nombreContraparte = c.ReceptorRFC, c.ReceptorNombre
+		contraparteID, ivaID, ivaRetID, isrRetID = conf.CuentaClientesID, conf.CuentaIVATrasladadoID,
+			conf.CuentaIVARetenidoAFavorID, conf.CuentaISRRetenidoAFavorID
+		porDefectoID = conf.CuentaIngresosID
+	}
+
+	cuenta := func(id *int, nombre string) (int, error) {
+		if id == nil {
+			return 0, fmt.Errorf("configure la cuenta de %s", nombre)
+		}
+		return *id, nil
+	}
+
+	// Con signo positivo el importe va al debe en una factura recibida
+	a := &asiento{saldos: make(map[int]float64)}
+	var iva, ivaRet, isrRet float64
+	for _, con := range comp.Conceptos {
+		importe := con.Importe - con.Descuento
+		for _, t := range con.Traslados {
+			if t.Impuesto == impuestoIVA {
+				iva += t.Importe
+			} else {
+				importe += t.Importe
+			}
+		}
+		for _, r := range con.Retenciones {
+			switch r.Impuesto {
+			case impuestoIVA:
+				ivaRet += r.Importe
+			case impuestoISR:
+				isrRet += r.Importe
+			}
+		}
+
+		cuentaID := 0
+		for i := range reglas {
+			if reglas[i].aplica(emitido, rfcContraparte, c.UsoCFDI, con.ClaveProdServ) {
+				cuentaID = reglas[i].CuentaID
+				break
+			}
+		}
+		if cuentaID == 0 {
+			id, err := cuenta(porDefectoID, map[bool]string{true: "ingresos", false: "gastos"}[emitido])
+			if err != nil {
+				return nil, fmt.Errorf("concepto %s sin regla: %w", con.ClaveProdServ, err)
+			}
+			cuentaID = id
+		}
+		a.cargar(cuentaID, importe*factor)
+	}
+
+	if iva != 0 {
+		id, err := cuenta(ivaID, map[bool]string{true: "IVA trasladado", false: "IVA acreditable"}[emitido])
+		if err != nil {
+			return nil, err
+		}
+		a.cargar(id, iva*factor)
+	}
+	if ivaRet != 0 {
+		id, err := cuenta(ivaRetID, "IVA retenido")
+		if err != nil {
+			return nil, err
+		}
+		a.abonar(id, ivaRet*factor)
+	}
+	if isrRet != 0 {
+		id, err := cuenta(isrRetID, "ISR retenido")
+		if err != nil {
+			return nil, err
+		}
+		a.abonar(id, isrRet*factor)
+	}
+	id, err := cuenta(contraparteID, map[bool]string{true: "clientes", false: "proveedores"}[emitido])
+	if err != nil {
+		return nil, err
+	}
+	a.abonar(id, c.Total*factor)
+
+	// El total del CFDI manda; las diferencias de redondeo van al primer concepto
+	var diferencia float64
+	for _, v := range a.saldos {
+		diferencia += v
+	}
+	if math.Abs(diferencia) > toleranciaCuadre*factor {
+		return nil, fmt.Errorf("%w por %.2f: el CFDI tiene impuestos que no se contabilizan automáticamente (p. ej. locales)",
+			ErrPolizaDescuadrada, diferencia)
+	}
+	a.saldos[a.orden[0]] -= diferencia
+
+	// Emitido e ingreso invierten el signo; egreso (nota de crédito) lo vuelve a invertir
+	signo := 1.0
+	if emitido {
+		signo = -signo
+	}
+	if c.TipoComprobante == cfdi.TipoEgreso {
+		signo = -signo
+	}
+
+	documento := map[bool]string{true: "Factura", false: "Nota de crédito"}[c.TipoComprobante == cfdi.TipoIngreso]
+	origen := map[bool]string{true: "emitida", false: "recibida"}[emitido]
+	concepto := strings.TrimSpace(fmt.Sprintf("%s %s %s%s %s", documento, origen, c.Serie, c.Folio, nombreContraparte))
+	if nombreContraparte == "" {
+		concepto += " " + rfcContraparte
+	}
+
+	req := &PolizaRequest{
+		Tipo:     TipoDiario,
+		Fecha:    c.Fecha.Format("2006-01-02"),
+		Concepto: recortar(concepto, 300),
+	}
+	for _, cuentaID := range a.orden {
+		importe := redondear(a.saldos[cuentaID] * signo)
+		m := MovimientoRequest{CuentaID: cuentaID, UUID: c.UUID}
+		switch {
+		case importe > 0:
+			m.Cargo = importe
+		case importe < 0:
+			m.Abono = -importe
+		default:
+			continue
+		}
+		req.Movimientos = append(req.Movimientos, m)
+	}
+
+	return req, nil
+}
+
+// RegistrarPolizas registra un lote de borradores; cada póliza se registra
+// por separado, así que un error no detiene a las demás
+func (s *Service) RegistrarPolizas(empresaID int, ids []int) *ResultadoRegistro {
+	resultado := &ResultadoRegistro{Registradas: []int{}}
+	for _, id := range ids {
+		if err := s.repo.RegistrarPoliza(empresaID, id); err != nil {
+			resultado.Errores = append(resultado.Errores, ErrorRegistro{PolizaID: id, Error: err.Error()})
+			continue
+		}
+		resultado.Registradas = append(resultado.Registradas, id)
+	}
+	return resultado
+}
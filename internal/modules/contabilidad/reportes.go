@@ -0,0 +1,504 @@
+// internal/modules/contabilidad/reportes.go
+package contabilidad
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jhvc/backend/internal/exportar"
+)
+
+var ErrRangoReporte = errors.New("rango inválido: desde debe ser anterior o igual a hasta")
+
+// Clases de cuenta según el primer dígito del código agrupador (Anexo 24)
+const (
+	claseActivo     = '1'
+	clasePasivo     = '2'
+	claseCapital    = '3'
+	claseIngresos   = '4'
+	claseCostos     = '5'
+	claseGastos     = '6'
+	claseFinanciero = '7'
+)
+
+const formatoFechaReporte = "2006-01-02"
+
+func claseCuenta(s SaldoCuenta) byte {
+	if s.CodigoAgrupador == "" {
+		return 0
+	}
+	return s.CodigoAgrupador[0]
+}
+
+func esResultados(s SaldoCuenta) bool {
+	clase := claseCuenta(s)
+	return clase >= claseIngresos && clase <= claseFinanciero
+}
+
+// deudor expresa un saldo con signo de naturaleza deudora
+func deudor(s SaldoCuenta, saldo float64) float64 {
+	if s.Naturaleza == NaturalezaAcreedora {
+		return -saldo
+	}
+	return saldo
+}
+
+// rangoReporte interpreta el filtro y calcula el rango comparativo, si se pidió
+func rangoReporte(f FiltroReporte) (desde, hasta time.Time, comparativo *[2]time.Time, err error) {
+	if hasta, err = parseFechaPoliza(f.Hasta); err != nil {
+		return
+	}
+	if f.Desde == "" {
+		desde = time.Date(hasta.Year(), hasta.Month(), 1, 0, 0, 0, 0, time.UTC)
+	} else if desde, err = parseFechaPoliza(f.Desde); err != nil {
+		return
+	}
+	if desde.After(hasta) {
+		err = ErrRangoReporte
+		return
+	}
+
+	switch f.Comparar {
+	case CompararEjercicioAnterior:
+		d, h := desde.AddDate(-1, 0, 0), hasta.AddDate(-1, 0, 0)
+		if esFinDeMes(hasta) {
+			_, h = limitesMes(hasta.Year()-1, int(hasta.Month()))
+		}
+		comparativo = &[2]time.Time{d, h}
+	case CompararPeriodoAnterior:
+		// Meses completos se comparan contra los meses anteriores; cualquier
+		// otro rango, contra el mismo número de días inmediatamente antes
+		h := desde.AddDate(0, 0, -1)
+		var d time.Time
+		if desde.Day() == 1 && esFinDeMes(hasta) {
+			meses := (hasta.Year()-desde.Year())*12 + int(hasta.Month()-desde.Month()) + 1
+			d = desde.AddDate(0, -meses, 0)
+		} else {
+			dias := int(hasta.Sub(desde).Hours()/24) + 1
+			d = h.AddDate(0, 0, -(dias - 1))
+		}
+		comparativo = &[2]time.Time{d, h}
+	}
+	return
+}
+
+func esFinDeMes(t time.Time) bool {
+	return t.AddDate(0, 0, 1).Day() == 1
+}
+
+// nuevoReporte llena el encabezado común de los reportes
+func (s *Service) nuevoReporte(empresaID int, titulo string, desde, hasta time.Time, comparativo *[2]time.Time) (*Reporte, error) {
+	rfc, err := s.repo.GetRFCEmpresa(empresaID)
+	if err != nil {
+		return nil, err
+	}
+	razon, err := s.repo.GetRazonSocial(empresaID)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reporte{
+		Titulo:      titulo,
+		RFC:         rfc,
+		RazonSocial: razon,
+		Desde:       desde.Format(formatoFechaReporte),
+		Hasta:       hasta.Format(formatoFechaReporte),
+		Secciones:   []SeccionReporte{},
+	}
+	if comparativo != nil {
+		r.Comparativo = &RangoReporte{
+			Desde: comparativo[0].Format(formatoFechaReporte),
+			Hasta: comparativo[1].Format(formatoFechaReporte),
+		}
+	}
+	return r, nil
+}
+
+// BalanzaComprobacion muestra saldos iniciales y finales separados en deudor
+// y acreedor, con cargos y abonos del rango; con comparativo agrega el saldo
+// final del otro rango. Las sumas salen de las cuentas de último nivel, así
+// que deudor y acreedor coinciden cuando la contabilidad cuadra.
+func (s *Service) BalanzaComprobacion(empresaID int, f FiltroReporte) (*Reporte, error) {
+	desde, hasta, comparativo, err := rangoReporte(f)
+	if err != nil {
+		return nil, err
+	}
+	reporte, err := s.nuevoReporte(empresaID, "Balanza de comprobación", desde, hasta, comparativo)
+	if err != nil {
+		return nil, err
+	}
+	reporte.Columnas = []string{"Saldo inicial deudor", "Saldo inicial acreedor", "Cargos", "Abonos",
+		"Saldo final deudor", "Saldo final acreedor"}
+
+	actual, err := s.BalanzaRango(empresaID, desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+	var anterior map[int]SaldoCuenta
+	if comparativo != nil {
+		reporte.Columnas = append(reporte.Columnas, "Saldo final deudor "+reporte.Comparativo.Hasta,
+			"Saldo final acreedor "+reporte.Comparativo.Hasta)
+		if anterior, err = s.saldosPorCuenta(empresaID, comparativo[0], comparativo[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	separar := func(c SaldoCuenta, saldo float64) (float64, float64) {
+		d := deudor(c, saldo)
+		if d < 0 {
+			return 0, -d
+		}
+		return d, 0
+	}
+
+	seccion := SeccionReporte{Nombre: "Cuentas", Total: RenglonReporte{Nombre: "Sumas", Importes: make([]float64, len(reporte.Columnas))}}
+	for _, c := range actual {
+		iniD, iniA := separar(c, c.SaldoInicial)
+		finD, finA := separar(c, c.SaldoFinal)
+		importes := []float64{iniD, iniA, c.Cargos, c.Abonos, finD, finA}
+		if anterior != nil {
+			antD, antA := separar(c, anterior[c.CuentaID].SaldoFinal)
+			importes = append(importes, antD, antA)
+		}
+
+		if c.EsHoja {
+			sumar(seccion.Total.Importes, importes)
+		}
+		if incluirRenglon(c, f.Nivel, importes) {
+			seccion.Renglones = append(seccion.Renglones, renglonCuenta(c, importes))
+		}
+	}
+	redondearImportes(seccion.Total.Importes)
+	reporte.Secciones = append(reporte.Secciones, seccion)
+
+	return reporte, nil
+}
+
+// BalanceGeneral presenta activo, pasivo y capital a la fecha hasta. El
+// resultado del ejercicio (desde el 1 de enero) y el de ejercicios anteriores
+// que no se ha traspasado a capital se muestran dentro del capital, así que
+// el activo siempre iguala a pasivo más capital.
+func (s *Service) BalanceGeneral(empresaID int, f FiltroReporte) (*Reporte, error) {
+	desde, hasta, comparativo, err := rangoReporte(f)
+	if err != nil {
+		return nil, err
+	}
+	reporte, err := s.nuevoReporte(empresaID, "Balance general", desde, hasta, comparativo)
+	if err != nil {
+		return nil, err
+	}
+	reporte.Columnas = []string{"Saldo al " + reporte.Hasta}
+
+	cortes := []time.Time{hasta}
+	if comparativo != nil {
+		reporte.Columnas = append(reporte.Columnas, "Saldo al "+reporte.Comparativo.Hasta)
+		cortes = append(cortes, comparativo[1])
+	}
+
+	// Cada corte se calcula desde el inicio de su ejercicio: el saldo inicial
+	// separa el resultado de ejercicios anteriores del resultado del ejercicio
+	var periodos []map[int]SaldoCuenta
+	var cuentas []SaldoCuenta
+	for i, corte := range cortes {
+		saldos, err := s.BalanzaRango(empresaID, time.Date(corte.Year(), 1, 1, 0, 0, 0, 0, time.UTC), corte)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			cuentas = saldos
+		}
+		periodos = append(periodos, indexarSaldos(saldos))
+	}
+
+	columnas := len(cortes)
+	resultadoEjercicio := make([]float64, columnas)
+	resultadoAnterior := make([]float64, columnas)
+	for i, saldos := range periodos {
+		for _, c := range saldos {
+			if c.EsHoja && esResultados(c) {
+				resultadoEjercicio[i] -= deudor(c, c.SaldoFinal) - deudor(c, c.SaldoInicial)
+				resultadoAnterior[i] -= deudor(c, c.SaldoInicial)
+			}
+		}
+	}
+	redondearImportes(resultadoEjercicio)
+	redondearImportes(resultadoAnterior)
+
+	saldoFinal := func(c SaldoCuenta) float64 { return c.SaldoFinal }
+	activo := seccionClase("Activo", claseActivo, cuentas, periodos, saldoFinal, false, f.Nivel)
+	pasivo := seccionClase("Pasivo", clasePasivo, cuentas, periodos, saldoFinal, true, f.Nivel)
+	capital := seccionClase("Capital contable", claseCapital, cuentas, periodos, saldoFinal, true, f.Nivel)
+
+	if !todosCero(resultadoAnterior) {
+		capital.Renglones = append(capital.Renglones, RenglonReporte{Nombre: "Resultado de ejercicios anteriores sin traspasar", Importes: resultadoAnterior})
+		sumar(capital.Total.Importes, resultadoAnterior)
+	}
+	capital.Renglones = append(capital.Renglones, RenglonReporte{Nombre: "Resultado del ejercicio", Importes: resultadoEjercicio})
+	sumar(capital.Total.Importes, resultadoEjercicio)
+	redondearImportes(capital.Total.Importes)
+
+	pasivoCapital := make([]float64, columnas)
+	sumar(pasivoCapital, pasivo.Total.Importes)
+	sumar(pasivoCapital, capital.Total.Importes)
+	redondearImportes(pasivoCapital)
+
+	reporte.Secciones = append(reporte.Secciones, activo, pasivo, capital)
+	reporte.Resumen = []RenglonReporte{{Nombre: "Total pasivo y capital", Importes: pasivoCapital}}
+	return reporte, nil
+}
+
+// EstadoResultados presenta los movimientos del rango en las cuentas de
+// resultados: ingresos en positivo y costos, gastos y financiamiento como
+// cargos netos, con las utilidades intermedias en el resumen
+func (s *Service) EstadoResultados(empresaID int, f FiltroReporte) (*Reporte, error) {
+	desde, hasta, comparativo, err := rangoReporte(f)
+	if err != nil {
+		return nil, err
+	}
+	reporte, err := s.nuevoReporte(empresaID, "Estado de resultados", desde, hasta, comparativo)
+	if err != nil {
+		return nil, err
+	}
+	reporte.Columnas = []string{reporte.Desde + " a " + reporte.Hasta}
+
+	actual, err := s.BalanzaRango(empresaID, desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+	periodos := []map[int]SaldoCuenta{indexarSaldos(actual)}
+	if comparativo != nil {
+		reporte.Columnas = append(reporte.Columnas, reporte.Comparativo.Desde+" a "+reporte.Comparativo.Hasta)
+		anterior, err := s.saldosPorCuenta(empresaID, comparativo[0], comparativo[1])
+		if err != nil {
+			return nil, err
+		}
+		periodos = append(periodos, anterior)
+	}
+
+	// El movimiento del periodo con el signo de la naturaleza de la cuenta
+	movimiento := func(c SaldoCuenta) float64 { return c.SaldoFinal - c.SaldoInicial }
+	ingresos := seccionClase("Ingresos", claseIngresos, actual, periodos, movimiento, true, f.Nivel)
+	costos := seccionClase("Costos", claseCostos, actual, periodos, movimiento, false, f.Nivel)
+	gastos := seccionClase("Gastos", claseGastos, actual, periodos, movimiento, false, f.Nivel)
+	financiero := seccionClase("Resultado integral de financiamiento", claseFinanciero, actual, periodos, movimiento, false, f.Nivel)
+
+	columnas := len(periodos)
+	bruta, operacion, neta := make([]float64, columnas), make([]float64, columnas), make([]float64, columnas)
+	for i := 0; i < columnas; i++ {
+		bruta[i] = redondear(ingresos.Total.Importes[i] - costos.Total.Importes[i])
+		operacion[i] = redondear(bruta[i] - gastos.Total.Importes[i])
+		neta[i] = redondear(operacion[i] - financiero.Total.Importes[i])
+	}
+
+	reporte.Secciones = append(reporte.Secciones, ingresos, costos, gastos, financiero)
+	reporte.Resumen = []RenglonReporte{
+		{Nombre: "Utilidad (pérdida) bruta", Importes: bruta},
+		{Nombre: "Utilidad (pérdida) de operación", Importes: operacion},
+		{Nombre: "Utilidad (pérdida) neta", Importes: neta},
+	}
+	return reporte, nil
+}
+
+// seccionClase arma una sección con las cuentas de una clase. El importe de
+// cada cuenta se expresa en la naturaleza de la sección (acreedora para
+// pasivo, capital e ingresos), así una cuenta complementaria resta. El total
+// sale de las cuentas de último nivel para no contar dos veces.
+func seccionClase(nombre string, clase byte, cuentas []SaldoCuenta, periodos []map[int]SaldoCuenta,
+	valor func(SaldoCuenta) float64, acreedora bool, nivel int) SeccionReporte {
+	seccion := SeccionReporte{
+		Nombre:    nombre,
+		Renglones: []RenglonReporte{},
+		Total:     RenglonReporte{Nombre: "Total " + nombre, Importes: make([]float64, len(periodos))},
+	}
+
+	for _, c := range cuentas {
+		if claseCuenta(c) != clase {
+			continue
+		}
+		importes := make([]float64, len(periodos))
+		for i, saldos := range periodos {
+			saldo, ok := saldos[c.CuentaID]
+			if !ok {
+				continue
+			}
+			importes[i] = deudor(saldo, valor(saldo))
+			if acreedora {
+				importes[i] = -importes[i]
+			}
+			importes[i] = redondear(importes[i])
+		}
+
+		if c.EsHoja {
+			sumar(seccion.Total.Importes, importes)
+		}
+		if incluirRenglon(c, nivel, importes) {
+			seccion.Renglones = append(seccion.Renglones, renglonCuenta(c, importes))
+		}
+	}
+	redondearImportes(seccion.Total.Importes)
+
+	return seccion
+}
+
+func (s *Service) saldosPorCuenta(empresaID int, desde, hasta time.Time) (map[int]SaldoCuenta, error) {
+	saldos, err := s.BalanzaRango(empresaID, desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+	return indexarSaldos(saldos), nil
+}
+
+func indexarSaldos(saldos []SaldoCuenta) map[int]SaldoCuenta {
+	indice := make(map[int]SaldoCuenta, len(saldos))
+	for _, s := range saldos {
+		indice[s.CuentaID] = s
+	}
+	return indice
+}
+
+func renglonCuenta(c SaldoCuenta, importes []float64) RenglonReporte {
+	return RenglonReporte{CuentaID: c.CuentaID, Codigo: c.Codigo, Nombre: c.Nombre, Nivel: c.Nivel, Importes: importes}
+}
+
+// incluirRenglon omite las cuentas más profundas que el nivel pedido y las que no tienen importes
+func incluirRenglon(c SaldoCuenta, nivel int, importes []float64) bool {
+	if nivel > 0 && c.Nivel > nivel {
+		return false
+	}
+	return !todosCero(importes)
+}
+
+func sumar(total, importes []float64) {
+	for i, v := range importes {
+		total[i] += v
+	}
+}
+
+func redondearImportes(importes []float64) {
+	for i, v := range importes {
+		importes[i] = redondear(v)
+	}
+}
+
+func todosCero(importes []float64) bool {
+	for _, v := range importes {
+		if centavos(v) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Auxiliar devuelve los movimientos de una cuenta (y sus subcuentas) en el
+// rango, para revisar las pólizas detrás de un renglón de los reportes
+func (s *Service) Auxiliar(empresaID, cuentaID int, f FiltroReporte) (*Auxiliar, error) {
+	desde, hasta, _, err := rangoReporte(f)
+	if err != nil {
+		return nil, err
+	}
+	cuenta, err := s.GetCuenta(empresaID, cuentaID)
+	if err != nil {
+		return nil, err
+	}
+
+	saldoDeudor, movimientos, err := s.repo.GetAuxiliar(empresaID, cuentaID, desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+
+	signo := 1.0
+	if cuenta.Naturaleza == NaturalezaAcreedora {
+		signo = -1
+	}
+
+	aux := &Auxiliar{
+		Cuenta:       *cuenta,
+		Desde:        desde.Format(formatoFechaReporte),
+		Hasta:        hasta.Format(formatoFechaReporte),
+		SaldoInicial: redondear(signo * saldoDeudor),
+		Movimientos:  []MovimientoAuxiliar{},
+	}
+	saldo := aux.SaldoInicial
+	for _, m := range movimientos {
+		saldo = redondear(saldo + signo*(m.Cargo-m.Abono))
+		m.Saldo = saldo
+		aux.Cargos += m.Cargo
+		aux.Abonos += m.Abono
+		aux.Movimientos = append(aux.Movimientos, m)
+	}
+	aux.Cargos = redondear(aux.Cargos)
+	aux.Abonos = redondear(aux.Abonos)
+	aux.SaldoFinal = saldo
+
+	return aux, nil
+}
+
+// Tabla prepara el reporte para exportarlo a PDF o XLSX
+func (r *Reporte) Tabla() exportar.Tabla {
+	t := exportar.Tabla{
+		Titulo:     r.Titulo,
+		Subtitulos: []string{r.RazonSocial + " (" + r.RFC + ")", "Del " + r.Desde + " al " + r.Hasta},
+		Columnas:   []exportar.Columna{{Titulo: "Cuenta", Ancho: 1.2}, {Titulo: "Nombre", Ancho: 3.5}},
+	}
+	if r.Titulo == "Balance general" {
+		t.Subtitulos[1] = "Al " + r.Hasta
+	}
+	if r.Comparativo != nil {
+		t.Subtitulos = append(t.Subtitulos, "Comparativo: del "+r.Comparativo.Desde+" al "+r.Comparativo.Hasta)
+	}
+	for _, c := range r.Columnas {
+		t.Columnas = append(t.Columnas, exportar.Columna{Titulo: c, Ancho: 1.4})
+	}
+
+	fila := func(codigo, nombre string, importes []float64, negrita bool, sangria int) exportar.Fila {
+		valores := []interface{}{codigo, nombre}
+		for _, v := range importes {
+			valores = append(valores, v)
+		}
+		return exportar.Fila{Valores: valores, Negrita: negrita, Sangria: sangria}
+	}
+
+	for _, s := range r.Secciones {
+		if len(r.Secciones) > 1 {
+			t.Filas = append(t.Filas, exportar.Fila{Valores: []interface{}{"", s.Nombre}, Negrita: true})
+		}
+		for _, g := range s.Renglones {
+			sangria := 0
+			if g.Nivel > 1 {
+				sangria = g.Nivel - 1
+			}
+			t.Filas = append(t.Filas, fila(g.Codigo, g.Nombre, g.Importes, g.Nivel == 1, sangria))
+		}
+		t.Filas = append(t.Filas, fila("", s.Total.Nombre, s.Total.Importes, true, 0))
+	}
+	for _, g := range r.Resumen {
+		t.Filas = append(t.Filas, fila("", g.Nombre, g.Importes, true, 0))
+	}
+
+	return t
+}
+
+// Tabla prepara el auxiliar para exportarlo a PDF o XLSX
+func (a *Auxiliar) Tabla() exportar.Tabla {
+	t := exportar.Tabla{
+		Titulo:     fmt.Sprintf("Auxiliar de %s %s", a.Cuenta.Codigo, a.Cuenta.Nombre),
+		Subtitulos: []string{"Del " + a.Desde + " al " + a.Hasta},
+		Columnas: []exportar.Columna{
+			{Titulo: "Fecha", Ancho: 1}, {Titulo: "Póliza", Ancho: 1}, {Titulo: "Cuenta", Ancho: 1.2},
+			{Titulo: "Concepto", Ancho: 3.5}, {Titulo: "Cargo", Ancho: 1.3}, {Titulo: "Abono", Ancho: 1.3},
+			{Titulo: "Saldo", Ancho: 1.3},
+		},
+	}
+
+	t.Filas = append(t.Filas, exportar.Fila{Valores: []interface{}{"", "", "", "Saldo inicial", "", "", a.SaldoInicial}, Negrita: true})
+	for _, m := range a.Movimientos {
+		poliza := fmt.Sprintf("%s-%d", letrasTipoPoliza[m.Tipo], m.Numero)
+		t.Filas = append(t.Filas, exportar.Fila{Valores: []interface{}{
+			m.Fecha.Format(formatoFechaReporte), poliza, m.CuentaCodigo, m.Concepto, m.Cargo, m.Abono, m.Saldo,
+		}})
+	}
+	t.Filas = append(t.Filas, exportar.Fila{Valores: []interface{}{"", "", "", "Totales", a.Cargos, a.Abonos, a.SaldoFinal}, Negrita: true})
+
+	return t
+}
@@ -0,0 +1,933 @@
+// internal/modules/contabilidad/repository.go
+package contabilidad
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const columnasCuenta = `c.id, c.empresa_id, c.codigo, c.nombre, c.naturaleza, c.nivel, c.padre_id,
+        c.codigo_agrupador, c.is_active, c.created_at, c.updated_at,
+        NOT EXISTS (SELECT 1 FROM cuentas_contables h WHERE h.padre_id = c.id) AS es_hoja`
+
+func (r *Repository) CrearCuenta(c *Cuenta) (int, error) {
+	var id int
+	err := r.db.QueryRow(`
+        INSERT INTO cuentas_contables (empresa_id, codigo, nombre, naturaleza, nivel, padre_id, codigo_agrupador)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id
+    `, c.EmpresaID, c.Codigo, c.Nombre, c.Naturaleza, c.Nivel, c.PadreID, c.CodigoAgrupador).Scan(&id)
+	return id, err
+}
+
+// ImportarPlantilla inserta el catálogo base en una sola transacción; las
+// cuentas vienen ordenadas de forma que cada padre precede a sus subcuentas
+func (r *Repository) ImportarPlantilla(empresaID int, cuentas []cuentaPlantilla) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	type insertada struct{ id, nivel int }
+	porCodigo := make(map[string]insertada, len(cuentas))
+
+	for _, c := range cuentas {
+		var padreID *int
+		nivel := 1
+		if padre, ok := porCodigo[c.padre]; ok {
+			padreID = &padre.id
+			nivel = padre.nivel + 1
+		}
+
+		var id int
+		err := tx.QueryRow(`
+            INSERT INTO cuentas_contables (empresa_id, codigo, nombre, naturaleza, nivel, padre_id, codigo_agrupador)
+            VALUES ($1, $2, $3, $4, $5, $6, $7)
+            RETURNING id
+        `, empresaID, c.codigo, c.nombre, c.naturaleza, nivel, padreID, c.agrupador).Scan(&id)
+		if err != nil {
+			return 0, err
+		}
+		porCodigo[c.codigo] = insertada{id: id, nivel: nivel}
+	}
+
+	return len(cuentas), tx.Commit()
+}
+
+func (r *Repository) ContarCuentas(empresaID int) (int, error) {
+	var total int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM cuentas_contables WHERE empresa_id = $1`, empresaID).Scan(&total)
+	return total, err
+}
+
+func (r *Repository) GetCuentas(empresaID int) ([]Cuenta, error) {
+	rows, err := r.db.Query(`
+        SELECT `+columnasCuenta+`
+        FROM cuentas_contables c
+        WHERE c.empresa_id = $1
+        ORDER BY c.codigo
+    `, empresaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cuentas []Cuenta
+	for rows.Next() {
+		c, err := scanCuenta(rows)
+		if err != nil {
+			continue
+		}
+		cuentas = append(cuentas, *c)
+	}
+
+	return cuentas, nil
+}
+
+func (r *Repository) GetCuenta(empresaID, id int) (*Cuenta, error) {
+	row := r.db.QueryRow(`
+        SELECT `+columnasCuenta+`
+        FROM cuentas_contables c
+        WHERE c.empresa_id = $1 AND c.id = $2
+    `, empresaID, id)
+	return scanCuenta(row)
+}
+
+func (r *Repository) GetCuentaPorCodigo(empresaID int, codigo string) (*Cuenta, error) {
+	row := r.db.QueryRow(`
+        SELECT `+columnasCuenta+`
+        FROM cuentas_contables c
+        WHERE c.empresa_id = $1 AND c.codigo = $2
+    `, empresaID, codigo)
+	return scanCuenta(row)
+}
+
+func (r *Repository) ActualizarCuenta(c *Cuenta) error {
+	_, err := r.db.Exec(`
+        UPDATE cuentas_contables
+        SET nombre = $1, naturaleza = $2, codigo_agrupador = $3, is_active = $4, updated_at = CURRENT_TIMESTAMP
+        WHERE empresa_id = $5 AND id = $6
+    `, c.Nombre, c.Naturaleza, c.CodigoAgrupador, c.IsActive, c.EmpresaID, c.ID)
+	return err
+}
+
+func (r *Repository) EliminarCuenta(empresaID, id int) error {
+	_, err := r.db.Exec(`DELETE FROM cuentas_contables WHERE empresa_id = $1 AND id = $2`, empresaID, id)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCuenta(row rowScanner) (*Cuenta, error) {
+	var c Cuenta
+	var padreID sql.NullInt64
+	err := row.Scan(&c.ID, &c.EmpresaID, &c.Codigo, &c.Nombre, &c.Naturaleza, &c.Nivel, &padreID,
+		&c.CodigoAgrupador, &c.IsActive, &c.CreatedAt, &c.UpdatedAt, &c.EsHoja)
+	if err != nil {
+		return nil, err
+	}
+	if padreID.Valid {
+		id := int(padreID.Int64)
+		c.PadreID = &id
+	}
+	return &c, nil
+}
+
+// TieneMovimientos indica si alguna póliza usa la cuenta
+func (r *Repository) TieneMovimientos(cuentaID int) (bool, error) {
+	var existe bool
+	err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM poliza_movimientos WHERE cuenta_id = $1)`, cuentaID).Scan(&existe)
+	return existe, err
+}
+
+// periodoCerrado consulta dentro de la transacción si el mes de la fecha está cerrado
+func periodoCerrado(tx *sql.Tx, empresaID int, fecha time.Time) (bool, error) {
+	var cerrado bool
+	err := tx.QueryRow(`
+        SELECT cerrado FROM periodos_contables
+        WHERE empresa_id = $1 AND ejercicio = $2 AND mes = $3
+    `, empresaID, fecha.Year(), int(fecha.Month())).Scan(&cerrado)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return cerrado, err
+}
+
+func validarPeriodoAbierto(tx *sql.Tx, empresaID int, fechas ...time.Time) error {
+	for _, f := range fechas {
+		cerrado, err := periodoCerrado(tx, empresaID, f)
+		if err != nil {
+			return err
+		}
+		if cerrado {
+			return fmt.Errorf("%04d-%02d: %w", f.Year(), int(f.Month()), ErrPeriodoCerrado)
+		}
+	}
+	return nil
+}
+
+func insertarMovimientos(tx *sql.Tx, polizaID int, movimientos []Movimiento) error {
+	for i, m := range movimientos {
+		_, err := tx.Exec(`
+            INSERT INTO poliza_movimientos (poliza_id, orden, cuenta_id, concepto, cargo, abono, uuid)
+            VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+        `, polizaID, i+1, m.CuentaID, m.Concepto, m.Cargo, m.Abono, m.UUID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bloquearPoliza toma la póliza para modificarla dentro de la transacción
+func bloquearPoliza(tx *sql.Tx, empresaID, id int) (estado string, fecha time.Time, revertidaPor sql.NullInt64, err error) {
+	err = tx.QueryRow(`
+        SELECT estado, fecha, revertida_por FROM polizas
+        WHERE empresa_id = $1 AND id = $2
+        FOR UPDATE
+    `, empresaID, id).Scan(&estado, &fecha, &revertidaPor)
+	if err == sql.ErrNoRows {
+		err = ErrPolizaNoEncontrada
+	}
+	return estado, fecha, revertidaPor, err
+}
+
+// bloquearEmpresa serializa las escrituras contables de una empresa: así dos
+// registros no toman el mismo número y nadie escribe en un mes que se está cerrando
+func bloquearEmpresa(tx *sql.Tx, empresaID int) error {
+	_, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, empresaID)
+	return err
+}
+
+// siguienteNumero asigna el consecutivo por empresa, tipo y mes
+func siguienteNumero(tx *sql.Tx, empresaID int, tipo string, fecha time.Time) (int, error) {
+	var numero int
+	err := tx.QueryRow(`
+        SELECT COALESCE(MAX(numero), 0) + 1 FROM polizas
+        WHERE empresa_id = $1 AND tipo = $2
+          AND EXTRACT(YEAR FROM fecha) = $3 AND EXTRACT(MONTH FROM fecha) = $4
+    `, empresaID, tipo, fecha.Year(), int(fecha.Month())).Scan(&numero)
+	return numero, err
+}
+
+// CrearPoliza guarda una póliza en borrador con sus movimientos
+func (r *Repository) CrearPoliza(p *Poliza) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := bloquearEmpresa(tx, p.EmpresaID); err != nil {
+		return 0, err
+	}
+
+	if err := validarPeriodoAbierto(tx, p.EmpresaID, p.Fecha); err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = tx.QueryRow(`
+        INSERT INTO polizas (empresa_id, tipo, fecha, concepto, estado, created_by, cfdi_uuid)
+        VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+        RETURNING id
+    `, p.EmpresaID, p.Tipo, p.Fecha, p.Concepto, EstadoBorrador, p.CreatedBy, p.CFDIUUID).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := insertarMovimientos(tx, id, p.Movimientos); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// ActualizarPoliza reemplaza encabezado y movimientos de un borrador
+func (r *Repository) ActualizarPoliza(p *Poliza) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := bloquearEmpresa(tx, p.EmpresaID); err != nil {
+		return err
+	}
+
+	estado, fechaAnterior, _, err := bloquearPoliza(tx, p.EmpresaID, p.ID)
+	if err != nil {
+		return err
+	}
+	if estado != EstadoBorrador {
+		return ErrPolizaRegistrada
+	}
+	if err := validarPeriodoAbierto(tx, p.EmpresaID, fechaAnterior, p.Fecha); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+        UPDATE polizas SET tipo = $1, fecha = $2, concepto = $3, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $4
+    `, p.Tipo, p.Fecha, p.Concepto, p.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM poliza_movimientos WHERE poliza_id = $1`, p.ID); err != nil {
+		return err
+	}
+	if err := insertarMovimientos(tx, p.ID, p.Movimientos); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// EliminarPoliza sólo descarta borradores
+func (r *Repository) EliminarPoliza(empresaID, id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := bloquearEmpresa(tx, empresaID); err != nil {
+		return err
+	}
+
+	estado, fecha, _, err := bloquearPoliza(tx, empresaID, id)
+	if err != nil {
+		return err
+	}
+	if estado != EstadoBorrador {
+		return ErrPolizaRegistrada
+	}
+	if err := validarPeriodoAbierto(tx, empresaID, fecha); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM polizas WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RegistrarPoliza vuelve a comprobar en la transacción que la póliza cuadre y
+// que todas sus cuentas sigan siendo afectables, y le asigna su número
+func (r *Repository) RegistrarPoliza(empresaID, id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := bloquearEmpresa(tx, empresaID); err != nil {
+		return err
+	}
+
+	if err := registrarEnTx(tx, empresaID, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func registrarEnTx(tx *sql.Tx, empresaID, id int) error {
+	estado, fecha, _, err := bloquearPoliza(tx, empresaID, id)
+	if err != nil {
+		return err
+	}
+	if estado != EstadoBorrador {
+		return ErrPolizaRegistrada
+	}
+	if err := validarPeriodoAbierto(tx, empresaID, fecha); err != nil {
+		return err
+	}
+
+	var cargos, abonos float64
+	var lineas, noAfectables int
+	err = tx.QueryRow(`
+        SELECT COALESCE(SUM(m.cargo), 0), COALESCE(SUM(m.abono), 0), COUNT(*),
+               COUNT(*) FILTER (WHERE NOT c.is_active
+                   OR EXISTS (SELECT 1 FROM cuentas_contables h WHERE h.padre_id = c.id))
+        FROM poliza_movimientos m
+        JOIN cuentas_contables c ON c.id = m.cuenta_id
+        WHERE m.poliza_id = $1
+    `, id).Scan(&cargos, &abonos, &lineas, &noAfectables)
+	if err != nil {
+		return err
+	}
+	if lineas < 2 {
+		return ErrPolizaSinMovimientos
+	}
+	if noAfectables > 0 {
+		return ErrCuentaNoAfectable
+	}
+	if centavos(cargos) != centavos(abonos) {
+		return fmt.Errorf("%w: cargos %.2f, abonos %.2f", ErrPolizaDescuadrada, cargos, abonos)
+	}
+
+	var tipo string
+	if err := tx.QueryRow(`SELECT tipo FROM polizas WHERE id = $1`, id).Scan(&tipo); err != nil {
+		return err
+	}
+	numero, err := siguienteNumero(tx, empresaID, tipo, fecha)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+        UPDATE polizas
+        SET estado = $1, numero = $2, registrada_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $3
+    `, EstadoRegistrada, numero, id)
+	return err
+}
+
+// RevertirPoliza registra una póliza con cargos y abonos invertidos y la
+// enlaza con la original
+func (r *Repository) RevertirPoliza(empresaID, id int, fecha time.Time, userID int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := bloquearEmpresa(tx, empresaID); err != nil {
+		return 0, err
+	}
+
+	estado, _, revertidaPor, err := bloquearPoliza(tx, empresaID, id)
+	if err != nil {
+		return 0, err
+	}
+	if estado != EstadoRegistrada {
+		return 0, ErrPolizaNoRegistrada
+	}
+	if revertidaPor.Valid {
+		return 0, ErrPolizaRevertida
+	}
+
+	var tipo, concepto string
+	var numero int
+	var fechaOriginal time.Time
+	err = tx.QueryRow(`SELECT tipo, numero, fecha, concepto FROM polizas WHERE id = $1`, id).
+		Scan(&tipo, &numero, &fechaOriginal, &concepto)
+	if err != nil {
+		return 0, err
+	}
+
+	var nuevaID int
+	err = tx.QueryRow(`
+        INSERT INTO polizas (empresa_id, tipo, fecha, concepto, estado, revierte_a, created_by)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id
+    `, empresaID, tipo, fecha, fmt.Sprintf("Reversión de póliza %s %d del %s: %s",
+		tipo, numero, fechaOriginal.Format("2006-01-02"), concepto), EstadoBorrador, id, userID).Scan(&nuevaID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO poliza_movimientos (poliza_id, orden, cuenta_id, concepto, cargo, abono, uuid)
+        SELECT $1::int, orden, cuenta_id, concepto, abono, cargo, uuid
+        FROM poliza_movimientos WHERE poliza_id = $2
+    `, nuevaID, id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := registrarEnTx(tx, empresaID, nuevaID); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`UPDATE polizas SET revertida_por = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, nuevaID, id)
+	if err != nil {
+		return 0, err
+	}
+
+	return nuevaID, tx.Commit()
+}
+
+const columnasPoliza = `p.id, p.empresa_id, p.tipo, COALESCE(p.numero, 0), p.fecha, p.concepto, p.estado,
+        p.revierte_a, p.revertida_por, p.created_by, p.registrada_at, p.created_at, p.updated_at,
+        COALESCE(p.cfdi_uuid, ''),
+        COALESCE((SELECT SUM(cargo) FROM poliza_movimientos WHERE poliza_id = p.id), 0),
+        COALESCE((SELECT SUM(abono) FROM poliza_movimientos WHERE poliza_id = p.id), 0)`
+
+func (r *Repository) GetPolizas(empresaID int, f FiltroPolizas) ([]Poliza, error) {
+	query := `SELECT ` + columnasPoliza + ` FROM polizas p WHERE p.empresa_id = $1`
+	args := []interface{}{empresaID}
+
+	if f.Ejercicio > 0 {
+		args = append(args, f.Ejercicio)
+		query += fmt.Sprintf(" AND EXTRACT(YEAR FROM p.fecha) = $%d", len(args))
+	}
+	if f.Mes > 0 {
+		args = append(args, f.Mes)
+		query += fmt.Sprintf(" AND EXTRACT(MONTH FROM p.fecha) = $%d", len(args))
+	}
+	if f.Tipo != "" {
+		args = append(args, f.Tipo)
+		query += fmt.Sprintf(" AND p.tipo = $%d", len(args))
+	}
+	if f.Estado != "" {
+		args = append(args, f.Estado)
+		query += fmt.Sprintf(" AND p.estado = $%d", len(args))
+	}
+	query += " ORDER BY p.fecha, p.tipo, p.numero, p.id"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var polizas []Poliza
+	for rows.Next() {
+		p, err := scanPoliza(rows)
+		if err != nil {
+			continue
+		}
+		polizas = append(polizas, *p)
+	}
+
+	return polizas, nil
+}
+
+func (r *Repository) GetPoliza(empresaID, id int) (*Poliza, error) {
+	row := r.db.QueryRow(`SELECT `+columnasPoliza+` FROM polizas p WHERE p.empresa_id = $1 AND p.id = $2`, empresaID, id)
+	p, err := scanPoliza(row)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Movimientos, err = r.GetMovimientos(id)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *Repository) GetMovimientos(polizaID int) ([]Movimiento, error) {
+	rows, err := r.db.Query(`
+        SELECT m.id, m.poliza_id, m.cuenta_id, c.codigo, c.nombre, m.concepto, m.cargo, m.abono, COALESCE(m.uuid, '')
+        FROM poliza_movimientos m
+        JOIN cuentas_contables c ON c.id = m.cuenta_id
+        WHERE m.poliza_id = $1
+        ORDER BY m.orden
+    `, polizaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movimientos []Movimiento
+	for rows.Next() {
+		var m Movimiento
+		err := rows.Scan(&m.ID, &m.PolizaID, &m.CuentaID, &m.CuentaCodigo, &m.CuentaNombre, &m.Concepto,
+			&m.Cargo, &m.Abono, &m.UUID)
+		if err != nil {
+			continue
+		}
+		movimientos = append(movimientos, m)
+	}
+
+	return movimientos, nil
+}
+
+func scanPoliza(row rowScanner) (*Poliza, error) {
+	var p Poliza
+	var revierteA, revertidaPor sql.NullInt64
+	var registradaAt sql.NullTime
+	err := row.Scan(&p.ID, &p.EmpresaID, &p.Tipo, &p.Numero, &p.Fecha, &p.Concepto, &p.Estado,
+		&revierteA, &revertidaPor, &p.CreatedBy, &registradaAt, &p.CreatedAt, &p.UpdatedAt,
+		&p.CFDIUUID, &p.TotalCargos, &p.TotalAbonos)
+	if err != nil {
+		return nil, err
+	}
+	if revierteA.Valid {
+		id := int(revierteA.Int64)
+		p.RevierteA = &id
+	}
+	if revertidaPor.Valid {
+		id := int(revertidaPor.Int64)
+		p.RevertidaPor = &id
+	}
+	if registradaAt.Valid {
+		p.RegistradaAt = &registradaAt.Time
+	}
+	return &p, nil
+}
+
+func (r *Repository) GetPeriodos(empresaID, ejercicio int) ([]Periodo, error) {
+	rows, err := r.db.Query(`
+        SELECT empresa_id, ejercicio, mes, cerrado, cerrado_at, cerrado_por
+        FROM periodos_contables
+        WHERE empresa_id = $1 AND ejercicio = $2
+        ORDER BY mes
+    `, empresaID, ejercicio)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var periodos []Periodo
+	for rows.Next() {
+		var p Periodo
+		var cerradoAt sql.NullTime
+		var cerradoPor sql.NullInt64
+		if err := rows.Scan(&p.EmpresaID, &p.Ejercicio, &p.Mes, &p.Cerrado, &cerradoAt, &cerradoPor); err != nil {
+			continue
+		}
+		if cerradoAt.Valid {
+			p.CerradoAt = &cerradoAt.Time
+		}
+		if cerradoPor.Valid {
+			id := int(cerradoPor.Int64)
+			p.CerradoPor = &id
+		}
+		periodos = append(periodos, p)
+	}
+
+	return periodos, nil
+}
+
+// CerrarPeriodo cierra el mes si no quedan borradores pendientes en él
+func (r *Repository) CerrarPeriodo(empresaID, ejercicio, mes, userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := bloquearEmpresa(tx, empresaID); err != nil {
+		return err
+	}
+
+	var borradores int
+	err = tx.QueryRow(`
+        SELECT COUNT(*) FROM polizas
+        WHERE empresa_id = $1 AND estado = $2
+          AND EXTRACT(YEAR FROM fecha) = $3 AND EXTRACT(MONTH FROM fecha) = $4
+    `, empresaID, EstadoBorrador, ejercicio, mes).Scan(&borradores)
+	if err != nil {
+		return err
+	}
+	if borradores > 0 {
+		return fmt.Errorf("%w (%d)", ErrPeriodoConBorradores, borradores)
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO periodos_contables (empresa_id, ejercicio, mes, cerrado, cerrado_at, cerrado_por)
+        VALUES ($1, $2, $3, true, CURRENT_TIMESTAMP, $4)
+        ON CONFLICT (empresa_id, ejercicio, mes) DO UPDATE
+        SET cerrado = true, cerrado_at = CURRENT_TIMESTAMP, cerrado_por = EXCLUDED.cerrado_por
+    `, empresaID, ejercicio, mes, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) ReabrirPeriodo(empresaID, ejercicio, mes int) error {
+	_, err := r.db.Exec(`
+        UPDATE periodos_contables SET cerrado = false, cerrado_at = NULL, cerrado_por = NULL
+        WHERE empresa_id = $1 AND ejercicio = $2 AND mes = $3
+    `, empresaID, ejercicio, mes)
+	return err
+}
+
+// acumulado son los cargos y abonos de una cuenta antes y dentro del periodo
+type acumulado struct {
+	cargosPrevios, abonosPrevios, cargos, abonos float64
+}
+
+// GetAcumulados suma los movimientos registrados por cuenta de último nivel:
+// antes de `desde` (saldo inicial) y entre `desde` y `hasta` inclusive
+func (r *Repository) GetAcumulados(empresaID int, desde, hasta time.Time) (map[int]acumulado, error) {
+	rows, err := r.db.Query(`
+        SELECT m.cuenta_id,
+               COALESCE(SUM(m.cargo) FILTER (WHERE p.fecha < $2), 0),
+               COALESCE(SUM(m.abono) FILTER (WHERE p.fecha < $2), 0),
+               COALESCE(SUM(m.cargo) FILTER (WHERE p.fecha >= $2), 0),
+               COALESCE(SUM(m.abono) FILTER (WHERE p.fecha >= $2), 0)
+        FROM poliza_movimientos m
+        JOIN polizas p ON p.id = m.poliza_id
+        WHERE p.empresa_id = $1 AND p.estado = $4 AND p.fecha <= $3
+        GROUP BY m.cuenta_id
+    `, empresaID, desde, hasta, EstadoRegistrada)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	acumulados := make(map[int]acumulado)
+	for rows.Next() {
+		var id int
+		var a acumulado
+		if err := rows.Scan(&id, &a.cargosPrevios, &a.abonosPrevios, &a.cargos, &a.abonos); err != nil {
+			continue
+		}
+		acumulados[id] = a
+	}
+
+	return acumulados, nil
+}
+
+func (r *Repository) GetRFCEmpresa(empresaID int) (string, error) {
+	var rfc string
+	err := r.db.QueryRow(`SELECT rfc FROM empresas WHERE id = $1`, empresaID).Scan(&rfc)
+	return rfc, err
+}
+
+// comprobanteNacional son los datos del CFDI que pide el nodo CompNal
+type comprobanteNacional struct {
+	emisorRFC, receptorRFC, moneda string
+	total, tipoCambio              float64
+}
+
+// GetComprobante busca el CFDI entre los de la empresa
+func (r *Repository) GetComprobante(empresaID int, uuid string) (*comprobanteNacional, error) {
+	var c comprobanteNacional
+	err := r.db.QueryRow(`
+        SELECT c.emisor_rfc, c.receptor_rfc, c.moneda, c.total, c.tipo_cambio
+        FROM cfdis c
+        WHERE c.empresa_id = $1 AND c.uuid = $2
+    `, empresaID, uuid).Scan(&c.emisorRFC, &c.receptorRFC, &c.moneda, &c.total, &c.tipoCambio)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+const columnasConfiguracion = `cuenta_clientes_id, cuenta_proveedores_id, cuenta_ingresos_id, cuenta_gastos_id,
+        cuenta_iva_trasladado_id, cuenta_iva_acreditable_id, cuenta_iva_retenido_por_pagar_id,
+        cuenta_isr_retenido_por_pagar_id, cuenta_iva_retenido_a_favor_id, cuenta_isr_retenido_a_favor_id`
+
+// cuentasConfiguracion expone los campos de la configuración en el orden de columnasConfiguracion
+func cuentasConfiguracion(c *ConfiguracionPolizas) []**int {
+	return []**int{
+		&c.CuentaClientesID, &c.CuentaProveedoresID, &c.CuentaIngresosID, &c.CuentaGastosID,
+		&c.CuentaIVATrasladadoID, &c.CuentaIVAAcreditableID, &c.CuentaIVARetenidoPorPagarID,
+		&c.CuentaISRRetenidoPorPagarID, &c.CuentaIVARetenidoAFavorID, &c.CuentaISRRetenidoAFavorID,
+	}
+}
+
+// GetConfiguracionPolizas devuelve la configuración; vacía si no se ha guardado
+func (r *Repository) GetConfiguracionPolizas(empresaID int) (*ConfiguracionPolizas, error) {
+	conf := &ConfiguracionPolizas{EmpresaID: empresaID}
+	campos := cuentasConfiguracion(conf)
+	valores := make([]sql.NullInt64, len(campos))
+
+	destinos := make([]interface{}, 0, len(campos)+1)
+	for i := range valores {
+		destinos = append(destinos, &valores[i])
+	}
+	destinos = append(destinos, &conf.UpdatedAt)
+
+	err := r.db.QueryRow(`SELECT `+columnasConfiguracion+`, updated_at
+        FROM configuracion_polizas WHERE empresa_id = $1`, empresaID).Scan(destinos...)
+	if err == sql.ErrNoRows {
+		return conf, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range valores {
+		if v.Valid {
+			id := int(v.Int64)
+			*campos[i] = &id
+		}
+	}
+	return conf, nil
+}
+
+func (r *Repository) GuardarConfiguracionPolizas(conf *ConfiguracionPolizas) error {
+	args := []interface{}{conf.EmpresaID}
+	for _, campo := range cuentasConfiguracion(conf) {
+		args = append(args, *campo)
+	}
+
+	_, err := r.db.Exec(`
+        INSERT INTO configuracion_polizas (empresa_id, `+columnasConfiguracion+`)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+        ON CONFLICT (empresa_id) DO UPDATE SET
+            cuenta_clientes_id = EXCLUDED.cuenta_clientes_id,
+            cuenta_proveedores_id = EXCLUDED.cuenta_proveedores_id,
+            cuenta_ingresos_id = EXCLUDED.cuenta_ingresos_id,
+            cuenta_gastos_id = EXCLUDED.cuenta_gastos_id,
+            cuenta_iva_trasladado_id = EXCLUDED.cuenta_iva_trasladado_id,
+            cuenta_iva_acreditable_id = EXCLUDED.cuenta_iva_acreditable_id,
+            cuenta_iva_retenido_por_pagar_id = EXCLUDED.cuenta_iva_retenido_por_pagar_id,
+            cuenta_isr_retenido_por_pagar_id = EXCLUDED.cuenta_isr_retenido_por_pagar_id,
+            cuenta_iva_retenido_a_favor_id = EXCLUDED.cuenta_iva_retenido_a_favor_id,
+            cuenta_isr_retenido_a_favor_id = EXCLUDED.cuenta_isr_retenido_a_favor_id,
+            updated_at = CURRENT_TIMESTAMP
+    `, args...)
+	return err
+}
+
+const columnasRegla = `g.id, g.empresa_id, g.nombre, g.prioridad, g.aplica_a, g.rfc, g.clave_prod_serv, g.uso_cfdi,
+        g.cuenta_id, c.codigo, c.nombre, g.is_active, g.created_at, g.updated_at`
+
+func (r *Repository) CrearRegla(g *ReglaPoliza) (int, error) {
+	var id int
+	err := r.db.QueryRow(`
+        INSERT INTO reglas_poliza (empresa_id, nombre, prioridad, aplica_a, rfc, clave_prod_serv, uso_cfdi, cuenta_id, is_active)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        RETURNING id
+    `, g.EmpresaID, g.Nombre, g.Prioridad, g.AplicaA, g.RFC, g.ClaveProdServ, g.UsoCFDI, g.CuentaID, g.IsActive).Scan(&id)
+	return id, err
+}
+
+// GetReglas devuelve las reglas en el orden en que se evalúan
+func (r *Repository) GetReglas(empresaID int) ([]ReglaPoliza, error) {
+	rows, err := r.db.Query(`
+        SELECT `+columnasRegla+`
+        FROM reglas_poliza g
+        JOIN cuentas_contables c ON c.id = g.cuenta_id
+        WHERE g.empresa_id = $1
+        ORDER BY g.prioridad, g.id
+    `, empresaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reglas []ReglaPoliza
+	for rows.Next() {
+		g, err := scanRegla(rows)
+		if err != nil {
+			continue
+		}
+		reglas = append(reglas, *g)
+	}
+
+	return reglas, nil
+}
+
+func (r *Repository) GetRegla(empresaID, id int) (*ReglaPoliza, error) {
+	row := r.db.QueryRow(`
+        SELECT `+columnasRegla+`
+        FROM reglas_poliza g
+        JOIN cuentas_contables c ON c.id = g.cuenta_id
+        WHERE g.empresa_id = $1 AND g.id = $2
+    `, empresaID, id)
+	return scanRegla(row)
+}
+
+func (r *Repository) ActualizarRegla(g *ReglaPoliza) error {
+	_, err := r.db.Exec(`
+        UPDATE reglas_poliza
+        SET nombre = $1, prioridad = $2, aplica_a = $3, rfc = $4, clave_prod_serv = $5, uso_cfdi = $6,
+            cuenta_id = $7, is_active = $8, updated_at = CURRENT_TIMESTAMP
+        WHERE empresa_id = $9 AND id = $10
+    `, g.Nombre, g.Prioridad, g.AplicaA, g.RFC, g.ClaveProdServ, g.UsoCFDI, g.CuentaID, g.IsActive, g.EmpresaID, g.ID)
+	return err
+}
+
+func (r *Repository) EliminarRegla(empresaID, id int) error {
+	_, err := r.db.Exec(`DELETE FROM reglas_poliza WHERE empresa_id = $1 AND id = $2`, empresaID, id)
+	return err
+}
+
+func scanRegla(row rowScanner) (*ReglaPoliza, error) {
+	var g ReglaPoliza
+	err := row.Scan(&g.ID, &g.EmpresaID, &g.Nombre, &g.Prioridad, &g.AplicaA, &g.RFC, &g.ClaveProdServ, &g.UsoCFDI,
+		&g.CuentaID, &g.CuentaCodigo, &g.CuentaNombre, &g.IsActive, &g.CreatedAt, &g.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// UUIDsContabilizados indica cuáles CFDI ya tienen póliza en la empresa
+func (r *Repository) UUIDsContabilizados(empresaID int) (map[string]bool, error) {
+	rows, err := r.db.Query(`SELECT cfdi_uuid FROM polizas WHERE empresa_id = $1 AND cfdi_uuid IS NOT NULL`, empresaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uuids := make(map[string]bool)
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			continue
+		}
+		uuids[uuid] = true
+	}
+
+	return uuids, nil
+}
+
+func (r *Repository) GetRazonSocial(empresaID int) (string, error) {
+	var razon string
+	err := r.db.QueryRow(`SELECT razon_social FROM empresas WHERE id = $1`, empresaID).Scan(&razon)
+	return razon, err
+}
+
+// subcuentasRecursivas es la cuenta $2 de la empresa $1 con todas sus subcuentas
+const subcuentasRecursivas = `WITH RECURSIVE sub AS (
+            SELECT id FROM cuentas_contables WHERE empresa_id = $1 AND id = $2
+            UNION ALL
+            SELECT c.id FROM cuentas_contables c JOIN sub ON c.padre_id = sub.id
+        )`
+
+// GetAuxiliar devuelve el saldo deudor (cargos - abonos) de la cuenta antes
+// de `desde` y sus movimientos registrados entre `desde` y `hasta`
+func (r *Repository) GetAuxiliar(empresaID, cuentaID int, desde, hasta time.Time) (float64, []MovimientoAuxiliar, error) {
+	var saldo float64
+	err := r.db.QueryRow(subcuentasRecursivas+`
+        SELECT COALESCE(SUM(m.cargo - m.abono), 0)
+        FROM poliza_movimientos m
+        JOIN polizas p ON p.id = m.poliza_id
+        WHERE m.cuenta_id IN (SELECT id FROM sub)
+          AND p.empresa_id = $1 AND p.estado = $3 AND p.fecha < $4
+    `, empresaID, cuentaID, EstadoRegistrada, desde).Scan(&saldo)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rows, err := r.db.Query(subcuentasRecursivas+`
+        SELECT p.id, p.tipo, COALESCE(p.numero, 0), p.fecha, c.codigo, m.concepto, m.cargo, m.abono, COALESCE(m.uuid, '')
+        FROM poliza_movimientos m
+        JOIN polizas p ON p.id = m.poliza_id
+        JOIN cuentas_contables c ON c.id = m.cuenta_id
+        WHERE m.cuenta_id IN (SELECT id FROM sub)
+          AND p.empresa_id = $1 AND p.estado = $3 AND p.fecha >= $4 AND p.fecha <= $5
+        ORDER BY p.fecha, p.tipo, p.numero, m.orden
+    `, empresaID, cuentaID, EstadoRegistrada, desde, hasta)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var movimientos []MovimientoAuxiliar
+	for rows.Next() {
+		var m MovimientoAuxiliar
+		err := rows.Scan(&m.PolizaID, &m.Tipo, &m.Numero, &m.Fecha, &m.CuentaCodigo, &m.Concepto,
+			&m.Cargo, &m.Abono, &m.UUID)
+		if err != nil {
+			continue
+		}
+		movimientos = append(movimientos, m)
+	}
+
+	return saldo, movimientos, nil
+}
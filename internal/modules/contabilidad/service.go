@@ -0,0 +1,396 @@
+// internal/modules/contabilidad/service.go
+package contabilidad
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jhvc/backend/internal/modules/catalogos"
+	"github.com/jhvc/backend/internal/modules/cfdi"
+)
+
+var (
+	ErrCuentaNoEncontrada   = errors.New("cuenta no encontrada")
+	ErrCodigoDuplicado      = errors.New("ya existe una cuenta con ese código")
+	ErrCuentaConSubcuentas  = errors.New("la cuenta tiene subcuentas")
+	ErrCuentaNoAfectable    = errors.New("sólo las cuentas de último nivel reciben movimientos")
+	ErrCuentaInactiva       = errors.New("la cuenta está inactiva")
+	ErrNaturalezaRequerida  = errors.New("las cuentas de primer nivel requieren naturaleza")
+	ErrCatalogoExistente    = errors.New("la empresa ya tiene catálogo de cuentas")
+	ErrCodigoAgrupador      = errors.New("código agrupador inválido: use el formato 101 o 101.01 del Anexo 24")
+	ErrCuentaConMovimientos = errors.New("la cuenta ya tiene movimientos; desactívela en lugar de modificar su estructura")
+
+	ErrPolizaNoEncontrada   = errors.New("póliza no encontrada")
+	ErrPolizaRegistrada     = errors.New("la póliza ya está registrada; corríjala con una reversión")
+	ErrPolizaNoRegistrada   = errors.New("sólo se pueden revertir pólizas registradas")
+	ErrPolizaRevertida      = errors.New("la póliza ya fue revertida")
+	ErrPolizaSinMovimientos = errors.New("la póliza requiere al menos dos movimientos")
+	ErrPolizaDescuadrada    = errors.New("la póliza no cuadra")
+	ErrMovimientoInvalido   = errors.New("cada movimiento lleva un cargo o un abono mayor a cero, no ambos")
+	ErrFechaPoliza          = errors.New("fecha inválida, use AAAA-MM-DD")
+	ErrPeriodoCerrado       = errors.New("el periodo está cerrado")
+	ErrPeriodoInvalido      = errors.New("periodo inválido")
+	ErrPeriodoConBorradores = errors.New("el periodo tiene pólizas en borrador")
+)
+
+// formatoAgrupador son los códigos de nivel 1 (101) y nivel 2 (101.01)
+var formatoAgrupador = regexp.MustCompile(`^\d{3}(\.\d{2})?$`)
+
+type Service struct {
+	repo      *Repository
+	catalogos *catalogos.Service
+	cfdis     *cfdi.Service
+}
+
+func NewService(repo *Repository, cat *catalogos.Service, cfdis *cfdi.Service) *Service {
+	return &Service{
+		repo:      repo,
+		catalogos: cat,
+		cfdis:     cfdis,
+	}
+}
+
+// ImportarPlantilla carga el catálogo base en una empresa sin cuentas.
+// Cumple empresas.PlantillaCuentas para ejecutarse al crear la empresa.
+func (s *Service) ImportarPlantilla(empresaID int) (int, error) {
+	total, err := s.repo.ContarCuentas(empresaID)
+	if err != nil {
+		return 0, err
+	}
+	if total > 0 {
+		return 0, ErrCatalogoExistente
+	}
+	return s.repo.ImportarPlantilla(empresaID, plantillaCuentas)
+}
+
+// GetCuentas devuelve el catálogo plano, o como árbol si arbol es true
+func (s *Service) GetCuentas(empresaID int, arbol bool) ([]Cuenta, error) {
+	cuentas, err := s.repo.GetCuentas(empresaID)
+	if err != nil || !arbol {
+		return cuentas, err
+	}
+	return construirArbol(cuentas), nil
+}
+
+func (s *Service) GetCuenta(empresaID, id int) (*Cuenta, error) {
+	c, err := s.repo.GetCuenta(empresaID, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrCuentaNoEncontrada
+	}
+	return c, err
+}
+
+func (s *Service) Crear(empresaID int, req CuentaRequest) (*Cuenta, error) {
+	cuenta := Cuenta{
+		EmpresaID:       empresaID,
+		Codigo:          strings.TrimSpace(req.Codigo),
+		Nombre:          strings.TrimSpace(req.Nombre),
+		Naturaleza:      req.Naturaleza,
+		Nivel:           1,
+		CodigoAgrupador: strings.TrimSpace(req.CodigoAgrupador),
+		IsActive:        true,
+		EsHoja:          true,
+	}
+
+	if _, err := s.repo.GetCuentaPorCodigo(empresaID, cuenta.Codigo); err == nil {
+		return nil, ErrCodigoDuplicado
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if req.PadreID != nil {
+		padre, err := s.GetCuenta(empresaID, *req.PadreID)
+		if err != nil {
+			return nil, fmt.Errorf("cuenta padre: %w", err)
+		}
+		if !padre.IsActive {
+			return nil, fmt.Errorf("cuenta padre: %w", ErrCuentaInactiva)
+		}
+		if padre.EsHoja {
+			usada, err := s.repo.TieneMovimientos(padre.ID)
+			if err != nil {
+				return nil, err
+			}
+			if usada {
+				return nil, fmt.Errorf("cuenta padre: %w", ErrCuentaConMovimientos)
+			}
+		}
+		cuenta.PadreID = &padre.ID
+		cuenta.Nivel = padre.Nivel + 1
+		if cuenta.Naturaleza == "" {
+			cuenta.Naturaleza = padre.Naturaleza
+		}
+	}
+	if cuenta.Naturaleza == "" {
+		return nil, ErrNaturalezaRequerida
+	}
+
+	if err := s.validarAgrupador(cuenta.CodigoAgrupador); err != nil {
+		return nil, err
+	}
+
+	var err error
+	cuenta.ID, err = s.repo.CrearCuenta(&cuenta)
+	if err != nil {
+		return nil, err
+	}
+	return &cuenta, nil
+}
+
+func (s *Service) Actualizar(empresaID, id int, req ActualizarCuentaRequest) (*Cuenta, error) {
+	cuenta, err := s.GetCuenta(empresaID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	agrupador := strings.TrimSpace(req.CodigoAgrupador)
+	if err := s.validarAgrupador(agrupador); err != nil {
+		return nil, err
+	}
+
+	cuenta.Nombre = strings.TrimSpace(req.Nombre)
+	cuenta.Naturaleza = req.Naturaleza
+	cuenta.CodigoAgrupador = agrupador
+	if req.IsActive != nil {
+		cuenta.IsActive = *req.IsActive
+	}
+
+	if err := s.repo.ActualizarCuenta(cuenta); err != nil {
+		return nil, err
+	}
+	return cuenta, nil
+}
+
+func (s *Service) Eliminar(empresaID, id int) error {
+	cuenta, err := s.GetCuenta(empresaID, id)
+	if err != nil {
+		return err
+	}
+	if !cuenta.EsHoja {
+		return ErrCuentaConSubcuentas
+	}
+	usada, err := s.repo.TieneMovimientos(id)
+	if err != nil {
+		return err
+	}
+	if usada {
+		return ErrCuentaConMovimientos
+	}
+	return s.repo.EliminarCuenta(empresaID, id)
+}
+
+// ValidarCuentaAfectable confirma que la cuenta existe, está activa y es de
+// último nivel antes de registrar movimientos en ella
+func (s *Service) ValidarCuentaAfectable(empresaID, id int) (*Cuenta, error) {
+	cuenta, err := s.GetCuenta(empresaID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !cuenta.IsActive {
+		return nil, fmt.Errorf("%s: %w", cuenta.Codigo, ErrCuentaInactiva)
+	}
+	if !cuenta.EsHoja {
+		return nil, fmt.Errorf("%s: %w", cuenta.Codigo, ErrCuentaNoAfectable)
+	}
+	return cuenta, nil
+}
+
+// validarAgrupador revisa el formato y, si el catálogo del SAT está cargado, la clave
+func (s *Service) validarAgrupador(codigo string) error {
+	if !formatoAgrupador.MatchString(codigo) {
+		return ErrCodigoAgrupador
+	}
+	if err := s.catalogos.ValidarClave(catalogos.CodigoAgrupador, codigo); err != nil &&
+		!errors.Is(err, catalogos.ErrCatalogoNoCargado) {
+		return fmt.Errorf("código agrupador: %w", err)
+	}
+	return nil
+}
+
+// construirArbol anida las cuentas bajo su padre conservando el orden por código
+func construirArbol(cuentas []Cuenta) []Cuenta {
+	hijos := make(map[int][]int)
+	var raices []int
+	for i, c := range cuentas {
+		if c.PadreID == nil {
+			raices = append(raices, i)
+			continue
+		}
+		hijos[*c.PadreID] = append(hijos[*c.PadreID], i)
+	}
+
+	var armar func(i int) Cuenta
+	armar = func(i int) Cuenta {
+		c := cuentas[i]
+		for _, h := range hijos[c.ID] {
+			c.Subcuentas = append(c.Subcuentas, armar(h))
+		}
+		return c
+	}
+
+	arbol := make([]Cuenta, 0, len(raices))
+	for _, i := range raices {
+		arbol = append(arbol, armar(i))
+	}
+	return arbol
+}
+
+// CrearPoliza guarda una póliza en borrador. Se puede guardar descuadrada
+// mientras se captura; la partida doble se exige al registrarla.
+func (s *Service) CrearPoliza(empresaID, userID int, req PolizaRequest) (*Poliza, error) {
+	poliza, err := s.armarPoliza(empresaID, req)
+	if err != nil {
+		return nil, err
+	}
+	poliza.CreatedBy = userID
+
+	id, err := s.repo.CrearPoliza(poliza)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetPoliza(empresaID, id)
+}
+
+func (s *Service) ActualizarPoliza(empresaID, id int, req PolizaRequest) (*Poliza, error) {
+	poliza, err := s.armarPoliza(empresaID, req)
+	if err != nil {
+		return nil, err
+	}
+	poliza.ID = id
+
+	if err := s.repo.ActualizarPoliza(poliza); err != nil {
+		return nil, err
+	}
+	return s.GetPoliza(empresaID, id)
+}
+
+func (s *Service) EliminarPoliza(empresaID, id int) error {
+	return s.repo.EliminarPoliza(empresaID, id)
+}
+
+// RegistrarPoliza valida la partida doble y la vuelve definitiva
+func (s *Service) RegistrarPoliza(empresaID, id int) (*Poliza, error) {
+	if err := s.repo.RegistrarPoliza(empresaID, id); err != nil {
+		return nil, err
+	}
+	return s.GetPoliza(empresaID, id)
+}
+
+// RevertirPoliza anula los efectos de una póliza registrada con otra de
+// signo contrario, en la fecha indicada o en la de hoy
+func (s *Service) RevertirPoliza(empresaID, id, userID int, req RevertirRequest) (*Poliza, error) {
+	fecha := time.Now()
+	if req.Fecha != "" {
+		var err error
+		if fecha, err = parseFechaPoliza(req.Fecha); err != nil {
+			return nil, err
+		}
+	}
+
+	nuevaID, err := s.repo.RevertirPoliza(empresaID, id, fecha, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetPoliza(empresaID, nuevaID)
+}
+
+func (s *Service) GetPolizas(empresaID int, f FiltroPolizas) ([]Poliza, error) {
+	return s.repo.GetPolizas(empresaID, f)
+}
+
+func (s *Service) GetPoliza(empresaID, id int) (*Poliza, error) {
+	p, err := s.repo.GetPoliza(empresaID, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrPolizaNoEncontrada
+	}
+	return p, err
+}
+
+func (s *Service) GetPeriodos(empresaID, ejercicio int) ([]Periodo, error) {
+	return s.repo.GetPeriodos(empresaID, ejercicio)
+}
+
+func (s *Service) CerrarPeriodo(empresaID, ejercicio, mes, userID int) error {
+	if err := validarPeriodo(ejercicio, mes); err != nil {
+		return err
+	}
+	return s.repo.CerrarPeriodo(empresaID, ejercicio, mes, userID)
+}
+
+func (s *Service) ReabrirPeriodo(empresaID, ejercicio, mes int) error {
+	if err := validarPeriodo(ejercicio, mes); err != nil {
+		return err
+	}
+	return s.repo.ReabrirPeriodo(empresaID, ejercicio, mes)
+}
+
+// armarPoliza valida encabezado y movimientos contra el catálogo de cuentas
+func (s *Service) armarPoliza(empresaID int, req PolizaRequest) (*Poliza, error) {
+	fecha, err := parseFechaPoliza(req.Fecha)
+	if err != nil {
+		return nil, err
+	}
+
+	poliza := &Poliza{
+		EmpresaID: empresaID,
+		Tipo:      req.Tipo,
+		Fecha:     fecha,
+		Concepto:  strings.TrimSpace(req.Concepto),
+		Estado:    EstadoBorrador,
+	}
+
+	for i, m := range req.Movimientos {
+		if (m.Cargo > 0) == (m.Abono > 0) {
+			return nil, fmt.Errorf("movimiento %d: %w", i+1, ErrMovimientoInvalido)
+		}
+		cuenta, err := s.ValidarCuentaAfectable(empresaID, m.CuentaID)
+		if err != nil {
+			return nil, fmt.Errorf("movimiento %d: %w", i+1, err)
+		}
+
+		concepto := strings.TrimSpace(m.Concepto)
+		if concepto == "" {
+			concepto = poliza.Concepto
+		}
+		poliza.Movimientos = append(poliza.Movimientos, Movimiento{
+			CuentaID:     cuenta.ID,
+			CuentaCodigo: cuenta.Codigo,
+			CuentaNombre: cuenta.Nombre,
+			Concepto:     concepto,
+			Cargo:        redondear(m.Cargo),
+			Abono:        redondear(m.Abono),
+			UUID:         strings.ToUpper(strings.TrimSpace(m.UUID)),
+		})
+	}
+
+	return poliza, nil
+}
+
+func parseFechaPoliza(s string) (time.Time, error) {
+	fecha, err := time.Parse("2006-01-02", strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, ErrFechaPoliza
+	}
+	return fecha, nil
+}
+
+func validarPeriodo(ejercicio, mes int) error {
+	if ejercicio < 2000 || mes < 1 || mes > 12 {
+		return ErrPeriodoInvalido
+	}
+	return nil
+}
+
+func redondear(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// centavos compara importes sin errores de punto flotante
+func centavos(v float64) int64 {
+	return int64(math.Round(v * 100))
+}
@@ -0,0 +1,129 @@
+// internal/modules/contabilidad/xml_sat.go
+package contabilidad
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+// Esquemas 1.3 de contabilidad electrónica (Anexo 24)
+const (
+	VersionContabilidadE = "1.3"
+
+	NamespaceXSI      = "http://www.w3.org/2001/XMLSchema-instance"
+	NamespaceCatalogo = "http://www.sat.gob.mx/esquemas/ContabilidadE/1_3/CatalogoCuentas"
+	NamespaceBalanza  = "http://www.sat.gob.mx/esquemas/ContabilidadE/1_3/BalanzaComprobacion"
+	NamespacePolizas  = "http://www.sat.gob.mx/esquemas/ContabilidadE/1_3/PolizasPeriodo"
+
+	SchemaLocationCatalogo = NamespaceCatalogo + " http://www.sat.gob.mx/esquemas/ContabilidadE/1_3/CatalogoCuentas/CatalogoCuentas_1_3.xsd"
+	SchemaLocationBalanza  = NamespaceBalanza + " http://www.sat.gob.mx/esquemas/ContabilidadE/1_3/BalanzaComprobacion/BalanzaComprobacion_1_3.xsd"
+	SchemaLocationPolizas  = NamespacePolizas + " http://www.sat.gob.mx/esquemas/ContabilidadE/1_3/PolizasPeriodo/PolizasPeriodo_1_3.xsd"
+)
+
+// Tipos de envío de la balanza y de solicitud de pólizas
+const (
+	EnvioNormal         = "N"
+	EnvioComplementario = "C"
+
+	SolicitudActoFiscalizacion     = "AF"
+	SolicitudFiscalizacionCompulsa = "FC"
+	SolicitudDevolucion            = "DE"
+	SolicitudCompensacion          = "CO"
+)
+
+// CatalogoXML es el catálogo de cuentas. Igual que en el CFDI, los prefijos
+// van en la etiqueta porque encoding/xml no los genera por sí mismo.
+type CatalogoXML struct {
+	XMLName        xml.Name         `xml:"catalogocuentas:Catalogo"`
+	Xmlns          string           `xml:"xmlns:catalogocuentas,attr"`
+	XmlnsXSI       string           `xml:"xmlns:xsi,attr"`
+	SchemaLocation string           `xml:"xsi:schemaLocation,attr"`
+	Version        string           `xml:"Version,attr"`
+	RFC            string           `xml:"RFC,attr"`
+	Mes            string           `xml:"Mes,attr"`
+	Anio           int              `xml:"Anio,attr"`
+	Ctas           []CuentaCatalogo `xml:"catalogocuentas:Ctas"`
+}
+
+type CuentaCatalogo struct {
+	CodAgrup string `xml:"CodAgrup,attr"`
+	NumCta   string `xml:"NumCta,attr"`
+	Desc     string `xml:"Desc,attr"`
+	SubCtaDe string `xml:"SubCtaDe,attr,omitempty"`
+	Nivel    int    `xml:"Nivel,attr"`
+	Natur    string `xml:"Natur,attr"`
+}
+
+// BalanzaXML es la balanza de comprobación normal o complementaria
+type BalanzaXML struct {
+	XMLName        xml.Name        `xml:"BCE:Balanza"`
+	Xmlns          string          `xml:"xmlns:BCE,attr"`
+	XmlnsXSI       string          `xml:"xmlns:xsi,attr"`
+	SchemaLocation string          `xml:"xsi:schemaLocation,attr"`
+	Version        string          `xml:"Version,attr"`
+	RFC            string          `xml:"RFC,attr"`
+	Mes            string          `xml:"Mes,attr"`
+	Anio           int             `xml:"Anio,attr"`
+	TipoEnvio      string          `xml:"TipoEnvio,attr"`
+	FechaModBal    string          `xml:"FechaModBal,attr,omitempty"`
+	Ctas           []CuentaBalanza `xml:"BCE:Ctas"`
+}
+
+type CuentaBalanza struct {
+	NumCta   string `xml:"NumCta,attr"`
+	SaldoIni string `xml:"SaldoIni,attr"`
+	Debe     string `xml:"Debe,attr"`
+	Haber    string `xml:"Haber,attr"`
+	SaldoFin string `xml:"SaldoFin,attr"`
+}
+
+// PolizasXML son las pólizas del periodo que se entregan a solicitud del SAT
+type PolizasXML struct {
+	XMLName        xml.Name    `xml:"PLZ:Polizas"`
+	Xmlns          string      `xml:"xmlns:PLZ,attr"`
+	XmlnsXSI       string      `xml:"xmlns:xsi,attr"`
+	SchemaLocation string      `xml:"xsi:schemaLocation,attr"`
+	Version        string      `xml:"Version,attr"`
+	RFC            string      `xml:"RFC,attr"`
+	Mes            string      `xml:"Mes,attr"`
+	Anio           int         `xml:"Anio,attr"`
+	TipoSolicitud  string      `xml:"TipoSolicitud,attr"`
+	NumOrden       string      `xml:"NumOrden,attr,omitempty"`
+	NumTramite     string      `xml:"NumTramite,attr,omitempty"`
+	Polizas        []PolizaSAT `xml:"PLZ:Poliza"`
+}
+
+type PolizaSAT struct {
+	NumUnIdenPol  string           `xml:"NumUnIdenPol,attr"`
+	Fecha         string           `xml:"Fecha,attr"`
+	Concepto      string           `xml:"Concepto,attr"`
+	Transacciones []TransaccionSAT `xml:"PLZ:Transaccion"`
+}
+
+type TransaccionSAT struct {
+	NumCta   string       `xml:"NumCta,attr"`
+	DesCta   string       `xml:"DesCta,attr"`
+	Concepto string       `xml:"Concepto,attr"`
+	Debe     string       `xml:"Debe,attr"`
+	Haber    string       `xml:"Haber,attr"`
+	CompNal  []CompNalSAT `xml:"PLZ:CompNal"`
+}
+
+// CompNalSAT relaciona la transacción con un CFDI
+type CompNalSAT struct {
+	UUID       string `xml:"UUID_CFDI,attr"`
+	RFC        string `xml:"RFC,attr"`
+	MontoTotal string `xml:"MontoTotal,attr"`
+	Moneda     string `xml:"Moneda,attr,omitempty"`
+	TipCamb    string `xml:"TipCamb,attr,omitempty"`
+}
+
+// serializarXML agrega la declaración UTF-8 al documento
+func serializarXML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,120 @@
+// internal/modules/diot/handler.go
+package diot
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler maneja las peticiones HTTP de la DIOT. La empresa llega en el
+// contexto como "empresaID" (ver middleware.EmpresaMiddleware).
+type Handler struct {
+	service *Service
+}
+
+// NewHandler crea una nueva instancia del handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetDIOT muestra la DIOT del mes para revisarla antes de descargar el TXT
+// @Router /empresas/{empresaId}/diot/{ejercicio}/{mes} [get]
+func (h *Handler) GetDIOT(c *gin.Context) {
+	ejercicio, _ := strconv.Atoi(c.Param("ejercicio"))
+	mes, _ := strconv.Atoi(c.Param("mes"))
+
+	d, err := h.service.Generar(c.GetInt("empresaID"), ejercicio, mes)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    d,
+	})
+}
+
+// DescargarDIOT entrega el TXT de carga masiva; si algún proveedor tiene
+// datos incompletos responde 422 con la lista de errores
+// @Router /empresas/{empresaId}/diot/{ejercicio}/{mes}/txt [get]
+func (h *Handler) DescargarDIOT(c *gin.Context) {
+	ejercicio, _ := strconv.Atoi(c.Param("ejercicio"))
+	mes, _ := strconv.Atoi(c.Param("mes"))
+
+	d, err := h.service.Generar(c.GetInt("empresaID"), ejercicio, mes)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	data, err := d.TXT()
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"success": false, "error": err.Error(), "errores": d.Errores})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+d.NombreTXT()+`"`)
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", data)
+}
+
+// @Router /empresas/{empresaId}/diot/proveedores [get]
+func (h *Handler) GetProveedores(c *gin.Context) {
+	proveedores, err := h.service.GetProveedores(c.GetInt("empresaID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    proveedores,
+	})
+}
+
+// GuardarProveedor ajusta tipo de tercero y de operación de un proveedor
+// @Router /empresas/{empresaId}/diot/proveedores [put]
+func (h *Handler) GuardarProveedor(c *gin.Context) {
+	var req ProveedorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	p, err := h.service.GuardarProveedor(c.GetInt("empresaID"), req)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    p,
+	})
+}
+
+// @Router /empresas/{empresaId}/diot/proveedores/{proveedorId} [delete]
+func (h *Handler) EliminarProveedor(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("proveedorId"))
+
+	if err := h.service.EliminarProveedor(c.GetInt("empresaID"), id); err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Proveedor eliminado",
+	})
+}
+
+func responderError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, ErrProveedorNoEncontrado) {
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
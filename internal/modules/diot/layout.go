@@ -0,0 +1,137 @@
+// internal/modules/diot/layout.go
+package diot
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// A partir del periodo enero de 2025 el SAT sólo acepta el layout de carga
+// masiva de la DIOT 2025 (54 campos). Los periodos anteriores, p. ej. en
+// complementarias, se siguen presentando con el layout anterior de 24 campos.
+const (
+	ejercicioLayout2025  = 2025
+	camposLayout2025     = 54
+	camposLayoutAnterior = 24
+)
+
+// efectosFiscalesSi es la respuesta 01 del campo 54 del layout 2025: se dio
+// efectos fiscales a los CFDI de las operaciones con el proveedor
+const efectosFiscalesSi = "01"
+
+// TXT genera el archivo de carga masiva de la DIOT con el layout que
+// corresponde al periodo: una línea por renglón, cada campo terminado en "|".
+// Los importes van en pesos sin decimales y se dejan vacíos cuando son cero.
+func (d *DIOT) TXT() ([]byte, error) {
+	if len(d.Errores) > 0 {
+		return nil, ErrDIOTConErrores
+	}
+
+	campos := camposLayout2025Renglon
+	if d.Ejercicio < ejercicioLayout2025 {
+		campos = camposLayoutAnteriorRenglon
+	}
+
+	var buf bytes.Buffer
+	for _, r := range d.Renglones {
+		for _, c := range campos(r) {
+			buf.WriteString(c)
+			buf.WriteByte('|')
+		}
+		// La aplicación del SAT corre en Windows y espera fin de línea CRLF
+		buf.WriteString("\r\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// camposLayout2025Renglon arma los 54 campos del layout de la DIOT 2025:
+//
+//	1 tipo de tercero, 2 tipo de operación, 3 RFC, 4 número de ID fiscal,
+//	5 nombre del extranjero, 6 país o jurisdicción de residencia fiscal,
+//	7 lugar de la jurisdicción fiscal,
+//	8-17 valor de los actos pagados y de sus devoluciones, descuentos y
+//	bonificaciones, en pares: región fronteriza norte, región fronteriza sur,
+//	16%, importación de bienes tangibles, importación de intangibles y servicios,
+//	18-27 IVA acreditable de esas cinco categorías, en pares: exclusivamente de
+//	actividades gravadas y en la proporción de actividades gravadas,
+//	28-47 IVA no acreditable de las cinco categorías (proporción, actividades
+//	exentas, actividades no objeto y requisitos no cumplidos),
+//	48 IVA retenido, 49 importaciones exentas, 50 actos exentos, 51 actos al 0%,
+//	52 no objeto en territorio nacional, 53 no objeto sin establecimiento en
+//	territorio nacional, 54 efectos fiscales de los CFDI (01 sí, 02 no)
+//
+// La tasa del 8% se declara como región fronteriza norte y todo el IVA
+// trasladado como acreditable de actividades gravadas: el sistema no registra
+// la región ni la proporción de actividades exentas.
+func camposLayout2025Renglon(r RenglonDIOT) []string {
+	campos := make([]string, camposLayout2025)
+	campos[0] = r.TipoTercero
+	campos[1] = r.TipoOperacion
+	switch r.TipoTercero {
+	case TerceroNacional:
+		campos[2] = r.RFC
+	case TerceroExtranjero:
+		campos[3] = r.IDFiscal
+		campos[4] = r.NombreExtranjero
+		campos[5] = r.Pais
+	}
+	campos[7] = pesos(r.Base8)
+	campos[8] = pesos(r.Devoluciones8)
+	campos[11] = pesos(r.Base16)
+	campos[12] = pesos(r.Devoluciones16)
+	campos[17] = pesos(r.IVA8)
+	campos[21] = pesos(r.IVA16)
+	campos[47] = pesos(r.IVARetenido)
+	campos[49] = pesos(r.Exento)
+	campos[50] = pesos(r.Base0)
+	campos[53] = efectosFiscalesSi
+	return campos
+}
+
+// camposLayoutAnteriorRenglon arma los 24 campos del layout vigente hasta
+// los periodos de 2024:
+//
+//	1 tipo de tercero, 2 tipo de operación, 3 RFC, 4 número de ID fiscal,
+//	5 nombre del extranjero, 6 país de residencia, 7 nacionalidad,
+//	8 actos pagados al 16%, 9 al 15%, 10 IVA no acreditable al 16%,
+//	11 actos al 11%, 12 al 10%, 13 región fronteriza norte (8%),
+//	14 IVA no acreditable al 11%, 15 IVA no acreditable frontera,
+//	16-20 importaciones (16%, IVA no acreditable, 11%, IVA no acreditable, exentas),
+//	21 actos al 0%, 22 exentos, 23 IVA retenido,
+//	24 IVA de devoluciones, descuentos y bonificaciones
+func camposLayoutAnteriorRenglon(r RenglonDIOT) []string {
+	campos := make([]string, camposLayoutAnterior)
+	campos[0] = r.TipoTercero
+	campos[1] = r.TipoOperacion
+	switch r.TipoTercero {
+	case TerceroNacional:
+		campos[2] = r.RFC
+	case TerceroExtranjero:
+		campos[3] = r.IDFiscal
+		campos[4] = r.NombreExtranjero
+		campos[5] = r.Pais
+		campos[6] = r.Nacionalidad
+	}
+	campos[7] = pesos(r.Base16)
+	campos[12] = pesos(r.Base8)
+	campos[20] = pesos(r.Base0)
+	campos[21] = pesos(r.Exento)
+	campos[22] = pesos(r.IVARetenido)
+	campos[23] = pesos(r.IVADevoluciones)
+	return campos
+}
+
+// NombreTXT sigue el patrón RFC + ejercicio + mes
+func (d *DIOT) NombreTXT() string {
+	return fmt.Sprintf("DIOT_%s_%04d%02d.txt", d.RFC, d.Ejercicio, d.Mes)
+}
+
+func pesos(v float64) string {
+	n := int64(math.Round(v))
+	if n == 0 {
+		return ""
+	}
+	return strconv.FormatInt(n, 10)
+}
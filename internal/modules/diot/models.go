@@ -0,0 +1,89 @@
+// internal/modules/diot/models.go
+package diot
+
+import "time"
+
+// Tipos de tercero del layout de la DIOT
+const (
+	TerceroNacional   = "04"
+	TerceroExtranjero = "05"
+	TerceroGlobal     = "15"
+)
+
+// Tipos de operación del layout de la DIOT
+const (
+	OperacionServiciosProfesionales = "03"
+	OperacionArrendamiento          = "06"
+	OperacionOtros                  = "85"
+)
+
+// Proveedor guarda cómo se declara un tercero. Los proveedores con el RFC
+// genérico de extranjeros se distinguen por el nombre del emisor del CFDI.
+type Proveedor struct {
+	ID               int       `json:"id"`
+	EmpresaID        int       `json:"empresa_id"`
+	RFC              string    `json:"rfc"`
+	Nombre           string    `json:"nombre,omitempty"`
+	TipoTercero      string    `json:"tipo_tercero"`
+	TipoOperacion    string    `json:"tipo_operacion"`
+	IDFiscal         string    `json:"id_fiscal,omitempty"`
+	NombreExtranjero string    `json:"nombre_extranjero,omitempty"`
+	Pais             string    `json:"pais,omitempty"`
+	Nacionalidad     string    `json:"nacionalidad,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// ProveedorRequest ajusta el tipo de tercero y de operación de un proveedor;
+// los datos del extranjero sólo se piden con tipo_tercero 05
+type ProveedorRequest struct {
+	RFC              string `json:"rfc" binding:"required,rfc"`
+	Nombre           string `json:"nombre" binding:"max=300"`
+	TipoTercero      string `json:"tipo_tercero" binding:"required,oneof=04 05 15"`
+	TipoOperacion    string `json:"tipo_operacion" binding:"required,oneof=03 06 85"`
+	IDFiscal         string `json:"id_fiscal" binding:"max=40"`
+	NombreExtranjero string `json:"nombre_extranjero" binding:"max=43"`
+	Pais             string `json:"pais" binding:"omitempty,len=2,alpha"`
+	Nacionalidad     string `json:"nacionalidad" binding:"max=40"`
+}
+
+// RenglonDIOT es una línea de la declaración: un tercero con un tipo de
+// operación y sus actos pagados en el mes, en pesos
+type RenglonDIOT struct {
+	TipoTercero      string   `json:"tipo_tercero"`
+	TipoOperacion    string   `json:"tipo_operacion"`
+	RFC              string   `json:"rfc,omitempty"`
+	IDFiscal         string   `json:"id_fiscal,omitempty"`
+	NombreExtranjero string   `json:"nombre_extranjero,omitempty"`
+	Pais             string   `json:"pais,omitempty"`
+	Nacionalidad     string   `json:"nacionalidad,omitempty"`
+	Nombre           string   `json:"nombre,omitempty"`
+	Base16           float64  `json:"base_16"`
+	Base8            float64  `json:"base_8_frontera"`
+	Base0            float64  `json:"base_0"`
+	Exento           float64  `json:"exento"`
+	IVA16            float64  `json:"iva_16"`
+	IVA8             float64  `json:"iva_8_frontera"`
+	IVARetenido      float64  `json:"iva_retenido"`
+	IVADevoluciones  float64  `json:"iva_devoluciones"`
+	Devoluciones16   float64  `json:"devoluciones_16"`         // Base de las notas de crédito al 16%
+	Devoluciones8    float64  `json:"devoluciones_8_frontera"` // Base de las notas de crédito al 8%
+	UUIDs            []string `json:"uuids"`
+}
+
+// CFDIOmitido explica por qué un CFDI no entró en la DIOT
+type CFDIOmitido struct {
+	UUID   string `json:"uuid"`
+	Motivo string `json:"motivo"`
+}
+
+// DIOT es la declaración de un mes lista para revisar o exportar a TXT.
+// Con errores, el TXT no se genera hasta corregir los proveedores.
+type DIOT struct {
+	RFC       string        `json:"rfc"`
+	Ejercicio int           `json:"ejercicio"`
+	Mes       int           `json:"mes"`
+	Renglones []RenglonDIOT `json:"renglones"`
+	Omitidos  []CFDIOmitido `json:"omitidos,omitempty"`
+	Errores   []string      `json:"errores,omitempty"`
+}
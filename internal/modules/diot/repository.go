@@ -0,0 +1,103 @@
+// internal/modules/diot/repository.go
+package diot
+
+import (
+	"database/sql"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// GetRFCEmpresa devuelve el RFC de la empresa que presenta la declaración
+func (r *Repository) GetRFCEmpresa(empresaID int) (string, error) {
+	var rfc string
+	err := r.db.QueryRow(`SELECT rfc FROM empresas WHERE id = $1`, empresaID).Scan(&rfc)
+	return rfc, err
+}
+
+const columnasProveedor = `id, empresa_id, rfc, nombre, tipo_tercero, tipo_operacion, id_fiscal,
+        nombre_extranjero, pais, nacionalidad, created_at, updated_at`
+
+// GuardarProveedor crea o actualiza la configuración del proveedor (rfc, nombre)
+func (r *Repository) GuardarProveedor(p *Proveedor) (int, error) {
+	var id int
+	err := r.db.QueryRow(`
+        INSERT INTO diot_proveedores (empresa_id, rfc, nombre, tipo_tercero, tipo_operacion, id_fiscal,
+            nombre_extranjero, pais, nacionalidad)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        ON CONFLICT (empresa_id, rfc, nombre) DO UPDATE SET
+            tipo_tercero = EXCLUDED.tipo_tercero,
+            tipo_operacion = EXCLUDED.tipo_operacion,
+            id_fiscal = EXCLUDED.id_fiscal,
+            nombre_extranjero = EXCLUDED.nombre_extranjero,
+            pais = EXCLUDED.pais,
+            nacionalidad = EXCLUDED.nacionalidad,
+            updated_at = CURRENT_TIMESTAMP
+        RETURNING id
+    `, p.EmpresaID, p.RFC, p.Nombre, p.TipoTercero, p.TipoOperacion, p.IDFiscal,
+		p.NombreExtranjero, p.Pais, p.Nacionalidad).Scan(&id)
+	return id, err
+}
+
+func (r *Repository) GetProveedores(empresaID int) ([]Proveedor, error) {
+	rows, err := r.db.Query(`
+        SELECT `+columnasProveedor+`
+        FROM diot_proveedores
+        WHERE empresa_id = $1
+        ORDER BY rfc, nombre
+    `, empresaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var proveedores []Proveedor
+	for rows.Next() {
+		p, err := scanProveedor(rows)
+		if err != nil {
+			continue
+		}
+		proveedores = append(proveedores, *p)
+	}
+
+	return proveedores, nil
+}
+
+func (r *Repository) GetProveedor(empresaID, id int) (*Proveedor, error) {
+	row := r.db.QueryRow(`
+        SELECT `+columnasProveedor+`
+        FROM diot_proveedores WHERE empresa_id = $1 AND id = $2
+    `, empresaID, id)
+	return scanProveedor(row)
+}
+
+func (r *Repository) EliminarProveedor(empresaID, id int) error {
+	_, err := r.db.Exec(`DELETE FROM diot_proveedores WHERE empresa_id = $1 AND id = $2`, empresaID, id)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProveedor(row rowScanner) (*Proveedor, error) {
+	var p Proveedor
+	var idFiscal, nombreExtranjero, pais, nacionalidad sql.NullString
+
+	err := row.Scan(&p.ID, &p.EmpresaID, &p.RFC, &p.Nombre, &p.TipoTercero, &p.TipoOperacion, &idFiscal,
+		&nombreExtranjero, &pais, &nacionalidad, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	p.IDFiscal = idFiscal.String
+	p.NombreExtranjero = nombreExtranjero.String
+	p.Pais = pais.String
+	p.Nacionalidad = nacionalidad.String
+	return &p, nil
+}
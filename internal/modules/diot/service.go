@@ -0,0 +1,379 @@
+// internal/modules/diot/service.go
+package diot
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jhvc/backend/internal/modules/cfdi"
+	"github.com/jhvc/backend/internal/validacion"
+)
+
+var (
+	ErrProveedorNoEncontrado = errors.New("proveedor no encontrado")
+	ErrPeriodoInvalido       = errors.New("periodo inválido")
+	ErrDatosExtranjero       = errors.New("un tercero extranjero requiere id_fiscal, nombre_extranjero y pais")
+	ErrTerceroNacional       = errors.New("un tercero nacional requiere un RFC que no sea genérico")
+	ErrTerceroGlobal         = errors.New("el RFC genérico nacional sólo se declara como proveedor global (15) con operación 85")
+	ErrDIOTConErrores        = errors.New("la DIOT tiene errores; corrija los proveedores antes de generar el TXT")
+)
+
+const impuestoIVA = "002"
+
+// Service arma la DIOT a partir de los CFDI recibidos que se pagaron en el mes
+type Service struct {
+	repo  *Repository
+	cfdis *cfdi.Service
+}
+
+// NewService crea una nueva instancia del servicio
+func NewService(repo *Repository, cfdis *cfdi.Service) *Service {
+	return &Service{repo: repo, cfdis: cfdis}
+}
+
+func (s *Service) GetProveedores(empresaID int) ([]Proveedor, error) {
+	return s.repo.GetProveedores(empresaID)
+}
+
+// GuardarProveedor crea o reemplaza la configuración de un proveedor. El
+// nombre sólo se conserva para el RFC genérico de extranjeros, que lo comparten
+// todos los proveedores del extranjero.
+func (s *Service) GuardarProveedor(empresaID int, req ProveedorRequest) (*Proveedor, error) {
+	p := &Proveedor{
+		EmpresaID:     empresaID,
+		RFC:           validacion.NormalizarRFC(req.RFC),
+		TipoTercero:   req.TipoTercero,
+		TipoOperacion: req.TipoOperacion,
+	}
+	if p.RFC == validacion.RFCGenericoExtranjero {
+		p.Nombre = normalizarNombre(req.Nombre)
+	}
+
+	switch p.TipoTercero {
+	case TerceroExtranjero:
+		p.IDFiscal = strings.TrimSpace(req.IDFiscal)
+		p.NombreExtranjero = normalizarNombre(req.NombreExtranjero)
+		p.Pais = strings.ToUpper(req.Pais)
+		p.Nacionalidad = normalizarNombre(req.Nacionalidad)
+		if p.IDFiscal == "" || p.NombreExtranjero == "" || p.Pais == "" {
+			return nil, ErrDatosExtranjero
+		}
+	case TerceroNacional:
+		if validacion.EsRFCGenerico(p.RFC) {
+			return nil, ErrTerceroNacional
+		}
+	}
+	if (p.TipoTercero == TerceroGlobal) != (p.RFC == validacion.RFCGenericoNacional) ||
+		p.TipoTercero == TerceroGlobal && p.TipoOperacion != OperacionOtros {
+		return nil, ErrTerceroGlobal
+	}
+
+	id, err := s.repo.GuardarProveedor(p)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetProveedor(empresaID, id)
+}
+
+func (s *Service) EliminarProveedor(empresaID, id int) error {
+	if _, err := s.repo.GetProveedor(empresaID, id); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrProveedorNoEncontrado
+		}
+		return err
+	}
+	return s.repo.EliminarProveedor(empresaID, id)
+}
+
+// Generar agrupa por tercero y tipo de operación los actos pagados en el mes:
+// los CFDI recibidos PUE (y las notas de crédito) por su fecha, y los PPD en
+// proporción a cada pago de sus complementos de pago.
+func (s *Service) Generar(empresaID, ejercicio, mes int) (*DIOT, error) {
+	if ejercicio < 2000 || mes < 1 || mes > 12 {
+		return nil, ErrPeriodoInvalido
+	}
+	rfc, err := s.repo.GetRFCEmpresa(empresaID)
+	if err != nil {
+		return nil, err
+	}
+	proveedores, err := s.repo.GetProveedores(empresaID)
+	if err != nil {
+		return nil, err
+	}
+
+	desde := time.Date(ejercicio, time.Month(mes), 1, 0, 0, 0, 0, time.UTC)
+	hasta := desde.AddDate(0, 1, 0)
+
+	d := &DIOT{RFC: rfc, Ejercicio: ejercicio, Mes: mes, Renglones: []RenglonDIOT{}}
+	g := newAgrupador(proveedores)
+	omitir := func(uuid, motivo string) {
+		d.Omitidos = append(d.Omitidos, CFDIOmitido{UUID: uuid, Motivo: motivo})
+	}
+
+	// Los XML se leen una vez aunque la factura tenga varios pagos en el mes
+	xmls := make(map[string]*cfdi.Comprobante)
+	comprobante := func(uuid string) (*cfdi.Comprobante, error) {
+		if comp, ok := xmls[uuid]; ok {
+			return comp, nil
+		}
+		data, err := s.cfdis.GetXML(empresaID, uuid)
+		if err != nil {
+			return nil, err
+		}
+		comp, err := cfdi.Parse(data)
+		if err != nil {
+			return nil, err
+		}
+		xmls[uuid] = comp
+		return comp, nil
+	}
+
+	recibidos, err := s.cfdis.GetCFDIs(empresaID, cfdi.FiltroCFDI{RFC: rfc, Desde: &desde, Hasta: &hasta})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range recibidos {
+		if !strings.EqualFold(c.ReceptorRFC, rfc) {
+			continue
+		}
+		if c.TipoComprobante != cfdi.TipoIngreso && c.TipoComprobante != cfdi.TipoEgreso {
+			continue
+		}
+		if c.TipoComprobante == cfdi.TipoIngreso && c.MetodoPago == cfdi.MetodoPPD {
+			continue
+		}
+		comp, err := comprobante(c.UUID)
+		if err != nil {
+			omitir(c.UUID, err.Error())
+			continue
+		}
+		if err := g.agregar(c, comp, 1); err != nil {
+			omitir(c.UUID, err.Error())
+		}
+	}
+
+	pagos, err := s.cfdis.GetPagosRecibidos(empresaID, rfc, desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pagos {
+		c, err := s.cfdis.GetCFDI(empresaID, p.DoctoUUID)
+		if err != nil {
+			omitir(p.DoctoUUID, err.Error())
+			continue
+		}
+		if c.MetodoPago != cfdi.MetodoPPD {
+			omitir(c.UUID, fmt.Sprintf("pago %s a una factura %s, que se declara en el mes de su emisión", p.PagoUUID, c.MetodoPago))
+			continue
+		}
+		if c.Total <= 0 {
+			omitir(c.UUID, "factura sin total")
+			continue
+		}
+		comp, err := comprobante(c.UUID)
+		if err != nil {
+			omitir(c.UUID, err.Error())
+			continue
+		}
+		if err := g.agregar(*c, comp, p.ImpPagado/c.Total); err != nil {
+			omitir(c.UUID, err.Error())
+		}
+	}
+
+	d.Renglones = g.renglones()
+	d.Errores = validarRenglones(d.Renglones)
+	return d, nil
+}
+
+// agrupador acumula los CFDI en renglones por tercero y tipo de operación
+type agrupador struct {
+	proveedores map[string]Proveedor
+	renglon     map[string]*RenglonDIOT
+}
+
+func newAgrupador(proveedores []Proveedor) *agrupador {
+	g := &agrupador{proveedores: make(map[string]Proveedor), renglon: make(map[string]*RenglonDIOT)}
+	for _, p := range proveedores {
+		g.proveedores[p.RFC+"|"+p.Nombre] = p
+	}
+	return g
+}
+
+// proveedor devuelve la configuración guardada o la de omisión: tercero
+// nacional, global para el RFC genérico nacional y extranjero para el genérico
+// de extranjeros; operación 85 (otros)
+func (g *agrupador) proveedor(rfc, nombre string) Proveedor {
+	if rfc == validacion.RFCGenericoExtranjero {
+		nombre = normalizarNombre(nombre)
+	} else {
+		nombre = ""
+	}
+	if p, ok := g.proveedores[rfc+"|"+nombre]; ok {
+		return p
+	}
+
+	p := Proveedor{RFC: rfc, Nombre: nombre, TipoTercero: TerceroNacional, TipoOperacion: OperacionOtros}
+	switch rfc {
+	case validacion.RFCGenericoNacional:
+		p.TipoTercero = TerceroGlobal
+	case validacion.RFCGenericoExtranjero:
+		p.TipoTercero = TerceroExtranjero
+	}
+	return p
+}
+
+// agregar suma la proporción pagada del CFDI, convertida a pesos. Se usa el
+// tipo de cambio del comprobante porque el de cada pago no se almacena.
+func (g *agrupador) agregar(c cfdi.CFDI, comp *cfdi.Comprobante, proporcion float64) error {
+	factor := proporcion
+	if c.Moneda != "" && c.Moneda != "MXN" && c.Moneda != "XXX" {
+		if c.TipoCambio <= 0 {
+			return fmt.Errorf("CFDI en %s sin tipo de cambio", c.Moneda)
+		}
+		factor *= c.TipoCambio
+	}
+
+	var base16, base8, base0, exento, iva16, iva8, ivaRet float64
+	for _, con := range comp.Conceptos {
+		// Los conceptos que no son objeto de impuesto no se declaran
+		if con.ObjetoImp == "01" {
+			continue
+		}
+		conIVA := false
+		for _, t := range con.Traslados {
+			if t.Impuesto != impuestoIVA {
+				continue
+			}
+			conIVA = true
+			switch {
+			case t.TipoFactor == "Exento":
+				exento += t.Base
+			case math.Abs(t.TasaOCuota-0.16) < 0.0001:
+				base16 += t.Base
+				iva16 += t.Importe
+			case math.Abs(t.TasaOCuota-0.08) < 0.0001:
+				base8 += t.Base
+				iva8 += t.Importe
+			case t.TasaOCuota == 0:
+				base0 += t.Base
+			default:
+				return fmt.Errorf("tasa de IVA %.6f no soportada en la DIOT", t.TasaOCuota)
+			}
+		}
+		if !conIVA {
+			exento += con.Importe - con.Descuento
+		}
+		for _, r := range con.Retenciones {
+			if r.Impuesto == impuestoIVA {
+				ivaRet += r.Importe
+			}
+		}
+	}
+
+	p := g.proveedor(c.EmisorRFC, c.EmisorNombre)
+	clave := strings.Join([]string{p.TipoTercero, p.TipoOperacion, p.RFC, p.Nombre}, "|")
+	r, ok := g.renglon[clave]
+	if !ok {
+		r = &RenglonDIOT{
+			TipoTercero:      p.TipoTercero,
+			TipoOperacion:    p.TipoOperacion,
+			RFC:              p.RFC,
+			IDFiscal:         p.IDFiscal,
+			NombreExtranjero: p.NombreExtranjero,
+			Pais:             p.Pais,
+			Nacionalidad:     p.Nacionalidad,
+			Nombre:           c.EmisorNombre,
+		}
+		g.renglon[clave] = r
+	}
+
+	// Las notas de crédito sólo informan las devoluciones y descuentos: el
+	// layout anterior pide su IVA y el de 2025 su valor por tasa
+	if c.TipoComprobante == cfdi.TipoEgreso {
+		r.IVADevoluciones += (iva16 + iva8) * factor
+		r.Devoluciones16 += base16 * factor
+		r.Devoluciones8 += base8 * factor
+	} else {
+		r.Base16 += base16 * factor
+		r.Base8 += base8 * factor
+		r.Base0 += base0 * factor
+		r.Exento += exento * factor
+		r.IVA16 += iva16 * factor
+		r.IVA8 += iva8 * factor
+		r.IVARetenido += ivaRet * factor
+	}
+
+	for _, uuid := range r.UUIDs {
+		if uuid == c.UUID {
+			return nil
+		}
+	}
+	r.UUIDs = append(r.UUIDs, c.UUID)
+	return nil
+}
+
+func (g *agrupador) renglones() []RenglonDIOT {
+	renglones := make([]RenglonDIOT, 0, len(g.renglon))
+	for _, r := range g.renglon {
+		for _, v := range []*float64{&r.Base16, &r.Base8, &r.Base0, &r.Exento, &r.IVA16, &r.IVA8,
+			&r.IVARetenido, &r.IVADevoluciones, &r.Devoluciones16, &r.Devoluciones8} {
+			*v = math.Round(*v*100) / 100
+		}
+		renglones = append(renglones, *r)
+	}
+	sort.Slice(renglones, func(i, j int) bool {
+		a, b := renglones[i], renglones[j]
+		if a.TipoTercero != b.TipoTercero {
+			return a.TipoTercero < b.TipoTercero
+		}
+		if a.RFC != b.RFC {
+			return a.RFC < b.RFC
+		}
+		if a.Nombre != b.Nombre {
+			return a.Nombre < b.Nombre
+		}
+		return a.TipoOperacion < b.TipoOperacion
+	})
+	return renglones
+}
+
+// validarRenglones revisa lo que el SAT rechaza al cargar el archivo
+func validarRenglones(renglones []RenglonDIOT) []string {
+	var errores []string
+	for _, r := range renglones {
+		tercero := r.RFC
+		if r.Nombre != "" {
+			tercero += " " + r.Nombre
+		}
+		switch r.TipoTercero {
+		case TerceroNacional:
+			if validacion.EsRFCGenerico(r.RFC) {
+				errores = append(errores, tercero+": "+ErrTerceroNacional.Error())
+			} else if _, err := validacion.ValidarRFC(r.RFC); err != nil {
+				errores = append(errores, tercero+": "+err.Error())
+			}
+		case TerceroExtranjero:
+			if r.IDFiscal == "" || r.NombreExtranjero == "" || r.Pais == "" {
+				errores = append(errores, tercero+": "+ErrDatosExtranjero.Error())
+			}
+		case TerceroGlobal:
+			if r.TipoOperacion != OperacionOtros {
+				errores = append(errores, tercero+": "+ErrTerceroGlobal.Error())
+			}
+		}
+	}
+	return errores
+}
+
+// normalizarNombre deja mayúsculas sin acentos ni espacios repetidos, como
+// los acepta la carga de la DIOT
+func normalizarNombre(s string) string {
+	s = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u",
+		"Á", "A", "É", "E", "Í", "I", "Ó", "O", "Ú", "U", "Ü", "U", "|", " ").Replace(s)
+	return strings.ToUpper(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,168 @@
+// internal/modules/directorio/csv.go
+package directorio
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/mail"
+	"strings"
+
+	"github.com/jhvc/backend/internal/validacion"
+)
+
+var (
+	ErrCSVVacio      = errors.New("el archivo no contiene contactos")
+	ErrCSVEncabezado = errors.New("el encabezado debe incluir rfc, razon_social, regimen_fiscal y codigo_postal")
+)
+
+// columnasCSV es el encabezado que se exporta y que se acepta al importar,
+// en cualquier orden
+var columnasCSV = []string{"rfc", "razon_social", "regimen_fiscal", "codigo_postal", "uso_cfdi", "email", "tipo"}
+
+// ExportarCSV escribe todos los contactos, activos e inactivos
+func (s *Service) ExportarCSV(empresaID int) ([]byte, error) {
+	contactos, err := s.repo.GetContactos(empresaID, FiltroContactos{Inactivos: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	// BOM para que Excel abra el archivo como UTF-8
+	buf.WriteString("\ufeff")
+	w := csv.NewWriter(&buf)
+	if err := w.Write(columnasCSV); err != nil {
+		return nil, err
+	}
+	for _, c := range contactos {
+		if err := w.Write([]string{c.RFC, c.RazonSocial, c.RegimenFiscal, c.CodigoPostal, c.UsoCFDI, c.Email, c.Tipo}); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// ImportarCSV da de alta los contactos del archivo. Cada renglón se valida
+// igual que en el alta manual; los errores se reportan por línea sin detener
+// la carga. Un RFC repetido dentro del archivo se toma una sola vez.
+func (s *Service) ImportarCSV(empresaID int, r io.Reader, actualizar bool) (*ResultadoImportacion, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	registros, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(registros) < 2 {
+		return nil, ErrCSVVacio
+	}
+
+	indice := make(map[string]int)
+	for i, col := range registros[0] {
+		col = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(col, "\ufeff")))
+		indice[strings.ReplaceAll(col, " ", "_")] = i
+	}
+	for _, requerida := range columnasCSV[:4] {
+		if _, ok := indice[requerida]; !ok {
+			return nil, ErrCSVEncabezado
+		}
+	}
+	campo := func(reg []string, col string) string {
+		if i, ok := indice[col]; ok && i < len(reg) {
+			return strings.TrimSpace(reg[i])
+		}
+		return ""
+	}
+
+	res := &ResultadoImportacion{}
+	vistos := make(map[string]bool)
+	for n, reg := range registros[1:] {
+		linea := n + 2
+		req := ContactoRequest{
+			Tipo:          strings.ToLower(campo(reg, "tipo")),
+			RFC:           campo(reg, "rfc"),
+			RazonSocial:   campo(reg, "razon_social"),
+			RegimenFiscal: campo(reg, "regimen_fiscal"),
+			CodigoPostal:  campo(reg, "codigo_postal"),
+			UsoCFDI:       campo(reg, "uso_cfdi"),
+			Email:         campo(reg, "email"),
+		}
+		if req.RFC == "" && req.RazonSocial == "" {
+			continue
+		}
+		fallo := func(err error) {
+			res.Errores = append(res.Errores, ErrorImportacion{Linea: linea, RFC: req.RFC, Error: err.Error()})
+		}
+
+		if err := validarRenglonCSV(req); err != nil {
+			fallo(err)
+			continue
+		}
+		c, err := s.armarContacto(empresaID, req)
+		if err != nil {
+			fallo(err)
+			continue
+		}
+
+		clave := c.RFC + "|" + strings.ToUpper(c.RazonSocial)
+		if !validacion.EsRFCGenerico(c.RFC) {
+			clave = c.RFC
+		}
+		if vistos[clave] {
+			res.Duplicados++
+			continue
+		}
+		vistos[clave] = true
+
+		existente, err := s.repo.GetDuplicado(empresaID, c.RFC, c.RazonSocial)
+		switch {
+		case err == sql.ErrNoRows:
+			if _, err := s.repo.CrearContacto(c); err != nil {
+				fallo(err)
+				continue
+			}
+			res.Creados++
+		case err != nil:
+			return nil, err
+		case actualizar:
+			c.ID = existente.ID
+			c.IsActive = existente.IsActive
+			if err := s.repo.ActualizarContacto(c); err != nil {
+				fallo(err)
+				continue
+			}
+			res.Actualizados++
+		default:
+			res.Duplicados++
+		}
+	}
+
+	return res, nil
+}
+
+// validarRenglonCSV repite las reglas de binding de ContactoRequest, que no
+// se aplican al leer el archivo
+func validarRenglonCSV(req ContactoRequest) error {
+	switch req.Tipo {
+	case "", TipoCliente, TipoProveedor, TipoAmbos:
+	default:
+		return errors.New("tipo debe ser cliente, proveedor o ambos")
+	}
+	if req.RazonSocial == "" || len(req.RazonSocial) > 300 {
+		return errors.New("razón social requerida (máximo 300 caracteres)")
+	}
+	if req.RegimenFiscal == "" {
+		return errors.New("régimen fiscal requerido")
+	}
+	if len(req.CodigoPostal) != 5 || strings.Trim(req.CodigoPostal, "0123456789") != "" {
+		return errors.New("código postal debe tener 5 dígitos")
+	}
+	if _, err := mail.ParseAddress(req.Email); req.Email != "" && (len(req.Email) > 255 || err != nil) {
+		return errors.New("email inválido")
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+// internal/modules/directorio/handler.go
+package directorio
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tamanoMaximoCSV limita el archivo de importación
+const tamanoMaximoCSV = 5 << 20
+
+// Handler maneja las peticiones HTTP del directorio. La empresa llega en el
+// contexto como "empresaID" (ver middleware.EmpresaMiddleware).
+type Handler struct {
+	service *Service
+}
+
+// NewHandler crea una nueva instancia del handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetContactos lista el directorio; con ?q=&limit=10 sirve para autocompletar
+// @Router /empresas/{empresaId}/contactos [get]
+func (h *Handler) GetContactos(c *gin.Context) {
+	var f FiltroContactos
+	if err := c.ShouldBindQuery(&f); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	contactos, err := h.service.GetContactos(c.GetInt("empresaID"), f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    contactos,
+	})
+}
+
+// @Router /empresas/{empresaId}/contactos/{contactoId} [get]
+func (h *Handler) GetContacto(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("contactoId"))
+
+	contacto, err := h.service.GetContacto(c.GetInt("empresaID"), id)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    contacto,
+	})
+}
+
+// GetReceptor devuelve el contacto con los campos del receptor de una factura
+// @Router /empresas/{empresaId}/contactos/{contactoId}/receptor [get]
+func (h *Handler) GetReceptor(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("contactoId"))
+
+	receptor, err := h.service.Receptor(c.GetInt("empresaID"), id)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    receptor,
+	})
+}
+
+// @Router /empresas/{empresaId}/contactos [post]
+func (h *Handler) CrearContacto(c *gin.Context) {
+	var req ContactoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	contacto, err := h.service.CrearContacto(c.GetInt("empresaID"), req)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    contacto,
+	})
+}
+
+// @Router /empresas/{empresaId}/contactos/{contactoId} [put]
+func (h *Handler) ActualizarContacto(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("contactoId"))
+
+	var req ContactoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	contacto, err := h.service.ActualizarContacto(c.GetInt("empresaID"), id, req)
+	if err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    contacto,
+	})
+}
+
+// @Router /empresas/{empresaId}/contactos/{contactoId} [delete]
+func (h *Handler) EliminarContacto(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("contactoId"))
+
+	if err := h.service.EliminarContacto(c.GetInt("empresaID"), id); err != nil {
+		responderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Contacto eliminado",
+	})
+}
+
+// ImportarCSV recibe el archivo en el campo multipart `archivo`; con
+// ?actualizar=true reemplaza los datos de los RFC que ya existen
+// @Router /empresas/{empresaId}/contactos/importar [post]
+func (h *Handler) ImportarCSV(c *gin.Context) {
+	fh, err := c.FormFile("archivo")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "archivo requerido"})
+		return
+	}
+	if fh.Size > tamanoMaximoCSV {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "el archivo excede 5 MB"})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer f.Close()
+
+	actualizar := c.Query("actualizar") == "true"
+	res, err := h.service.ImportarCSV(c.GetInt("empresaID"), f, actualizar)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    res,
+	})
+}
+
+// @Router /empresas/{empresaId}/contactos/exportar [get]
+func (h *Handler) ExportarCSV(c *gin.Context) {
+	data, err := h.service.ExportarCSV(c.GetInt("empresaID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="contactos.csv"`)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+}
+
+func responderError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, ErrContactoNoEncontrado):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrContactoDuplicado):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
@@ -0,0 +1,74 @@
+// internal/modules/directorio/models.go
+package directorio
+
+import "time"
+
+// Tipos de contacto; "ambos" aparece tanto en clientes como en proveedores
+const (
+	TipoCliente   = "cliente"
+	TipoProveedor = "proveedor"
+	TipoAmbos     = "ambos"
+)
+
+// Contacto es un cliente o proveedor de la empresa con sus datos fiscales
+type Contacto struct {
+	ID            int       `json:"id"`
+	EmpresaID     int       `json:"empresa_id"`
+	Tipo          string    `json:"tipo"`
+	RFC           string    `json:"rfc"`
+	RazonSocial   string    `json:"razon_social"`
+	RegimenFiscal string    `json:"regimen_fiscal"`
+	CodigoPostal  string    `json:"codigo_postal"`
+	UsoCFDI       string    `json:"uso_cfdi,omitempty"`
+	Email         string    `json:"email,omitempty"`
+	IsActive      bool      `json:"is_active"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type ContactoRequest struct {
+	Tipo          string `json:"tipo" binding:"omitempty,oneof=cliente proveedor ambos"`
+	RFC           string `json:"rfc" binding:"required,rfc"`
+	RazonSocial   string `json:"razon_social" binding:"required,max=300"`
+	RegimenFiscal string `json:"regimen_fiscal" binding:"required"`
+	CodigoPostal  string `json:"codigo_postal" binding:"required,len=5,numeric"`
+	UsoCFDI       string `json:"uso_cfdi"`
+	Email         string `json:"email" binding:"omitempty,email,max=255"`
+	IsActive      *bool  `json:"is_active"`
+}
+
+// FiltroContactos sirve para listar y para autocompletar: q busca al inicio
+// del RFC o en cualquier parte de la razón social
+type FiltroContactos struct {
+	Q         string `form:"q"`
+	Tipo      string `form:"tipo" binding:"omitempty,oneof=cliente proveedor"`
+	Limit     int    `form:"limit" binding:"min=0"`
+	Inactivos bool   `form:"inactivos"`
+}
+
+// ReceptorCFDI son los datos del contacto con los nombres de campo del
+// receptor de una factura, para llenarla desde el directorio
+type ReceptorCFDI struct {
+	Rfc                     string `json:"rfc"`
+	Nombre                  string `json:"nombre"`
+	DomicilioFiscalReceptor string `json:"domicilio_fiscal"`
+	RegimenFiscalReceptor   string `json:"regimen_fiscal"`
+	UsoCFDI                 string `json:"uso_cfdi"`
+	Email                   string `json:"email,omitempty"`
+}
+
+// ErrorImportacion indica qué renglón del CSV no se pudo importar
+type ErrorImportacion struct {
+	Linea int    `json:"linea"`
+	RFC   string `json:"rfc,omitempty"`
+	Error string `json:"error"`
+}
+
+// ResultadoImportacion resume la carga de un CSV. Los RFC que ya existen se
+// actualizan sólo si se pidió; si no, cuentan como duplicados.
+type ResultadoImportacion struct {
+	Creados      int                `json:"creados"`
+	Actualizados int                `json:"actualizados"`
+	Duplicados   int                `json:"duplicados"`
+	Errores      []ErrorImportacion `json:"errores,omitempty"`
+}
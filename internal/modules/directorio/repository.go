@@ -0,0 +1,123 @@
+// internal/modules/directorio/repository.go
+package directorio
+
+import (
+	"database/sql"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const columnasContacto = `id, empresa_id, tipo, rfc, razon_social, regimen_fiscal, codigo_postal,
+        uso_cfdi, email, is_active, created_at, updated_at`
+
+// claveDuplicado replica el índice único idx_contactos_rfc: un RFC por
+// empresa, salvo los genéricos, que se distinguen por la razón social
+const claveDuplicado = `(CASE WHEN rfc IN ('XAXX010101000', 'XEXX010101000') THEN UPPER(razon_social) ELSE '' END)`
+
+func (r *Repository) CrearContacto(c *Contacto) (int, error) {
+	var id int
+	err := r.db.QueryRow(`
+        INSERT INTO contactos (empresa_id, tipo, rfc, razon_social, regimen_fiscal, codigo_postal,
+            uso_cfdi, email, is_active)
+        VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), $9)
+        RETURNING id
+    `, c.EmpresaID, c.Tipo, c.RFC, c.RazonSocial, c.RegimenFiscal, c.CodigoPostal,
+		c.UsoCFDI, c.Email, c.IsActive).Scan(&id)
+	return id, err
+}
+
+func (r *Repository) ActualizarContacto(c *Contacto) error {
+	_, err := r.db.Exec(`
+        UPDATE contactos
+        SET tipo = $1, rfc = $2, razon_social = $3, regimen_fiscal = $4, codigo_postal = $5,
+            uso_cfdi = NULLIF($6, ''), email = NULLIF($7, ''), is_active = $8, updated_at = CURRENT_TIMESTAMP
+        WHERE empresa_id = $9 AND id = $10
+    `, c.Tipo, c.RFC, c.RazonSocial, c.RegimenFiscal, c.CodigoPostal,
+		c.UsoCFDI, c.Email, c.IsActive, c.EmpresaID, c.ID)
+	return err
+}
+
+func (r *Repository) EliminarContacto(empresaID, id int) error {
+	_, err := r.db.Exec(`DELETE FROM contactos WHERE empresa_id = $1 AND id = $2`, empresaID, id)
+	return err
+}
+
+func (r *Repository) GetContacto(empresaID, id int) (*Contacto, error) {
+	row := r.db.QueryRow(`
+        SELECT `+columnasContacto+`
+        FROM contactos WHERE empresa_id = $1 AND id = $2
+    `, empresaID, id)
+	return scanContacto(row)
+}
+
+// GetDuplicado busca el contacto que ocupa el mismo RFC (y razón social, si
+// el RFC es genérico); devuelve sql.ErrNoRows si no hay
+func (r *Repository) GetDuplicado(empresaID int, rfc, razonSocial string) (*Contacto, error) {
+	row := r.db.QueryRow(`
+        SELECT `+columnasContacto+`
+        FROM contactos
+        WHERE empresa_id = $1 AND rfc = $2
+          AND `+claveDuplicado+` = (CASE WHEN $2 IN ('XAXX010101000', 'XEXX010101000') THEN UPPER($3) ELSE '' END)
+    `, empresaID, rfc, razonSocial)
+	return scanContacto(row)
+}
+
+// GetContactos filtra por tipo y texto; con q, primero van los RFC que
+// empiezan con el texto. q ya viene escapado para ILIKE.
+func (r *Repository) GetContactos(empresaID int, f FiltroContactos) ([]Contacto, error) {
+	var limit interface{}
+	if f.Limit > 0 {
+		limit = f.Limit
+	}
+
+	rows, err := r.db.Query(`
+        SELECT `+columnasContacto+`
+        FROM contactos
+        WHERE empresa_id = $1
+          AND ($2 = '' OR rfc ILIKE $2 || '%' OR razon_social ILIKE '%' || $2 || '%')
+          AND ($3 = '' OR tipo = $3 OR tipo = 'ambos')
+          AND ($4 OR is_active)
+        ORDER BY ($2 <> '' AND rfc ILIKE $2 || '%') DESC, razon_social, rfc
+        LIMIT $5
+    `, empresaID, f.Q, f.Tipo, f.Inactivos, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contactos []Contacto
+	for rows.Next() {
+		c, err := scanContacto(rows)
+		if err != nil {
+			continue
+		}
+		contactos = append(contactos, *c)
+	}
+
+	return contactos, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanContacto(row rowScanner) (*Contacto, error) {
+	var c Contacto
+	var usoCFDI, email sql.NullString
+
+	err := row.Scan(&c.ID, &c.EmpresaID, &c.Tipo, &c.RFC, &c.RazonSocial, &c.RegimenFiscal, &c.CodigoPostal,
+		&usoCFDI, &email, &c.IsActive, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	c.UsoCFDI = usoCFDI.String
+	c.Email = email.String
+	return &c, nil
+}
@@ -0,0 +1,174 @@
+// internal/modules/directorio/service.go
+package directorio
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jhvc/backend/internal/modules/catalogos"
+	"github.com/jhvc/backend/internal/validacion"
+)
+
+var (
+	ErrContactoNoEncontrado = errors.New("contacto no encontrado")
+	ErrContactoDuplicado    = errors.New("ya existe un contacto con ese RFC")
+)
+
+const limiteBusquedaMaximo = 200
+
+// Service maneja el directorio de clientes y proveedores de cada empresa
+type Service struct {
+	repo      *Repository
+	catalogos *catalogos.Service
+}
+
+// NewService crea una nueva instancia del servicio
+func NewService(repo *Repository, cat *catalogos.Service) *Service {
+	return &Service{repo: repo, catalogos: cat}
+}
+
+func (s *Service) GetContactos(empresaID int, f FiltroContactos) ([]Contacto, error) {
+	f.Q = escaparLike(strings.TrimSpace(f.Q))
+	if f.Limit > limiteBusquedaMaximo {
+		f.Limit = limiteBusquedaMaximo
+	}
+	return s.repo.GetContactos(empresaID, f)
+}
+
+func (s *Service) GetContacto(empresaID, id int) (*Contacto, error) {
+	c, err := s.repo.GetContacto(empresaID, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrContactoNoEncontrado
+	}
+	return c, err
+}
+
+// Receptor devuelve el contacto con la forma del receptor de una factura
+func (s *Service) Receptor(empresaID, id int) (*ReceptorCFDI, error) {
+	c, err := s.GetContacto(empresaID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &ReceptorCFDI{
+		Rfc:                     c.RFC,
+		Nombre:                  c.RazonSocial,
+		DomicilioFiscalReceptor: c.CodigoPostal,
+		RegimenFiscalReceptor:   c.RegimenFiscal,
+		UsoCFDI:                 c.UsoCFDI,
+		Email:                   c.Email,
+	}, nil
+}
+
+func (s *Service) CrearContacto(empresaID int, req ContactoRequest) (*Contacto, error) {
+	c, err := s.armarContacto(empresaID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetDuplicado(empresaID, c.RFC, c.RazonSocial); err == nil {
+		return nil, ErrContactoDuplicado
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	id, err := s.repo.CrearContacto(c)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetContacto(empresaID, id)
+}
+
+func (s *Service) ActualizarContacto(empresaID, id int, req ContactoRequest) (*Contacto, error) {
+	actual, err := s.GetContacto(empresaID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.armarContacto(empresaID, req)
+	if err != nil {
+		return nil, err
+	}
+	c.ID = id
+	if req.IsActive == nil {
+		c.IsActive = actual.IsActive
+	}
+
+	if otro, err := s.repo.GetDuplicado(empresaID, c.RFC, c.RazonSocial); err == nil && otro.ID != id {
+		return nil, ErrContactoDuplicado
+	} else if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if err := s.repo.ActualizarContacto(c); err != nil {
+		return nil, err
+	}
+	return s.GetContacto(empresaID, id)
+}
+
+func (s *Service) EliminarContacto(empresaID, id int) error {
+	if _, err := s.GetContacto(empresaID, id); err != nil {
+		return err
+	}
+	return s.repo.EliminarContacto(empresaID, id)
+}
+
+// armarContacto normaliza y valida los datos fiscales: RFC con dígito
+// verificador, régimen que aplique al tipo de persona y, si se da, un uso de
+// CFDI compatible con ese régimen
+func (s *Service) armarContacto(empresaID int, req ContactoRequest) (*Contacto, error) {
+	info, err := validacion.ValidarRFC(req.RFC)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Contacto{
+		EmpresaID:     empresaID,
+		Tipo:          req.Tipo,
+		RFC:           info.RFC,
+		RazonSocial:   strings.Join(strings.Fields(req.RazonSocial), " "),
+		RegimenFiscal: strings.TrimSpace(req.RegimenFiscal),
+		CodigoPostal:  req.CodigoPostal,
+		UsoCFDI:       strings.ToUpper(strings.TrimSpace(req.UsoCFDI)),
+		Email:         strings.ToLower(strings.TrimSpace(req.Email)),
+		IsActive:      true,
+	}
+	if c.Tipo == "" {
+		c.Tipo = TipoCliente
+	}
+	if req.IsActive != nil {
+		c.IsActive = *req.IsActive
+	}
+	if c.RazonSocial == "" {
+		return nil, errors.New("razón social requerida")
+	}
+
+	ok, err := s.catalogos.RegimenAplica(c.RegimenFiscal, info.Tipo)
+	if err == catalogos.ErrClaveNoEncontrada {
+		return nil, fmt.Errorf("régimen fiscal %s no existe en el catálogo", c.RegimenFiscal)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("el régimen fiscal no aplica para persona " + info.Tipo)
+	}
+
+	if c.UsoCFDI != "" {
+		compat, err := s.catalogos.ValidarUsoCFDI(c.UsoCFDI, c.RegimenFiscal, info.Tipo)
+		if err != nil {
+			return nil, err
+		}
+		if !compat.Permitido {
+			return nil, fmt.Errorf("uso CFDI %s: %s", c.UsoCFDI, compat.Motivo)
+		}
+	}
+
+	return c, nil
+}
+
+// escaparLike evita que % y _ del texto buscado funcionen como comodines
+func escaparLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
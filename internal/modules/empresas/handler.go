@@ -0,0 +1,203 @@
+// internal/modules/empresas/handler.go
+package empresas
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler maneja las peticiones HTTP de empresas
+type Handler struct {
+	service *Service
+}
+
+// NewHandler crea una nueva instancia del handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Crear registra una empresa y opcionalmente su catálogo de cuentas base
+// @Router /empresas [post]
+func (h *Handler) Crear(c *gin.Context) {
+	var req CrearEmpresaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	res, err := h.service.Crear(c.GetInt("userID"), req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if err == ErrEmpresaDuplicada {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Empresa creada",
+		"data":    res,
+	})
+}
+
+// GetEmpresas lista las empresas de las que el usuario es miembro, con su rol
+// @Router /empresas [get]
+func (h *Handler) GetEmpresas(c *gin.Context) {
+	empresas, err := h.service.GetEmpresas(c.GetInt("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    empresas,
+	})
+}
+
+// GetEmpresa devuelve una empresa
+// @Router /empresas/{empresaId} [get]
+func (h *Handler) GetEmpresa(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("empresaId"))
+
+	empresa, err := h.service.GetEmpresa(c.GetInt("userID"), id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == ErrEmpresaNoEncontrada {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    empresa,
+	})
+}
+
+// Actualizar modifica los datos fiscales de la empresa (el RFC no cambia)
+// @Router /empresas/{empresaId} [put]
+func (h *Handler) Actualizar(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("empresaId"))
+
+	var req ActualizarEmpresaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	empresa, err := h.service.Actualizar(c.GetInt("userID"), id, req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if err == ErrEmpresaNoEncontrada {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Empresa actualizada",
+		"data":    empresa,
+	})
+}
+
+// GetMiembros lista los usuarios con acceso a la empresa
+// @Router /empresas/{empresaId}/miembros [get]
+func (h *Handler) GetMiembros(c *gin.Context) {
+	miembros, err := h.service.GetMiembros(c.GetInt("empresaID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    miembros,
+	})
+}
+
+// AgregarMiembro da acceso a un usuario registrado (sólo propietarios)
+// @Router /empresas/{empresaId}/miembros [post]
+func (h *Handler) AgregarMiembro(c *gin.Context) {
+	var req MiembroRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	miembro, err := h.service.AgregarMiembro(c.GetInt("empresaID"), req)
+	if err != nil {
+		responderErrorMiembro(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Miembro agregado",
+		"data":    miembro,
+	})
+}
+
+// ActualizarMiembro cambia el rol de un miembro (sólo propietarios)
+// @Router /empresas/{empresaId}/miembros/{userId} [put]
+func (h *Handler) ActualizarMiembro(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("userId"))
+
+	var req RolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	miembro, err := h.service.ActualizarMiembro(c.GetInt("empresaID"), userID, req)
+	if err != nil {
+		responderErrorMiembro(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Rol actualizado",
+		"data":    miembro,
+	})
+}
+
+// EliminarMiembro quita el acceso a la empresa. Los propietarios pueden quitar
+// a cualquiera; los demás miembros, incluidos los lectores, sólo a sí mismos.
+// @Router /empresas/{empresaId}/miembros/{userId} [delete]
+func (h *Handler) EliminarMiembro(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("userId"))
+
+	if userID != c.GetInt("userID") && c.GetString("rolEmpresa") != RolPropietario {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "error": "sólo un propietario puede quitar a otros miembros"})
+		return
+	}
+
+	if err := h.service.EliminarMiembro(c.GetInt("empresaID"), userID); err != nil {
+		responderErrorMiembro(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Miembro eliminado",
+	})
+}
+
+func responderErrorMiembro(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch err {
+	case ErrMiembroNoEncontrado, ErrUsuarioNoEncontrado:
+		status = http.StatusNotFound
+	case ErrMiembroDuplicado, ErrUltimoPropietario:
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
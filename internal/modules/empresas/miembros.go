@@ -0,0 +1,95 @@
+// internal/modules/empresas/miembros.go
+package empresas
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var (
+	ErrMiembroNoEncontrado = errors.New("el usuario no es miembro de la empresa")
+	ErrMiembroDuplicado    = errors.New("el usuario ya es miembro de la empresa")
+	ErrUsuarioNoEncontrado = errors.New("no hay un usuario activo con ese email")
+	ErrUltimoPropietario   = errors.New("la empresa debe conservar al menos un propietario")
+)
+
+// PuedeEscribir indica si el rol permite capturar o modificar datos
+func PuedeEscribir(rol string) bool {
+	return rol == RolPropietario || rol == RolContador
+}
+
+func (s *Service) GetMiembros(empresaID int) ([]Miembro, error) {
+	return s.repo.GetMiembros(empresaID)
+}
+
+// AgregarMiembro da acceso a la empresa a un usuario ya registrado
+func (s *Service) AgregarMiembro(empresaID int, req MiembroRequest) (*Miembro, error) {
+	userID, err := s.repo.GetUsuarioPorEmail(req.Email)
+	if err == sql.ErrNoRows {
+		return nil, ErrUsuarioNoEncontrado
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetMiembro(empresaID, userID); err == nil {
+		return nil, ErrMiembroDuplicado
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if err := s.repo.AgregarMiembro(empresaID, userID, req.Rol); err != nil {
+		return nil, err
+	}
+	return s.repo.GetMiembro(empresaID, userID)
+}
+
+func (s *Service) ActualizarMiembro(empresaID, userID int, req RolRequest) (*Miembro, error) {
+	m, err := s.getMiembro(empresaID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Rol == RolPropietario && req.Rol != RolPropietario {
+		if err := s.validarOtroPropietario(empresaID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.ActualizarRol(empresaID, userID, req.Rol); err != nil {
+		return nil, err
+	}
+	return s.repo.GetMiembro(empresaID, userID)
+}
+
+// EliminarMiembro quita el acceso; un usuario también puede salir por sí mismo
+func (s *Service) EliminarMiembro(empresaID, userID int) error {
+	m, err := s.getMiembro(empresaID, userID)
+	if err != nil {
+		return err
+	}
+	if m.Rol == RolPropietario {
+		if err := s.validarOtroPropietario(empresaID); err != nil {
+			return err
+		}
+	}
+	return s.repo.EliminarMiembro(empresaID, userID)
+}
+
+func (s *Service) getMiembro(empresaID, userID int) (*Miembro, error) {
+	m, err := s.repo.GetMiembro(empresaID, userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrMiembroNoEncontrado
+	}
+	return m, err
+}
+
+func (s *Service) validarOtroPropietario(empresaID int) error {
+	n, err := s.repo.ContarPropietarios(empresaID)
+	if err != nil {
+		return err
+	}
+	if n <= 1 {
+		return ErrUltimoPropietario
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// internal/modules/empresas/models.go
+package empresas
+
+import "time"
+
+// Roles de un usuario en una empresa: el propietario administra la empresa y
+// sus miembros, el contador captura y el lector sólo consulta
+const (
+	RolPropietario = "owner"
+	RolContador    = "accountant"
+	RolLector      = "viewer"
+)
+
+// Empresa es un contribuyente cuya contabilidad se lleva en el sistema.
+// UserID es quien la registró; el acceso se da por membresía (Rol).
+type Empresa struct {
+	ID            int       `json:"id"`
+	UserID        int       `json:"user_id"`
+	Rol           string    `json:"rol,omitempty"`
+	RFC           string    `json:"rfc"`
+	RazonSocial   string    `json:"razon_social"`
+	RegimenFiscal string    `json:"regimen_fiscal"`
+	CodigoPostal  string    `json:"codigo_postal"`
+	IsActive      bool      `json:"is_active"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type CrearEmpresaRequest struct {
+	RFC           string `json:"rfc" binding:"required,rfc"`
+	RazonSocial   string `json:"razon_social" binding:"required"`
+	RegimenFiscal string `json:"regimen_fiscal" binding:"required"`
+	CodigoPostal  string `json:"codigo_postal" binding:"required,len=5,numeric"`
+	// PlantillaCuentas carga el catálogo de cuentas base al crear la empresa
+	PlantillaCuentas bool `json:"plantilla_cuentas"`
+}
+
+type ActualizarEmpresaRequest struct {
+	RazonSocial   string `json:"razon_social" binding:"required"`
+	RegimenFiscal string `json:"regimen_fiscal" binding:"required"`
+	CodigoPostal  string `json:"codigo_postal" binding:"required,len=5,numeric"`
+	IsActive      *bool  `json:"is_active"`
+}
+
+// ResultadoCreacion incluye cuántas cuentas se cargaron de la plantilla
+type ResultadoCreacion struct {
+	Empresa      Empresa  `json:"empresa"`
+	Cuentas      int      `json:"cuentas,omitempty"`
+	Advertencias []string `json:"advertencias,omitempty"`
+}
+
+// Miembro es un usuario con acceso a la empresa
+type Miembro struct {
+	EmpresaID int       `json:"empresa_id"`
+	UserID    int       `json:"user_id"`
+	Email     string    `json:"email"`
+	FullName  string    `json:"full_name"`
+	Rol       string    `json:"rol"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MiembroRequest da acceso a un usuario ya registrado, identificado por email
+type MiembroRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Rol   string `json:"rol" binding:"required,oneof=owner accountant viewer"`
+}
+
+type RolRequest struct {
+	Rol string `json:"rol" binding:"required,oneof=owner accountant viewer"`
+}
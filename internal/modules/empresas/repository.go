@@ -0,0 +1,187 @@
+// internal/modules/empresas/repository.go
+package empresas
+
+import "database/sql"
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// CrearEmpresa registra la empresa con su creador como propietario
+func (r *Repository) CrearEmpresa(e *Empresa) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`
+        INSERT INTO empresas (user_id, rfc, razon_social, regimen_fiscal, codigo_postal)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id
+    `, e.UserID, e.RFC, e.RazonSocial, e.RegimenFiscal, e.CodigoPostal).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO empresa_miembros (empresa_id, user_id, rol) VALUES ($1, $2, $3)`,
+		id, e.UserID, RolPropietario)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+func (r *Repository) GetEmpresas(userID int) ([]Empresa, error) {
+	rows, err := r.db.Query(`
+        SELECT e.id, e.user_id, m.rol, e.rfc, e.razon_social, e.regimen_fiscal, e.codigo_postal,
+            e.is_active, e.created_at, e.updated_at
+        FROM empresas e
+        JOIN empresa_miembros m ON m.empresa_id = e.id
+        WHERE m.user_id = $1
+        ORDER BY e.razon_social
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var empresas []Empresa
+	for rows.Next() {
+		var e Empresa
+		err := rows.Scan(&e.ID, &e.UserID, &e.Rol, &e.RFC, &e.RazonSocial, &e.RegimenFiscal, &e.CodigoPostal,
+			&e.IsActive, &e.CreatedAt, &e.UpdatedAt)
+		if err != nil {
+			continue
+		}
+		empresas = append(empresas, e)
+	}
+
+	return empresas, nil
+}
+
+// GetEmpresa devuelve la empresa con el rol del usuario, si es miembro
+func (r *Repository) GetEmpresa(userID, id int) (*Empresa, error) {
+	var e Empresa
+	err := r.db.QueryRow(`
+        SELECT e.id, e.user_id, m.rol, e.rfc, e.razon_social, e.regimen_fiscal, e.codigo_postal,
+            e.is_active, e.created_at, e.updated_at
+        FROM empresas e
+        JOIN empresa_miembros m ON m.empresa_id = e.id
+        WHERE m.user_id = $1 AND e.id = $2
+    `, userID, id).Scan(&e.ID, &e.UserID, &e.Rol, &e.RFC, &e.RazonSocial, &e.RegimenFiscal, &e.CodigoPostal,
+		&e.IsActive, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *Repository) ActualizarEmpresa(e *Empresa) error {
+	_, err := r.db.Exec(`
+        UPDATE empresas
+        SET razon_social = $1, regimen_fiscal = $2, codigo_postal = $3, is_active = $4,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE id = $5
+    `, e.RazonSocial, e.RegimenFiscal, e.CodigoPostal, e.IsActive, e.ID)
+	return err
+}
+
+// ExisteRFC indica si el usuario ya tiene acceso a una empresa con ese RFC
+func (r *Repository) ExisteRFC(userID int, rfc string) (bool, error) {
+	var existe bool
+	err := r.db.QueryRow(`
+        SELECT EXISTS(
+            SELECT 1 FROM empresas e
+            JOIN empresa_miembros m ON m.empresa_id = e.id
+            WHERE m.user_id = $1 AND e.rfc = $2
+        )
+    `, userID, rfc).Scan(&existe)
+	return existe, err
+}
+
+const columnasMiembro = `m.empresa_id, m.user_id, u.email, u.full_name, m.rol, m.created_at`
+
+func (r *Repository) GetMiembros(empresaID int) ([]Miembro, error) {
+	rows, err := r.db.Query(`
+        SELECT `+columnasMiembro+`
+        FROM empresa_miembros m
+        JOIN users u ON u.id = m.user_id
+        WHERE m.empresa_id = $1
+        ORDER BY m.created_at
+    `, empresaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var miembros []Miembro
+	for rows.Next() {
+		m, err := scanMiembro(rows)
+		if err != nil {
+			continue
+		}
+		miembros = append(miembros, *m)
+	}
+
+	return miembros, nil
+}
+
+func (r *Repository) GetMiembro(empresaID, userID int) (*Miembro, error) {
+	row := r.db.QueryRow(`
+        SELECT `+columnasMiembro+`
+        FROM empresa_miembros m
+        JOIN users u ON u.id = m.user_id
+        WHERE m.empresa_id = $1 AND m.user_id = $2
+    `, empresaID, userID)
+	return scanMiembro(row)
+}
+
+// GetUsuarioPorEmail devuelve el id de un usuario activo
+func (r *Repository) GetUsuarioPorEmail(email string) (int, error) {
+	var id int
+	err := r.db.QueryRow(`SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND is_active = true`, email).Scan(&id)
+	return id, err
+}
+
+func (r *Repository) AgregarMiembro(empresaID, userID int, rol string) error {
+	_, err := r.db.Exec(`INSERT INTO empresa_miembros (empresa_id, user_id, rol) VALUES ($1, $2, $3)`,
+		empresaID, userID, rol)
+	return err
+}
+
+func (r *Repository) ActualizarRol(empresaID, userID int, rol string) error {
+	_, err := r.db.Exec(`UPDATE empresa_miembros SET rol = $1 WHERE empresa_id = $2 AND user_id = $3`,
+		rol, empresaID, userID)
+	return err
+}
+
+func (r *Repository) EliminarMiembro(empresaID, userID int) error {
+	_, err := r.db.Exec(`DELETE FROM empresa_miembros WHERE empresa_id = $1 AND user_id = $2`, empresaID, userID)
+	return err
+}
+
+func (r *Repository) ContarPropietarios(empresaID int) (int, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM empresa_miembros WHERE empresa_id = $1 AND rol = $2`,
+		empresaID, RolPropietario).Scan(&n)
+	return n, err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMiembro(row rowScanner) (*Miembro, error) {
+	var m Miembro
+	if err := row.Scan(&m.EmpresaID, &m.UserID, &m.Email, &m.FullName, &m.Rol, &m.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -0,0 +1,136 @@
+// internal/modules/empresas/service.go
+package empresas
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/jhvc/backend/internal/modules/catalogos"
+	"github.com/jhvc/backend/internal/validacion"
+)
+
+var (
+	ErrEmpresaNoEncontrada = errors.New("empresa no encontrada")
+	ErrEmpresaDuplicada    = errors.New("ya existe una empresa con ese RFC")
+)
+
+// PlantillaCuentas carga el catálogo de cuentas inicial de una empresa nueva.
+// La implementa el módulo de contabilidad.
+type PlantillaCuentas interface {
+	ImportarPlantilla(empresaID int) (int, error)
+}
+
+type Service struct {
+	repo      *Repository
+	catalogos *catalogos.Service
+	plantilla PlantillaCuentas
+}
+
+func NewService(repo *Repository, cat *catalogos.Service) *Service {
+	return &Service{
+		repo:      repo,
+		catalogos: cat,
+	}
+}
+
+// UsarPlantilla conecta el módulo que carga el catálogo de cuentas inicial.
+// Se separa del constructor porque contabilidad depende a su vez de empresas.
+func (s *Service) UsarPlantilla(p PlantillaCuentas) {
+	s.plantilla = p
+}
+
+func (s *Service) Crear(userID int, req CrearEmpresaRequest) (*ResultadoCreacion, error) {
+	info, err := validacion.ValidarRFC(req.RFC)
+	if err != nil {
+		return nil, err
+	}
+	if info.Generico {
+		return nil, errors.New("una empresa no puede usar un RFC genérico")
+	}
+	if err := s.validarRegimen(req.RegimenFiscal, info.Tipo); err != nil {
+		return nil, err
+	}
+
+	existe, err := s.repo.ExisteRFC(userID, info.RFC)
+	if err != nil {
+		return nil, err
+	}
+	if existe {
+		return nil, ErrEmpresaDuplicada
+	}
+
+	empresa := Empresa{
+		UserID:        userID,
+		RFC:           info.RFC,
+		RazonSocial:   strings.TrimSpace(req.RazonSocial),
+		RegimenFiscal: req.RegimenFiscal,
+		CodigoPostal:  req.CodigoPostal,
+		IsActive:      true,
+	}
+	empresa.ID, err = s.repo.CrearEmpresa(&empresa)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ResultadoCreacion{Empresa: empresa}
+	if req.PlantillaCuentas && s.plantilla != nil {
+		// La empresa ya existe; si la plantilla falla se puede importar después
+		res.Cuentas, err = s.plantilla.ImportarPlantilla(empresa.ID)
+		if err != nil {
+			res.Advertencias = append(res.Advertencias, "no se cargó la plantilla de cuentas: "+err.Error())
+		}
+	}
+
+	return res, nil
+}
+
+func (s *Service) GetEmpresas(userID int) ([]Empresa, error) {
+	return s.repo.GetEmpresas(userID)
+}
+
+func (s *Service) GetEmpresa(userID, id int) (*Empresa, error) {
+	e, err := s.repo.GetEmpresa(userID, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrEmpresaNoEncontrada
+	}
+	return e, err
+}
+
+func (s *Service) Actualizar(userID, id int, req ActualizarEmpresaRequest) (*Empresa, error) {
+	empresa, err := s.GetEmpresa(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := validacion.ValidarRFC(empresa.RFC)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validarRegimen(req.RegimenFiscal, info.Tipo); err != nil {
+		return nil, err
+	}
+
+	empresa.RazonSocial = strings.TrimSpace(req.RazonSocial)
+	empresa.RegimenFiscal = req.RegimenFiscal
+	empresa.CodigoPostal = req.CodigoPostal
+	if req.IsActive != nil {
+		empresa.IsActive = *req.IsActive
+	}
+
+	if err := s.repo.ActualizarEmpresa(empresa); err != nil {
+		return nil, err
+	}
+	return empresa, nil
+}
+
+func (s *Service) validarRegimen(regimen, tipoPersona string) error {
+	ok, err := s.catalogos.RegimenAplica(regimen, tipoPersona)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("el régimen fiscal no aplica para persona " + tipoPersona)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// internal/modules/facturacion/cadena.go
+package facturacion
+
+import "strings"
+
+// cadena acumula los atributos en el orden de la hoja XSLT del SAT
+type cadena struct {
+	b strings.Builder
+}
+
+// requerido agrega el atributo aunque esté vacío
+func (c *cadena) requerido(v string) {
+	c.b.WriteString("|")
+	c.b.WriteString(normalizarEspacios(v))
+}
+
+// opcional agrega el atributo sólo si tiene valor
+func (c *cadena) opcional(v string) {
+	if v != "" {
+		c.requerido(v)
+	}
+}
+
+// CadenaOriginal replica en Go la transformación cadenaoriginal_4_0.xslt para
+// los nodos que genera este módulo (comprobante, relacionados, emisor, receptor,
+// conceptos con impuestos, resumen de impuestos e implocal). Se sella con ella.
+func CadenaOriginal(comp *Comprobante) string {
+	var c cadena
+	c.b.WriteString("|")
+
+	c.requerido(comp.Version)
+	c.opcional(comp.Serie)
+	c.opcional(comp.Folio)
+	c.requerido(comp.Fecha)
+	c.opcional(comp.FormaPago)
+	c.requerido(comp.NoCertificado)
+	c.opcional(comp.CondicionesDePago)
+	c.requerido(comp.SubTotal)
+	c.opcional(comp.Descuento)
+	c.requerido(comp.Moneda)
+	c.opcional(comp.TipoCambio)
+	c.requerido(comp.Total)
+	c.requerido(comp.TipoDeComprobante)
+	c.requerido(comp.Exportacion)
+	c.opcional(comp.MetodoPago)
+	c.requerido(comp.LugarExpedicion)
+
+	if rel := comp.CfdiRelacionados; rel != nil {
+		c.requerido(rel.TipoRelacion)
+		for _, r := range rel.CfdiRelacionado {
+			c.requerido(r.UUID)
+		}
+	}
+
+	c.requerido(comp.Emisor.Rfc)
+	c.requerido(comp.Emisor.Nombre)
+	c.requerido(comp.Emisor.RegimenFiscal)
+
+	c.requerido(comp.Receptor.Rfc)
+	c.requerido(comp.Receptor.Nombre)
+	c.requerido(comp.Receptor.DomicilioFiscalReceptor)
+	c.requerido(comp.Receptor.RegimenFiscalReceptor)
+	c.requerido(comp.Receptor.UsoCFDI)
+
+	for _, con := range comp.Conceptos {
+		c.requerido(con.ClaveProdServ)
+		c.opcional(con.NoIdentificacion)
+		c.requerido(con.Cantidad)
+		c.requerido(con.ClaveUnidad)
+		c.opcional(con.Unidad)
+		c.requerido(con.Descripcion)
+		c.requerido(con.ValorUnitario)
+		c.requerido(con.Importe)
+		c.opcional(con.Descuento)
+		c.requerido(con.ObjetoImp)
+
+		if imp := con.Impuestos; imp != nil {
+			for _, t := range imp.Traslados {
+				c.requerido(t.Base)
+				c.requerido(t.Impuesto)
+				c.requerido(t.TipoFactor)
+				c.opcional(t.TasaOCuota)
+				c.opcional(t.Importe)
+			}
+			for _, r := range imp.Retenciones {
+				c.requerido(r.Base)
+				c.requerido(r.Impuesto)
+				c.requerido(r.TipoFactor)
+				c.requerido(r.TasaOCuota)
+				c.requerido(r.Importe)
+			}
+		}
+	}
+
+	if imp := comp.Impuestos; imp != nil {
+		for _, r := range imp.Retenciones {
+			c.requerido(r.Impuesto)
+			c.requerido(r.Importe)
+		}
+		c.opcional(imp.TotalImpuestosRetenidos)
+		for _, t := range imp.Traslados {
+			c.requerido(t.Base)
+			c.requerido(t.Impuesto)
+			c.requerido(t.TipoFactor)
+			c.opcional(t.TasaOCuota)
+			c.opcional(t.Importe)
+		}
+		c.opcional(imp.TotalImpuestosTrasladados)
+	}
+
+	if comp.Complemento != nil && comp.Complemento.ImpuestosLocales != nil {
+		loc := comp.Complemento.ImpuestosLocales
+		c.requerido(loc.Version)
+		c.requerido(loc.TotaldeRetenciones)
+		c.requerido(loc.TotaldeTraslados)
+		for _, r := range loc.RetencionesLocales {
+			c.requerido(r.ImpLocRetenido)
+			c.requerido(r.TasadeRetencion)
+			c.requerido(r.Importe)
+		}
+		for _, t := range loc.TrasladosLocales {
+			c.requerido(t.ImpLocTrasladado)
+			c.requerido(t.TasadeTraslado)
+			c.requerido(t.Importe)
+		}
+	}
+
+	c.b.WriteString("||")
+	return c.b.String()
+}
+
+// normalizarEspacios equivale a normalize-space() de XPath
+func normalizarEspacios(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
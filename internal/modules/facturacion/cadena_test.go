@@ -0,0 +1,80 @@
+package facturacion
+
+import "testing"
+
+func TestCadenaOriginal(t *testing.T) {
+	comp := &Comprobante{
+		Version:           "4.0",
+		Serie:             "A",
+		Folio:             "100",
+		Fecha:             "2025-03-10T12:30:00",
+		FormaPago:         "03",
+		NoCertificado:     "30001000000500003416",
+		SubTotal:          "1000.00",
+		Moneda:            "MXN",
+		Total:             "1160.00",
+		TipoDeComprobante: "I",
+		Exportacion:       "01",
+		MetodoPago:        "PUE",
+		LugarExpedicion:   "42501",
+		// El sello y el certificado no forman parte de la cadena
+		Sello:       "c2VsbG8=",
+		Certificado: "Y2VydA==",
+		CfdiRelacionados: &CfdiRelacionados{
+			TipoRelacion:    "04",
+			CfdiRelacionado: []CfdiRelacionado{{UUID: "5FB2822E-396D-4725-8521-CDC4BDD20CCF"}},
+		},
+		Emisor:   Emisor{Rfc: "EKU9003173C9", Nombre: "ESCUELA KEMPER URGATE", RegimenFiscal: "601"},
+		Receptor: Receptor{Rfc: "XIA190128J61", Nombre: "XENON  INDUSTRIAL\tARTICLES ", DomicilioFiscalReceptor: "76343", RegimenFiscalReceptor: "601", UsoCFDI: "G03"},
+		Conceptos: []Concepto{{
+			ClaveProdServ: "84111500",
+			Cantidad:      "1.00",
+			ClaveUnidad:   "E48",
+			Descripcion:   "Asesoría contable",
+			ValorUnitario: "1000.00",
+			Importe:       "1000.00",
+			ObjetoImp:     "02",
+			Impuestos: &ImpuestosConcepto{
+				Traslados: []Traslado{{Base: "1000.00", Impuesto: "002", TipoFactor: "Tasa", TasaOCuota: "0.160000", Importe: "160.00"}},
+			},
+		}},
+		Impuestos: &Impuestos{
+			TotalImpuestosTrasladados: "160.00",
+			Traslados:                 []Traslado{{Base: "1000.00", Impuesto: "002", TipoFactor: "Tasa", TasaOCuota: "0.160000", Importe: "160.00"}},
+		},
+	}
+
+	esperada := "||4.0|A|100|2025-03-10T12:30:00|03|30001000000500003416|1000.00|MXN|1160.00|I|01|PUE|42501" +
+		"|04|5FB2822E-396D-4725-8521-CDC4BDD20CCF" +
+		"|EKU9003173C9|ESCUELA KEMPER URGATE|601" +
+		"|XIA190128J61|XENON INDUSTRIAL ARTICLES|76343|601|G03" +
+		"|84111500|1.00|E48|Asesoría contable|1000.00|1000.00|02|1000.00|002|Tasa|0.160000|160.00" +
+		"|1000.00|002|Tasa|0.160000|160.00|160.00||"
+
+	if cadena := CadenaOriginal(comp); cadena != esperada {
+		t.Errorf("CadenaOriginal =\n%s\nse esperaba\n%s", cadena, esperada)
+	}
+}
+
+// TestCadenaOriginalRetencionesEImpLocal revisa el orden del resumen
+// (retenciones antes que traslados) y los atributos de implocal
+func TestCadenaOriginalRetencionesEImpLocal(t *testing.T) {
+	comp := comprobantePrueba(t)
+	comp.NoCertificado = noCertificadoPrueba
+
+	esperada := "||4.0|A|100|2025-03-10T12:30:00|03|30001000000500003416|4000.00|100.00|MXN|4211.50|I|01|PUE|42501" +
+		"|EKU9003173C9|ESCUELA KEMPER URGATE|601" +
+		"|XIA190128J61|XENON INDUSTRIAL ARTICLES|76343|601|G03" +
+		"|84111500|2.00|E48|Asesoría contable|1500.00|3000.00|02" +
+		"|3000.00|002|Tasa|0.160000|480.00" +
+		"|3000.00|001|Tasa|0.012500|37.50|3000.00|002|Tasa|0.106667|320.00" +
+		"|90111800|1.00|E48|Hospedaje|1000.00|1000.00|100.00|02" +
+		"|900.00|002|Tasa|0.160000|144.00" +
+		"|001|37.50|002|320.00|357.50" +
+		"|3900.00|002|Tasa|0.160000|624.00|624.00" +
+		"|1.0|0.00|45.00|ISH|5.00|45.00||"
+
+	if cadena := CadenaOriginal(comp); cadena != esperada {
+		t.Errorf("CadenaOriginal =\n%s\nse esperaba\n%s", cadena, esperada)
+	}
+}
@@ -0,0 +1,127 @@
+// internal/modules/facturacion/cancelacion.go
+package facturacion
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Motivos de cancelación vigentes desde 2022
+const (
+	MotivoErroresConRelacion  = "01"
+	MotivoErroresSinRelacion  = "02"
+	MotivoNoSeLlevoACabo      = "03"
+	MotivoOperacionNominativa = "04"
+)
+
+var MotivosCancelacion = map[string]string{
+	MotivoErroresConRelacion:  "Comprobante emitido con errores con relación",
+	MotivoErroresSinRelacion:  "Comprobante emitido con errores sin relación",
+	MotivoNoSeLlevoACabo:      "No se llevó a cabo la operación",
+	MotivoOperacionNominativa: "Operación nominativa relacionada en una factura global",
+}
+
+// Estados de una solicitud de cancelación
+const (
+	CancelacionEnProceso     = "en_proceso"
+	CancelacionConAceptacion = "cancelable_con_aceptacion"
+	CancelacionCancelado     = "cancelado"
+	CancelacionRechazado     = "rechazado"
+	CancelacionError         = "error"
+)
+
+var ErrCancelacionNoConfigurada = errors.New("no hay un servicio de cancelación configurado")
+
+// SolicitudCancelacion son los datos que se envían al servicio de cancelación
+type SolicitudCancelacion struct {
+	UUID             string
+	RfcEmisor        string
+	RfcReceptor      string
+	Total            float64
+	Motivo           string
+	FolioSustitucion string
+}
+
+// RespuestaCancelacion es el estado que reporta el servicio de cancelación
+type RespuestaCancelacion struct {
+	Estado  string
+	Mensaje string
+	Acuse   string
+}
+
+// Canceler envía solicitudes de cancelación al SAT (directamente o vía PAC)
+// y consulta su estado mientras el receptor no responde
+type Canceler interface {
+	Cancelar(ctx context.Context, s SolicitudCancelacion) (*RespuestaCancelacion, error)
+	ConsultarEstado(ctx context.Context, s SolicitudCancelacion) (*RespuestaCancelacion, error)
+}
+
+// montoSinAceptacion es el total hasta el que el SAT cancela sin pedir
+// aceptación al receptor
+const montoSinAceptacion = 1000
+
+// CancelerLocal simula el servicio del SAT para pruebas: cancela de inmediato
+// los comprobantes de hasta $1,000 o con RFC genérico y pide aceptación en los
+// demás; el receptor "acepta" después de `plazoAceptacion`.
+type CancelerLocal struct {
+	plazoAceptacion time.Duration
+
+	mu          sync.Mutex
+	solicitudes map[string]time.Time
+}
+
+func NewCancelerLocal(plazoAceptacion time.Duration) *CancelerLocal {
+	return &CancelerLocal{
+		plazoAceptacion: plazoAceptacion,
+		solicitudes:     make(map[string]time.Time),
+	}
+}
+
+func (c *CancelerLocal) Cancelar(ctx context.Context, s SolicitudCancelacion) (*RespuestaCancelacion, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if s.Total <= montoSinAceptacion || s.RfcReceptor == "XAXX010101000" || s.RfcReceptor == "XEXX010101000" {
+		return &RespuestaCancelacion{
+			Estado:  CancelacionCancelado,
+			Mensaje: "Cancelado sin aceptación",
+			Acuse:   acuseLocal(s),
+		}, nil
+	}
+
+	c.solicitudes[s.UUID] = time.Now()
+	return &RespuestaCancelacion{
+		Estado:  CancelacionConAceptacion,
+		Mensaje: "En espera de la aceptación del receptor",
+	}, nil
+}
+
+func (c *CancelerLocal) ConsultarEstado(ctx context.Context, s SolicitudCancelacion) (*RespuestaCancelacion, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	solicitada, ok := c.solicitudes[s.UUID]
+	if !ok {
+		return &RespuestaCancelacion{Estado: CancelacionError, Mensaje: "No existe una solicitud para el UUID"}, nil
+	}
+	if time.Since(solicitada) < c.plazoAceptacion {
+		return &RespuestaCancelacion{
+			Estado:  CancelacionConAceptacion,
+			Mensaje: "En espera de la aceptación del receptor",
+		}, nil
+	}
+
+	delete(c.solicitudes, s.UUID)
+	return &RespuestaCancelacion{
+		Estado:  CancelacionCancelado,
+		Mensaje: "Cancelado con aceptación",
+		Acuse:   acuseLocal(s),
+	}, nil
+}
+
+func acuseLocal(s SolicitudCancelacion) string {
+	return `<Acuse Fecha="` + time.Now().In(zonaCentro).Format(formatoFecha) + `" RfcEmisor="` + s.RfcEmisor +
+		`"><Folios><UUID>` + s.UUID + `</UUID><EstatusUUID>201</EstatusUUID></Folios></Acuse>`
+}
@@ -0,0 +1,154 @@
+// internal/modules/facturacion/cfdi40.go
+package facturacion
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+const (
+	VersionCFDI = "4.0"
+
+	NamespaceCFDI          = "http://www.sat.gob.mx/cfd/4"
+	NamespaceXSI           = "http://www.w3.org/2001/XMLSchema-instance"
+	NamespaceImpLocal      = "http://www.sat.gob.mx/implocal"
+	SchemaLocationCFDI     = "http://www.sat.gob.mx/cfd/4 http://www.sat.gob.mx/sitio_internet/cfd/4/cfdv40.xsd"
+	SchemaLocationImpLocal = "http://www.sat.gob.mx/implocal http://www.sat.gob.mx/sitio_internet/cfd/implocal/implocal.xsd"
+)
+
+// Comprobante es el nodo raíz de un CFDI 4.0 listo para serializar. Los importes
+// se guardan ya formateados para que el XML y la cadena original coincidan.
+// Los prefijos van en la etiqueta porque encoding/xml no los genera por sí mismo.
+type Comprobante struct {
+	XMLName           xml.Name          `xml:"cfdi:Comprobante"`
+	XmlnsCFDI         string            `xml:"xmlns:cfdi,attr"`
+	XmlnsXSI          string            `xml:"xmlns:xsi,attr"`
+	XmlnsImpLocal     string            `xml:"xmlns:implocal,attr,omitempty"`
+	SchemaLocation    string            `xml:"xsi:schemaLocation,attr"`
+	Version           string            `xml:"Version,attr"`
+	Serie             string            `xml:"Serie,attr,omitempty"`
+	Folio             string            `xml:"Folio,attr,omitempty"`
+	Fecha             string            `xml:"Fecha,attr"`
+	Sello             string            `xml:"Sello,attr,omitempty"`
+	FormaPago         string            `xml:"FormaPago,attr,omitempty"`
+	NoCertificado     string            `xml:"NoCertificado,attr,omitempty"`
+	Certificado       string            `xml:"Certificado,attr,omitempty"`
+	CondicionesDePago string            `xml:"CondicionesDePago,attr,omitempty"`
+	SubTotal          string            `xml:"SubTotal,attr"`
+	Descuento         string            `xml:"Descuento,attr,omitempty"`
+	Moneda            string            `xml:"Moneda,attr"`
+	TipoCambio        string            `xml:"TipoCambio,attr,omitempty"`
+	Total             string            `xml:"Total,attr"`
+	TipoDeComprobante string            `xml:"TipoDeComprobante,attr"`
+	Exportacion       string            `xml:"Exportacion,attr"`
+	MetodoPago        string            `xml:"MetodoPago,attr,omitempty"`
+	LugarExpedicion   string            `xml:"LugarExpedicion,attr"`
+	CfdiRelacionados  *CfdiRelacionados `xml:"cfdi:CfdiRelacionados,omitempty"`
+	Emisor            Emisor            `xml:"cfdi:Emisor"`
+	Receptor          Receptor          `xml:"cfdi:Receptor"`
+	Conceptos         []Concepto        `xml:"cfdi:Conceptos>cfdi:Concepto"`
+	Impuestos         *Impuestos        `xml:"cfdi:Impuestos,omitempty"`
+	Complemento       *Complemento      `xml:"cfdi:Complemento,omitempty"`
+}
+
+type CfdiRelacionados struct {
+	TipoRelacion    string            `xml:"TipoRelacion,attr"`
+	CfdiRelacionado []CfdiRelacionado `xml:"cfdi:CfdiRelacionado"`
+}
+
+type CfdiRelacionado struct {
+	UUID string `xml:"UUID,attr"`
+}
+
+type Emisor struct {
+	Rfc           string `xml:"Rfc,attr"`
+	Nombre        string `xml:"Nombre,attr"`
+	RegimenFiscal string `xml:"RegimenFiscal,attr"`
+}
+
+type Receptor struct {
+	Rfc                     string `xml:"Rfc,attr"`
+	Nombre                  string `xml:"Nombre,attr"`
+	DomicilioFiscalReceptor string `xml:"DomicilioFiscalReceptor,attr"`
+	RegimenFiscalReceptor   string `xml:"RegimenFiscalReceptor,attr"`
+	UsoCFDI                 string `xml:"UsoCFDI,attr"`
+}
+
+type Concepto struct {
+	ClaveProdServ    string             `xml:"ClaveProdServ,attr"`
+	NoIdentificacion string             `xml:"NoIdentificacion,attr,omitempty"`
+	Cantidad         string             `xml:"Cantidad,attr"`
+	ClaveUnidad      string             `xml:"ClaveUnidad,attr"`
+	Unidad           string             `xml:"Unidad,attr,omitempty"`
+	Descripcion      string             `xml:"Descripcion,attr"`
+	ValorUnitario    string             `xml:"ValorUnitario,attr"`
+	Importe          string             `xml:"Importe,attr"`
+	Descuento        string             `xml:"Descuento,attr,omitempty"`
+	ObjetoImp        string             `xml:"ObjetoImp,attr"`
+	Impuestos        *ImpuestosConcepto `xml:"cfdi:Impuestos,omitempty"`
+}
+
+type ImpuestosConcepto struct {
+	Traslados   []Traslado  `xml:"cfdi:Traslados>cfdi:Traslado,omitempty"`
+	Retenciones []Retencion `xml:"cfdi:Retenciones>cfdi:Retencion,omitempty"`
+}
+
+// Traslado se usa tanto en conceptos como en el resumen del comprobante
+type Traslado struct {
+	Base       string `xml:"Base,attr"`
+	Impuesto   string `xml:"Impuesto,attr"`
+	TipoFactor string `xml:"TipoFactor,attr"`
+	TasaOCuota string `xml:"TasaOCuota,attr,omitempty"`
+	Importe    string `xml:"Importe,attr,omitempty"`
+}
+
+// Retencion lleva Base y TasaOCuota sólo a nivel concepto
+type Retencion struct {
+	Base       string `xml:"Base,attr,omitempty"`
+	Impuesto   string `xml:"Impuesto,attr"`
+	TipoFactor string `xml:"TipoFactor,attr,omitempty"`
+	TasaOCuota string `xml:"TasaOCuota,attr,omitempty"`
+	Importe    string `xml:"Importe,attr"`
+}
+
+type Impuestos struct {
+	TotalImpuestosRetenidos   string      `xml:"TotalImpuestosRetenidos,attr,omitempty"`
+	TotalImpuestosTrasladados string      `xml:"TotalImpuestosTrasladados,attr,omitempty"`
+	Retenciones               []Retencion `xml:"cfdi:Retenciones>cfdi:Retencion,omitempty"`
+	Traslados                 []Traslado  `xml:"cfdi:Traslados>cfdi:Traslado,omitempty"`
+}
+
+type Complemento struct {
+	ImpuestosLocales *ImpuestosLocales `xml:"implocal:ImpuestosLocales,omitempty"`
+}
+
+// ImpuestosLocales es el complemento implocal 1.0 (p. ej. ISH)
+type ImpuestosLocales struct {
+	Version            string           `xml:"version,attr"`
+	TotaldeRetenciones string           `xml:"TotaldeRetenciones,attr"`
+	TotaldeTraslados   string           `xml:"TotaldeTraslados,attr"`
+	RetencionesLocales []RetencionLocal `xml:"implocal:RetencionesLocales,omitempty"`
+	TrasladosLocales   []TrasladoLocal  `xml:"implocal:TrasladosLocales,omitempty"`
+}
+
+type RetencionLocal struct {
+	ImpLocRetenido  string `xml:"ImpLocRetenido,attr"`
+	TasadeRetencion string `xml:"TasadeRetencion,attr"`
+	Importe         string `xml:"Importe,attr"`
+}
+
+type TrasladoLocal struct {
+	ImpLocTrasladado string `xml:"ImpLocTrasladado,attr"`
+	TasadeTraslado   string `xml:"TasadeTraslado,attr"`
+	Importe          string `xml:"Importe,attr"`
+}
+
+// XML serializa el comprobante con la declaración UTF-8
+func (c *Comprobante) XML() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,59 @@
+// internal/modules/facturacion/cifrado.go
+package facturacion
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+)
+
+var (
+	ErrDatosCifrados        = errors.New("no se pudieron descifrar los datos del CSD")
+	ErrCifradoNoConfigurado = errors.New("no hay una llave de cifrado de CSD configurada (CSD_ENCRYPTION_KEY)")
+)
+
+// Cifrador protege en reposo las llaves privadas con AES-256-GCM. El nonce
+// aleatorio se antepone al texto cifrado.
+type Cifrador struct {
+	aead cipher.AEAD
+}
+
+// NewCifrador deriva la llave AES de la frase configurada en CSD_ENCRYPTION_KEY
+func NewCifrador(secreto string) (*Cifrador, error) {
+	if secreto == "" {
+		return nil, errors.New("se requiere una llave de cifrado para los CSD")
+	}
+
+	llave := sha256.Sum256([]byte(secreto))
+	bloque, err := aes.NewCipher(llave[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(bloque)
+	if err != nil {
+		return nil, err
+	}
+	return &Cifrador{aead: aead}, nil
+}
+
+func (c *Cifrador) Cifrar(plano []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plano, nil), nil
+}
+
+func (c *Cifrador) Descifrar(datos []byte) ([]byte, error) {
+	n := c.aead.NonceSize()
+	if len(datos) < n {
+		return nil, ErrDatosCifrados
+	}
+	plano, err := c.aead.Open(nil, datos[:n], datos[n:], nil)
+	if err != nil {
+		return nil, ErrDatosCifrados
+	}
+	return plano, nil
+}
@@ -0,0 +1,110 @@
+// internal/modules/facturacion/csd.go
+package facturacion
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jhvc/backend/internal/validacion"
+)
+
+var (
+	ErrCertificadoFormato  = errors.New("el archivo .cer no es un certificado X.509 válido")
+	ErrCertificadoVigencia = errors.New("el certificado no está vigente")
+	ErrCertificadoNoCSD    = errors.New("el certificado es una e.firma (FIEL), no un Certificado de Sello Digital")
+	ErrCertificadoLlave    = errors.New("la llave privada no corresponde al certificado")
+	ErrCertificadoRFC      = errors.New("el certificado no contiene un RFC válido")
+)
+
+// oidUniqueIdentifier (x500UniqueIdentifier) guarda "RFC / RFC del representante"
+var oidUniqueIdentifier = asn1.ObjectIdentifier{2, 5, 4, 45}
+
+// CSD es un Certificado de Sello Digital ya validado junto con su llave privada
+type CSD struct {
+	Certificado   *x509.Certificate
+	Llave         *rsa.PrivateKey
+	NoCertificado string
+	RFC           string
+	RazonSocial   string
+}
+
+// parsearCertificado acepta el .cer en DER (como lo entrega el SAT) o en PEM
+func parsearCertificado(data []byte) (*x509.Certificate, error) {
+	if bloque, _ := pem.Decode(data); bloque != nil {
+		data = bloque.Bytes
+	}
+	cert, err := x509.ParseCertificate(data)
+	if err != nil {
+		return nil, ErrCertificadoFormato
+	}
+	return cert, nil
+}
+
+// NoCertificado obtiene los 20 dígitos del número de serie: el SAT codifica
+// cada dígito como un byte ASCII dentro del serial del certificado
+func NoCertificado(cert *x509.Certificate) string {
+	serial := cert.SerialNumber.Bytes()
+	for _, b := range serial {
+		if b < '0' || b > '9' {
+			return cert.SerialNumber.String()
+		}
+	}
+	return string(serial)
+}
+
+// rfcCertificado extrae el RFC del titular del sujeto del certificado
+func rfcCertificado(cert *x509.Certificate) (string, error) {
+	for _, atr := range cert.Subject.Names {
+		if !atr.Type.Equal(oidUniqueIdentifier) {
+			continue
+		}
+		valor, ok := atr.Value.(string)
+		if !ok {
+			break
+		}
+		rfc := validacion.NormalizarRFC(strings.SplitN(valor, "/", 2)[0])
+		if validacion.EsRFCValido(rfc) {
+			return rfc, nil
+		}
+	}
+	return "", ErrCertificadoRFC
+}
+
+// esCSD distingue el sello digital de la e.firma: la FIEL también permite
+// cifrado y acuerdo de llaves, el CSD sólo firma
+func esCSD(cert *x509.Certificate) bool {
+	return cert.KeyUsage&(x509.KeyUsageKeyAgreement|x509.KeyUsageDataEncipherment) == 0
+}
+
+// NuevoCSD valida que el certificado sea un CSD vigente y que la llave le corresponda
+func NuevoCSD(cert *x509.Certificate, llave *rsa.PrivateKey, ahora time.Time) (*CSD, error) {
+	if ahora.Before(cert.NotBefore) || ahora.After(cert.NotAfter) {
+		return nil, ErrCertificadoVigencia
+	}
+	if !esCSD(cert) {
+		return nil, ErrCertificadoNoCSD
+	}
+
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok || !pub.Equal(&llave.PublicKey) {
+		return nil, ErrCertificadoLlave
+	}
+
+	rfc, err := rfcCertificado(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CSD{
+		Certificado:   cert,
+		Llave:         llave,
+		NoCertificado: NoCertificado(cert),
+		RFC:           rfc,
+		RazonSocial:   cert.Subject.CommonName,
+	}, nil
+}
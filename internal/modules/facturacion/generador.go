@@ -0,0 +1,314 @@
+// internal/modules/facturacion/generador.go
+package facturacion
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jhvc/backend/internal/modules/calculadora"
+	"github.com/jhvc/backend/internal/validacion"
+)
+
+// Claves del Anexo 20 que usa el generador
+const (
+	impuestoISR = "001"
+	impuestoIVA = "002"
+
+	factorTasa = "Tasa"
+
+	objetoImpNo = "01"
+	objetoImpSi = "02"
+
+	formaPagoPorDefinir = "99"
+	exportacionNoAplica = "01"
+	monedaNacional      = "MXN"
+	tipoIngreso         = "I"
+
+	formatoFecha = "2006-01-02T15:04:05"
+)
+
+// zonaCentro es la hora del centro de México (sin horario de verano desde 2022),
+// que se usa para la Fecha del comprobante
+var zonaCentro = time.FixedZone("CST", -6*60*60)
+
+var ErrConceptoSinImpuestos = errors.New("ObjetoImp 01 no admite impuestos; use una configuración sin impuestos")
+
+// ErroresValidacion agrupa todas las reglas incumplidas de un comprobante
+type ErroresValidacion []string
+
+func (e ErroresValidacion) Error() string {
+	return "CFDI inválido: " + strings.Join(e, "; ")
+}
+
+// construirComprobante arma el CFDI 4.0 de ingreso a partir del cálculo de los
+// conceptos. Los impuestos del resumen se agregan desde los importes ya
+// redondeados de cada concepto, como exige la matriz de validación del SAT.
+func construirComprobante(req GenerarFacturaRequest, calc *calculadora.CalculoMultiple, fecha time.Time) (*Comprobante, error) {
+	if len(calc.Conceptos) != len(req.Conceptos) {
+		return nil, errors.New("el cálculo no corresponde a los conceptos")
+	}
+
+	comp := &Comprobante{
+		XmlnsCFDI:         NamespaceCFDI,
+		XmlnsXSI:          NamespaceXSI,
+		SchemaLocation:    SchemaLocationCFDI,
+		Version:           VersionCFDI,
+		Serie:             strings.TrimSpace(req.Serie),
+		Folio:             strings.TrimSpace(req.Folio),
+		Fecha:             fecha.In(zonaCentro).Format(formatoFecha),
+		FormaPago:         req.FormaPago,
+		CondicionesDePago: strings.TrimSpace(req.CondicionesDePago),
+		Moneda:            valorODefecto(req.Moneda, monedaNacional),
+		TipoDeComprobante: tipoIngreso,
+		Exportacion:       valorODefecto(req.Exportacion, exportacionNoAplica),
+		MetodoPago:        req.MetodoPago,
+		LugarExpedicion:   req.LugarExpedicion,
+		Emisor: Emisor{
+			Rfc:           validacion.NormalizarRFC(req.Emisor.Rfc),
+			Nombre:        strings.ToUpper(normalizarEspacios(req.Emisor.Nombre)),
+			RegimenFiscal: req.Emisor.RegimenFiscal,
+		},
+		Receptor: Receptor{
+			Rfc:                     validacion.NormalizarRFC(req.Receptor.Rfc),
+			Nombre:                  strings.ToUpper(normalizarEspacios(req.Receptor.Nombre)),
+			DomicilioFiscalReceptor: req.Receptor.DomicilioFiscalReceptor,
+			RegimenFiscalReceptor:   req.Receptor.RegimenFiscalReceptor,
+			UsoCFDI:                 strings.ToUpper(req.Receptor.UsoCFDI),
+		},
+	}
+
+	if comp.Moneda != monedaNacional && comp.Moneda != "XXX" {
+		comp.TipoCambio = formatearDecimal(req.TipoCambio, 6)
+	}
+
+	if rel := req.Relacionados; rel != nil {
+		comp.CfdiRelacionados = &CfdiRelacionados{TipoRelacion: rel.TipoRelacion}
+		for _, uuid := range rel.UUIDs {
+			comp.CfdiRelacionados.CfdiRelacionado = append(comp.CfdiRelacionados.CfdiRelacionado,
+				CfdiRelacionado{UUID: strings.ToUpper(strings.TrimSpace(uuid))})
+		}
+	}
+
+	resumen := newResumenImpuestos()
+	var subtotal, descuento, ish float64
+	var tasaISH float64
+
+	for i, con := range req.Conceptos {
+		calcCon := calc.Conceptos[i]
+		objetoImp := valorODefecto(con.ObjetoImp, objetoImpSi)
+
+		concepto := Concepto{
+			ClaveProdServ:    con.ClaveProdServ,
+			NoIdentificacion: strings.TrimSpace(con.NoIdentificacion),
+			Cantidad:         formatearCantidad(con.Cantidad),
+			ClaveUnidad:      strings.ToUpper(con.ClaveUnidad),
+			Unidad:           strings.TrimSpace(con.Unidad),
+			Descripcion:      normalizarEspacios(con.Descripcion),
+			ValorUnitario:    formatearCantidad(con.ValorUnitario),
+			Importe:          formatearImporte(calcCon.Importe),
+			ObjetoImp:        objetoImp,
+		}
+		if calcCon.Descuento > 0 {
+			concepto.Descuento = formatearImporte(calcCon.Descuento)
+		}
+
+		tieneImpuestos := calcCon.IVA > 0 || calcCon.RetencionISR > 0 || calcCon.RetencionIVA > 0 || calcCon.ISH > 0
+		switch objetoImp {
+		case objetoImpNo:
+			if tieneImpuestos {
+				return nil, ErrConceptoSinImpuestos
+			}
+		case objetoImpSi:
+			concepto.Impuestos = impuestosConcepto(calcCon, resumen)
+		default:
+			return nil, errors.New("ObjetoImp " + objetoImp + " no soportado")
+		}
+
+		subtotal += calcCon.Importe
+		descuento += calcCon.Descuento
+		if calcCon.ISH > 0 {
+			ish += calcCon.ISH
+			tasaISH = calcCon.ISHRate
+		}
+
+		comp.Conceptos = append(comp.Conceptos, concepto)
+	}
+
+	comp.SubTotal = formatearImporte(subtotal)
+	if descuento > 0 {
+		comp.Descuento = formatearImporte(descuento)
+	}
+	comp.Impuestos = resumen.impuestos()
+
+	total := subtotal - descuento + resumen.totalTrasladados - resumen.totalRetenidos
+	if ish > 0 {
+		ish = redondear(ish)
+		total += ish
+		comp.XmlnsImpLocal = NamespaceImpLocal
+		comp.SchemaLocation += " " + SchemaLocationImpLocal
+		comp.Complemento = &Complemento{ImpuestosLocales: &ImpuestosLocales{
+			Version:            "1.0",
+			TotaldeRetenciones: formatearImporte(0),
+			TotaldeTraslados:   formatearImporte(ish),
+			TrasladosLocales: []TrasladoLocal{{
+				ImpLocTrasladado: "ISH",
+				TasadeTraslado:   formatearImporte(tasaISH * 100),
+				Importe:          formatearImporte(ish),
+			}},
+		}}
+	}
+	comp.Total = formatearImporte(total)
+
+	return comp, nil
+}
+
+// impuestosConcepto desglosa IVA trasladado y retenciones de ISR e IVA del concepto
+func impuestosConcepto(c calculadora.CalculoConcepto, resumen *resumenImpuestos) *ImpuestosConcepto {
+	imp := &ImpuestosConcepto{}
+	base := c.Subtotal
+
+	// Siempre se traslada IVA cuando el concepto es objeto de impuesto (tasa 0% incluida)
+	imp.Traslados = append(imp.Traslados, Traslado{
+		Base:       formatearImporte(base),
+		Impuesto:   impuestoIVA,
+		TipoFactor: factorTasa,
+		TasaOCuota: formatearDecimal(c.IVARate, 6),
+		Importe:    formatearImporte(c.IVA),
+	})
+	resumen.trasladar(impuestoIVA, factorTasa, formatearDecimal(c.IVARate, 6), base, c.IVA)
+
+	if c.RetencionISR > 0 {
+		imp.Retenciones = append(imp.Retenciones, Retencion{
+			Base:       formatearImporte(base),
+			Impuesto:   impuestoISR,
+			TipoFactor: factorTasa,
+			TasaOCuota: formatearDecimal(c.ISRRate, 6),
+			Importe:    formatearImporte(c.RetencionISR),
+		})
+		resumen.retener(impuestoISR, c.RetencionISR)
+	}
+
+	if c.RetencionIVA > 0 {
+		imp.Retenciones = append(imp.Retenciones, Retencion{
+			Base:       formatearImporte(base),
+			Impuesto:   impuestoIVA,
+			TipoFactor: factorTasa,
+			TasaOCuota: formatearDecimal(c.RetencionIVARate, 6),
+			Importe:    formatearImporte(c.RetencionIVA),
+		})
+		resumen.retener(impuestoIVA, c.RetencionIVA)
+	}
+
+	return imp
+}
+
+// resumenImpuestos agrupa traslados por impuesto, factor y tasa, y retenciones por impuesto
+type resumenImpuestos struct {
+	traslados        map[string]*acumuladoTraslado
+	retenciones      map[string]float64
+	totalTrasladados float64
+	totalRetenidos   float64
+}
+
+type acumuladoTraslado struct {
+	impuesto, factor, tasa string
+	base, importe          float64
+}
+
+func newResumenImpuestos() *resumenImpuestos {
+	return &resumenImpuestos{
+		traslados:   make(map[string]*acumuladoTraslado),
+		retenciones: make(map[string]float64),
+	}
+}
+
+func (r *resumenImpuestos) trasladar(impuesto, factor, tasa string, base, importe float64) {
+	clave := impuesto + "|" + factor + "|" + tasa
+	acc, ok := r.traslados[clave]
+	if !ok {
+		acc = &acumuladoTraslado{impuesto: impuesto, factor: factor, tasa: tasa}
+		r.traslados[clave] = acc
+	}
+	acc.base += base
+	acc.importe += importe
+	r.totalTrasladados += importe
+}
+
+func (r *resumenImpuestos) retener(impuesto string, importe float64) {
+	r.retenciones[impuesto] += importe
+	r.totalRetenidos += importe
+}
+
+func (r *resumenImpuestos) impuestos() *Impuestos {
+	if len(r.traslados) == 0 && len(r.retenciones) == 0 {
+		return nil
+	}
+
+	imp := &Impuestos{}
+
+	retenidos := make([]string, 0, len(r.retenciones))
+	for k := range r.retenciones {
+		retenidos = append(retenidos, k)
+	}
+	sort.Strings(retenidos)
+	for _, k := range retenidos {
+		imp.Retenciones = append(imp.Retenciones, Retencion{Impuesto: k, Importe: formatearImporte(r.retenciones[k])})
+	}
+	if len(imp.Retenciones) > 0 {
+		imp.TotalImpuestosRetenidos = formatearImporte(r.totalRetenidos)
+	}
+
+	trasladados := make([]string, 0, len(r.traslados))
+	for k := range r.traslados {
+		trasladados = append(trasladados, k)
+	}
+	sort.Strings(trasladados)
+	for _, k := range trasladados {
+		acc := r.traslados[k]
+		imp.Traslados = append(imp.Traslados, Traslado{
+			Base:       formatearImporte(acc.base),
+			Impuesto:   acc.impuesto,
+			TipoFactor: acc.factor,
+			TasaOCuota: acc.tasa,
+			Importe:    formatearImporte(acc.importe),
+		})
+	}
+	if len(imp.Traslados) > 0 {
+		imp.TotalImpuestosTrasladados = formatearImporte(r.totalTrasladados)
+	}
+
+	return imp
+}
+
+func valorODefecto(v, def string) string {
+	if v = strings.TrimSpace(v); v != "" {
+		return v
+	}
+	return def
+}
+
+func redondear(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func formatearImporte(v float64) string {
+	return strconv.FormatFloat(redondear(v), 'f', 2, 64)
+}
+
+func formatearDecimal(v float64, decimales int) string {
+	return strconv.FormatFloat(v, 'f', decimales, 64)
+}
+
+// formatearCantidad usa entre 2 y 6 decimales, sin ceros sobrantes
+func formatearCantidad(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 6, 64)
+	s = strings.TrimRight(s, "0")
+	if i := strings.IndexByte(s, '.'); len(s)-i-1 < 2 {
+		s += strings.Repeat("0", 2-(len(s)-i-1))
+	}
+	return s
+}
@@ -0,0 +1,341 @@
+// internal/modules/facturacion/handler.go
+package facturacion
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jhvc/backend/internal/modules/directorio"
+)
+
+// tamanoMaximoCSD limita el tamaño de los archivos .cer y .key
+const tamanoMaximoCSD = 64 << 10
+
+// Handler maneja las peticiones HTTP de facturación electrónica
+type Handler struct {
+	service *Service
+}
+
+// NewHandler crea una nueva instancia del handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Generar crea un CFDI 4.0 sin sellar a partir de un cálculo de varios conceptos
+// @Accept json
+// @Produce json
+// @Param request body GenerarFacturaRequest true "Emisor, receptor y conceptos"
+// @Success 201 {object} ResultadoGeneracion
+// @Router /facturacion/facturas [post]
+func (h *Handler) Generar(c *gin.Context) {
+	var req GenerarFacturaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	resultado, err := h.service.Generar(c.GetInt("empresaID"), c.GetInt("userID"), req)
+	if err != nil {
+		if errs, ok := err.(ErroresValidacion); ok {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"success": false, "error": err.Error(), "errores": errs})
+			return
+		}
+		if err == directorio.ErrContactoNoEncontrado {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Factura generada",
+		"data":    resultado,
+	})
+}
+
+// GetFacturas lista las facturas de la empresa
+// @Router /facturacion/facturas [get]
+func (h *Handler) GetFacturas(c *gin.Context) {
+	facturas, err := h.service.GetFacturas(c.GetInt("empresaID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    facturas,
+	})
+}
+
+// GetFactura devuelve una factura
+// @Router /facturacion/facturas/{id} [get]
+func (h *Handler) GetFactura(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	factura, err := h.service.GetFactura(c.GetInt("empresaID"), id)
+	if err == ErrFacturaNoEncontrada {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    factura,
+	})
+}
+
+// GetXML descarga el XML de la factura en su etapa actual
+// @Router /facturacion/facturas/{id}/xml [get]
+func (h *Handler) GetXML(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	data, err := h.service.GetXML(c.GetInt("empresaID"), id)
+	if err == ErrFacturaNoEncontrada {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="factura-`+c.Param("id")+`.xml"`)
+	c.Data(http.StatusOK, "application/xml", data)
+}
+
+// Sellar firma la factura con el CSD activo del emisor
+// @Router /facturacion/facturas/{id}/sellar [post]
+func (h *Handler) Sellar(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	resultado, err := h.service.Sellar(c.GetInt("empresaID"), id)
+	switch err {
+	case nil:
+	case ErrFacturaNoEncontrada:
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	case ErrFacturaTimbrada:
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error()})
+		return
+	case ErrCifradoNoConfigurado:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": err.Error()})
+		return
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Factura sellada",
+		"data":    resultado,
+	})
+}
+
+// CargarCSD recibe el certificado (`cer`), la llave privada (`key`) y su
+// contraseña (`password`) como multipart/form-data
+// @Accept multipart/form-data
+// @Router /facturacion/csd [post]
+func (h *Handler) CargarCSD(c *gin.Context) {
+	cer, err := leerArchivo(c, "cer")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "archivo .cer requerido"})
+		return
+	}
+	key, err := leerArchivo(c, "key")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "archivo .key requerido"})
+		return
+	}
+	password := c.PostForm("password")
+	if password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "contraseña de la llave requerida"})
+		return
+	}
+
+	certificado, err := h.service.CargarCSD(c.GetInt("empresaID"), c.GetInt("userID"), cer, key, password)
+	if err == ErrCifradoNoConfigurado {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "CSD cargado",
+		"data":    certificado,
+	})
+}
+
+// GetCSDs lista los certificados cargados (sin llaves)
+// @Router /facturacion/csd [get]
+func (h *Handler) GetCSDs(c *gin.Context) {
+	certificados, err := h.service.GetCSDs(c.GetInt("empresaID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    certificados,
+	})
+}
+
+// EliminarCSD borra un certificado y su llave
+// @Router /facturacion/csd/{id} [delete]
+func (h *Handler) EliminarCSD(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	if err := h.service.EliminarCSD(c.GetInt("empresaID"), id); err != nil {
+		status := http.StatusInternalServerError
+		if err == ErrCSDNoEncontrado {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "CSD eliminado",
+	})
+}
+
+func leerArchivo(c *gin.Context, campo string) ([]byte, error) {
+	fh, err := c.FormFile(campo)
+	if err != nil {
+		return nil, err
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, tamanoMaximoCSD))
+}
+
+// Timbrar envía la factura sellada al PAC configurado
+// @Router /facturacion/facturas/{id}/timbrar [post]
+func (h *Handler) Timbrar(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	factura, err := h.service.Timbrar(c.Request.Context(), c.GetInt("empresaID"), c.GetInt("userID"), id)
+	if err != nil {
+		var pacErr *ErrorPAC
+		switch {
+		case err == ErrFacturaNoEncontrada:
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		case err == ErrTimbradoEnProceso:
+			c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error()})
+		case err == ErrTimbradoNoConfigurado:
+			c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": err.Error()})
+		case err == ErrFacturaSinSello:
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		case errors.As(err, &pacErr) && !pacErr.Temporal:
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"success": false, "error": err.Error()})
+		case errors.As(err, &pacErr):
+			c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Factura timbrada",
+		"data":    factura,
+	})
+}
+
+// Cancelar solicita la cancelación de una factura timbrada
+// @Param request body CancelarRequest true "Motivo y, para el motivo 01, folio de sustitución"
+// @Router /facturacion/facturas/{id}/cancelar [post]
+func (h *Handler) Cancelar(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var req CancelarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	cancelacion, err := h.service.Cancelar(c.Request.Context(), c.GetInt("empresaID"), c.GetInt("userID"), id, req)
+	if err != nil {
+		errorCancelacion(c, cancelacion, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Solicitud de cancelación registrada",
+		"data":    cancelacion,
+	})
+}
+
+// ConsultarCancelacion actualiza el estado de la solicitud en curso
+// @Router /facturacion/facturas/{id}/cancelacion/consultar [post]
+func (h *Handler) ConsultarCancelacion(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	cancelacion, err := h.service.ConsultarCancelacion(c.Request.Context(), c.GetInt("empresaID"), id)
+	if err != nil {
+		errorCancelacion(c, nil, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    cancelacion,
+	})
+}
+
+// GetCancelaciones devuelve el historial de cancelación de la factura
+// @Router /facturacion/facturas/{id}/cancelaciones [get]
+func (h *Handler) GetCancelaciones(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	cancelaciones, err := h.service.GetCancelaciones(c.GetInt("empresaID"), id)
+	if err != nil {
+		errorCancelacion(c, nil, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    cancelaciones,
+	})
+}
+
+func errorCancelacion(c *gin.Context, cancelacion *Cancelacion, err error) {
+	switch err {
+	case ErrFacturaNoEncontrada:
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+	case ErrCancelacionNoConfigurada:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": err.Error()})
+	case ErrFacturaCancelada, ErrCancelacionPendiente, ErrSinCancelacionPendiente:
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error()})
+	case ErrFacturaNoTimbrada, ErrFolioSustitucion, ErrFolioSinRelacion:
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+	default:
+		if cancelacion != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"success": false, "error": err.Error(), "data": cancelacion})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+	}
+}
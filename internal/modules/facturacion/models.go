@@ -0,0 +1,140 @@
+// internal/modules/facturacion/models.go
+package facturacion
+
+import (
+	"time"
+
+	"github.com/jhvc/backend/internal/modules/calculadora"
+)
+
+// Estados de una factura
+const (
+	EstadoBorrador  = "borrador"
+	EstadoSellada   = "sellada"
+	EstadoTimbrando = "timbrando"
+	EstadoTimbrada  = "timbrada"
+	EstadoCancelada = "cancelada"
+)
+
+// Factura es un CFDI emitido desde el sistema en cualquiera de sus etapas
+type Factura struct {
+	ID             int        `json:"id"`
+	EmpresaID      int        `json:"empresa_id"`
+	UserID         int        `json:"user_id"`
+	Serie          string     `json:"serie,omitempty"`
+	Folio          string     `json:"folio,omitempty"`
+	Fecha          time.Time  `json:"fecha"`
+	EmisorRFC      string     `json:"emisor_rfc"`
+	ReceptorRFC    string     `json:"receptor_rfc"`
+	ReceptorNombre string     `json:"receptor_nombre"`
+	Moneda         string     `json:"moneda"`
+	SubTotal       float64    `json:"subtotal"`
+	Total          float64    `json:"total"`
+	Estado         string     `json:"estado"`
+	CadenaOriginal string     `json:"cadena_original,omitempty"`
+	NoCertificado  string     `json:"no_certificado,omitempty"`
+	UUID           string     `json:"uuid,omitempty"`
+	FechaTimbrado  *time.Time `json:"fecha_timbrado,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// CertificadoCSD son los datos públicos de un CSD cargado; la llave privada
+// nunca sale del repositorio
+type CertificadoCSD struct {
+	ID            int       `json:"id"`
+	EmpresaID     int       `json:"empresa_id"`
+	UserID        int       `json:"user_id"`
+	RFC           string    `json:"rfc"`
+	NoCertificado string    `json:"no_certificado"`
+	RazonSocial   string    `json:"razon_social"`
+	ValidoDesde   time.Time `json:"valido_desde"`
+	ValidoHasta   time.Time `json:"valido_hasta"`
+	Activo        bool      `json:"activo"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Cancelacion es una solicitud de cancelación de una factura timbrada
+type Cancelacion struct {
+	ID               int                 `json:"id"`
+	FacturaID        int                 `json:"factura_id"`
+	EmpresaID        int                 `json:"empresa_id"`
+	UserID           int                 `json:"user_id"`
+	UUID             string              `json:"uuid"`
+	Motivo           string              `json:"motivo"`
+	FolioSustitucion string              `json:"folio_sustitucion,omitempty"`
+	Estado           string              `json:"estado"`
+	Mensaje          string              `json:"mensaje,omitempty"`
+	Acuse            string              `json:"acuse,omitempty"`
+	Eventos          []EventoCancelacion `json:"eventos,omitempty"`
+	CreatedAt        time.Time           `json:"created_at"`
+	UpdatedAt        time.Time           `json:"updated_at"`
+}
+
+// EventoCancelacion registra cada cambio de estado de una solicitud
+type EventoCancelacion struct {
+	Estado    string    `json:"estado"`
+	Mensaje   string    `json:"mensaje,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CancelarRequest struct {
+	Motivo           string `json:"motivo" binding:"required,oneof=01 02 03 04"`
+	FolioSustitucion string `json:"folio_sustitucion"`
+}
+
+type EmisorRequest struct {
+	Rfc           string `json:"rfc" binding:"required,rfc"`
+	Nombre        string `json:"nombre" binding:"required"`
+	RegimenFiscal string `json:"regimen_fiscal" binding:"required"`
+}
+
+type ReceptorRequest struct {
+	Rfc                     string `json:"rfc" binding:"required,rfc"`
+	Nombre                  string `json:"nombre" binding:"required"`
+	DomicilioFiscalReceptor string `json:"domicilio_fiscal" binding:"required,len=5,numeric"`
+	RegimenFiscalReceptor   string `json:"regimen_fiscal" binding:"required"`
+	UsoCFDI                 string `json:"uso_cfdi" binding:"required"`
+}
+
+// ConceptoRequest agrega los datos del CFDI a un concepto de la calculadora
+type ConceptoRequest struct {
+	calculadora.ConceptoCalculo
+	ClaveProdServ    string `json:"clave_prod_serv" binding:"required"`
+	NoIdentificacion string `json:"no_identificacion"`
+	ClaveUnidad      string `json:"clave_unidad" binding:"required"`
+	Unidad           string `json:"unidad"`
+	ObjetoImp        string `json:"objeto_imp"`
+}
+
+type RelacionadosRequest struct {
+	TipoRelacion string   `json:"tipo_relacion" binding:"required"`
+	UUIDs        []string `json:"uuids" binding:"required,min=1"`
+}
+
+// GenerarFacturaRequest son los datos para generar un CFDI 4.0 de ingreso
+type GenerarFacturaRequest struct {
+	Serie             string               `json:"serie"`
+	Folio             string               `json:"folio"`
+	FormaPago         string               `json:"forma_pago" binding:"required"`
+	MetodoPago        string               `json:"metodo_pago" binding:"required,oneof=PUE PPD"`
+	CondicionesDePago string               `json:"condiciones_pago"`
+	Moneda            string               `json:"moneda"`
+	TipoCambio        float64              `json:"tipo_cambio"`
+	Exportacion       string               `json:"exportacion"`
+	LugarExpedicion   string               `json:"lugar_expedicion" binding:"required,len=5,numeric"`
+	Relacionados      *RelacionadosRequest `json:"relacionados"`
+	Emisor            EmisorRequest        `json:"emisor" binding:"required"`
+	Receptor          *ReceptorRequest     `json:"receptor" binding:"required_without=ContactoID"`
+	ContactoID        int                  `json:"contacto_id"` // Opcional: toma el receptor del directorio
+	UsoCFDI           string               `json:"uso_cfdi"`    // Con contacto_id, reemplaza el uso del contacto
+	Conceptos         []ConceptoRequest    `json:"conceptos" binding:"required,min=1,dive"`
+}
+
+// ResultadoGeneracion es la factura en borrador con su XML sin sellar
+type ResultadoGeneracion struct {
+	Factura      Factura                      `json:"factura"`
+	XML          string                       `json:"xml"`
+	Calculo      *calculadora.CalculoMultiple `json:"calculo"`
+	Advertencias []string                     `json:"advertencias,omitempty"`
+}
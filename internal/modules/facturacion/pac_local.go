@@ -0,0 +1,190 @@
+// internal/modules/facturacion/pac_local.go
+package facturacion
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	NamespaceTFD      = "http://www.sat.gob.mx/TimbreFiscalDigital"
+	SchemaLocationTFD = "http://www.sat.gob.mx/TimbreFiscalDigital http://www.sat.gob.mx/sitio_internet/cfd/TimbreFiscalDigital/TimbreFiscalDigitalv11.xsd"
+
+	// rfcPACPruebas es el RFC de proveedor que el SAT publica para pruebas
+	rfcPACPruebas = "SPR190613I52"
+)
+
+// TimbreFiscalDigital es el complemento 1.1 que agrega el PAC
+type TimbreFiscalDigital struct {
+	XMLName          xml.Name `xml:"tfd:TimbreFiscalDigital"`
+	XmlnsTFD         string   `xml:"xmlns:tfd,attr"`
+	SchemaLocation   string   `xml:"xsi:schemaLocation,attr"`
+	Version          string   `xml:"Version,attr"`
+	UUID             string   `xml:"UUID,attr"`
+	FechaTimbrado    string   `xml:"FechaTimbrado,attr"`
+	RfcProvCertif    string   `xml:"RfcProvCertif,attr"`
+	SelloCFD         string   `xml:"SelloCFD,attr"`
+	NoCertificadoSAT string   `xml:"NoCertificadoSAT,attr"`
+	SelloSAT         string   `xml:"SelloSAT,attr"`
+}
+
+// cadenaOriginalTFD es la cadena del timbre que firma el SAT/PAC
+func (t *TimbreFiscalDigital) cadenaOriginal() string {
+	return fmt.Sprintf("||%s|%s|%s|%s|%s|%s||",
+		t.Version, t.UUID, t.FechaTimbrado, t.RfcProvCertif, t.SelloCFD, t.NoCertificadoSAT)
+}
+
+// PACLocal es un PAC de pruebas que timbra sin conexión con un certificado
+// generado al arrancar. Sus UUID no existen ante el SAT: sólo sirve para
+// desarrollo y pruebas del flujo completo de emisión.
+type PACLocal struct {
+	llave         *rsa.PrivateKey
+	noCertificado string
+
+	mu       sync.Mutex
+	timbrado map[int]*Timbre
+}
+
+// NewPACLocal genera la llave y el certificado de pruebas del PAC
+func NewPACLocal() (*PACLocal, error) {
+	llave, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	// Igual que en los certificados del SAT, el serial son los dígitos en ASCII
+	noCertificado := "30001000000500003456"
+	plantilla := &x509.Certificate{
+		SerialNumber: new(big.Int).SetBytes([]byte(noCertificado)),
+		Subject:      pkix.Name{CommonName: "PAC LOCAL DE PRUEBAS"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if _, err := x509.CreateCertificate(rand.Reader, plantilla, plantilla, &llave.PublicKey, llave); err != nil {
+		return nil, err
+	}
+
+	return &PACLocal{
+		llave:         llave,
+		noCertificado: noCertificado,
+		timbrado:      make(map[int]*Timbre),
+	}, nil
+}
+
+// Timbrar agrega el TimbreFiscalDigital al comprobante. Si la misma factura
+// con el mismo sello ya se timbró, devuelve el timbre anterior.
+func (p *PACLocal) Timbrar(ctx context.Context, facturaID int, xmlSellado []byte) (*Timbre, error) {
+	sello, err := selloDelXML(xmlSellado)
+	if err != nil {
+		return nil, err
+	}
+	if sello == "" {
+		return nil, &ErrorPAC{Codigo: "CFDI40102", Mensaje: "el comprobante no está sellado"}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if previo, ok := p.timbrado[facturaID]; ok {
+		if previoSello, _ := selloDelXML(previo.XML); previoSello == sello {
+			return previo, nil
+		}
+	}
+
+	uuid, err := nuevoUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	tfd := TimbreFiscalDigital{
+		XmlnsTFD:         NamespaceTFD,
+		SchemaLocation:   SchemaLocationTFD,
+		Version:          "1.1",
+		UUID:             uuid,
+		FechaTimbrado:    time.Now().In(zonaCentro).Format(formatoFecha),
+		RfcProvCertif:    rfcPACPruebas,
+		SelloCFD:         sello,
+		NoCertificadoSAT: p.noCertificado,
+	}
+	digest := sha256.Sum256([]byte(tfd.cadenaOriginal()))
+	firma, err := rsa.SignPKCS1v15(rand.Reader, p.llave, crypto.SHA256, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	tfd.SelloSAT = base64.StdEncoding.EncodeToString(firma)
+
+	nodo, err := xml.Marshal(tfd)
+	if err != nil {
+		return nil, err
+	}
+
+	timbrado, err := insertarComplemento(xmlSellado, nodo)
+	if err != nil {
+		return nil, err
+	}
+	timbre, err := timbreDesdeXML(timbrado)
+	if err != nil {
+		return nil, err
+	}
+	p.timbrado[facturaID] = timbre
+	return timbre, nil
+}
+
+// selloDelXML lee el atributo Sello del nodo raíz
+func selloDelXML(data []byte) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", &ErrorPAC{Codigo: "CFDI40101", Mensaje: "XML mal formado"}
+		}
+		if inicio, ok := tok.(xml.StartElement); ok {
+			for _, atr := range inicio.Attr {
+				if atr.Name.Local == "Sello" {
+					return atr.Value, nil
+				}
+			}
+			return "", nil
+		}
+	}
+}
+
+// insertarComplemento coloca el nodo dentro de cfdi:Complemento, creándolo si no existe
+func insertarComplemento(data, nodo []byte) ([]byte, error) {
+	if i := bytes.LastIndex(data, []byte("</cfdi:Complemento>")); i >= 0 {
+		return concatenar(data[:i], nodo, data[i:]), nil
+	}
+	i := bytes.LastIndex(data, []byte("</cfdi:Comprobante>"))
+	if i < 0 {
+		return nil, &ErrorPAC{Codigo: "CFDI40101", Mensaje: "no es un CFDI 4.0"}
+	}
+	return concatenar(data[:i], []byte("<cfdi:Complemento>"), nodo, []byte("</cfdi:Complemento>"), data[i:]), nil
+}
+
+func concatenar(partes ...[]byte) []byte {
+	return bytes.Join(partes, nil)
+}
+
+// nuevoUUID genera un UUID versión 4 en mayúsculas, como los del SAT
+func nuevoUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,98 @@
+// internal/modules/facturacion/pac_sw.go
+package facturacion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PACSW timbra con la API REST de SW Sapien (servicio stamp v4, que devuelve
+// el XML completo ya timbrado)
+type PACSW struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewPACSW crea el cliente con la URL del ambiente (pruebas o producción) y el token de la cuenta
+func NewPACSW(url, token string) *PACSW {
+	return &PACSW{
+		url:    strings.TrimRight(url, "/"),
+		token:  token,
+		client: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+type respuestaSW struct {
+	Status        string `json:"status"`
+	Message       string `json:"message"`
+	MessageDetail string `json:"messageDetail"`
+	Data          struct {
+		CFDI string `json:"cfdi"`
+	} `json:"data"`
+}
+
+func (p *PACSW) Timbrar(ctx context.Context, facturaID int, xmlSellado []byte) (*Timbre, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("xml", fmt.Sprintf("factura-%d.xml", facturaID))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(xmlSellado); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url+"/cfdi33/stamp/v4", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "bearer "+p.token)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var res respuestaSW
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, &ErrorPAC{
+			Codigo:   resp.Status,
+			Mensaje:  "respuesta inválida del PAC",
+			Temporal: resp.StatusCode >= 500,
+		}
+	}
+
+	// Un comprobante ya timbrado (código 307) regresa el mismo timbre, lo que
+	// hace seguro reintentar una solicitud cuya respuesta se perdió
+	if res.Data.CFDI != "" {
+		return timbreDesdeXML([]byte(res.Data.CFDI))
+	}
+
+	mensaje := strings.TrimSpace(res.Message + " " + res.MessageDetail)
+	if mensaje == "" {
+		mensaje = "el PAC no devolvió el CFDI timbrado"
+	}
+	return nil, &ErrorPAC{
+		Codigo:   resp.Status,
+		Mensaje:  mensaje,
+		Temporal: resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests,
+	}
+}
@@ -0,0 +1,157 @@
+// internal/modules/facturacion/pkcs8.go
+package facturacion
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var (
+	ErrLlaveFormato   = errors.New("el archivo .key no es una llave privada PKCS#8 cifrada")
+	ErrLlaveAlgoritmo = errors.New("algoritmo de cifrado de la llave no soportado")
+	ErrLlavePassword  = errors.New("contraseña de la llave privada incorrecta")
+	ErrLlaveNoRSA     = errors.New("la llave privada no es RSA")
+)
+
+// OIDs de PKCS#5 v2 (PBES2) que usan las llaves que entrega el SAT
+var (
+	oidPBES2      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidDESEDE3CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+type encryptedPrivateKeyInfo struct {
+	Algoritmo pkix.AlgorithmIdentifier
+	Datos     []byte
+}
+
+type pbes2Params struct {
+	KDF     pkix.AlgorithmIdentifier
+	Cifrado pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt        []byte
+	Iteraciones int
+	Longitud    int                      `asn1:"optional"`
+	PRF         pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// descifrarLlavePKCS8 abre un .key del SAT (PKCS#8 cifrado con PBES2) y
+// devuelve la llave RSA junto con su DER PKCS#8 sin cifrar
+func descifrarLlavePKCS8(der []byte, password string) (*rsa.PrivateKey, []byte, error) {
+	var info encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil || len(rest) > 0 {
+		return nil, nil, ErrLlaveFormato
+	}
+	if !info.Algoritmo.Algorithm.Equal(oidPBES2) {
+		return nil, nil, ErrLlaveAlgoritmo
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algoritmo.Parameters.FullBytes, &params); err != nil {
+		return nil, nil, ErrLlaveFormato
+	}
+	if !params.KDF.Algorithm.Equal(oidPBKDF2) {
+		return nil, nil, ErrLlaveAlgoritmo
+	}
+
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KDF.Parameters.FullBytes, &kdf); err != nil {
+		return nil, nil, ErrLlaveFormato
+	}
+
+	var prf func() hash.Hash
+	switch {
+	case len(kdf.PRF.Algorithm) == 0, kdf.PRF.Algorithm.Equal(oidHMACSHA1):
+		prf = sha1.New
+	case kdf.PRF.Algorithm.Equal(oidHMACSHA256):
+		prf = sha256.New
+	default:
+		return nil, nil, ErrLlaveAlgoritmo
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.Cifrado.Parameters.FullBytes, &iv); err != nil {
+		return nil, nil, ErrLlaveFormato
+	}
+
+	var longitud int
+	var nuevoBloque func([]byte) (cipher.Block, error)
+	switch {
+	case params.Cifrado.Algorithm.Equal(oidDESEDE3CBC):
+		longitud, nuevoBloque = 24, des.NewTripleDESCipher
+	case params.Cifrado.Algorithm.Equal(oidAES128CBC):
+		longitud, nuevoBloque = 16, aes.NewCipher
+	case params.Cifrado.Algorithm.Equal(oidAES192CBC):
+		longitud, nuevoBloque = 24, aes.NewCipher
+	case params.Cifrado.Algorithm.Equal(oidAES256CBC):
+		longitud, nuevoBloque = 32, aes.NewCipher
+	default:
+		return nil, nil, ErrLlaveAlgoritmo
+	}
+
+	llave := pbkdf2.Key([]byte(password), kdf.Salt, kdf.Iteraciones, longitud, prf)
+	bloque, err := nuevoBloque(llave)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(iv) != bloque.BlockSize() || len(info.Datos) == 0 || len(info.Datos)%bloque.BlockSize() != 0 {
+		return nil, nil, ErrLlaveFormato
+	}
+
+	plano := make([]byte, len(info.Datos))
+	cipher.NewCBCDecrypter(bloque, iv).CryptBlocks(plano, info.Datos)
+
+	// Con una contraseña incorrecta el relleno PKCS#5 casi nunca es válido
+	plano, err = quitarRelleno(plano, bloque.BlockSize())
+	if err != nil {
+		return nil, nil, ErrLlavePassword
+	}
+
+	key, err := parsearLlavePKCS8(plano)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, plano, nil
+}
+
+func parsearLlavePKCS8(der []byte) (*rsa.PrivateKey, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, ErrLlavePassword
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrLlaveNoRSA
+	}
+	return rsaKey, nil
+}
+
+func quitarRelleno(b []byte, tamBloque int) ([]byte, error) {
+	n := int(b[len(b)-1])
+	if n == 0 || n > tamBloque || n > len(b) {
+		return nil, ErrLlavePassword
+	}
+	if !bytes.Equal(b[len(b)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+		return nil, ErrLlavePassword
+	}
+	return b[:len(b)-n], nil
+}
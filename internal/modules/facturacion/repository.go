@@ -0,0 +1,399 @@
+// internal/modules/facturacion/repository.go
+package facturacion
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// GetRFCEmpresa devuelve el RFC de la empresa, que es el único emisor permitido
+func (r *Repository) GetRFCEmpresa(empresaID int) (string, error) {
+	var rfc string
+	err := r.db.QueryRow(`SELECT rfc FROM empresas WHERE id = $1`, empresaID).Scan(&rfc)
+	return rfc, err
+}
+
+const columnasFactura = `id, empresa_id, user_id, serie, folio, fecha, emisor_rfc, receptor_rfc, receptor_nombre,
+        moneda, subtotal, total, estado, cadena_original, COALESCE(no_certificado, ''), COALESCE(uuid, ''), fecha_timbrado, created_at, updated_at`
+
+func (r *Repository) CrearFactura(f *Factura, comp *Comprobante, xmlData []byte) (int, error) {
+	datos, err := json.Marshal(comp)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = r.db.QueryRow(`
+        INSERT INTO facturas (empresa_id, user_id, serie, folio, fecha, emisor_rfc, receptor_rfc, receptor_nombre,
+            moneda, subtotal, total, estado, comprobante, xml, cadena_original)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+        RETURNING id
+    `, f.EmpresaID, f.UserID, f.Serie, f.Folio, f.Fecha, f.EmisorRFC, f.ReceptorRFC, f.ReceptorNombre,
+		f.Moneda, f.SubTotal, f.Total, f.Estado, string(datos), string(xmlData), f.CadenaOriginal).Scan(&id)
+	return id, err
+}
+
+func (r *Repository) GetFacturas(empresaID int) ([]Factura, error) {
+	rows, err := r.db.Query(`
+        SELECT `+columnasFactura+`
+        FROM facturas
+        WHERE empresa_id = $1
+        ORDER BY created_at DESC
+    `, empresaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var facturas []Factura
+	for rows.Next() {
+		f, err := scanFactura(rows)
+		if err != nil {
+			continue
+		}
+		facturas = append(facturas, *f)
+	}
+
+	return facturas, nil
+}
+
+func (r *Repository) GetFactura(empresaID, id int) (*Factura, error) {
+	row := r.db.QueryRow(`
+        SELECT `+columnasFactura+`
+        FROM facturas WHERE empresa_id = $1 AND id = $2
+    `, empresaID, id)
+	return scanFactura(row)
+}
+
+func (r *Repository) GetXML(empresaID, id int) ([]byte, error) {
+	var data string
+	err := r.db.QueryRow(`SELECT xml FROM facturas WHERE empresa_id = $1 AND id = $2`, empresaID, id).Scan(&data)
+	return []byte(data), err
+}
+
+// GetComprobante recupera el comprobante tal como se generó para poder sellarlo
+func (r *Repository) GetComprobante(empresaID, id int) (*Comprobante, error) {
+	var datos []byte
+	err := r.db.QueryRow(`SELECT comprobante FROM facturas WHERE empresa_id = $1 AND id = $2`, empresaID, id).Scan(&datos)
+	if err != nil {
+		return nil, err
+	}
+
+	var comp Comprobante
+	if err := json.Unmarshal(datos, &comp); err != nil {
+		return nil, err
+	}
+	return &comp, nil
+}
+
+// GuardarSellado reemplaza el comprobante y su XML por la versión sellada.
+// Devuelve sql.ErrNoRows si la factura ya pasó a timbrado mientras se sellaba.
+func (r *Repository) GuardarSellado(f *Factura, comp *Comprobante, xmlData []byte) error {
+	datos, err := json.Marshal(comp)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.Exec(`
+        UPDATE facturas
+        SET fecha = $1, estado = $2, comprobante = $3, xml = $4, cadena_original = $5,
+            no_certificado = $6, updated_at = CURRENT_TIMESTAMP
+        WHERE empresa_id = $7 AND id = $8 AND estado IN ($9, $10)
+    `, f.Fecha, f.Estado, string(datos), string(xmlData), f.CadenaOriginal, f.NoCertificado, f.EmpresaID, f.ID,
+		EstadoBorrador, EstadoSellada)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ReservarTimbrado pasa la factura sellada a "timbrando" para que sólo una
+// solicitud la envíe al PAC. Una reserva abandonada se libera tras cinco minutos.
+func (r *Repository) ReservarTimbrado(empresaID, id int) (bool, error) {
+	res, err := r.db.Exec(`
+        UPDATE facturas
+        SET estado = $1, updated_at = CURRENT_TIMESTAMP
+        WHERE empresa_id = $2 AND id = $3
+          AND (estado = $4 OR (estado = $1 AND updated_at < CURRENT_TIMESTAMP - INTERVAL '5 minutes'))
+    `, EstadoTimbrando, empresaID, id, EstadoSellada)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// LiberarTimbrado regresa la factura a "sellada" cuando el PAC la rechaza
+func (r *Repository) LiberarTimbrado(empresaID, id int) error {
+	_, err := r.db.Exec(`
+        UPDATE facturas SET estado = $1, updated_at = CURRENT_TIMESTAMP
+        WHERE empresa_id = $2 AND id = $3 AND estado = $4
+    `, EstadoSellada, empresaID, id, EstadoTimbrando)
+	return err
+}
+
+// GuardarTimbre almacena el XML timbrado y el folio fiscal asignado
+func (r *Repository) GuardarTimbre(empresaID, id int, t *Timbre) error {
+	_, err := r.db.Exec(`
+        UPDATE facturas
+        SET estado = $1, uuid = $2, fecha_timbrado = $3, xml = $4, updated_at = CURRENT_TIMESTAMP
+        WHERE empresa_id = $5 AND id = $6
+    `, EstadoTimbrada, t.UUID, t.FechaTimbrado, string(t.XML), empresaID, id)
+	return err
+}
+
+// GuardarCSD registra un certificado de la empresa y lo deja como el activo
+func (r *Repository) GuardarCSD(c *CertificadoCSD, certDER, llaveCifrada []byte) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE csd_certificados SET activo = false WHERE empresa_id = $1 AND rfc = $2`, c.EmpresaID, c.RFC)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = tx.QueryRow(`
+        INSERT INTO csd_certificados (empresa_id, user_id, rfc, no_certificado, razon_social, certificado, llave_cifrada,
+            valido_desde, valido_hasta, activo)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, true)
+        ON CONFLICT (empresa_id, no_certificado) DO UPDATE
+        SET llave_cifrada = EXCLUDED.llave_cifrada, activo = true
+        RETURNING id
+    `, c.EmpresaID, c.UserID, c.RFC, c.NoCertificado, c.RazonSocial, certDER, llaveCifrada, c.ValidoDesde, c.ValidoHasta).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+func (r *Repository) GetCSDs(empresaID int) ([]CertificadoCSD, error) {
+	rows, err := r.db.Query(`
+        SELECT id, empresa_id, user_id, rfc, no_certificado, razon_social, valido_desde, valido_hasta, activo, created_at
+        FROM csd_certificados
+        WHERE empresa_id = $1
+        ORDER BY created_at DESC
+    `, empresaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var certificados []CertificadoCSD
+	for rows.Next() {
+		var c CertificadoCSD
+		err := rows.Scan(&c.ID, &c.EmpresaID, &c.UserID, &c.RFC, &c.NoCertificado, &c.RazonSocial,
+			&c.ValidoDesde, &c.ValidoHasta, &c.Activo, &c.CreatedAt)
+		if err != nil {
+			continue
+		}
+		certificados = append(certificados, c)
+	}
+
+	return certificados, nil
+}
+
+// GetCSDActivo devuelve el certificado y la llave cifrada vigentes para el RFC
+func (r *Repository) GetCSDActivo(empresaID int, rfc string) (certDER, llaveCifrada []byte, err error) {
+	err = r.db.QueryRow(`
+        SELECT certificado, llave_cifrada
+        FROM csd_certificados
+        WHERE empresa_id = $1 AND rfc = $2 AND activo = true
+    `, empresaID, rfc).Scan(&certDER, &llaveCifrada)
+	return certDER, llaveCifrada, err
+}
+
+func (r *Repository) EliminarCSD(empresaID, id int) error {
+	res, err := r.db.Exec(`DELETE FROM csd_certificados WHERE empresa_id = $1 AND id = $2`, empresaID, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CrearCancelacion registra la solicitud en proceso junto con su primer evento
+func (r *Repository) CrearCancelacion(c *Cancelacion) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// idx_cancelaciones_pendiente impide dos solicitudes en curso de la misma
+	// factura aunque lleguen al mismo tiempo
+	var id int
+	err = tx.QueryRow(`
+        INSERT INTO cancelaciones (factura_id, empresa_id, user_id, uuid, motivo, folio_sustitucion, estado)
+        VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+        RETURNING id
+    `, c.FacturaID, c.EmpresaID, c.UserID, c.UUID, c.Motivo, c.FolioSustitucion, c.Estado).Scan(&id)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+		return 0, ErrCancelacionPendiente
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO cancelacion_eventos (cancelacion_id, estado) VALUES ($1, $2)`, id, c.Estado)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// ActualizarCancelacion cambia el estado, guarda el evento y, si quedó
+// cancelada, marca la factura
+func (r *Repository) ActualizarCancelacion(c *Cancelacion) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+        UPDATE cancelaciones
+        SET estado = $1, mensaje = $2, acuse = NULLIF($3, ''), updated_at = CURRENT_TIMESTAMP
+        WHERE id = $4
+    `, c.Estado, c.Mensaje, c.Acuse, c.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO cancelacion_eventos (cancelacion_id, estado, mensaje)
+        VALUES ($1, $2, $3)
+    `, c.ID, c.Estado, c.Mensaje)
+	if err != nil {
+		return err
+	}
+
+	if c.Estado == CancelacionCancelado {
+		_, err = tx.Exec(`
+            UPDATE facturas SET estado = $1, updated_at = CURRENT_TIMESTAMP
+            WHERE id = $2 AND empresa_id = $3
+        `, EstadoCancelada, c.FacturaID, c.EmpresaID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+const columnasCancelacion = `id, factura_id, empresa_id, user_id, uuid, motivo, COALESCE(folio_sustitucion, ''), estado,
+        COALESCE(mensaje, ''), COALESCE(acuse, ''), created_at, updated_at`
+
+// GetCancelacionPendiente devuelve la solicitud que aún espera respuesta, si existe
+func (r *Repository) GetCancelacionPendiente(empresaID, facturaID int) (*Cancelacion, error) {
+	row := r.db.QueryRow(`
+        SELECT `+columnasCancelacion+`
+        FROM cancelaciones
+        WHERE empresa_id = $1 AND factura_id = $2 AND estado IN ($3, $4)
+        ORDER BY created_at DESC
+        LIMIT 1
+    `, empresaID, facturaID, CancelacionEnProceso, CancelacionConAceptacion)
+	return scanCancelacion(row)
+}
+
+// GetCancelaciones devuelve todas las solicitudes de la factura con sus eventos
+func (r *Repository) GetCancelaciones(empresaID, facturaID int) ([]Cancelacion, error) {
+	rows, err := r.db.Query(`
+        SELECT `+columnasCancelacion+`
+        FROM cancelaciones
+        WHERE empresa_id = $1 AND factura_id = $2
+        ORDER BY created_at DESC
+    `, empresaID, facturaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cancelaciones []Cancelacion
+	indice := make(map[int]int)
+	for rows.Next() {
+		c, err := scanCancelacion(rows)
+		if err != nil {
+			continue
+		}
+		indice[c.ID] = len(cancelaciones)
+		cancelaciones = append(cancelaciones, *c)
+	}
+	if len(cancelaciones) == 0 {
+		return cancelaciones, nil
+	}
+
+	eventos, err := r.db.Query(`
+        SELECT e.cancelacion_id, e.estado, COALESCE(e.mensaje, ''), e.created_at
+        FROM cancelacion_eventos e
+        JOIN cancelaciones c ON c.id = e.cancelacion_id
+        WHERE c.empresa_id = $1 AND c.factura_id = $2
+        ORDER BY e.created_at, e.id
+    `, empresaID, facturaID)
+	if err != nil {
+		return nil, err
+	}
+	defer eventos.Close()
+
+	for eventos.Next() {
+		var id int
+		var e EventoCancelacion
+		if err := eventos.Scan(&id, &e.Estado, &e.Mensaje, &e.CreatedAt); err != nil {
+			continue
+		}
+		if i, ok := indice[id]; ok {
+			cancelaciones[i].Eventos = append(cancelaciones[i].Eventos, e)
+		}
+	}
+
+	return cancelaciones, nil
+}
+
+func scanCancelacion(row rowScanner) (*Cancelacion, error) {
+	var c Cancelacion
+	err := row.Scan(&c.ID, &c.FacturaID, &c.EmpresaID, &c.UserID, &c.UUID, &c.Motivo, &c.FolioSustitucion, &c.Estado,
+		&c.Mensaje, &c.Acuse, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanFactura(row rowScanner) (*Factura, error) {
+	var f Factura
+	var fechaTimbrado sql.NullTime
+	err := row.Scan(&f.ID, &f.EmpresaID, &f.UserID, &f.Serie, &f.Folio, &f.Fecha, &f.EmisorRFC, &f.ReceptorRFC, &f.ReceptorNombre,
+		&f.Moneda, &f.SubTotal, &f.Total, &f.Estado, &f.CadenaOriginal, &f.NoCertificado, &f.UUID, &fechaTimbrado,
+		&f.CreatedAt, &f.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if fechaTimbrado.Valid {
+		f.FechaTimbrado = &fechaTimbrado.Time
+	}
+	return &f, nil
+}
@@ -0,0 +1,58 @@
+// internal/modules/facturacion/sello.go
+package facturacion
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+var ErrCSDEmisor = errors.New("el CSD no pertenece al RFC emisor del comprobante")
+
+// Sellar llena NoCertificado y Certificado, recalcula la cadena original y
+// firma con SHA-256/RSA (PKCS #1 v1.5) para obtener el Sello. Devuelve la cadena.
+func Sellar(comp *Comprobante, csd *CSD) (string, error) {
+	if csd.RFC != comp.Emisor.Rfc {
+		return "", ErrCSDEmisor
+	}
+
+	comp.NoCertificado = csd.NoCertificado
+	comp.Certificado = base64.StdEncoding.EncodeToString(csd.Certificado.Raw)
+	comp.Sello = ""
+
+	cadena := CadenaOriginal(comp)
+	digest := sha256.Sum256([]byte(cadena))
+	firma, err := rsa.SignPKCS1v15(rand.Reader, csd.Llave, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	comp.Sello = base64.StdEncoding.EncodeToString(firma)
+	return cadena, nil
+}
+
+// VerificarSello comprueba el Sello de un comprobante contra su propio certificado
+func VerificarSello(comp *Comprobante) error {
+	der, err := base64.StdEncoding.DecodeString(comp.Certificado)
+	if err != nil {
+		return ErrCertificadoFormato
+	}
+	cert, err := parsearCertificado(der)
+	if err != nil {
+		return err
+	}
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return ErrCertificadoFormato
+	}
+
+	firma, err := base64.StdEncoding.DecodeString(comp.Sello)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(CadenaOriginal(comp)))
+	return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], firma)
+}
@@ -0,0 +1,103 @@
+package facturacion
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// noCertificadoPrueba tiene el formato de 20 dígitos de los CSD del SAT
+const noCertificadoPrueba = "30001000000500003416"
+
+// csdPrueba genera un CSD autofirmado para el RFC, con el RFC en el
+// x500UniqueIdentifier y el serial en dígitos ASCII como los del SAT
+func csdPrueba(t *testing.T, rfc, razonSocial string) *CSD {
+	t.Helper()
+
+	llave, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plantilla := &x509.Certificate{
+		SerialNumber: new(big.Int).SetBytes([]byte(noCertificadoPrueba)),
+		Subject: pkix.Name{
+			CommonName: razonSocial,
+			ExtraNames: []pkix.AttributeTypeAndValue{
+				{Type: asn1.ObjectIdentifier{2, 5, 4, 45}, Value: rfc + " / XAXX010101000"},
+			},
+		},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().AddDate(4, 0, 0),
+		KeyUsage:  x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, plantilla, plantilla, &llave.PublicKey, llave)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := parsearCertificado(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csd, err := NuevoCSD(cert, llave, time.Now())
+	if err != nil {
+		t.Fatalf("NuevoCSD: %v", err)
+	}
+	return csd
+}
+
+func TestNuevoCSD(t *testing.T) {
+	csd := csdPrueba(t, "EKU9003173C9", "ESCUELA KEMPER URGATE")
+	if csd.RFC != "EKU9003173C9" || csd.NoCertificado != noCertificadoPrueba || csd.RazonSocial != "ESCUELA KEMPER URGATE" {
+		t.Errorf("NuevoCSD = %+v", csd)
+	}
+
+	if _, err := NuevoCSD(csd.Certificado, csd.Llave, time.Now().AddDate(5, 0, 0)); err != ErrCertificadoVigencia {
+		t.Errorf("certificado vencido: error = %v, se esperaba %v", err, ErrCertificadoVigencia)
+	}
+
+	otra, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NuevoCSD(csd.Certificado, otra, time.Now()); err != ErrCertificadoLlave {
+		t.Errorf("llave ajena: error = %v, se esperaba %v", err, ErrCertificadoLlave)
+	}
+}
+
+func TestSellarYVerificar(t *testing.T) {
+	csd := csdPrueba(t, "EKU9003173C9", "ESCUELA KEMPER URGATE")
+	comp := comprobantePrueba(t)
+
+	cadena, err := Sellar(comp, csd)
+	if err != nil {
+		t.Fatalf("Sellar: %v", err)
+	}
+	if comp.NoCertificado != noCertificadoPrueba || comp.Certificado == "" || comp.Sello == "" {
+		t.Fatalf("Sellar no llenó el certificado y el sello: %+v", comp)
+	}
+	if cadena != CadenaOriginal(comp) {
+		t.Errorf("la cadena sellada no es la cadena original del comprobante")
+	}
+	if err := VerificarSello(comp); err != nil {
+		t.Fatalf("VerificarSello: %v", err)
+	}
+
+	// Cualquier cambio en un atributo de la cadena invalida el sello
+	comp.Total = "1.00"
+	if err := VerificarSello(comp); err == nil {
+		t.Error("VerificarSello aceptó un comprobante alterado")
+	}
+}
+
+func TestSellarCSDAjeno(t *testing.T) {
+	csd := csdPrueba(t, "XIA190128J61", "XENON INDUSTRIAL ARTICLES")
+	if _, err := Sellar(comprobantePrueba(t), csd); err != ErrCSDEmisor {
+		t.Errorf("error = %v, se esperaba %v", err, ErrCSDEmisor)
+	}
+}
@@ -0,0 +1,510 @@
+// internal/modules/facturacion/service.go
+package facturacion
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jhvc/backend/internal/modules/calculadora"
+	"github.com/jhvc/backend/internal/modules/catalogos"
+	"github.com/jhvc/backend/internal/modules/cfdi"
+	"github.com/jhvc/backend/internal/modules/directorio"
+)
+
+var (
+	ErrFacturaNoEncontrada = errors.New("factura no encontrada")
+	ErrCSDNoEncontrado     = errors.New("no hay un CSD activo para el RFC emisor")
+	ErrEmisorAjeno         = errors.New("el RFC emisor no corresponde a la empresa")
+	ErrCSDAjeno            = errors.New("el CSD no pertenece al RFC de la empresa")
+	ErrFacturaTimbrada     = errors.New("la factura ya fue timbrada")
+	ErrFacturaSinSello     = errors.New("la factura debe sellarse antes de timbrar")
+	ErrTimbradoEnProceso   = errors.New("la factura se está timbrando")
+	ErrReceptorRequerido   = errors.New("indica el receptor o un contacto del directorio")
+	ErrContactoSinUsoCFDI  = errors.New("el contacto no tiene uso de CFDI; indícalo en uso_cfdi")
+
+	ErrFacturaNoTimbrada       = errors.New("sólo se pueden cancelar facturas timbradas")
+	ErrFacturaCancelada        = errors.New("la factura ya está cancelada")
+	ErrCancelacionPendiente    = errors.New("la factura ya tiene una solicitud de cancelación en curso")
+	ErrSinCancelacionPendiente = errors.New("la factura no tiene una solicitud de cancelación en curso")
+	ErrFolioSustitucion        = errors.New("el motivo 01 requiere el UUID del CFDI que sustituye a esta factura")
+	ErrFolioSinRelacion        = errors.New("el folio de sustitución sólo aplica al motivo 01")
+)
+
+// Service genera y administra los CFDI emitidos
+type Service struct {
+	repo        *Repository
+	calculadora *calculadora.Service
+	catalogos   *catalogos.Service
+	cifrador    *Cifrador
+	stamper     Stamper
+	canceler    Canceler
+	cfdis       *cfdi.Service
+	directorio  *directorio.Service
+}
+
+// NewService crea una nueva instancia del servicio. stamper y canceler pueden
+// ser nil si no hay PAC configurado, y cifrador si no hay llave para los CSD
+// (entonces no se cargan ni se usan CSD); los CFDI timbrados se registran en cfdis.
+// El receptor puede tomarse de un contacto del directorio.
+func NewService(repo *Repository, calc *calculadora.Service, cat *catalogos.Service, cifrador *Cifrador,
+	stamper Stamper, canceler Canceler, cfdis *cfdi.Service, dir *directorio.Service) *Service {
+	return &Service{
+		repo:        repo,
+		calculadora: calc,
+		catalogos:   cat,
+		cifrador:    cifrador,
+		stamper:     stamper,
+		canceler:    canceler,
+		cfdis:       cfdis,
+		directorio:  dir,
+	}
+}
+
+// Generar calcula los conceptos, arma el CFDI 4.0 sin sellar, lo valida contra
+// los catálogos del SAT y lo guarda como borrador junto con su cadena original.
+// El emisor debe ser la empresa.
+func (s *Service) Generar(empresaID, userID int, req GenerarFacturaRequest) (*ResultadoGeneracion, error) {
+	if req.ContactoID > 0 {
+		receptor, err := s.receptorDeContacto(empresaID, req.ContactoID, req.UsoCFDI)
+		if err != nil {
+			return nil, err
+		}
+		req.Receptor = receptor
+	}
+	if req.Receptor == nil {
+		return nil, ErrReceptorRequerido
+	}
+
+	conceptos := make([]calculadora.ConceptoCalculo, len(req.Conceptos))
+	for i, c := range req.Conceptos {
+		conceptos[i] = c.ConceptoCalculo
+	}
+
+	calc, err := s.calculadora.CalcularConceptos(conceptos)
+	if err != nil {
+		return nil, err
+	}
+
+	fecha := time.Now()
+	comp, err := construirComprobante(req, calc, fecha)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validarRFCEmpresa(empresaID, comp.Emisor.Rfc, ErrEmisorAjeno); err != nil {
+		return nil, err
+	}
+
+	advertencias, err := validarComprobante(comp, s.catalogos)
+	if err != nil {
+		return nil, err
+	}
+
+	xmlData, err := comp.XML()
+	if err != nil {
+		return nil, err
+	}
+
+	subtotal, _ := strconv.ParseFloat(comp.SubTotal, 64)
+	total, _ := strconv.ParseFloat(comp.Total, 64)
+	factura := Factura{
+		EmpresaID:      empresaID,
+		UserID:         userID,
+		Serie:          comp.Serie,
+		Folio:          comp.Folio,
+		Fecha:          fecha,
+		EmisorRFC:      comp.Emisor.Rfc,
+		ReceptorRFC:    comp.Receptor.Rfc,
+		ReceptorNombre: comp.Receptor.Nombre,
+		Moneda:         comp.Moneda,
+		SubTotal:       subtotal,
+		Total:          total,
+		Estado:         EstadoBorrador,
+		CadenaOriginal: CadenaOriginal(comp),
+	}
+
+	factura.ID, err = s.repo.CrearFactura(&factura, comp, xmlData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResultadoGeneracion{
+		Factura:      factura,
+		XML:          string(xmlData),
+		Calculo:      calc,
+		Advertencias: advertencias,
+	}, nil
+}
+
+// receptorDeContacto arma el receptor con los datos fiscales del contacto;
+// usoCFDI, si viene, reemplaza el del contacto
+func (s *Service) receptorDeContacto(empresaID, contactoID int, usoCFDI string) (*ReceptorRequest, error) {
+	r, err := s.directorio.Receptor(empresaID, contactoID)
+	if err != nil {
+		return nil, err
+	}
+	if usoCFDI == "" {
+		usoCFDI = r.UsoCFDI
+	}
+	if usoCFDI == "" {
+		return nil, ErrContactoSinUsoCFDI
+	}
+	return &ReceptorRequest{
+		Rfc:                     r.Rfc,
+		Nombre:                  r.Nombre,
+		DomicilioFiscalReceptor: r.DomicilioFiscalReceptor,
+		RegimenFiscalReceptor:   r.RegimenFiscalReceptor,
+		UsoCFDI:                 usoCFDI,
+	}, nil
+}
+
+func (s *Service) GetFacturas(empresaID int) ([]Factura, error) {
+	return s.repo.GetFacturas(empresaID)
+}
+
+func (s *Service) GetFactura(empresaID, id int) (*Factura, error) {
+	f, err := s.repo.GetFactura(empresaID, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrFacturaNoEncontrada
+	}
+	return f, err
+}
+
+func (s *Service) GetXML(empresaID, id int) ([]byte, error) {
+	data, err := s.repo.GetXML(empresaID, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrFacturaNoEncontrada
+	}
+	return data, err
+}
+
+// CargarCSD valida el par .cer/.key de la empresa y guarda la llave privada
+// cifrada en reposo
+func (s *Service) CargarCSD(empresaID, userID int, cer, key []byte, password string) (*CertificadoCSD, error) {
+	if s.cifrador == nil {
+		return nil, ErrCifradoNoConfigurado
+	}
+
+	cert, err := parsearCertificado(cer)
+	if err != nil {
+		return nil, err
+	}
+	llave, llaveDER, err := descifrarLlavePKCS8(key, password)
+	if err != nil {
+		return nil, err
+	}
+	csd, err := NuevoCSD(cert, llave, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validarRFCEmpresa(empresaID, csd.RFC, ErrCSDAjeno); err != nil {
+		return nil, err
+	}
+
+	llaveCifrada, err := s.cifrador.Cifrar(llaveDER)
+	if err != nil {
+		return nil, err
+	}
+
+	registro := CertificadoCSD{
+		EmpresaID:     empresaID,
+		UserID:        userID,
+		RFC:           csd.RFC,
+		NoCertificado: csd.NoCertificado,
+		RazonSocial:   csd.RazonSocial,
+		ValidoDesde:   cert.NotBefore,
+		ValidoHasta:   cert.NotAfter,
+		Activo:        true,
+	}
+	registro.ID, err = s.repo.GuardarCSD(&registro, cert.Raw, llaveCifrada)
+	if err != nil {
+		return nil, err
+	}
+
+	return &registro, nil
+}
+
+func (s *Service) GetCSDs(empresaID int) ([]CertificadoCSD, error) {
+	return s.repo.GetCSDs(empresaID)
+}
+
+func (s *Service) EliminarCSD(empresaID, id int) error {
+	err := s.repo.EliminarCSD(empresaID, id)
+	if err == sql.ErrNoRows {
+		return ErrCSDNoEncontrado
+	}
+	return err
+}
+
+// csdActivo recupera y descifra el CSD vigente del RFC, revalidando su vigencia
+func (s *Service) csdActivo(empresaID int, rfc string) (*CSD, error) {
+	if s.cifrador == nil {
+		return nil, ErrCifradoNoConfigurado
+	}
+
+	certDER, llaveCifrada, err := s.repo.GetCSDActivo(empresaID, rfc)
+	if err == sql.ErrNoRows {
+		return nil, ErrCSDNoEncontrado
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := parsearCertificado(certDER)
+	if err != nil {
+		return nil, err
+	}
+	llaveDER, err := s.cifrador.Descifrar(llaveCifrada)
+	if err != nil {
+		return nil, err
+	}
+	llave, err := parsearLlavePKCS8(llaveDER)
+	if err != nil {
+		return nil, err
+	}
+
+	return NuevoCSD(cert, llave, time.Now())
+}
+
+// Sellar firma la factura con el CSD activo del emisor. La Fecha se actualiza
+// al momento del sellado porque el PAC sólo acepta comprobantes recientes.
+func (s *Service) Sellar(empresaID, id int) (*ResultadoGeneracion, error) {
+	factura, err := s.GetFactura(empresaID, id)
+	if err != nil {
+		return nil, err
+	}
+	if factura.Estado != EstadoBorrador && factura.Estado != EstadoSellada {
+		return nil, ErrFacturaTimbrada
+	}
+
+	comp, err := s.repo.GetComprobante(empresaID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	csd, err := s.csdActivo(empresaID, comp.Emisor.Rfc)
+	if err != nil {
+		return nil, err
+	}
+
+	fecha := time.Now()
+	comp.Fecha = fecha.In(zonaCentro).Format(formatoFecha)
+	cadena, err := Sellar(comp, csd)
+	if err != nil {
+		return nil, err
+	}
+
+	xmlData, err := comp.XML()
+	if err != nil {
+		return nil, err
+	}
+
+	factura.Fecha = fecha
+	factura.Estado = EstadoSellada
+	factura.CadenaOriginal = cadena
+	factura.NoCertificado = csd.NoCertificado
+	// El estado se vuelve a comprobar al guardar: un timbrado que empezó
+	// después de la lectura no debe perder el XML que envió al PAC
+	if err := s.repo.GuardarSellado(factura, comp, xmlData); err == sql.ErrNoRows {
+		return nil, ErrFacturaTimbrada
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &ResultadoGeneracion{
+		Factura: *factura,
+		XML:     string(xmlData),
+	}, nil
+}
+
+// Timbrar envía la factura sellada al PAC y guarda el XML timbrado. Es
+// idempotente por factura: una factura ya timbrada devuelve su timbre y dos
+// solicitudes simultáneas no llegan juntas al PAC.
+func (s *Service) Timbrar(ctx context.Context, empresaID, userID, id int) (*Factura, error) {
+	if s.stamper == nil {
+		return nil, ErrTimbradoNoConfigurado
+	}
+
+	factura, err := s.GetFactura(empresaID, id)
+	if err != nil {
+		return nil, err
+	}
+	switch factura.Estado {
+	case EstadoTimbrada:
+		return factura, nil
+	case EstadoBorrador:
+		return nil, ErrFacturaSinSello
+	}
+
+	reservada, err := s.repo.ReservarTimbrado(empresaID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !reservada {
+		return nil, ErrTimbradoEnProceso
+	}
+
+	xmlSellado, err := s.repo.GetXML(empresaID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	timbre, err := s.stamper.Timbrar(ctx, id, xmlSellado)
+	if err != nil {
+		if errLib := s.repo.LiberarTimbrado(empresaID, id); errLib != nil {
+			log.Printf("⚠️  No se pudo liberar la factura %d: %v", id, errLib)
+		}
+		return nil, err
+	}
+
+	if err := s.repo.GuardarTimbre(empresaID, id, timbre); err != nil {
+		return nil, err
+	}
+
+	// El CFDI emitido también forma parte del repositorio de comprobantes
+	if _, err := s.cfdis.Ingerir(empresaID, userID, timbre.XML); err != nil {
+		log.Printf("⚠️  Factura %d timbrada pero no registrada en CFDIs: %v", id, err)
+	}
+
+	return s.GetFactura(empresaID, id)
+}
+
+// Cancelar registra la solicitud de cancelación y la envía al servicio del SAT.
+// El motivo 01 exige un CFDI sustituto ya registrado.
+func (s *Service) Cancelar(ctx context.Context, empresaID, userID, id int, req CancelarRequest) (*Cancelacion, error) {
+	if s.canceler == nil {
+		return nil, ErrCancelacionNoConfigurada
+	}
+
+	factura, err := s.GetFactura(empresaID, id)
+	if err != nil {
+		return nil, err
+	}
+	switch factura.Estado {
+	case EstadoTimbrada:
+	case EstadoCancelada:
+		return nil, ErrFacturaCancelada
+	default:
+		return nil, ErrFacturaNoTimbrada
+	}
+
+	if _, err := s.repo.GetCancelacionPendiente(empresaID, id); err == nil {
+		return nil, ErrCancelacionPendiente
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	folio := cfdi.NormalizarUUID(req.FolioSustitucion)
+	if req.Motivo == MotivoErroresConRelacion {
+		if folio == "" || folio == factura.UUID {
+			return nil, ErrFolioSustitucion
+		}
+		if _, err := s.cfdis.GetCFDI(empresaID, folio); err != nil {
+			return nil, ErrFolioSustitucion
+		}
+	} else if folio != "" {
+		return nil, ErrFolioSinRelacion
+	}
+
+	cancelacion := &Cancelacion{
+		FacturaID:        id,
+		EmpresaID:        empresaID,
+		UserID:           userID,
+		UUID:             factura.UUID,
+		Motivo:           req.Motivo,
+		FolioSustitucion: folio,
+		Estado:           CancelacionEnProceso,
+	}
+	cancelacion.ID, err = s.repo.CrearCancelacion(cancelacion)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.canceler.Cancelar(ctx, solicitudCancelacion(factura, cancelacion))
+	if err != nil {
+		resp = &RespuestaCancelacion{Estado: CancelacionError, Mensaje: err.Error()}
+	}
+	if errAct := s.aplicarRespuesta(cancelacion, resp); errAct != nil {
+		return nil, errAct
+	}
+
+	return cancelacion, err
+}
+
+// ConsultarCancelacion pregunta al SAT por la solicitud en curso, típicamente
+// mientras espera la aceptación del receptor
+func (s *Service) ConsultarCancelacion(ctx context.Context, empresaID, id int) (*Cancelacion, error) {
+	if s.canceler == nil {
+		return nil, ErrCancelacionNoConfigurada
+	}
+
+	factura, err := s.GetFactura(empresaID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cancelacion, err := s.repo.GetCancelacionPendiente(empresaID, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrSinCancelacionPendiente
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.canceler.ConsultarEstado(ctx, solicitudCancelacion(factura, cancelacion))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Estado == cancelacion.Estado && resp.Mensaje == cancelacion.Mensaje {
+		return cancelacion, nil
+	}
+	if err := s.aplicarRespuesta(cancelacion, resp); err != nil {
+		return nil, err
+	}
+
+	return cancelacion, nil
+}
+
+// GetCancelaciones devuelve el historial de solicitudes de la factura
+func (s *Service) GetCancelaciones(empresaID, id int) ([]Cancelacion, error) {
+	if _, err := s.GetFactura(empresaID, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetCancelaciones(empresaID, id)
+}
+
+// validarRFCEmpresa compara el RFC con el de la empresa; si difiere devuelve ajeno
+func (s *Service) validarRFCEmpresa(empresaID int, rfc string, ajeno error) error {
+	rfcEmpresa, err := s.repo.GetRFCEmpresa(empresaID)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(strings.TrimSpace(rfc), rfcEmpresa) {
+		return ajeno
+	}
+	return nil
+}
+
+func (s *Service) aplicarRespuesta(c *Cancelacion, resp *RespuestaCancelacion) error {
+	c.Estado = resp.Estado
+	c.Mensaje = resp.Mensaje
+	c.Acuse = resp.Acuse
+	return s.repo.ActualizarCancelacion(c)
+}
+
+func solicitudCancelacion(f *Factura, c *Cancelacion) SolicitudCancelacion {
+	return SolicitudCancelacion{
+		UUID:             c.UUID,
+		RfcEmisor:        f.EmisorRFC,
+		RfcReceptor:      f.ReceptorRFC,
+		Total:            f.Total,
+		Motivo:           c.Motivo,
+		FolioSustitucion: c.FolioSustitucion,
+	}
+}
@@ -0,0 +1,116 @@
+// internal/modules/facturacion/timbrado.go
+package facturacion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jhvc/backend/internal/modules/cfdi"
+)
+
+var ErrTimbradoNoConfigurado = errors.New("no hay un proveedor de timbrado configurado")
+
+// Timbre es el resultado de timbrar un comprobante con un PAC
+type Timbre struct {
+	UUID             string    `json:"uuid"`
+	FechaTimbrado    time.Time `json:"fecha_timbrado"`
+	NoCertificadoSAT string    `json:"no_certificado_sat"`
+	SelloSAT         string    `json:"sello_sat"`
+	XML              []byte    `json:"-"`
+}
+
+// Stamper timbra un CFDI ya sellado. Puede ser un PAC real o uno local de pruebas.
+// facturaID identifica la solicitud para que el proveedor pueda ser idempotente.
+type Stamper interface {
+	Timbrar(ctx context.Context, facturaID int, xmlSellado []byte) (*Timbre, error)
+}
+
+// ErrorPAC es un rechazo del proveedor. Temporal indica que vale la pena
+// reintentar (caída del servicio, saturación); las validaciones no lo son.
+type ErrorPAC struct {
+	Codigo   string
+	Mensaje  string
+	Temporal bool
+}
+
+func (e *ErrorPAC) Error() string {
+	if e.Codigo == "" {
+		return "PAC: " + e.Mensaje
+	}
+	return fmt.Sprintf("PAC %s: %s", e.Codigo, e.Mensaje)
+}
+
+// esTemporal decide si un error de timbrado se reintenta. Los errores que no
+// vienen del PAC (red, tiempo de espera) se consideran temporales.
+func esTemporal(err error) bool {
+	var pacErr *ErrorPAC
+	if errors.As(err, &pacErr) {
+		return pacErr.Temporal
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// stamperConReintentos reintenta los errores temporales con espera exponencial
+type stamperConReintentos struct {
+	stamper  Stamper
+	intentos int
+	espera   time.Duration
+}
+
+// ConReintentos envuelve un Stamper para reintentar hasta `intentos` veces,
+// duplicando la espera entre cada intento
+func ConReintentos(s Stamper, intentos int, espera time.Duration) Stamper {
+	if intentos < 1 {
+		intentos = 1
+	}
+	return &stamperConReintentos{stamper: s, intentos: intentos, espera: espera}
+}
+
+func (s *stamperConReintentos) Timbrar(ctx context.Context, facturaID int, xmlSellado []byte) (*Timbre, error) {
+	espera := s.espera
+	var err error
+
+	for intento := 1; ; intento++ {
+		var timbre *Timbre
+		timbre, err = s.stamper.Timbrar(ctx, facturaID, xmlSellado)
+		if err == nil {
+			return timbre, nil
+		}
+		if intento >= s.intentos || !esTemporal(err) {
+			return nil, err
+		}
+
+		log.Printf("⚠️  Timbrado de factura %d falló (intento %d/%d): %v", facturaID, intento, s.intentos, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(espera):
+		}
+		espera *= 2
+	}
+}
+
+// timbreDesdeXML lee el TimbreFiscalDigital del XML que devuelve el PAC
+func timbreDesdeXML(data []byte) (*Timbre, error) {
+	comp, err := cfdi.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	tfd := comp.Complemento.TimbreFiscalDigital
+	fecha, err := cfdi.ParseFecha(tfd.FechaTimbrado)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Timbre{
+		UUID:             comp.UUID(),
+		FechaTimbrado:    fecha,
+		NoCertificadoSAT: tfd.NoCertificadoSAT,
+		SelloSAT:         tfd.SelloSAT,
+		XML:              data,
+	}, nil
+}
@@ -0,0 +1,245 @@
+package facturacion
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/jhvc/backend/internal/modules/calculadora"
+	"github.com/jhvc/backend/internal/modules/cfdi"
+)
+
+// solicitudPrueba es una factura de dos conceptos: honorarios RESICO con
+// retenciones y un concepto de hospedaje con ISH
+func solicitudPrueba() GenerarFacturaRequest {
+	return GenerarFacturaRequest{
+		Serie:           "A",
+		Folio:           "100",
+		FormaPago:       "03",
+		MetodoPago:      "PUE",
+		LugarExpedicion: "42501",
+		Emisor: EmisorRequest{
+			Rfc:           "EKU9003173C9",
+			Nombre:        "Escuela Kemper Urgate",
+			RegimenFiscal: "601",
+		},
+		Receptor: &ReceptorRequest{
+			Rfc:                     "XIA190128J61",
+			Nombre:                  "Xenon Industrial  Articles",
+			DomicilioFiscalReceptor: "76343",
+			RegimenFiscalReceptor:   "601",
+			UsoCFDI:                 "g03",
+		},
+		Conceptos: []ConceptoRequest{
+			{
+				ConceptoCalculo: calculadora.ConceptoCalculo{Descripcion: "Asesoría contable", Cantidad: 2, ValorUnitario: 1500, ConfigIndex: 0},
+				ClaveProdServ:   "84111500",
+				ClaveUnidad:     "E48",
+			},
+			{
+				ConceptoCalculo: calculadora.ConceptoCalculo{Descripcion: "Hospedaje", Cantidad: 1, ValorUnitario: 1000, Descuento: 100, ConfigIndex: 4},
+				ClaveProdServ:   "90111800",
+				ClaveUnidad:     "E48",
+			},
+		},
+	}
+}
+
+// comprobantePrueba es el paso Generar sin base de datos: calcula los
+// conceptos y arma el CFDI sin sellar
+func comprobantePrueba(t *testing.T) *Comprobante {
+	t.Helper()
+
+	req := solicitudPrueba()
+	conceptos := make([]calculadora.ConceptoCalculo, len(req.Conceptos))
+	for i, c := range req.Conceptos {
+		conceptos[i] = c.ConceptoCalculo
+	}
+	calc, err := calculadora.NewService().CalcularConceptos(conceptos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comp, err := construirComprobante(req, calc, time.Date(2025, 3, 10, 18, 30, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("construirComprobante: %v", err)
+	}
+	return comp
+}
+
+func TestConstruirComprobante(t *testing.T) {
+	comp := comprobantePrueba(t)
+
+	if comp.Fecha != "2025-03-10T12:30:00" {
+		t.Errorf("Fecha = %s, se esperaba la hora del centro", comp.Fecha)
+	}
+	if comp.Receptor.Nombre != "XENON INDUSTRIAL ARTICLES" || comp.Receptor.UsoCFDI != "G03" {
+		t.Errorf("Receptor = %+v", comp.Receptor)
+	}
+	// 3000 + 1000 - 100 de descuento; IVA 480 + 144; ret. ISR 37.50 y ret. IVA 320; ISH 45
+	if comp.SubTotal != "4000.00" || comp.Descuento != "100.00" || comp.Total != "4211.50" {
+		t.Errorf("SubTotal/Descuento/Total = %s/%s/%s", comp.SubTotal, comp.Descuento, comp.Total)
+	}
+	if imp := comp.Impuestos; imp == nil || imp.TotalImpuestosTrasladados != "624.00" || imp.TotalImpuestosRetenidos != "357.50" {
+		t.Errorf("Impuestos = %+v", comp.Impuestos)
+	}
+	if comp.Complemento == nil || comp.Complemento.ImpuestosLocales.TotaldeTraslados != "45.00" {
+		t.Errorf("falta el ISH en implocal: %+v", comp.Complemento)
+	}
+}
+
+// TestFlujoEmision recorre generar, sellar y timbrar con el PAC local, como
+// lo hace el servicio, y revisa el timbre resultante
+func TestFlujoEmision(t *testing.T) {
+	csd := csdPrueba(t, "EKU9003173C9", "ESCUELA KEMPER URGATE")
+	pac, err := NewPACLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comp := comprobantePrueba(t)
+	if _, err := Sellar(comp, csd); err != nil {
+		t.Fatalf("Sellar: %v", err)
+	}
+	xmlSellado, err := comp.XML()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const facturaID = 7
+	timbre, err := pac.Timbrar(context.Background(), facturaID, xmlSellado)
+	if err != nil {
+		t.Fatalf("Timbrar: %v", err)
+	}
+	if cfdi.NormalizarUUID(timbre.UUID) != timbre.UUID || len(timbre.UUID) != 36 {
+		t.Errorf("UUID = %q", timbre.UUID)
+	}
+	if timbre.NoCertificadoSAT != pac.noCertificado {
+		t.Errorf("NoCertificadoSAT = %q", timbre.NoCertificadoSAT)
+	}
+
+	// El PAC sólo agrega el complemento: el comprobante sellado queda intacto
+	i := bytes.Index(timbre.XML, []byte("<cfdi:Complemento>"))
+	if i < 0 || !bytes.HasPrefix(xmlSellado, timbre.XML[:i]) {
+		t.Fatal("el XML timbrado no conserva el comprobante sellado")
+	}
+	if err := VerificarSello(comp); err != nil {
+		t.Errorf("VerificarSello: %v", err)
+	}
+
+	tfd := tfdDelXML(t, timbre.XML)
+	if tfd.SelloCFD != comp.Sello {
+		t.Error("SelloCFD del timbre no es el sello del comprobante")
+	}
+	if tfd.RfcProvCertif != rfcPACPruebas || tfd.Version != "1.1" {
+		t.Errorf("TimbreFiscalDigital = %+v", tfd)
+	}
+	firma, err := base64.StdEncoding.DecodeString(tfd.SelloSAT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(tfd.cadenaOriginal()))
+	if err := rsa.VerifyPKCS1v15(&pac.llave.PublicKey, crypto.SHA256, digest[:], firma); err != nil {
+		t.Errorf("SelloSAT no corresponde a la cadena del timbre: %v", err)
+	}
+
+	// Reintentar la misma factura devuelve el mismo timbre
+	otra, err := pac.Timbrar(context.Background(), facturaID, xmlSellado)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otra.UUID != timbre.UUID || !bytes.Equal(otra.XML, timbre.XML) {
+		t.Error("el retimbrado de la misma factura generó un timbre nuevo")
+	}
+
+	// Un nuevo sellado de la factura (con otra Fecha) sí se timbra de nuevo
+	comp.Fecha = "2025-03-10T12:45:00"
+	if _, err := Sellar(comp, csd); err != nil {
+		t.Fatal(err)
+	}
+	resellado, err := comp.XML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nuevo, err := pac.Timbrar(context.Background(), facturaID, resellado)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nuevo.UUID == timbre.UUID {
+		t.Error("un comprobante con otro sello reutilizó el UUID anterior")
+	}
+}
+
+func TestPACLocalSinSello(t *testing.T) {
+	pac, err := NewPACLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	xmlData, err := comprobantePrueba(t).XML()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = pac.Timbrar(context.Background(), 1, xmlData)
+	if pacErr, ok := err.(*ErrorPAC); !ok || pacErr.Codigo != "CFDI40102" || esTemporal(err) {
+		t.Errorf("error = %v, se esperaba el rechazo CFDI40102", err)
+	}
+}
+
+func TestCancelerLocal(t *testing.T) {
+	canceler := NewCancelerLocal(0)
+	ctx := context.Background()
+
+	menor := SolicitudCancelacion{UUID: "A", RfcEmisor: "EKU9003173C9", RfcReceptor: "XIA190128J61", Total: 500, Motivo: "02"}
+	resp, err := canceler.Cancelar(ctx, menor)
+	if err != nil || resp.Estado != CancelacionCancelado || resp.Acuse == "" {
+		t.Errorf("Cancelar(%+v) = %+v, %v", menor, resp, err)
+	}
+
+	mayor := SolicitudCancelacion{UUID: "B", RfcEmisor: "EKU9003173C9", RfcReceptor: "XIA190128J61", Total: 4211.50, Motivo: "02"}
+	resp, err = canceler.Cancelar(ctx, mayor)
+	if err != nil || resp.Estado != CancelacionConAceptacion {
+		t.Fatalf("Cancelar(%+v) = %+v, %v", mayor, resp, err)
+	}
+	resp, err = canceler.ConsultarEstado(ctx, mayor)
+	if err != nil || resp.Estado != CancelacionCancelado {
+		t.Errorf("ConsultarEstado = %+v, %v", resp, err)
+	}
+}
+
+// tfdDelXML lee el TimbreFiscalDigital del XML timbrado
+func tfdDelXML(t *testing.T, data []byte) TimbreFiscalDigital {
+	t.Helper()
+
+	var doc struct {
+		Complemento struct {
+			TFD struct {
+				Version          string `xml:"Version,attr"`
+				UUID             string `xml:"UUID,attr"`
+				FechaTimbrado    string `xml:"FechaTimbrado,attr"`
+				RfcProvCertif    string `xml:"RfcProvCertif,attr"`
+				SelloCFD         string `xml:"SelloCFD,attr"`
+				NoCertificadoSAT string `xml:"NoCertificadoSAT,attr"`
+				SelloSAT         string `xml:"SelloSAT,attr"`
+			} `xml:"TimbreFiscalDigital"`
+		} `xml:"Complemento"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	tfd := doc.Complemento.TFD
+	return TimbreFiscalDigital{
+		Version:          tfd.Version,
+		UUID:             tfd.UUID,
+		FechaTimbrado:    tfd.FechaTimbrado,
+		RfcProvCertif:    tfd.RfcProvCertif,
+		SelloCFD:         tfd.SelloCFD,
+		NoCertificadoSAT: tfd.NoCertificadoSAT,
+		SelloSAT:         tfd.SelloSAT,
+	}
+}
@@ -0,0 +1,103 @@
+// internal/modules/facturacion/validar.go
+package facturacion
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jhvc/backend/internal/modules/catalogos"
+	"github.com/jhvc/backend/internal/validacion"
+)
+
+// validarComprobante revisa el comprobante contra los catálogos vigentes y las
+// reglas del Anexo 20 que no cubre el esquema. Los catálogos grandes que aún
+// no se han importado (ClaveProdServ, ClaveUnidad, CodigoPostal) sólo generan
+// advertencias; cualquier otra falla impide emitir el XML.
+func validarComprobante(comp *Comprobante, cat *catalogos.Service) (advertencias []string, err error) {
+	var errs ErroresValidacion
+
+	clave := func(catalogo, valor, campo string) {
+		if err := cat.ValidarClave(catalogo, valor); err != nil {
+			if errors.Is(err, catalogos.ErrCatalogoNoCargado) {
+				advertencias = append(advertencias, fmt.Sprintf("%s no se validó: %s", campo, err))
+				return
+			}
+			errs = append(errs, fmt.Sprintf("%s: %s", campo, err))
+		}
+	}
+
+	infoEmisor, err := validacion.ValidarRFC(comp.Emisor.Rfc)
+	if err != nil {
+		errs = append(errs, "Emisor.Rfc: "+err.Error())
+	} else if infoEmisor.Generico {
+		errs = append(errs, "Emisor.Rfc: no puede ser un RFC genérico")
+	}
+
+	infoReceptor, err := validacion.ValidarRFC(comp.Receptor.Rfc)
+	if err != nil {
+		errs = append(errs, "Receptor.Rfc: "+err.Error())
+	}
+
+	clave(catalogos.FormaPago, comp.FormaPago, "FormaPago")
+	clave(catalogos.MetodoPago, comp.MetodoPago, "MetodoPago")
+	clave(catalogos.Moneda, comp.Moneda, "Moneda")
+	clave(catalogos.Exportacion, comp.Exportacion, "Exportacion")
+	clave(catalogos.TipoDeComprobante, comp.TipoDeComprobante, "TipoDeComprobante")
+	clave(catalogos.CodigoPostal, comp.LugarExpedicion, "LugarExpedicion")
+	clave(catalogos.CodigoPostal, comp.Receptor.DomicilioFiscalReceptor, "DomicilioFiscalReceptor")
+
+	if comp.MetodoPago == "PPD" && comp.FormaPago != formaPagoPorDefinir {
+		errs = append(errs, "FormaPago: con MetodoPago PPD debe ser 99 (Por definir)")
+	}
+	if comp.Moneda != monedaNacional && comp.Moneda != "XXX" && (comp.TipoCambio == "" || comp.TipoCambio == formatearDecimal(0, 6)) {
+		errs = append(errs, "TipoCambio: requerido cuando la moneda no es MXN")
+	}
+
+	if rel := comp.CfdiRelacionados; rel != nil {
+		clave(catalogos.TipoRelacion, rel.TipoRelacion, "CfdiRelacionados.TipoRelacion")
+	}
+
+	if infoEmisor != nil {
+		if ok, err := cat.RegimenAplica(comp.Emisor.RegimenFiscal, infoEmisor.Tipo); err != nil {
+			errs = append(errs, "Emisor.RegimenFiscal: "+err.Error())
+		} else if !ok {
+			errs = append(errs, "Emisor.RegimenFiscal: no aplica para persona "+infoEmisor.Tipo)
+		}
+	}
+
+	if infoReceptor != nil {
+		if infoReceptor.Generico {
+			if comp.Receptor.UsoCFDI != "S01" {
+				errs = append(errs, "Receptor.UsoCFDI: con RFC genérico debe ser S01")
+			}
+			if comp.Receptor.RegimenFiscalReceptor != "616" {
+				errs = append(errs, "Receptor.RegimenFiscalReceptor: con RFC genérico debe ser 616")
+			}
+			if comp.Receptor.DomicilioFiscalReceptor != comp.LugarExpedicion {
+				errs = append(errs, "Receptor.DomicilioFiscalReceptor: con RFC genérico debe ser igual a LugarExpedicion")
+			}
+		} else {
+			res, err := cat.ValidarUsoCFDI(comp.Receptor.UsoCFDI, comp.Receptor.RegimenFiscalReceptor, infoReceptor.Tipo)
+			if err != nil {
+				errs = append(errs, "Receptor.UsoCFDI: "+err.Error())
+			} else if !res.Permitido {
+				errs = append(errs, "Receptor.UsoCFDI: "+res.Motivo)
+			}
+		}
+	}
+
+	for i, con := range comp.Conceptos {
+		campo := fmt.Sprintf("Conceptos[%d]", i)
+		if con.Descripcion == "" {
+			errs = append(errs, campo+".Descripcion: requerida")
+		}
+		clave(catalogos.ClaveProdServ, con.ClaveProdServ, campo+".ClaveProdServ")
+		clave(catalogos.ClaveUnidad, con.ClaveUnidad, campo+".ClaveUnidad")
+		clave(catalogos.ObjetoImp, con.ObjetoImp, campo+".ObjetoImp")
+	}
+
+	if len(errs) > 0 {
+		return advertencias, errs
+	}
+	return advertencias, nil
+}
@@ -0,0 +1,105 @@
+// Package ratelimit limita los intentos fallidos por clave (IP, email, código
+// de licencia). Cada fallo por encima de los permitidos duplica la espera
+// hasta un máximo. El estado vive en un Store: en memoria para una sola
+// instancia o en Postgres cuando hay varias detrás del balanceador.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Store guarda los fallos por clave
+type Store interface {
+	// Fallo suma un fallo a la clave (reinicia la cuenta si el anterior fue
+	// hace más de ventana), aplica la espera que corresponde al total y la
+	// devuelve
+	Fallo(clave string, ventana time.Duration, espera func(fallos int) time.Duration) (time.Duration, error)
+	// Espera devuelve cuánto falta para que la clave vuelva a intentar
+	Espera(clave string) (time.Duration, error)
+	// Limpiar borra los fallos de la clave
+	Limpiar(clave string) error
+}
+
+// Regla define cuántos fallos se toleran y cómo crece la espera
+type Regla struct {
+	Libres  int           // Fallos sin espera
+	Base    time.Duration // Espera después del primer fallo no libre
+	Max     time.Duration // Tope de la espera
+	Ventana time.Duration // Sin fallos durante este tiempo la cuenta se reinicia
+}
+
+// espera es Base * 2^(fallos-Libres-1), con tope en Max
+func (r Regla) espera(fallos int) time.Duration {
+	n := fallos - r.Libres
+	if n <= 0 {
+		return 0
+	}
+	if n > 30 {
+		return r.Max
+	}
+	d := time.Duration(float64(r.Base) * math.Pow(2, float64(n-1)))
+	if d > r.Max {
+		return r.Max
+	}
+	return d
+}
+
+// Bloqueo es el error que devuelve Permitir mientras la clave está en espera
+type Bloqueo struct {
+	Espera time.Duration
+}
+
+func (b *Bloqueo) Error() string {
+	return fmt.Sprintf("demasiados intentos; intenta de nuevo en %s", formatoEspera(b.Espera))
+}
+
+// Segundos redondea hacia arriba, para el encabezado Retry-After
+func (b *Bloqueo) Segundos() int {
+	return int(math.Ceil(b.Espera.Seconds()))
+}
+
+func formatoEspera(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%d segundos", int(math.Ceil(d.Seconds())))
+	}
+	return fmt.Sprintf("%d minutos", int(math.Ceil(d.Minutes())))
+}
+
+// Limiter aplica una Regla a las claves de un mismo tipo; nombre evita que
+// choquen claves iguales de limitadores distintos en el mismo Store
+type Limiter struct {
+	store  Store
+	nombre string
+	regla  Regla
+}
+
+func New(store Store, nombre string, regla Regla) *Limiter {
+	return &Limiter{store: store, nombre: nombre, regla: regla}
+}
+
+func (l *Limiter) clave(clave string) string {
+	return l.nombre + ":" + clave
+}
+
+// Permitir devuelve *Bloqueo si la clave está en espera. Si el Store falla se
+// deja pasar: una caída de la base no debe impedir el login.
+func (l *Limiter) Permitir(clave string) error {
+	espera, err := l.store.Espera(l.clave(clave))
+	if err != nil || espera <= 0 {
+		return nil
+	}
+	return &Bloqueo{Espera: espera}
+}
+
+// Fallo registra un intento fallido
+func (l *Limiter) Fallo(clave string) error {
+	_, err := l.store.Fallo(l.clave(clave), l.regla.Ventana, l.regla.espera)
+	return err
+}
+
+// Limpiar borra los fallos de la clave (intento exitoso o desbloqueo manual)
+func (l *Limiter) Limpiar(clave string) error {
+	return l.store.Limpiar(l.clave(clave))
+}
@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// purgaMemoria es cada cuánto se descartan las claves sin fallos recientes
+const purgaMemoria = time.Minute
+
+type registro struct {
+	fallos int
+	ultimo time.Time
+	hasta  time.Time
+}
+
+// MemoriaStore guarda los fallos en el proceso; sólo sirve con una instancia
+type MemoriaStore struct {
+	mu          sync.Mutex
+	claves      map[string]*registro
+	ttl         time.Duration
+	ultimaPurga time.Time
+}
+
+// NewMemoriaStore crea el store; ttl es cuánto se conserva una clave después
+// de su último fallo y debe cubrir la ventana y la espera más largas
+func NewMemoriaStore(ttl time.Duration) *MemoriaStore {
+	return &MemoriaStore{claves: make(map[string]*registro), ttl: ttl}
+}
+
+func (s *MemoriaStore) Fallo(clave string, ventana time.Duration, espera func(fallos int) time.Duration) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ahora := time.Now()
+	s.purgar(ahora)
+
+	r, ok := s.claves[clave]
+	if !ok || ahora.Sub(r.ultimo) > ventana {
+		r = &registro{}
+		s.claves[clave] = r
+	}
+	r.fallos++
+	r.ultimo = ahora
+
+	d := espera(r.fallos)
+	if d > 0 {
+		r.hasta = ahora.Add(d)
+	}
+	return d, nil
+}
+
+func (s *MemoriaStore) Espera(clave string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.claves[clave]
+	if !ok {
+		return 0, nil
+	}
+	return time.Until(r.hasta), nil
+}
+
+func (s *MemoriaStore) Limpiar(clave string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.claves, clave)
+	return nil
+}
+
+func (s *MemoriaStore) purgar(ahora time.Time) {
+	if ahora.Sub(s.ultimaPurga) < purgaMemoria {
+		return
+	}
+	s.ultimaPurga = ahora
+	for clave, r := range s.claves {
+		if ahora.Sub(r.ultimo) > s.ttl && ahora.After(r.hasta) {
+			delete(s.claves, clave)
+		}
+	}
+}
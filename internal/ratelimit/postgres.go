@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// PostgresStore comparte los fallos entre instancias en la tabla rate_limits.
+// Los tiempos se calculan con el reloj de la base para no depender del de
+// cada instancia.
+type PostgresStore struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+// NewPostgresStore crea el store; ttl es cuánto se conserva una clave después
+// de su último fallo (ver Iniciar)
+func NewPostgresStore(db *sql.DB, ttl time.Duration) *PostgresStore {
+	return &PostgresStore{db: db, ttl: ttl}
+}
+
+func (s *PostgresStore) Fallo(clave string, ventana time.Duration, espera func(fallos int) time.Duration) (time.Duration, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var fallos int
+	err = tx.QueryRow(`
+        INSERT INTO rate_limits (clave, fallos, ultimo_fallo)
+        VALUES ($1, 1, CURRENT_TIMESTAMP)
+        ON CONFLICT (clave) DO UPDATE SET
+            fallos = CASE
+                WHEN rate_limits.ultimo_fallo < CURRENT_TIMESTAMP - $2 * INTERVAL '1 millisecond' THEN 1
+                ELSE rate_limits.fallos + 1
+            END,
+            ultimo_fallo = CURRENT_TIMESTAMP
+        RETURNING fallos
+    `, clave, ventana.Milliseconds()).Scan(&fallos)
+	if err != nil {
+		return 0, err
+	}
+
+	d := espera(fallos)
+	if d > 0 {
+		_, err = tx.Exec(`
+            UPDATE rate_limits SET bloqueado_hasta = CURRENT_TIMESTAMP + $2 * INTERVAL '1 millisecond'
+            WHERE clave = $1
+        `, clave, d.Milliseconds())
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return d, tx.Commit()
+}
+
+func (s *PostgresStore) Espera(clave string) (time.Duration, error) {
+	var segundos float64
+	err := s.db.QueryRow(`
+        SELECT EXTRACT(EPOCH FROM (bloqueado_hasta - CURRENT_TIMESTAMP))
+        FROM rate_limits
+        WHERE clave = $1 AND bloqueado_hasta > CURRENT_TIMESTAMP
+    `, clave).Scan(&segundos)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(segundos * float64(time.Second)), nil
+}
+
+func (s *PostgresStore) Limpiar(clave string) error {
+	_, err := s.db.Exec(`DELETE FROM rate_limits WHERE clave = $1`, clave)
+	return err
+}
+
+// Iniciar borra cada intervalo las claves sin fallos recientes hasta que se
+// cancele el contexto
+func (s *PostgresStore) Iniciar(ctx context.Context, intervalo time.Duration) {
+	ticker := time.NewTicker(intervalo)
+	defer ticker.Stop()
+
+	for {
+		_, err := s.db.Exec(`
+            DELETE FROM rate_limits
+            WHERE ultimo_fallo < CURRENT_TIMESTAMP - $1 * INTERVAL '1 millisecond'
+              AND (bloqueado_hasta IS NULL OR bloqueado_hasta < CURRENT_TIMESTAMP)
+        `, s.ttl.Milliseconds())
+		if err != nil {
+			log.Println("⚠️  Error purgando rate_limits:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
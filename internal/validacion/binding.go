@@ -0,0 +1,28 @@
+// internal/validacion/binding.go
+package validacion
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// RegistrarValidadores agrega las etiquetas `rfc` y `curp` al validador de gin,
+// p. ej. `binding:"omitempty,rfc"`
+func RegistrarValidadores() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("motor de validación no soportado")
+	}
+
+	if err := v.RegisterValidation("rfc", func(fl validator.FieldLevel) bool {
+		return EsRFCValido(fl.Field().String())
+	}); err != nil {
+		return err
+	}
+
+	return v.RegisterValidation("curp", func(fl validator.FieldLevel) bool {
+		return EsCURPValida(fl.Field().String())
+	})
+}
@@ -0,0 +1,97 @@
+// internal/validacion/curp.go
+package validacion
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCURPFormato = errors.New("CURP con formato inválido")
+	ErrCURPFecha   = errors.New("CURP con fecha de nacimiento inválida")
+	ErrCURPEstado  = errors.New("CURP con entidad federativa inválida")
+	ErrCURPDigito  = errors.New("CURP con dígito verificador inválido")
+)
+
+// curpAlfabeto es la tabla de RENAPO para el cálculo del dígito verificador
+const curpAlfabeto = "0123456789ABCDEFGHIJKLMNÑOPQRSTUVWXYZ"
+
+var curpRegex = regexp.MustCompile(`^[A-Z][AEIOUX][A-Z]{2}[0-9]{6}[HMX][A-Z]{2}[B-DF-HJ-NP-TV-Z]{3}[A-Z0-9][0-9]$`)
+
+// EntidadesCURP son las claves de entidad federativa válidas en la CURP (NE = nacido en el extranjero)
+var EntidadesCURP = map[string]string{
+	"AS": "Aguascalientes", "BC": "Baja California", "BS": "Baja California Sur",
+	"CC": "Campeche", "CL": "Coahuila", "CM": "Colima", "CS": "Chiapas",
+	"CH": "Chihuahua", "DF": "Ciudad de México", "DG": "Durango",
+	"GT": "Guanajuato", "GR": "Guerrero", "HG": "Hidalgo", "JC": "Jalisco",
+	"MC": "Estado de México", "MN": "Michoacán", "MS": "Morelos", "NT": "Nayarit",
+	"NL": "Nuevo León", "OC": "Oaxaca", "PL": "Puebla", "QT": "Querétaro",
+	"QR": "Quintana Roo", "SP": "San Luis Potosí", "SL": "Sinaloa", "SR": "Sonora",
+	"TC": "Tabasco", "TS": "Tamaulipas", "TL": "Tlaxcala", "VZ": "Veracruz",
+	"YN": "Yucatán", "ZS": "Zacatecas", "NE": "Nacido en el extranjero",
+}
+
+// InfoCURP describe una CURP ya validada
+type InfoCURP struct {
+	CURP            string `json:"curp"`
+	FechaNacimiento string `json:"fecha_nacimiento"`
+	Sexo            string `json:"sexo"`
+	Entidad         string `json:"entidad"`
+}
+
+// NormalizarCURP quita espacios y convierte a mayúsculas
+func NormalizarCURP(curp string) string {
+	return strings.ReplaceAll(strings.ToUpper(strings.TrimSpace(curp)), " ", "")
+}
+
+// ValidarCURP valida estructura, fecha de nacimiento, entidad y dígito verificador
+func ValidarCURP(curp string) (*InfoCURP, error) {
+	curp = NormalizarCURP(curp)
+	if !curpRegex.MatchString(curp) {
+		return nil, ErrCURPFormato
+	}
+
+	// El carácter 17 distingue el siglo: dígito para nacidos antes de 2000, letra a partir de 2000
+	siglo := "19"
+	if curp[16] >= 'A' && curp[16] <= 'Z' {
+		siglo = "20"
+	}
+	fecha, err := time.Parse("20060102", siglo+curp[4:10])
+	if err != nil {
+		return nil, ErrCURPFecha
+	}
+
+	entidad, ok := EntidadesCURP[curp[11:13]]
+	if !ok {
+		return nil, ErrCURPEstado
+	}
+
+	if DigitoVerificadorCURP(curp[:17]) != rune(curp[17]) {
+		return nil, ErrCURPDigito
+	}
+
+	return &InfoCURP{
+		CURP:            curp,
+		FechaNacimiento: fecha.Format("2006-01-02"),
+		Sexo:            curp[10:11],
+		Entidad:         entidad,
+	}, nil
+}
+
+// EsCURPValida es un atajo de ValidarCURP para cuando sólo interesa el resultado
+func EsCURPValida(curp string) bool {
+	_, err := ValidarCURP(curp)
+	return err == nil
+}
+
+// DigitoVerificadorCURP calcula el dígito verificador sobre los primeros 17 caracteres
+func DigitoVerificadorCURP(base string) rune {
+	alfabeto := []rune(curpAlfabeto)
+	suma := 0
+	for i, r := range []rune(base) {
+		suma += indiceRune(alfabeto, r) * (18 - i)
+	}
+	return rune('0' + (10-suma%10)%10)
+}
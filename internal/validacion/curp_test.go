@@ -0,0 +1,53 @@
+package validacion
+
+import "testing"
+
+func TestValidarCURP(t *testing.T) {
+	casos := []struct {
+		curp    string
+		fecha   string
+		sexo    string
+		entidad string
+		err     error
+	}{
+		// Ejemplo del instructivo de RENAPO
+		{curp: "HEGG560427MVZRRL04", fecha: "1956-04-27", sexo: "M", entidad: "Veracruz"},
+		{curp: "MAAR790213HMNRLF03", fecha: "1979-02-13", sexo: "H", entidad: "Michoacán"},
+		{curp: " hegg560427mvzrrl04 ", fecha: "1956-04-27", sexo: "M", entidad: "Veracruz"},
+		// Nacidos a partir de 2000 llevan una letra en la posición 17
+		{curp: "LOPA050310HDFPRNA" + string(DigitoVerificadorCURP("LOPA050310HDFPRNA")), fecha: "2005-03-10", sexo: "H", entidad: "Ciudad de México"},
+
+		{curp: "HEGG560427MVZRRL05", err: ErrCURPDigito},
+		{curp: "MAAR790213HMNRLF13", err: ErrCURPDigito},
+		{curp: "HEGG561327MVZRRL04", err: ErrCURPFecha},
+		{curp: "HEGG560427MXXRRL04", err: ErrCURPEstado},
+		{curp: "HEGG560427ZVZRRL04", err: ErrCURPFormato},
+		{curp: "HEGG560427MVZRRL0", err: ErrCURPFormato},
+	}
+
+	for _, c := range casos {
+		info, err := ValidarCURP(c.curp)
+		if err != c.err {
+			t.Errorf("ValidarCURP(%q) error = %v, se esperaba %v", c.curp, err, c.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if info.FechaNacimiento != c.fecha || info.Sexo != c.sexo || info.Entidad != c.entidad {
+			t.Errorf("ValidarCURP(%q) = %+v", c.curp, info)
+		}
+	}
+}
+
+func TestDigitoVerificadorCURP(t *testing.T) {
+	casos := map[string]rune{
+		"HEGG560427MVZRRL0": '4',
+		"MAAR790213HMNRLF0": '3',
+	}
+	for base, digito := range casos {
+		if d := DigitoVerificadorCURP(base); d != digito {
+			t.Errorf("DigitoVerificadorCURP(%q) = %c, se esperaba %c", base, d, digito)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// internal/validacion/handler.go
+package validacion
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler expone la validación de RFC y CURP por HTTP
+type Handler struct{}
+
+// NewHandler crea una nueva instancia del handler
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+// ValidarRFC valida un RFC recibido en la ruta
+// @Summary Valida un RFC
+// @Tags validacion
+// @Produce json
+// @Param rfc path string true "RFC a validar"
+// @Router /validacion/rfc/{rfc} [get]
+func (h *Handler) ValidarRFC(c *gin.Context) {
+	info, err := ValidarRFC(c.Param("rfc"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    gin.H{"valido": false, "error": err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"valido": true, "rfc": info},
+	})
+}
+
+// ValidarCURP valida una CURP recibida en la ruta
+// @Summary Valida una CURP
+// @Tags validacion
+// @Produce json
+// @Param curp path string true "CURP a validar"
+// @Router /validacion/curp/{curp} [get]
+func (h *Handler) ValidarCURP(c *gin.Context) {
+	info, err := ValidarCURP(c.Param("curp"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    gin.H{"valido": false, "error": err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"valido": true, "curp": info},
+	})
+}
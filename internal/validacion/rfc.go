@@ -0,0 +1,132 @@
+// internal/validacion/rfc.go
+package validacion
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrRFCFormato = errors.New("RFC con formato inválido")
+	ErrRFCFecha   = errors.New("RFC con fecha inválida")
+	ErrRFCDigito  = errors.New("RFC con dígito verificador inválido")
+)
+
+const (
+	TipoPersonaFisica = "fisica"
+	TipoPersonaMoral  = "moral"
+
+	// RFCGenericoNacional se usa para operaciones con el público en general
+	RFCGenericoNacional = "XAXX010101000"
+	// RFCGenericoExtranjero se usa para residentes en el extranjero
+	RFCGenericoExtranjero = "XEXX010101000"
+)
+
+// rfcAlfabeto es la tabla de valores del SAT para el cálculo del dígito verificador
+const rfcAlfabeto = "0123456789ABCDEFGHIJKLMN&OPQRSTUVWXYZ Ñ"
+
+var (
+	rfcFisicaRegex = regexp.MustCompile(`^[A-ZÑ&]{4}[0-9]{6}[A-Z0-9]{2}[0-9A]$`)
+	rfcMoralRegex  = regexp.MustCompile(`^[A-ZÑ&]{3}[0-9]{6}[A-Z0-9]{2}[0-9A]$`)
+)
+
+// InfoRFC describe un RFC ya validado
+type InfoRFC struct {
+	RFC      string `json:"rfc"`
+	Tipo     string `json:"tipo"`
+	Generico bool   `json:"generico"`
+	Fecha    string `json:"fecha,omitempty"`
+}
+
+// NormalizarRFC quita espacios y guiones y convierte a mayúsculas
+func NormalizarRFC(rfc string) string {
+	rfc = strings.ToUpper(strings.TrimSpace(rfc))
+	rfc = strings.ReplaceAll(rfc, "-", "")
+	return strings.ReplaceAll(rfc, " ", "")
+}
+
+// EsRFCGenerico indica si el RFC es uno de los genéricos del SAT
+func EsRFCGenerico(rfc string) bool {
+	rfc = NormalizarRFC(rfc)
+	return rfc == RFCGenericoNacional || rfc == RFCGenericoExtranjero
+}
+
+// ValidarRFC valida formato, fecha y homoclave de un RFC de persona física o moral.
+// Los RFC genéricos se aceptan sin verificar el dígito.
+func ValidarRFC(rfc string) (*InfoRFC, error) {
+	rfc = NormalizarRFC(rfc)
+
+	if EsRFCGenerico(rfc) {
+		return &InfoRFC{RFC: rfc, Tipo: TipoPersonaFisica, Generico: true}, nil
+	}
+
+	var tipo string
+	switch {
+	case rfcFisicaRegex.MatchString(rfc):
+		tipo = TipoPersonaFisica
+	case rfcMoralRegex.MatchString(rfc):
+		tipo = TipoPersonaMoral
+	default:
+		return nil, ErrRFCFormato
+	}
+
+	runes := []rune(rfc)
+	inicioFecha := len(runes) - 9
+	fecha, ok := parseFechaAAMMDD(string(runes[inicioFecha : inicioFecha+6]))
+	if !ok {
+		return nil, ErrRFCFecha
+	}
+
+	if DigitoVerificadorRFC(string(runes[:len(runes)-1])) != runes[len(runes)-1] {
+		return nil, ErrRFCDigito
+	}
+
+	return &InfoRFC{RFC: rfc, Tipo: tipo, Fecha: fecha}, nil
+}
+
+// EsRFCValido es un atajo de ValidarRFC para cuando sólo interesa el resultado
+func EsRFCValido(rfc string) bool {
+	_, err := ValidarRFC(rfc)
+	return err == nil
+}
+
+// DigitoVerificadorRFC calcula el dígito verificador de un RFC sin su último carácter
+// (11 caracteres para personas morales, 12 para físicas).
+func DigitoVerificadorRFC(base string) rune {
+	runes := []rune(base)
+	// Las personas morales se completan con un espacio a la izquierda
+	for len(runes) < 12 {
+		runes = append([]rune{' '}, runes...)
+	}
+
+	alfabeto := []rune(rfcAlfabeto)
+	suma := 0
+	for i, r := range runes {
+		suma += indiceRune(alfabeto, r) * (13 - i)
+	}
+
+	return alfabeto[(11-suma%11)%11]
+}
+
+// parseFechaAAMMDD valida una fecha AAMMDD. Como el siglo es ambiguo, se
+// prefiere 20xx salvo que quede en el futuro, y se acepta 19xx en otro caso.
+func parseFechaAAMMDD(s string) (string, bool) {
+	if t, err := time.Parse("20060102", "20"+s); err == nil && !t.After(time.Now()) {
+		return t.Format("2006-01-02"), true
+	}
+	if t, err := time.Parse("20060102", "19"+s); err == nil {
+		return t.Format("2006-01-02"), true
+	}
+	return "", false
+}
+
+func indiceRune(alfabeto []rune, r rune) int {
+	for i, a := range alfabeto {
+		if a == r {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,72 @@
+package validacion
+
+import "testing"
+
+func TestValidarRFC(t *testing.T) {
+	casos := []struct {
+		rfc   string
+		tipo  string
+		fecha string
+		err   error
+	}{
+		// RFC de prueba publicados por el SAT
+		{rfc: "EKU9003173C9", tipo: TipoPersonaMoral, fecha: "1990-03-17"},
+		{rfc: "XIA190128J61", tipo: TipoPersonaMoral, fecha: "2019-01-28"},
+		{rfc: "IIA040805DZ4", tipo: TipoPersonaMoral, fecha: "2004-08-05"},
+		{rfc: "GODE561231GR8", tipo: TipoPersonaFisica, fecha: "1956-12-31"},
+		{rfc: "CACX7605101P8", tipo: TipoPersonaFisica, fecha: "1976-05-10"},
+		{rfc: " gode-561231-gr8 ", tipo: TipoPersonaFisica, fecha: "1956-12-31"},
+
+		{rfc: "EKU9003173C8", err: ErrRFCDigito},
+		{rfc: "GODE561231GR9", err: ErrRFCDigito},
+		{rfc: "GODE561331GR8", err: ErrRFCFecha},
+		{rfc: "EKU900230AB1", err: ErrRFCFecha},
+		{rfc: "EK9003173C9", err: ErrRFCFormato},
+		{rfc: "GODE561231GR", err: ErrRFCFormato},
+		{rfc: "", err: ErrRFCFormato},
+	}
+
+	for _, c := range casos {
+		info, err := ValidarRFC(c.rfc)
+		if err != c.err {
+			t.Errorf("ValidarRFC(%q) error = %v, se esperaba %v", c.rfc, err, c.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if info.Tipo != c.tipo || info.Fecha != c.fecha || info.Generico {
+			t.Errorf("ValidarRFC(%q) = %+v, se esperaba tipo %s y fecha %s", c.rfc, info, c.tipo, c.fecha)
+		}
+	}
+}
+
+func TestValidarRFCGenerico(t *testing.T) {
+	for _, rfc := range []string{RFCGenericoNacional, RFCGenericoExtranjero, "xaxx010101000"} {
+		info, err := ValidarRFC(rfc)
+		if err != nil {
+			t.Fatalf("ValidarRFC(%q): %v", rfc, err)
+		}
+		if !info.Generico || !EsRFCGenerico(rfc) {
+			t.Errorf("ValidarRFC(%q) no se marcó como genérico", rfc)
+		}
+	}
+
+	if EsRFCGenerico("EKU9003173C9") {
+		t.Error("EKU9003173C9 no es un RFC genérico")
+	}
+}
+
+func TestDigitoVerificadorRFC(t *testing.T) {
+	casos := map[string]rune{
+		"EKU9003173C":  '9',
+		"XIA190128J6":  '1',
+		"GODE561231GR": '8',
+		"KICR630120NX": '3',
+	}
+	for base, digito := range casos {
+		if d := DigitoVerificadorRFC(base); d != digito {
+			t.Errorf("DigitoVerificadorRFC(%q) = %c, se esperaba %c", base, d, digito)
+		}
+	}
+}